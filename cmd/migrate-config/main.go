@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 
@@ -19,41 +20,128 @@ func main() {
 			printUsage()
 			os.Exit(1)
 		}
-		inputFile := os.Args[2]
-		outputFile := os.Args[3]
+		input := os.Args[2]
+		output := os.Args[3]
 
-		if err := migration.MigrateConfigFile(inputFile, outputFile); err != nil {
+		var result *migration.Result
+		var err error
+		if isDir(input) {
+			result, err = migration.MigrateDirectory(input, output)
+		} else {
+			result, err = migration.MigrateConfigFile(input, output)
+		}
+		if err != nil {
 			log.Fatalf("Migration failed: %v", err)
 		}
 
 		log.Println("Configuration migrated successfully!")
-		log.Printf("Input:  %s", inputFile)
-		log.Printf("Output: %s", outputFile)
-		log.Println("\nPlease review the output file and test the configuration before applying.")
+		log.Printf("Input:  %s", input)
+		log.Printf("Output: %s", output)
+		if len(result.Moved) > 0 {
+			log.Printf("\nConverted %d resource(s); moved blocks keep their state:", len(result.Moved))
+			for _, moved := range result.Moved {
+				log.Printf("  %s: %s → %s", moved.File, moved.From, moved.To)
+			}
+		}
+		printIssues("\nResources left for manual migration", result.Issues)
+		log.Println("\nPlease review the output and run terraform plan before applying.")
 
 	case "validate":
 		if len(os.Args) < 3 {
 			printUsage()
 			os.Exit(1)
 		}
-		inputFile := os.Args[2]
+		input := os.Args[2]
 
-		issues, err := migration.ValidateConfigFile(inputFile)
+		var issues []migration.ValidationIssue
+		var err error
+		if isDir(input) {
+			issues, err = migration.ValidateDirectory(input)
+		} else {
+			issues, err = migration.ValidateConfigFile(input)
+		}
 		if err != nil {
 			log.Fatalf("Validation failed: %v", err)
 		}
 
 		if len(issues) == 0 {
-			log.Printf(" Configuration %s is compatible with the new architecture.", inputFile)
+			log.Printf(" Configuration %s is compatible with the new architecture.", input)
 		} else {
-			log.Printf(" Found %d compatibility issue(s) in %s:\n", len(issues), inputFile)
-			for i, issue := range issues {
-				log.Printf("%d. %s", i+1, issue.Description)
-				if issue.Suggestion != "" {
-					log.Printf("   Suggestion: %s", issue.Suggestion)
-				}
+			printIssues(" Compatibility issues in "+input, issues)
+		}
+
+	case "generate":
+		flags := flag.NewFlagSet("generate", flag.ExitOnError)
+		layout := flags.String("layout", string(migration.LayoutAuto), "repository layout: auto, plain, dotted or stow")
+		name := flags.String("name", "", "name of the dotfiles_repository resource (default \"dotfiles\")")
+		source := flags.String("source", "", "source_path of the repository, e.g. a Git URL (default: the scanned path)")
+		home := flags.String("home", "", "home directory checked for targets already in place (default: $HOME)")
+		if err := flags.Parse(os.Args[2:]); err != nil || flags.NArg() != 2 {
+			printUsage()
+			os.Exit(1)
+		}
+		repository := flags.Arg(0)
+		output := flags.Arg(1)
+
+		result, err := migration.GenerateFromRepository(migration.GenerateOptions{
+			RepositoryPath: repository,
+			RepositoryName: *name,
+			SourcePath:     *source,
+			Layout:         migration.Layout(*layout),
+			HomeDir:        *home,
+		}, output)
+		if err != nil {
+			log.Fatalf("Generation failed: %v", err)
+		}
+
+		imported := 0
+		for _, resource := range result.Resources {
+			if resource.Imported {
+				imported++
+			}
+		}
+		log.Println("Configuration generated successfully!")
+		log.Printf("Repository: %s (%s layout)", repository, result.Layout)
+		log.Printf("Output:     %s", output)
+		log.Printf("\nManaging %d file(s); %d target(s) already in place get an import block:", len(result.Resources), imported)
+		for _, resource := range result.Resources {
+			marker := " "
+			if resource.Imported {
+				marker = "*"
 			}
+			log.Printf(" %s %s: %s → %s", marker, resource.Address, resource.Source, resource.Target)
 		}
+		log.Println("\nPlease review the output and run terraform plan before applying.")
+
+	case "import-chezmoi":
+		flags := flag.NewFlagSet("import-chezmoi", flag.ExitOnError)
+		name := flags.String("name", "", "name of the dotfiles_repository resource (default \"dotfiles\")")
+		source := flags.String("source", "", "source_path of the repository, e.g. a Git URL (default: the source directory)")
+		if err := flags.Parse(os.Args[2:]); err != nil || flags.NArg() != 2 {
+			printUsage()
+			os.Exit(1)
+		}
+		sourceDir := flags.Arg(0)
+		output := flags.Arg(1)
+
+		result, err := migration.ImportChezmoiDirectory(migration.ChezmoiOptions{
+			SourceDir:      sourceDir,
+			RepositoryName: *name,
+			SourcePath:     *source,
+		}, output)
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+
+		log.Println("chezmoi source state translated successfully!")
+		log.Printf("Source: %s", sourceDir)
+		log.Printf("Output: %s", output)
+		log.Printf("\nGenerated %d resource(s):", len(result.Resources))
+		for _, resource := range result.Resources {
+			log.Printf("  %s → %s", resource.Address, resource.Target)
+		}
+		printIssues("\nSource state left for manual migration", result.Issues)
+		log.Println("\nPlease review the output and run terraform plan before applying.")
 
 	default:
 		printUsage()
@@ -61,6 +149,28 @@ func main() {
 	}
 }
 
+func printIssues(heading string, issues []migration.ValidationIssue) {
+	if len(issues) == 0 {
+		return
+	}
+	log.Printf("%s (%d):\n", heading, len(issues))
+	for i, issue := range issues {
+		if issue.LineNumber > 0 {
+			log.Printf("%d. %s:%d: %s", i+1, issue.File, issue.LineNumber, issue.Description)
+		} else {
+			log.Printf("%d. %s: %s", i+1, issue.File, issue.Description)
+		}
+		if issue.Suggestion != "" {
+			log.Printf("   Suggestion: %s", issue.Suggestion)
+		}
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
 func printUsage() {
 	log.Printf(`terraform-provider-dotfiles Migration Tool
 
@@ -68,18 +178,50 @@ This tool helps migrate Terraform configurations to the new explicit resource ar
 where the strategy field has been removed from dotfiles_file resource.
 
 Usage:
-  %s migrate <input.tf> <output.tf>     Migrate configuration file
-  %s validate <input.tf>                Check for compatibility issues
+  %s migrate <input> <output>     Migrate a configuration file or module directory
+  %s validate <input>             Check a file or module directory for compatibility issues
+  %s generate [options] <repository> <output>
+                                  Generate configuration for an existing dotfiles repository
+  %s import-chezmoi [options] <source-dir> <output>
+                                  Translate a chezmoi source directory into resources
 
 Examples:
   %s migrate main.tf main-migrated.tf
-  %s validate main.tf
+  %s migrate ./dotfiles ./dotfiles       Migrate every .tf file of a module in place
+  %s validate ./dotfiles
+  %s generate -source git@github.com:me/dotfiles.git ~/dotfiles dotfiles.tf
+  %s import-chezmoi ~/.local/share/chezmoi dotfiles.tf
 
 Migration Details:
-- dotfiles_file resources with strategy="symlink" → dotfiles_symlink resources
+- dotfiles_file resources with strategy="symlink" → dotfiles_symlink resources, with a moved
+  block so existing state follows the resource; references in every file are updated
 - dotfiles_file resources with strategy="copy" → dotfiles_file resources (strategy field removed)
 - dotfiles_file resources with strategy="template" → dotfiles_file resources (converted to is_template=true)
+- Formatting and comments are preserved; migrated resources are annotated with comments
 - Complex patterns with multiple strategies → dotfiles_application resources
 
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+Generate Options:
+  -layout auto|plain|dotted|stow  How repository files map to the home directory (default auto):
+                                  plain: vimrc → ~/.vimrc, dotted: .vimrc → ~/.vimrc,
+                                  stow: vim/.vimrc → ~/.vimrc with one package per application
+  -name <name>                    Name of the dotfiles_repository resource (default dotfiles)
+  -source <path or URL>           source_path of the repository (default: the scanned path)
+  -home <dir>                     Home directory checked for existing targets (default $HOME)
+
+Generated resources: files → dotfiles_symlink, templates (.tmpl, .tpl, .gotmpl, .hbs,
+.handlebars, .mustache) → dotfiles_file with is_template = true, directories and each
+~/.config entry → dotfiles_directory, applications with several files → dotfiles_application.
+Targets already in place get an import block.
+
+Import-chezmoi Options:
+  -name <name>                    Name of the dotfiles_repository resource (default dotfiles)
+  -source <path or URL>           source_path of the repository (default: the source directory)
+
+chezmoi files become dotfiles_file resources: dot_ → leading dot, private_ → 0600,
+executable_ → 0755, readonly_ → no write bits, .tmpl → is_template with the chezmoi template
+engine and .chezmoidata.json as template_vars, create_ → ignore_changes = all. Private and
+read-only directories get dotfiles_file_permissions. Scripts, symlinks, encrypted files,
+externals, modify_/remove_ entries and unsupported template functions are reported.
+
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
@@ -0,0 +1,507 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package configmerge merges fragments into structured JSON, YAML and TOML configuration
+// files that applications also write to, touching only the keys a fragment sets.
+package configmerge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies a structured configuration file format.
+type Format string
+
+// Supported formats.
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatJSON, FormatYAML, FormatTOML}
+
+// DetectFormat returns the format of a file from its extension.
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".jsonc":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("cannot detect the format of %s: expected a .json, .yaml, .yml or .toml extension", path)
+}
+
+// Document is a parsed configuration file whose top level is an object. Key order and
+// comments are kept for JSON and YAML, and survive a merge. TOML files are written with
+// sorted keys and without comments; see DropsComments.
+type Document struct {
+	format Format
+	root   *yaml.Node
+	// doc is the YAML document node holding root, which carries document-level comments.
+	doc *yaml.Node
+	// trailer holds JSON comments after the top-level object.
+	trailer string
+	// comments is set when a TOML file has comments.
+	comments bool
+	indent   string
+}
+
+// Parse parses data in the given format. Empty data is an empty document.
+func Parse(format Format, data []byte) (*Document, error) {
+	d := &Document{format: format, indent: detectIndent(data)}
+	var err error
+	switch format {
+	case FormatJSON:
+		d.root, d.trailer, err = decodeJSON(data)
+	case FormatYAML:
+		d.root, d.doc, err = decodeYAML(data)
+	case FormatTOML:
+		var values map[string]interface{}
+		if err = toml.Unmarshal(data, &values); err == nil {
+			d.root, err = valueNode(values)
+			d.comments = hasTOMLComments(data)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", strings.ToUpper(string(format)), err)
+	}
+	return d, nil
+}
+
+// FromValue returns a document holding values, such as a fragment given as an object in
+// the configuration. Keys are sorted.
+func FromValue(values map[string]interface{}) (*Document, error) {
+	root, err := valueNode(values)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{root: root}, nil
+}
+
+// Format returns the format the document was parsed from.
+func (d *Document) Format() Format {
+	return d.format
+}
+
+// Value returns the document as plain Go values.
+func (d *Document) Value() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := d.root.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// DropsComments reports whether Encode loses comments of the parsed file, which is the
+// case for TOML files with comments.
+func (d *Document) DropsComments() bool {
+	return d.format == FormatTOML && d.comments
+}
+
+// Encode returns the document in its format.
+func (d *Document) Encode() ([]byte, error) {
+	switch d.format {
+	case FormatJSON:
+		var buf bytes.Buffer
+		indent := d.jsonIndent()
+		writeJSONComments(&buf, d.root.HeadComment, indent, 0)
+		if err := encodeJSON(&buf, d.root, indent, 0); err != nil {
+			return nil, err
+		}
+		if d.root.LineComment != "" {
+			buf.WriteString(" " + d.root.LineComment)
+		}
+		buf.WriteByte('\n')
+		writeJSONComments(&buf, d.trailer, indent, 0)
+		return buf.Bytes(), nil
+	case FormatYAML:
+		node := d.root
+		if d.doc != nil {
+			node = d.doc
+		}
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(d.yamlIndent())
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatTOML:
+		values, err := d.Value()
+		if err != nil {
+			return nil, err
+		}
+		return toml.Marshal(values)
+	}
+	return nil, fmt.Errorf("unsupported format %q", d.format)
+}
+
+func (d *Document) jsonIndent() string {
+	if d.indent == "" {
+		return "  "
+	}
+	return d.indent
+}
+
+func (d *Document) yamlIndent() int {
+	if d.indent == "" || strings.Contains(d.indent, "\t") {
+		return 2
+	}
+	return len(d.indent)
+}
+
+// detectIndent returns the leading whitespace of the first indented line.
+func detectIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return ""
+}
+
+// decodeYAML parses a YAML document whose top level is a mapping.
+func decodeYAML(data []byte) (*yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return mappingNode(), nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		if root.ShortTag() == "!!null" {
+			doc.Content[0] = mappingNode()
+			return doc.Content[0], &doc, nil
+		}
+		return nil, nil, fmt.Errorf("top level must be a mapping")
+	}
+	return root, &doc, nil
+}
+
+// decodeJSON parses a JSON object, keeping key order. Comments and trailing commas, as
+// found in editor settings files, are accepted. Comments are attached to the nodes they
+// precede, or follow on the same line, and comments after the object are returned.
+func decodeJSON(data []byte) (*yaml.Node, string, error) {
+	blanked, comments := blankJSONComments(data)
+	if len(bytes.TrimSpace(blanked)) == 0 {
+		root := mappingNode()
+		for _, comment := range comments {
+			root.HeadComment = joinComment(root.HeadComment, comment.text, "\n")
+		}
+		return root, "", nil
+	}
+
+	d := &jsonDecoder{Decoder: json.NewDecoder(bytes.NewReader(blanked)), source: data, data: blanked, comments: comments}
+	d.UseNumber()
+	head := d.attach(nil, 0)
+	root, err := d.value()
+	if err != nil {
+		return nil, "", err
+	}
+	root.HeadComment = head
+	end := int(d.InputOffset())
+	if _, err := d.Token(); err != io.EOF {
+		return nil, "", fmt.Errorf("unexpected content after the top-level object")
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, "", fmt.Errorf("top level must be an object")
+	}
+	return root, d.attach(root, end), nil
+}
+
+// jsonComment is a comment at [start, end) of a JSON source.
+type jsonComment struct {
+	start, end int
+	text       string
+}
+
+// jsonDecoder decodes JSON whose comments were blanked out, attaching the comments to the
+// decoded nodes.
+type jsonDecoder struct {
+	*json.Decoder
+	// source is the original input and data the input with comments blanked out
+	source, data []byte
+	// comments not yet attached, in order
+	comments []jsonComment
+}
+
+func (d *jsonDecoder) value() (*yaml.Node, error) {
+	token, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		node := mappingNode()
+		if value == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		var previous *yaml.Node
+		end := int(d.InputOffset())
+		for d.More() {
+			head := d.attach(previous, end)
+			if node.Kind == yaml.MappingNode {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				keyNode := stringNode(key.(string))
+				// Comments between a key and its value move above the key
+				keyNode.HeadComment = joinComment(head, d.attach(nil, int(d.InputOffset())), "\n")
+				node.Content = append(node.Content, keyNode)
+			}
+			child, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			if node.Kind == yaml.SequenceNode {
+				child.HeadComment = head
+			}
+			node.Content = append(node.Content, child)
+			previous, end = child, int(d.InputOffset())
+		}
+		node.FootComment = d.attach(previous, end)
+		// Closing delimiter
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return stringNode(value), nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// attach takes the comments before the next token. Those on the same line as previous,
+// which ends at offset end, become its line comment; the others are returned.
+func (d *jsonDecoder) attach(previous *yaml.Node, end int) string {
+	next := int(d.InputOffset())
+	for next < len(d.data) && strings.IndexByte(" \t\r\n,:", d.data[next]) >= 0 {
+		next++
+	}
+
+	var head string
+	for len(d.comments) > 0 && d.comments[0].start < next {
+		comment := d.comments[0]
+		d.comments = d.comments[1:]
+		if previous != nil && comment.start >= end && !bytes.ContainsRune(d.source[end:comment.start], '\n') {
+			previous.LineComment = joinComment(previous.LineComment, comment.text, " ")
+			continue
+		}
+		head = joinComment(head, comment.text, "\n")
+	}
+	return head
+}
+
+func joinComment(comments, comment, separator string) string {
+	if comments == "" || comment == "" {
+		return comments + comment
+	}
+	return comments + separator + comment
+}
+
+// encodeJSON writes node as indented JSON.
+func encodeJSON(w *bytes.Buffer, node *yaml.Node, indent string, level int) error {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close := "{", "}"
+		if node.Kind == yaml.SequenceNode {
+			open, close = "[", "]"
+		}
+		if len(node.Content) == 0 && node.FootComment == "" {
+			w.WriteString(open + close)
+			return nil
+		}
+		w.WriteString(open + "\n")
+		step := 1
+		if node.Kind == yaml.MappingNode {
+			step = 2
+		}
+		for i := 0; i < len(node.Content); i += step {
+			value := node.Content[i+step-1]
+			writeJSONComments(w, node.Content[i].HeadComment, indent, level+1)
+			w.WriteString(strings.Repeat(indent, level+1))
+			if node.Kind == yaml.MappingNode {
+				writeJSONString(w, node.Content[i].Value)
+				w.WriteString(": ")
+			}
+			if err := encodeJSON(w, value, indent, level+1); err != nil {
+				return err
+			}
+			if i+step < len(node.Content) {
+				w.WriteByte(',')
+			}
+			if value.LineComment != "" {
+				w.WriteString(" " + value.LineComment)
+			}
+			w.WriteByte('\n')
+		}
+		writeJSONComments(w, node.FootComment, indent, level+1)
+		w.WriteString(strings.Repeat(indent, level) + close)
+		return nil
+	case yaml.AliasNode:
+		return encodeJSON(w, node.Alias, indent, level)
+	}
+
+	switch node.ShortTag() {
+	case "!!str", "!!timestamp", "!!binary":
+		writeJSONString(w, node.Value)
+		return nil
+	case "!!null":
+		w.WriteString("null")
+		return nil
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot write %q as JSON: %w", node.Value, err)
+	}
+	w.Write(encoded)
+	return nil
+}
+
+func writeJSONString(w *bytes.Buffer, s string) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// writeJSONComments writes comments on lines of their own at the given level. Continuation
+// lines of block comments are written as they were.
+func writeJSONComments(w *bytes.Buffer, comments string, indent string, level int) {
+	if comments == "" {
+		return
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") {
+			line = strings.Repeat(indent, level) + trimmed
+		}
+		w.WriteString(line + "\n")
+	}
+}
+
+// blankJSONComments returns data with // and /* */ comments, and trailing commas before
+// closing brackets, replaced by spaces, leaving string contents and line breaks untouched
+// so offsets still match data. It also returns the comments it blanked out.
+func blankJSONComments(data []byte) ([]byte, []jsonComment) {
+	out := append([]byte(nil), data...)
+	var comments []jsonComment
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(data) && (data[i+1] == '/' || data[i+1] == '*'):
+			end := len(data)
+			if data[i+1] == '/' {
+				if newline := bytes.IndexByte(data[i:], '\n'); newline >= 0 {
+					end = i + newline
+				}
+			} else if close := bytes.Index(data[i+2:], []byte("*/")); close >= 0 {
+				end = i + 2 + close + 2
+			}
+			comments = append(comments, jsonComment{start: i, end: end, text: strings.TrimRight(string(data[i:end]), " \t\r")})
+			for j := i; j < end; j++ {
+				if out[j] != '\n' {
+					out[j] = ' '
+				}
+			}
+			i = end - 1
+		case c == '}' || c == ']':
+			j := i - 1
+			for j >= 0 && strings.IndexByte(" \t\r\n", out[j]) >= 0 {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out[j] = ' '
+			}
+		}
+	}
+	return out, comments
+}
+
+// hasTOMLComments reports whether TOML data has a comment outside strings.
+func hasTOMLComments(data []byte) bool {
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '#':
+			return true
+		case '"', '\'':
+			delimiter := []byte{c}
+			if bytes.HasPrefix(data[i:], []byte{c, c, c}) {
+				delimiter = []byte{c, c, c}
+			}
+			i += len(delimiter)
+			for i < len(data) && !bytes.HasPrefix(data[i:], delimiter) {
+				if c == '"' && data[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i += len(delimiter) - 1
+		}
+	}
+	return false
+}
+
+// valueNode converts plain Go values into a node tree.
+func valueNode(values map[string]interface{}) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(values); err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.MappingNode {
+		return mappingNode(), nil
+	}
+	return node, nil
+}
+
+func mappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package configmerge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]Format{
+		"settings.json":   FormatJSON,
+		"tsconfig.JSONC":  FormatJSON,
+		"config.yml":      FormatYAML,
+		"alacritty.yaml":  FormatYAML,
+		"starship.toml":   FormatTOML,
+		"dir/config.toml": FormatTOML,
+	}
+	for path, expected := range tests {
+		if got, err := DetectFormat(path); err != nil || got != expected {
+			t.Errorf("DetectFormat(%q) = %q, %v; expected %q", path, got, err, expected)
+		}
+	}
+	if _, err := DetectFormat("config"); err == nil {
+		t.Error("Expected an error for a file without a known extension")
+	}
+}
+
+func TestParseJSONKeepsOrderIndentAndComments(t *testing.T) {
+	input := `{
+	// editor settings
+	"workbench.colorTheme": "Solarized",
+	"editor.fontSize": 12, /* too small */
+	"url": "https://example.com/a//b",
+	"files.exclude": {"**/.git": true,},
+	"list": [1, 2.5, null, "<tag>"],
+}
+`
+	doc, err := Parse(FormatJSON, []byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	expected := "{\n" +
+		"\t// editor settings\n" +
+		"\t\"workbench.colorTheme\": \"Solarized\",\n" +
+		"\t\"editor.fontSize\": 12, /* too small */\n" +
+		"\t\"url\": \"https://example.com/a//b\",\n" +
+		"\t\"files.exclude\": {\n\t\t\"**/.git\": true\n\t},\n" +
+		"\t\"list\": [\n\t\t1,\n\t\t2.5,\n\t\tnull,\n\t\t\"<tag>\"\n\t]\n" +
+		"}\n"
+	if string(encoded) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, encoded)
+	}
+
+	for _, invalid := range []string{`[1, 2]`, `{"a": 1} {}`, `{"a": }`} {
+		if _, err := Parse(FormatJSON, []byte(invalid)); err == nil {
+			t.Errorf("Expected an error parsing %s", invalid)
+		}
+	}
+}
+
+func TestJSONCommentsRoundTrip(t *testing.T) {
+	input := `// VS Code user settings
+{
+  // Appearance
+  "workbench.colorTheme": "Solarized", // light theme
+  "editor.rulers": [
+    80, // code
+    /* prose */ 100,
+  ],
+  "files.exclude": {
+    // generated
+    "**/node_modules": true,
+  },
+  /*
+   * Telemetry
+   */
+  "telemetry.telemetryLevel": "off"
+  // end of settings
+}
+// trailing note
+`
+	doc, err := Parse(FormatJSON, []byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if doc.DropsComments() {
+		t.Error("JSON comments should be written back")
+	}
+	fragment, err := FromValue(map[string]interface{}{
+		"workbench.colorTheme": "Monokai",
+		"files.exclude":        map[string]interface{}{"**/.git": true},
+		"editor.fontSize":      14,
+	})
+	if err != nil {
+		t.Fatalf("FromValue failed: %v", err)
+	}
+	doc.Merge(fragment, Options{Arrays: ArrayReplace})
+
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	expected := `// VS Code user settings
+{
+  // Appearance
+  "workbench.colorTheme": "Monokai", // light theme
+  "editor.rulers": [
+    80, // code
+    /* prose */
+    100
+  ],
+  "files.exclude": {
+    // generated
+    "**/node_modules": true,
+    "**/.git": true
+  },
+  /*
+   * Telemetry
+   */
+  "telemetry.telemetryLevel": "off",
+  "editor.fontSize": 14
+  // end of settings
+}
+// trailing note
+`
+	if string(encoded) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, encoded)
+	}
+
+	// Writing the result back out is stable
+	reparsed, err := Parse(FormatJSON, encoded)
+	if err != nil {
+		t.Fatalf("Parse of the encoded document failed: %v", err)
+	}
+	if again, err := reparsed.Encode(); err != nil || string(again) != expected {
+		t.Errorf("Expected a stable round trip, got:\n%s (%v)", again, err)
+	}
+}
+
+func TestTOMLComments(t *testing.T) {
+	tests := map[string]bool{
+		"# prompt\nadd_newline = false\n":              true,
+		"add_newline = false # trailing\n":             true,
+		"format = \"$all # not a comment\"\n":          false,
+		"format = '#literal'\n":                        false,
+		"format = \"\"\"\n# inside\n\"\"\"\n":          false,
+		"format = \"escaped \\\" # still a string\"\n": false,
+	}
+	for input, comments := range tests {
+		doc, err := Parse(FormatTOML, []byte(input))
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", input, err)
+		}
+		if doc.DropsComments() != comments {
+			t.Errorf("DropsComments() for %q = %v, expected %v", input, doc.DropsComments(), comments)
+		}
+	}
+}
+
+func TestParseEmptyDocuments(t *testing.T) {
+	for _, format := range Formats {
+		doc, err := Parse(format, nil)
+		if err != nil {
+			t.Fatalf("Parse of an empty %s document failed: %v", format, err)
+		}
+		values, err := doc.Value()
+		if err != nil || len(values) != 0 {
+			t.Errorf("Expected an empty %s document, got %v (%v)", format, values, err)
+		}
+	}
+	if _, err := Parse(FormatYAML, []byte("- a\n- b\n")); err == nil {
+		t.Error("Expected an error for a YAML document that is not a mapping")
+	}
+	if _, err := Parse("ini", nil); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestEncodeYAMLKeepsComments(t *testing.T) {
+	input := "# terminal settings\nfont:\n    size: 12 # points\n    family: Hack\n"
+	doc, err := Parse(FormatYAML, []byte(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("Expected an unchanged document:\n%s\ngot:\n%s", input, encoded)
+	}
+}
+
+func TestEncodeTOML(t *testing.T) {
+	doc, err := Parse(FormatTOML, []byte("add_newline = false\n\n[character]\nsuccess_symbol = \"[➜](bold green)\"\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	reparsed, err := Parse(FormatTOML, encoded)
+	if err != nil {
+		t.Fatalf("Encoded TOML does not parse: %v\n%s", err, encoded)
+	}
+	values, _ := reparsed.Value()
+	character, _ := values["character"].(map[string]interface{})
+	if values["add_newline"] != false || character["success_symbol"] != "[➜](bold green)" {
+		t.Errorf("Unexpected values after a round trip: %v", values)
+	}
+	if !strings.Contains(string(encoded), "[character]") {
+		t.Errorf("Expected a character table:\n%s", encoded)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package configmerge
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ArrayStrategy says how an array in a fragment is merged into an existing array.
+type ArrayStrategy string
+
+// Array strategies.
+const (
+	// ArrayReplace replaces the existing array.
+	ArrayReplace ArrayStrategy = "replace"
+	// ArrayAppend appends the fragment's items the existing array lacks.
+	ArrayAppend ArrayStrategy = "append"
+)
+
+// Options configures a merge.
+type Options struct {
+	Arrays ArrayStrategy
+}
+
+// Path is the sequence of object keys leading to a value.
+type Path []string
+
+// String returns the path as a JSON pointer (RFC 6901).
+func (p Path) String() string {
+	var b strings.Builder
+	for _, key := range p {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(key))
+	}
+	return b.String()
+}
+
+// ParsePath parses a JSON pointer.
+func ParsePath(pointer string) (Path, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid key path %q: must start with /", pointer)
+	}
+	keys := strings.Split(pointer[1:], "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+	return keys, nil
+}
+
+// Merge deep-merges fragment into the document. Objects are merged key by key; any other
+// value in the fragment replaces the document's, except that arrays are extended with
+// ArrayAppend. Keys the fragment does not set are left alone.
+func (d *Document) Merge(fragment *Document, opts Options) {
+	mergeMapping(d.root, fragment.root, opts)
+}
+
+// Leaves returns the paths of the values a fragment sets: nested objects are descended
+// into, and any other value, including an empty object, is a leaf.
+func (d *Document) Leaves() []Path {
+	var paths []Path
+	collectLeaves(d.root, nil, &paths)
+	return paths
+}
+
+// Owned returns the values the document holds at the paths fragment sets. A document is in
+// sync with a fragment when its owned values equal the fragment's own.
+func (d *Document) Owned(fragment *Document, opts Options) (map[string]interface{}, error) {
+	projected := &Document{root: project(d.root, fragment.root, opts)}
+	return projected.Value()
+}
+
+// InSync reports whether merging fragment would leave the document's values unchanged.
+func (d *Document) InSync(fragment *Document, opts Options) (bool, error) {
+	owned, err := d.Owned(fragment, opts)
+	if err != nil {
+		return false, err
+	}
+	expected, err := fragment.Owned(fragment, opts)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(owned, expected), nil
+}
+
+// Remove deletes the values at paths. With ArrayAppend, an array fragment appends to only
+// loses the fragment's items. Objects emptied by a removal are removed as well. The
+// fragment may be nil, in which case values are deleted whole.
+func (d *Document) Remove(paths []Path, fragment *Document, opts Options) {
+	var fragmentRoot *yaml.Node
+	if fragment != nil {
+		fragmentRoot = fragment.root
+	}
+	for _, path := range paths {
+		if len(path) > 0 {
+			removePath(d.root, path, fragmentRoot, opts)
+		}
+	}
+}
+
+func mergeMapping(target, fragment *yaml.Node, opts Options) {
+	for i := 0; i+1 < len(fragment.Content); i += 2 {
+		key, value := fragment.Content[i], resolve(fragment.Content[i+1])
+		index := keyIndex(target, key.Value)
+		if index < 0 {
+			target.Content = append(target.Content, copyNode(key), copyNode(value))
+			continue
+		}
+
+		existing := resolve(target.Content[index+1])
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeMapping(existing, value, opts)
+		case opts.Arrays == ArrayAppend && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			for _, item := range value.Content {
+				if !containsNode(existing, item) {
+					existing.Content = append(existing.Content, copyNode(item))
+				}
+			}
+		case !nodesEqual(existing, value):
+			replacement := copyNode(value)
+			replacement.HeadComment = target.Content[index+1].HeadComment
+			replacement.LineComment = target.Content[index+1].LineComment
+			replacement.FootComment = target.Content[index+1].FootComment
+			target.Content[index+1] = replacement
+		}
+	}
+}
+
+// project returns the parts of target at the keys fragment sets.
+func project(target, fragment *yaml.Node, opts Options) *yaml.Node {
+	result := mappingNode()
+	for i := 0; i+1 < len(fragment.Content); i += 2 {
+		key, value := fragment.Content[i], resolve(fragment.Content[i+1])
+		index := keyIndex(target, key.Value)
+		if index < 0 {
+			continue
+		}
+
+		existing := resolve(target.Content[index+1])
+		projected := existing
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			projected = project(existing, value, opts)
+		case opts.Arrays == ArrayAppend && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			projected = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for _, item := range value.Content {
+				if containsNode(existing, item) {
+					projected.Content = append(projected.Content, item)
+				}
+			}
+		}
+		result.Content = append(result.Content, key, projected)
+	}
+	return result
+}
+
+func collectLeaves(node *yaml.Node, prefix Path, paths *[]Path) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		path := append(append(Path{}, prefix...), node.Content[i].Value)
+		value := resolve(node.Content[i+1])
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			collectLeaves(value, path, paths)
+			continue
+		}
+		*paths = append(*paths, path)
+	}
+}
+
+// removePath removes the value at path below mapping and reports whether anything changed.
+func removePath(mapping *yaml.Node, path Path, fragment *yaml.Node, opts Options) bool {
+	index := keyIndex(mapping, path[0])
+	if index < 0 {
+		return false
+	}
+	value := resolve(mapping.Content[index+1])
+	var fragmentValue *yaml.Node
+	if fragment != nil {
+		if i := keyIndex(fragment, path[0]); i >= 0 {
+			fragmentValue = resolve(fragment.Content[i+1])
+		}
+	}
+
+	switch {
+	case len(path) > 1:
+		if value.Kind != yaml.MappingNode || !removePath(value, path[1:], fragmentValue, opts) {
+			return false
+		}
+		if len(value.Content) > 0 {
+			return true
+		}
+	case opts.Arrays == ArrayAppend && value.Kind == yaml.SequenceNode && fragmentValue != nil && fragmentValue.Kind == yaml.SequenceNode:
+		kept := value.Content[:0]
+		for _, item := range value.Content {
+			if !containsNode(fragmentValue, item) {
+				kept = append(kept, item)
+			}
+		}
+		value.Content = kept
+		if len(kept) > 0 {
+			return true
+		}
+	}
+	mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	return true
+}
+
+func keyIndex(mapping *yaml.Node, key string) int {
+	if mapping.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsNode(sequence, item *yaml.Node) bool {
+	for _, existing := range sequence.Content {
+		if nodesEqual(existing, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodesEqual compares the values of two nodes, regardless of style and source format.
+func nodesEqual(a, b *yaml.Node) bool {
+	var valueA, valueB interface{}
+	if a.Decode(&valueA) != nil || b.Decode(&valueB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+// resolve follows YAML aliases.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// copyNode returns a deep copy of node without its comments and anchors.
+func copyNode(node *yaml.Node) *yaml.Node {
+	node = resolve(node)
+	copied := &yaml.Node{Kind: node.Kind, Style: node.Style, Tag: node.Tag, Value: node.Value}
+	for _, child := range node.Content {
+		copied.Content = append(copied.Content, copyNode(child))
+	}
+	return copied
+}
@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package configmerge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, format Format, data string) *Document {
+	t.Helper()
+	doc, err := Parse(format, []byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return doc
+}
+
+func mustEncode(t *testing.T, doc *Document) string {
+	t.Helper()
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestMergeOverlay(t *testing.T) {
+	target := mustParse(t, FormatJSON, `{"window.zoomLevel": 1, "editor": {"tabSize": 2, "rulers": [80]}, "telemetry": false}`)
+	fragment := mustParse(t, FormatYAML, "editor:\n  tabSize: 4\n  rulers: [100]\n  fontFamily: Hack\nnew: true\n")
+
+	target.Merge(fragment, Options{Arrays: ArrayReplace})
+	expected := "{\n" +
+		"  \"window.zoomLevel\": 1,\n" +
+		"  \"editor\": {\n    \"tabSize\": 4,\n    \"rulers\": [\n      100\n    ],\n    \"fontFamily\": \"Hack\"\n  },\n" +
+		"  \"telemetry\": false,\n" +
+		"  \"new\": true\n" +
+		"}\n"
+	if got := mustEncode(t, target); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if inSync, err := target.InSync(fragment, Options{Arrays: ArrayReplace}); err != nil || !inSync {
+		t.Errorf("Expected the merged document to be in sync, got %v (%v)", inSync, err)
+	}
+}
+
+func TestMergeArrayAppend(t *testing.T) {
+	opts := Options{Arrays: ArrayAppend}
+	target := mustParse(t, FormatYAML, "plugins:\n  - git\n  - docker\n")
+	fragment := mustParse(t, FormatJSON, `{"plugins": ["docker", "fzf"]}`)
+
+	if inSync, _ := target.InSync(fragment, opts); inSync {
+		t.Error("Expected a missing item to be out of sync")
+	}
+	target.Merge(fragment, opts)
+	if got := mustEncode(t, target); got != "plugins:\n  - git\n  - docker\n  - fzf\n" {
+		t.Errorf("Expected fzf to be appended once, got:\n%s", got)
+	}
+	if inSync, _ := target.InSync(fragment, opts); !inSync {
+		t.Error("Expected the merged document to be in sync")
+	}
+
+	// Items added by others are not drift
+	target = mustParse(t, FormatYAML, "plugins: [zsh, docker, fzf, git]\n")
+	if inSync, _ := target.InSync(fragment, opts); !inSync {
+		t.Error("Expected extra items not to be drift")
+	}
+
+	target.Remove(fragment.Leaves(), fragment, opts)
+	if got := mustEncode(t, target); got != "plugins: [zsh, git]\n" {
+		t.Errorf("Expected only the fragment's items to be removed, got:\n%s", got)
+	}
+}
+
+func TestMergeKeepsYAMLComments(t *testing.T) {
+	target := mustParse(t, FormatYAML, "# managed by the app\nfont:\n  size: 12 # points\nshell: zsh\n")
+	target.Merge(mustParse(t, FormatJSON, `{"font": {"size": 14}}`), Options{})
+	expected := "# managed by the app\nfont:\n  size: 14 # points\nshell: zsh\n"
+	if got := mustEncode(t, target); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestOwnedDetectsDriftOnlyInOwnedKeys(t *testing.T) {
+	fragment, err := FromValue(map[string]interface{}{
+		"format": "$all",
+		"character": map[string]interface{}{
+			"success_symbol": ">",
+		},
+	})
+	if err != nil {
+		t.Fatalf("FromValue failed: %v", err)
+	}
+	target := mustParse(t, FormatTOML, "format = \"$all\"\nscan_timeout = 10\n[character]\nsuccess_symbol = \">\"\nerror_symbol = \"x\"\n")
+	if inSync, _ := target.InSync(fragment, Options{}); !inSync {
+		t.Error("Expected unrelated keys not to be drift")
+	}
+
+	target = mustParse(t, FormatTOML, "format = \"$all\"\n[character]\nsuccess_symbol = \"$\"\n")
+	owned, err := target.Owned(fragment, Options{})
+	if err != nil {
+		t.Fatalf("Owned failed: %v", err)
+	}
+	expected := map[string]interface{}{"format": "$all", "character": map[string]interface{}{"success_symbol": "$"}}
+	if !reflect.DeepEqual(owned, expected) {
+		t.Errorf("Expected owned values %v, got %v", expected, owned)
+	}
+	if inSync, _ := target.InSync(fragment, Options{}); inSync {
+		t.Error("Expected a changed owned key to be drift")
+	}
+}
+
+func TestRemoveLeaves(t *testing.T) {
+	fragment := mustParse(t, FormatJSON, `{"a": {"b": 1, "c": {"d": 2}}, "e": {}}`)
+	leaves := fragment.Leaves()
+	var pointers []string
+	for _, leaf := range leaves {
+		pointers = append(pointers, leaf.String())
+	}
+	if expected := []string{"/a/b", "/a/c/d", "/e"}; !reflect.DeepEqual(pointers, expected) {
+		t.Errorf("Expected leaves %v, got %v", expected, pointers)
+	}
+
+	target := mustParse(t, FormatJSON, `{"a": {"b": 1, "c": {"d": 2}, "keep": true}, "e": {}, "z": 0}`)
+	target.Remove(leaves, nil, Options{})
+	if got := mustEncode(t, target); got != "{\n  \"a\": {\n    \"keep\": true\n  },\n  \"z\": 0\n}\n" {
+		t.Errorf("Expected owned keys and emptied objects to be removed, got:\n%s", got)
+	}
+}
+
+func TestPathPointers(t *testing.T) {
+	path := Path{"files.exclude", "**/.git", "a~b"}
+	pointer := path.String()
+	if pointer != "/files.exclude/**~1.git/a~0b" {
+		t.Errorf("Unexpected pointer %s", pointer)
+	}
+	parsed, err := ParsePath(pointer)
+	if err != nil || !reflect.DeepEqual(parsed, path) {
+		t.Errorf("Expected %v, got %v (%v)", path, parsed, err)
+	}
+	if _, err := ParsePath("a/b"); err == nil {
+		t.Error("Expected an error for a pointer without a leading slash")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package fileformat checks that file content parses in the format the application reading
+// it expects, so a broken template is caught before it replaces a working file.
+package fileformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/inifile"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/sshconfig"
+)
+
+// Format identifies a file format content can be validated as.
+type Format string
+
+// Supported formats.
+const (
+	FormatJSON      Format = "json"
+	FormatYAML      Format = "yaml"
+	FormatTOML      Format = "toml"
+	FormatINI       Format = "ini"
+	FormatXML       Format = "xml"
+	FormatSSHConfig Format = "sshconfig"
+	FormatGitConfig Format = "gitconfig"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatJSON, FormatYAML, FormatTOML, FormatINI, FormatXML, FormatSSHConfig, FormatGitConfig}
+
+// names are the formats as written in error messages.
+var names = map[Format]string{
+	FormatJSON:      "JSON",
+	FormatYAML:      "YAML",
+	FormatTOML:      "TOML",
+	FormatINI:       "INI",
+	FormatXML:       "XML",
+	FormatSSHConfig: "ssh_config",
+	FormatGitConfig: "git config",
+}
+
+// Name returns the format as written in messages, e.g. `JSON`.
+func (f Format) Name() string {
+	if name, ok := names[f]; ok {
+		return name
+	}
+	return string(f)
+}
+
+// Error describes content that does not parse. Line and Column are 1-based and zero when
+// the parser does not report them.
+type Error struct {
+	Format Format
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("invalid %s at line %d, column %d: %v", e.Format.Name(), e.Line, e.Column, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("invalid %s at line %d: %v", e.Format.Name(), e.Line, e.Err)
+	}
+	return fmt.Sprintf("invalid %s: %v", e.Format.Name(), e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Validate parses content as format. It returns an *Error when the content is invalid.
+func Validate(format Format, content []byte) error {
+	var err error
+	switch format {
+	case FormatJSON:
+		err = validateJSON(content)
+	case FormatYAML:
+		err = validateYAML(content)
+	case FormatTOML:
+		err = validateTOML(content)
+	case FormatINI:
+		_, err = inifile.Parse(inifile.SyntaxINI, string(content))
+		err = lineError(err)
+	case FormatGitConfig:
+		_, err = inifile.Parse(inifile.SyntaxGit, string(content))
+		err = lineError(err)
+	case FormatXML:
+		err = validateXML(content)
+	case FormatSSHConfig:
+		err = lineError(sshconfig.Validate(string(content)))
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if err == nil {
+		return nil
+	}
+	var formatErr *Error
+	if !errors.As(err, &formatErr) {
+		formatErr = &Error{Err: err}
+	}
+	formatErr.Format = format
+	return formatErr
+}
+
+func validateJSON(content []byte) error {
+	var value interface{}
+	err := json.Unmarshal(content, &value)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, column := position(content, syntaxErr.Offset)
+		return &Error{Line: line, Column: column, Err: err}
+	}
+	return err
+}
+
+// yamlLine matches the position yaml.v3 puts in its messages.
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func validateYAML(content []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+				line, _ := strconv.Atoi(match[1])
+				return &Error{Line: line, Err: errors.New(match[2])}
+			}
+			return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	}
+}
+
+func validateTOML(content []byte) error {
+	var value map[string]interface{}
+	err := toml.Unmarshal(content, &value)
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		line, column := decodeErr.Position()
+		return &Error{Line: line, Column: column, Err: errors.New(strings.TrimPrefix(decodeErr.Error(), "toml: "))}
+	}
+	return err
+}
+
+func validateXML(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = true
+	root := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if !root {
+				return errors.New("no root element")
+			}
+			return nil
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return &Error{Line: syntaxErr.Line, Err: errors.New(syntaxErr.Msg)}
+			}
+			line, column := decoder.InputPos()
+			return &Error{Line: line, Column: column, Err: err}
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			root = true
+		}
+	}
+}
+
+// linePrefix matches the `line N: ` prefix of the inifile and sshconfig errors.
+var linePrefix = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// lineError moves the line number of an inifile or sshconfig error into an *Error.
+func lineError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if match := linePrefix.FindStringSubmatch(err.Error()); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		return &Error{Line: line, Err: errors.New(match[2])}
+	}
+	return err
+}
+
+// position converts the offset encoding/json reports, just past the offending byte, into the
+// 1-based line and column of that byte.
+func position(content []byte, offset int64) (int, int) {
+	offset = min(max(offset-1, 0), int64(len(content)))
+	before := content[:offset]
+	return bytes.Count(before, []byte("\n")) + 1, len(before) - bytes.LastIndexByte(before, '\n')
+}
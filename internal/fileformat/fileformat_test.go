@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileformat
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := map[Format]string{
+		FormatJSON:      "{\n  \"profiles\": [{\"name\": \"Default\"}]\n}\n",
+		FormatYAML:      "font:\n  size: 12\n---\nother: true\n",
+		FormatTOML:      "[font]\nsize = 12\n",
+		FormatINI:       "[Settings]\ntheme = dark\n",
+		FormatXML:       "<?xml version=\"1.0\"?>\n<plist><dict/></plist>\n",
+		FormatSSHConfig: "Host github.com\n  User git\n",
+		FormatGitConfig: "[user]\n\tname = Test\n[remote \"origin\"]\n\turl = x\n",
+	}
+	for format, content := range valid {
+		if err := Validate(format, []byte(content)); err != nil {
+			t.Errorf("Expected valid %s, got %v", format, err)
+		}
+	}
+
+	for _, test := range []struct {
+		format  Format
+		content string
+		line    int
+		column  int
+	}{
+		{FormatJSON, "{\n  \"a\": 1,\n  \"b\": }\n", 3, 8},
+		{FormatJSON, "", 1, 1},
+		{FormatYAML, "a: 1\nb: 2\nc: d: e\n", 3, 0},
+		{FormatTOML, "[font]\nsize = = 12\n", 2, 8},
+		{FormatINI, "[Settings\ntheme = dark\n", 1, 0},
+		{FormatXML, "<plist>\n  <dict>\n</plist>\n", 3, 0},
+		{FormatXML, "", 0, 0},
+		{FormatSSHConfig, "Host x\n  Usr git\n", 2, 0},
+		{FormatGitConfig, "[user]\n\tname = \"open\n", 2, 0},
+	} {
+		err := Validate(test.format, []byte(test.content))
+		var formatErr *Error
+		if !errors.As(err, &formatErr) {
+			t.Errorf("Expected a format error for invalid %s %q, got %v", test.format, test.content, err)
+			continue
+		}
+		if formatErr.Format != test.format || formatErr.Line != test.line || formatErr.Column != test.column {
+			t.Errorf("Expected %s error at %d:%d, got %d:%d (%v)", test.format, test.line, test.column, formatErr.Line, formatErr.Column, err)
+		}
+	}
+
+	err := Validate(FormatJSON, []byte("{\"a\" 1}"))
+	if err == nil || err.Error() != "invalid JSON at line 1, column 6: invalid character '1' after object key" {
+		t.Errorf("Unexpected message: %v", err)
+	}
+	if err := Validate("csv", nil); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces the content of path by writing a temporary file next to it and
+// renaming it into place, so readers never see a partially written file. An existing file
+// keeps its permissions; mode applies to new files. A symlink at path is followed, so the
+// file it points to is updated instead of the link being replaced.
+func (fm *FileManager) WriteFileAtomic(path string, content []byte, mode os.FileMode) error {
+	return fm.writeFileAtomic(path, content, mode, false)
+}
+
+// WriteFileAtomicWithMode is WriteFileAtomic, but sets mode on an existing file too. The mode
+// is set before the file is renamed into place, so the new content is never readable with
+// broader permissions.
+func (fm *FileManager) WriteFileAtomicWithMode(path string, content []byte, mode os.FileMode) error {
+	return fm.writeFileAtomic(path, content, mode, true)
+}
+
+func (fm *FileManager) writeFileAtomic(path string, content []byte, mode os.FileMode, forceMode bool) error {
+	if fm.dryRun {
+		fmt.Printf("DRY RUN: Would write %d bytes to %s\n", len(content), path)
+		return nil
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if info, err := os.Stat(path); err == nil && !forceMode {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
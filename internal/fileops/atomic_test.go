@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+)
+
+func TestFileManager_WriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fm := NewFileManager(platform.DetectPlatform(), false)
+
+	t.Run("New file uses mode", func(t *testing.T) {
+		path := filepath.Join(dir, "nested", "config")
+		if err := fm.WriteFileAtomic(path, []byte("new\n"), 0600); err != nil {
+			t.Fatalf("WriteFileAtomic failed: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected a 0600 file, got %v (%v)", info, err)
+		}
+	})
+
+	t.Run("Existing file keeps mode and symlinks are followed", func(t *testing.T) {
+		real := filepath.Join(dir, "real")
+		link := filepath.Join(dir, "link")
+		if err := os.WriteFile(real, []byte("old\n"), 0640); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Symlink(real, link); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if err := fm.WriteFileAtomic(link, []byte("updated\n"), 0644); err != nil {
+			t.Fatalf("WriteFileAtomic failed: %v", err)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Error("Expected the symlink to be kept")
+		}
+		content, _ := os.ReadFile(real)
+		info, _ := os.Stat(real)
+		if string(content) != "updated\n" || info.Mode().Perm() != 0640 {
+			t.Errorf("Expected the link target to be updated with mode 0640, got %q %v", content, info.Mode().Perm())
+		}
+
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if strings.Contains(entry.Name(), ".tmp-") {
+				t.Errorf("Temporary file %s left behind", entry.Name())
+			}
+		}
+	})
+
+	t.Run("Mode is applied to an existing file", func(t *testing.T) {
+		path := filepath.Join(dir, "id_ed25519")
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := fm.WriteFileAtomicWithMode(path, []byte("secret\n"), 0600); err != nil {
+			t.Fatalf("WriteFileAtomicWithMode failed: %v", err)
+		}
+		content, _ := os.ReadFile(path)
+		info, _ := os.Stat(path)
+		if string(content) != "secret\n" || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the file to be replaced with mode 0600, got %q %v", content, info.Mode().Perm())
+		}
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		path := filepath.Join(dir, "dry")
+		if err := NewFileManager(platform.DetectPlatform(), true).WriteFileAtomic(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFileAtomic failed: %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("Expected no file to be written in dry run mode")
+		}
+	})
+}
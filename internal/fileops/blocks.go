@@ -0,0 +1,166 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Positions at which a missing managed block is inserted.
+const (
+	BlockPositionEnd       = "end"
+	BlockPositionBeginning = "beginning"
+	BlockPositionBefore    = "before"
+	BlockPositionAfter     = "after"
+)
+
+// DefaultBlockCommentPrefix starts the marker lines of blocks in shell-style files.
+const DefaultBlockCommentPrefix = "#"
+
+// ManagedBlock is a named, marker-delimited section of a file shared with other tools:
+//
+//	# BEGIN dotfiles:<name>
+//	...
+//	# END dotfiles:<name>
+//
+// Only the lines between the markers are owned; the rest of the file is left untouched.
+type ManagedBlock struct {
+	Name string
+	// CommentPrefix starts the marker lines (default: "#").
+	CommentPrefix string
+}
+
+// BlockPlacement says where a block that does not exist yet is inserted. Before and after
+// insert next to the first line matching Anchor, or at the end when no line matches.
+type BlockPlacement struct {
+	Position string
+	Anchor   *regexp.Regexp
+}
+
+// BeginMarker returns the line that opens the block.
+func (b ManagedBlock) BeginMarker() string {
+	return fmt.Sprintf("%s BEGIN dotfiles:%s", b.commentPrefix(), b.Name)
+}
+
+// EndMarker returns the line that closes the block.
+func (b ManagedBlock) EndMarker() string {
+	return fmt.Sprintf("%s END dotfiles:%s", b.commentPrefix(), b.Name)
+}
+
+// Find returns the content between the block's markers and whether the block exists.
+func (b ManagedBlock) Find(content string) (string, bool, error) {
+	lines := splitLines(content)
+	begin, end, found, err := b.locate(lines)
+	if err != nil || !found {
+		return "", false, err
+	}
+	return joinLines(lines[begin+1 : end]), true, nil
+}
+
+// Upsert returns content with the block's body replaced by body, inserting the block
+// according to placement when it does not exist yet.
+func (b ManagedBlock) Upsert(content, body string, placement BlockPlacement) (string, error) {
+	lines := splitLines(content)
+	begin, end, found, err := b.locate(lines)
+	if err != nil {
+		return "", err
+	}
+
+	block := append([]string{b.BeginMarker()}, splitLines(body)...)
+	block = append(block, b.EndMarker())
+
+	if !found {
+		begin = insertionIndex(lines, placement)
+		end = begin - 1
+	}
+	result := make([]string, 0, len(lines)+len(block))
+	result = append(result, lines[:begin]...)
+	result = append(result, block...)
+	result = append(result, lines[end+1:]...)
+	return joinLines(result), nil
+}
+
+// Remove returns content without the block, markers included. Content without the block is
+// returned unchanged.
+func (b ManagedBlock) Remove(content string) (string, error) {
+	lines := splitLines(content)
+	begin, end, found, err := b.locate(lines)
+	if err != nil || !found {
+		return content, err
+	}
+	return joinLines(append(lines[:begin:begin], lines[end+1:]...)), nil
+}
+
+// locate returns the line indexes of the block's markers. A block that is opened twice or
+// never closed is an error, so a damaged file is never rewritten.
+func (b ManagedBlock) locate(lines []string) (int, int, bool, error) {
+	beginMarker, endMarker := b.BeginMarker(), b.EndMarker()
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case beginMarker:
+			if begin >= 0 {
+				return 0, 0, false, fmt.Errorf("block %q is opened again on line %d", b.Name, i+1)
+			}
+			begin = i
+		case endMarker:
+			if begin < 0 {
+				return 0, 0, false, fmt.Errorf("block %q is closed on line %d before it is opened", b.Name, i+1)
+			}
+			if end < 0 {
+				end = i
+			}
+		}
+	}
+	if begin >= 0 && end < 0 {
+		return 0, 0, false, fmt.Errorf("block %q opened on line %d is never closed", b.Name, begin+1)
+	}
+	return begin, end, begin >= 0, nil
+}
+
+func (b ManagedBlock) commentPrefix() string {
+	if b.CommentPrefix == "" {
+		return DefaultBlockCommentPrefix
+	}
+	return b.CommentPrefix
+}
+
+// insertionIndex returns the line index a new block is inserted at.
+func insertionIndex(lines []string, placement BlockPlacement) int {
+	switch placement.Position {
+	case BlockPositionBeginning:
+		return 0
+	case BlockPositionBefore, BlockPositionAfter:
+		if placement.Anchor == nil {
+			break
+		}
+		for i, line := range lines {
+			if placement.Anchor.MatchString(line) {
+				if placement.Position == BlockPositionAfter {
+					return i + 1
+				}
+				return i
+			}
+		}
+	}
+	return len(lines)
+}
+
+// splitLines splits content into lines without their trailing newlines.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// joinLines joins lines into content ending with a newline.
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestManagedBlockUpsert(t *testing.T) {
+	block := ManagedBlock{Name: "path"}
+	rc := "export EDITOR=vim\n# nvm\nsource ~/.nvm/nvm.sh\n"
+
+	tests := []struct {
+		name      string
+		content   string
+		placement BlockPlacement
+		expected  string
+	}{
+		{
+			name:     "empty file",
+			expected: "# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n",
+		},
+		{
+			name:     "end",
+			content:  rc,
+			expected: rc + "# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n",
+		},
+		{
+			name:      "beginning",
+			content:   rc,
+			placement: BlockPlacement{Position: BlockPositionBeginning},
+			expected:  "# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n" + rc,
+		},
+		{
+			name:      "before anchor",
+			content:   rc,
+			placement: BlockPlacement{Position: BlockPositionBefore, Anchor: regexp.MustCompile(`^# nvm`)},
+			expected:  "export EDITOR=vim\n# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n# nvm\nsource ~/.nvm/nvm.sh\n",
+		},
+		{
+			name:      "after anchor",
+			content:   rc,
+			placement: BlockPlacement{Position: BlockPositionAfter, Anchor: regexp.MustCompile(`EDITOR`)},
+			expected:  "export EDITOR=vim\n# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n# nvm\nsource ~/.nvm/nvm.sh\n",
+		},
+		{
+			name:      "anchor without match",
+			content:   "no newline at end",
+			placement: BlockPlacement{Position: BlockPositionAfter, Anchor: regexp.MustCompile(`^missing`)},
+			expected:  "no newline at end\n# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\n",
+		},
+		{
+			name:      "existing block is replaced in place",
+			content:   "a\n# BEGIN dotfiles:path\nold\nlines\n  # END dotfiles:path\nb\n",
+			placement: BlockPlacement{Position: BlockPositionBeginning},
+			expected:  "a\n# BEGIN dotfiles:path\nexport PATH=~/bin:$PATH\n# END dotfiles:path\nb\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := block.Upsert(tt.content, "export PATH=~/bin:$PATH\n", tt.placement)
+			if err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestManagedBlockFindAndRemove(t *testing.T) {
+	block := ManagedBlock{Name: "plugins", CommentPrefix: `"`}
+	content := "set nocompatible\n\" BEGIN dotfiles:plugins\nPlug 'tpope/vim-sensible'\n\n\" END dotfiles:plugins\nsyntax on\n"
+
+	body, found, err := block.Find(content)
+	if err != nil || !found {
+		t.Fatalf("Expected to find the block, got found=%v err=%v", found, err)
+	}
+	if body != "Plug 'tpope/vim-sensible'\n\n" {
+		t.Errorf("Unexpected block body %q", body)
+	}
+	if _, found, _ := (ManagedBlock{Name: "plugins"}).Find(content); found {
+		t.Error("Expected markers with another comment prefix not to match")
+	}
+
+	removed, err := block.Remove(content)
+	if err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if removed != "set nocompatible\nsyntax on\n" {
+		t.Errorf("Expected only the block to be removed, got %q", removed)
+	}
+	if unchanged, _ := block.Remove(removed); unchanged != removed {
+		t.Errorf("Expected content without the block to be unchanged, got %q", unchanged)
+	}
+
+	// Upserting the body that was found leaves the content untouched
+	if same, _ := block.Upsert(content, body, BlockPlacement{}); same != content {
+		t.Errorf("Expected an identical upsert to keep the content, got %q", same)
+	}
+}
+
+func TestManagedBlockDamagedMarkers(t *testing.T) {
+	block := ManagedBlock{Name: "path"}
+	for name, content := range map[string]string{
+		"unterminated":  "# BEGIN dotfiles:path\nexport A=1\n",
+		"opened twice":  "# BEGIN dotfiles:path\n# END dotfiles:path\n# BEGIN dotfiles:path\n# END dotfiles:path\n",
+		"closed before": "# END dotfiles:path\n# BEGIN dotfiles:path\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := block.Upsert(content, "x\n", BlockPlacement{}); err == nil {
+				t.Error("Expected Upsert to fail")
+			}
+			if _, err := block.Remove(content); err == nil {
+				t.Error("Expected Remove to fail")
+			}
+		})
+	}
+}
@@ -10,14 +10,16 @@ import (
 	"time"
 
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/template"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/utils"
 )
 
 // FileManager handles file operations for dotfiles management.
 type FileManager struct {
-	platform platform.PlatformProvider
-	dryRun   bool
+	platform    platform.PlatformProvider
+	dryRun      bool
+	concurrency *services.ConcurrencyManager
 }
 
 // ConflictResolution represents the result of conflict resolution.
@@ -35,6 +37,13 @@ func NewFileManager(platformProvider platform.PlatformProvider, dryRun bool) *Fi
 	}
 }
 
+// WithConcurrency sets the concurrency manager used to parallelize recursive walks.
+// Without one, walks are processed sequentially.
+func (fm *FileManager) WithConcurrency(cm *services.ConcurrencyManager) *FileManager {
+	fm.concurrency = cm
+	return fm
+}
+
 // CopyFile copies a file from source to target with specified permissions.
 func (fm *FileManager) CopyFile(sourcePath, targetPath, fileMode string) error {
 	if fm.dryRun {
@@ -4,6 +4,7 @@
 package fileops
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
@@ -108,8 +109,20 @@ func (fm *FileManager) applyFilePermissions(filePath string, config *PermissionC
 }
 
 // applyPermissionsRecursively applies permissions recursively to directory contents.
+// The tree is walked first and the collected entries are then updated through the
+// file manager's concurrency manager, if one is set.
 func (fm *FileManager) applyPermissionsRecursively(dirPath string, config *PermissionConfig) error {
-	return filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+	var dirMode os.FileMode
+	if config.DirectoryMode != "" {
+		mode, err := parsePermissionString(config.DirectoryMode)
+		if err != nil {
+			return fmt.Errorf("invalid directory permission %s: %w", config.DirectoryMode, err)
+		}
+		dirMode = mode
+	}
+
+	var tasks []func() error
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -121,24 +134,29 @@ func (fm *FileManager) applyPermissionsRecursively(dirPath string, config *Permi
 
 		if d.IsDir() {
 			if config.DirectoryMode != "" {
-				mode, parseErr := parsePermissionString(config.DirectoryMode)
-				if parseErr != nil {
-					return fmt.Errorf("invalid directory permission %s: %w", config.DirectoryMode, parseErr)
-				}
-
-				if setErr := fm.platform.SetPermissions(path, mode); setErr != nil {
-					return fmt.Errorf("failed to set directory permissions for %s: %w", path, setErr)
-				}
+				tasks = append(tasks, func() error {
+					if setErr := fm.platform.SetPermissions(path, dirMode); setErr != nil {
+						return fmt.Errorf("failed to set directory permissions for %s: %w", path, setErr)
+					}
+					return nil
+				})
 			}
 		} else {
-			// Apply file permissions
-			if applyErr := fm.applyFilePermissions(path, config); applyErr != nil {
-				return fmt.Errorf("failed to apply file permissions for %s: %w", path, applyErr)
-			}
+			tasks = append(tasks, func() error {
+				if applyErr := fm.applyFilePermissions(path, config); applyErr != nil {
+					return fmt.Errorf("failed to apply file permissions for %s: %w", path, applyErr)
+				}
+				return nil
+			})
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	return fm.concurrency.RunParallel(context.Background(), tasks)
 }
 
 // CreateSymlinkWithPermissions creates a symlink and applies permissions to the source.
@@ -4,11 +4,13 @@
 package fileops
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 )
 
 func TestPermissionConfig(t *testing.T) {
@@ -318,3 +320,85 @@ func TestDryRun(t *testing.T) {
 		t.Errorf("Dry run should not fail with nonexistent path: %v", err)
 	}
 }
+
+func TestApplyPermissionsRecursivelyWithConcurrency(t *testing.T) {
+	tempDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		subDir := filepath.Join(tempDir, fmt.Sprintf("sub%d", i%4))
+		if err := os.MkdirAll(subDir, 0777); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		name := fmt.Sprintf("file%d.txt", i)
+		if i%5 == 0 {
+			name = fmt.Sprintf("id_key%d", i)
+		}
+		if err := os.WriteFile(filepath.Join(subDir, name), []byte("content"), 0666); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	fm := NewFileManager(platform.DetectPlatform(), false).WithConcurrency(services.NewConcurrencyManager(4))
+	config := &PermissionConfig{
+		DirectoryMode: "0750",
+		FileMode:      "0644",
+		Recursive:     true,
+		Rules:         map[string]string{"id_*": "0600"},
+	}
+
+	if err := fm.ApplyPermissions(tempDir, config); err != nil {
+		t.Fatalf("Failed to apply permissions: %v", err)
+	}
+
+	err := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		expected := os.FileMode(0644)
+		switch {
+		case info.IsDir():
+			expected = 0750
+		case matchPattern("id_*", info.Name()):
+			expected = 0600
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("Expected %s to have permission %o, got %o", path, expected, info.Mode().Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk directory: %v", err)
+	}
+}
+
+func BenchmarkApplyPermissionsRecursively(b *testing.B) {
+	tempDir := b.TempDir()
+	for i := 0; i < 500; i++ {
+		subDir := filepath.Join(tempDir, fmt.Sprintf("sub%d", i%10))
+		if err := os.MkdirAll(subDir, 0755); err != nil {
+			b.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(subDir, fmt.Sprintf("file%d", i)), []byte("content"), 0644); err != nil {
+			b.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	config := &PermissionConfig{DirectoryMode: "0755", FileMode: "0644", Recursive: true}
+
+	b.Run("sequential", func(b *testing.B) {
+		fm := NewFileManager(platform.DetectPlatform(), false)
+		for i := 0; i < b.N; i++ {
+			if err := fm.ApplyPermissions(tempDir, config); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		fm := NewFileManager(platform.DetectPlatform(), false).
+			WithConcurrency(services.NewConcurrencyManager(services.DefaultMaxConcurrency))
+		for i := 0; i < b.N; i++ {
+			if err := fm.ApplyPermissions(tempDir, config); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
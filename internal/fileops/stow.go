@@ -0,0 +1,356 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultStowIgnorePatterns mirrors the entries GNU Stow skips by default.
+var DefaultStowIgnorePatterns = []string{
+	".git",
+	".gitignore",
+	".gitmodules",
+	".stow-local-ignore",
+	"README*",
+	"LICENSE*",
+	"COPYING",
+	".DS_Store",
+}
+
+// StowOptions configures how a package tree is linked into a target directory.
+type StowOptions struct {
+	// Ignore holds glob patterns matched against entry names and package-relative paths.
+	Ignore []string
+	// NoFolding links every file individually instead of linking whole directories.
+	NoFolding bool
+	// OwnerRoot is the directory whose links are considered managed; links resolving
+	// below it may be unfolded when another package needs the same directory.
+	OwnerRoot string
+}
+
+// StowOpKind identifies a planned stow operation.
+type StowOpKind string
+
+// Stow operation kinds.
+const (
+	StowOpLink   StowOpKind = "link"
+	StowOpMkdir  StowOpKind = "mkdir"
+	StowOpUnfold StowOpKind = "unfold"
+)
+
+// StowOp is a single planned operation. Source is empty for mkdir and unfold operations.
+type StowOp struct {
+	Kind   StowOpKind
+	Source string
+	Target string
+}
+
+// StowPlan is the ordered set of operations needed to link a package.
+type StowPlan struct {
+	Operations []StowOp
+	// Links holds every link target path the package owns once applied, sorted.
+	Links []string
+	// Conflicts lists target paths occupied by unmanaged files or foreign links.
+	Conflicts []string
+}
+
+// Pending reports whether applying the plan would change the filesystem.
+func (p *StowPlan) Pending() bool {
+	return len(p.Operations) > 0
+}
+
+// stowNodeKind describes what occupies a target path, on disk or in the planned overlay.
+type stowNodeKind int
+
+const (
+	stowNodeNone stowNodeKind = iota
+	stowNodeDir
+	stowNodeLink
+	stowNodeFile
+)
+
+type stowNode struct {
+	kind       stowNodeKind
+	linkTarget string
+}
+
+// stowPlanner computes a StowPlan, tracking planned changes in an overlay over the filesystem.
+type stowPlanner struct {
+	opts        StowOptions
+	packageRoot string
+	targetRoot  string
+	plan        *StowPlan
+	overlay     map[string]stowNode
+}
+
+// PlanStow computes the operations needed to link packageDir into targetRoot.
+// Directories missing from the target are linked as a whole (tree folding) unless
+// NoFolding is set; folded directories owned by another package are unfolded.
+func PlanStow(packageDir, targetRoot string, opts StowOptions) (*StowPlan, error) {
+	info, err := os.Stat(packageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat package directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("package path is not a directory: %s", packageDir)
+	}
+
+	planner := &stowPlanner{
+		opts:        opts,
+		packageRoot: packageDir,
+		targetRoot:  targetRoot,
+		plan:        &StowPlan{},
+		overlay:     make(map[string]stowNode),
+	}
+	if err := planner.stowDir(packageDir, targetRoot); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(planner.plan.Links)
+	sort.Strings(planner.plan.Conflicts)
+	return planner.plan, nil
+}
+
+// stowDir plans links for the contents of sourceDir into targetDir.
+func (sp *stowPlanner) stowDir(sourceDir, targetDir string) error {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to read package directory %s: %w", sourceDir, err)
+	}
+
+	for _, entry := range entries {
+		source := filepath.Join(sourceDir, entry.Name())
+		if sp.ignored(source) {
+			continue
+		}
+		if err := sp.stowEntry(source, filepath.Join(targetDir, entry.Name()), entry.IsDir()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stowEntry plans a single package entry against what currently occupies its target path.
+func (sp *stowPlanner) stowEntry(source, target string, isDir bool) error {
+	node, err := sp.lookup(target)
+	if err != nil {
+		return err
+	}
+
+	switch node.kind {
+	case stowNodeNone:
+		if isDir && sp.opts.NoFolding {
+			sp.add(StowOp{Kind: StowOpMkdir, Target: target}, stowNode{kind: stowNodeDir})
+			return sp.stowDir(source, target)
+		}
+		sp.add(StowOp{Kind: StowOpLink, Source: source, Target: target}, stowNode{kind: stowNodeLink, linkTarget: source})
+		sp.plan.Links = append(sp.plan.Links, target)
+	case stowNodeLink:
+		return sp.stowOverLink(source, target, isDir, node)
+	case stowNodeDir:
+		if !isDir {
+			sp.plan.Conflicts = append(sp.plan.Conflicts, target)
+			return nil
+		}
+		return sp.stowDir(source, target)
+	default:
+		sp.plan.Conflicts = append(sp.plan.Conflicts, target)
+	}
+	return nil
+}
+
+// stowOverLink handles a target path that is already a symlink.
+func (sp *stowPlanner) stowOverLink(source, target string, isDir bool, node stowNode) error {
+	resolved := ResolveLinkTarget(target, node.linkTarget)
+	if SymlinkPointsTo(target, node.linkTarget, source) {
+		sp.plan.Links = append(sp.plan.Links, target)
+		return nil
+	}
+
+	// A folded directory from another managed package: replace it with a real
+	// directory holding links to the other package's entries, then merge ours.
+	resolvedInfo, statErr := os.Stat(resolved)
+	if isDir && statErr == nil && resolvedInfo.IsDir() && sp.owned(resolved) {
+		sp.add(StowOp{Kind: StowOpUnfold, Target: target}, stowNode{kind: stowNodeDir})
+		if err := sp.relinkForeign(resolved, target, sp.foreignRoot(resolved, target)); err != nil {
+			return err
+		}
+		return sp.stowDir(source, target)
+	}
+
+	sp.plan.Conflicts = append(sp.plan.Conflicts, target)
+	return nil
+}
+
+// relinkForeign plans links for the entries of a foreign package directory after unfolding,
+// skipping those the ignore patterns exclude relative to the foreign package root.
+func (sp *stowPlanner) relinkForeign(foreignDir, targetDir, foreignRoot string) error {
+	entries, err := os.ReadDir(foreignDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", foreignDir, err)
+	}
+	for _, entry := range entries {
+		source := filepath.Join(foreignDir, entry.Name())
+		if sp.ignoredIn(foreignRoot, source) {
+			continue
+		}
+		target := filepath.Join(targetDir, entry.Name())
+		sp.add(StowOp{Kind: StowOpLink, Source: source, Target: target}, stowNode{kind: stowNodeLink, linkTarget: source})
+	}
+	return nil
+}
+
+// add records an operation and its effect on the overlay.
+func (sp *stowPlanner) add(op StowOp, node stowNode) {
+	sp.plan.Operations = append(sp.plan.Operations, op)
+	sp.overlay[op.Target] = node
+}
+
+// lookup returns what occupies path, preferring planned state over the filesystem.
+func (sp *stowPlanner) lookup(path string) (stowNode, error) {
+	if node, ok := sp.overlay[path]; ok {
+		return node, nil
+	}
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return stowNode{kind: stowNodeNone}, nil
+	}
+	if err != nil {
+		return stowNode{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		linkTarget, err := os.Readlink(path)
+		if err != nil {
+			return stowNode{}, fmt.Errorf("failed to read link %s: %w", path, err)
+		}
+		return stowNode{kind: stowNodeLink, linkTarget: linkTarget}, nil
+	case info.IsDir():
+		return stowNode{kind: stowNodeDir}, nil
+	default:
+		return stowNode{kind: stowNodeFile}, nil
+	}
+}
+
+// owned reports whether path lies within the managed owner root.
+func (sp *stowPlanner) owned(path string) bool {
+	if sp.opts.OwnerRoot == "" {
+		return false
+	}
+	rel, err := filepath.Rel(sp.opts.OwnerRoot, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// foreignRoot returns the root of the package a folded link at target was stowed from:
+// the directory that sits at the target root's position above foreignDir. When the
+// link does not mirror the target layout, foreignDir itself is used.
+func (sp *stowPlanner) foreignRoot(foreignDir, target string) string {
+	rel, err := filepath.Rel(sp.targetRoot, target)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return foreignDir
+	}
+	root := foreignDir
+	for range strings.Split(filepath.ToSlash(rel), "/") {
+		root = filepath.Dir(root)
+	}
+	if filepath.Join(root, rel) != filepath.Clean(foreignDir) {
+		return foreignDir
+	}
+	return root
+}
+
+// ignored reports whether a package entry matches an ignore pattern.
+func (sp *stowPlanner) ignored(source string) bool {
+	return sp.ignoredIn(sp.packageRoot, source)
+}
+
+// ignoredIn reports whether an entry of the package at root matches an ignore pattern.
+func (sp *stowPlanner) ignoredIn(root, source string) bool {
+	name := filepath.Base(source)
+	rel, err := filepath.Rel(root, source)
+	if err != nil {
+		rel = name
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range sp.opts.Ignore {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyStowPlan executes a stow plan. Plans with conflicts are rejected without changes.
+func (fm *FileManager) ApplyStowPlan(plan *StowPlan) error {
+	if len(plan.Conflicts) > 0 {
+		return fmt.Errorf("stow conflicts with existing files: %s", strings.Join(plan.Conflicts, ", "))
+	}
+
+	for _, op := range plan.Operations {
+		if err := fm.applyStowOp(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyStowOp executes a single stow operation.
+func (fm *FileManager) applyStowOp(op StowOp) error {
+	switch op.Kind {
+	case StowOpLink:
+		if err := fm.CreateSymlinkWithParents(op.Source, op.Target); err != nil {
+			return fmt.Errorf("failed to link %s: %w", op.Target, err)
+		}
+	case StowOpMkdir, StowOpUnfold:
+		if fm.dryRun {
+			fmt.Printf("DRY RUN: Would %s directory %s\n", op.Kind, op.Target)
+			return nil
+		}
+		if op.Kind == StowOpUnfold {
+			if err := os.Remove(op.Target); err != nil {
+				return fmt.Errorf("failed to remove folded link %s: %w", op.Target, err)
+			}
+		}
+		if err := os.MkdirAll(op.Target, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", op.Target, err)
+		}
+	default:
+		return fmt.Errorf("unknown stow operation: %s", op.Kind)
+	}
+	return nil
+}
+
+// UnstowLinks removes links that still point into packageDir and returns the removed paths.
+// Links that were replaced or now point elsewhere are left untouched.
+func (fm *FileManager) UnstowLinks(links []string, packageDir string) ([]string, error) {
+	var removed []string
+	for _, link := range links {
+		linkTarget, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(packageDir, ResolveLinkTarget(link, linkTarget))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if fm.dryRun {
+			fmt.Printf("DRY RUN: Would remove link %s\n", link)
+		} else if err := os.Remove(link); err != nil {
+			return removed, fmt.Errorf("failed to remove link %s: %w", link, err)
+		}
+		removed = append(removed, link)
+	}
+	return removed, nil
+}
@@ -0,0 +1,224 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+)
+
+// stowTestEnv holds a Stow-layout repository and a target home directory.
+type stowTestEnv struct {
+	repo string
+	home string
+	fm   *FileManager
+}
+
+func setupStowTestEnvironment(t *testing.T) *stowTestEnv {
+	t.Helper()
+	tempDir := t.TempDir()
+	env := &stowTestEnv{
+		repo: filepath.Join(tempDir, "repo"),
+		home: filepath.Join(tempDir, "home"),
+		fm:   NewFileManager(platform.DetectPlatform(), false),
+	}
+
+	files := []string{
+		"zsh/.zshrc",
+		"zsh/README.md",
+		"nvim/.config/nvim/init.lua",
+		"nvim/.config/nvim/lua/plugins.lua",
+		"fish/.config/fish/config.fish",
+	}
+	for _, name := range files {
+		path := filepath.Join(env.repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.MkdirAll(env.home, 0755); err != nil {
+		t.Fatalf("Failed to create home: %v", err)
+	}
+	return env
+}
+
+func (env *stowTestEnv) stow(t *testing.T, pkg string, opts StowOptions) *StowPlan {
+	t.Helper()
+	opts.OwnerRoot = env.repo
+	plan, err := PlanStow(filepath.Join(env.repo, pkg), env.home, opts)
+	if err != nil {
+		t.Fatalf("PlanStow(%s) failed: %v", pkg, err)
+	}
+	if err := env.fm.ApplyStowPlan(plan); err != nil {
+		t.Fatalf("ApplyStowPlan(%s) failed: %v", pkg, err)
+	}
+	return plan
+}
+
+func assertLinkTo(t *testing.T, link, source string) {
+	t.Helper()
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("Expected %s to be a symlink: %v", link, err)
+	}
+	if !SymlinkPointsTo(link, target, source) {
+		t.Errorf("Expected %s to point to %s, got %s", link, source, target)
+	}
+}
+
+func TestPlanStowFolding(t *testing.T) {
+	env := setupStowTestEnvironment(t)
+
+	plan := env.stow(t, "zsh", StowOptions{Ignore: DefaultStowIgnorePatterns})
+	if !reflect.DeepEqual(plan.Links, []string{filepath.Join(env.home, ".zshrc")}) {
+		t.Errorf("Unexpected links for zsh: %v", plan.Links)
+	}
+	if _, err := os.Lstat(filepath.Join(env.home, "README.md")); !os.IsNotExist(err) {
+		t.Error("Ignored README.md should not be linked")
+	}
+
+	// A missing directory is folded into a single link
+	plan = env.stow(t, "nvim", StowOptions{})
+	if !reflect.DeepEqual(plan.Links, []string{filepath.Join(env.home, ".config")}) {
+		t.Errorf("Expected folded .config link, got %v", plan.Links)
+	}
+	assertLinkTo(t, filepath.Join(env.home, ".config"), filepath.Join(env.repo, "nvim", ".config"))
+
+	// A second package needing .config unfolds the first package's link
+	plan = env.stow(t, "fish", StowOptions{})
+	if !reflect.DeepEqual(plan.Links, []string{filepath.Join(env.home, ".config", "fish")}) {
+		t.Errorf("Unexpected links for fish: %v", plan.Links)
+	}
+	info, err := os.Lstat(filepath.Join(env.home, ".config"))
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Expected .config to be unfolded into a real directory")
+	}
+	assertLinkTo(t, filepath.Join(env.home, ".config", "nvim"), filepath.Join(env.repo, "nvim", ".config", "nvim"))
+	assertLinkTo(t, filepath.Join(env.home, ".config", "fish"), filepath.Join(env.repo, "fish", ".config", "fish"))
+
+	// Re-planning a stowed package is a no-op
+	for _, pkg := range []string{"nvim", "fish", "zsh"} {
+		plan, err := PlanStow(filepath.Join(env.repo, pkg), env.home, StowOptions{Ignore: DefaultStowIgnorePatterns, OwnerRoot: env.repo})
+		if err != nil {
+			t.Fatalf("PlanStow(%s) failed: %v", pkg, err)
+		}
+		if plan.Pending() || len(plan.Conflicts) > 0 {
+			t.Errorf("Expected no pending changes for %s, got %+v", pkg, plan)
+		}
+	}
+}
+
+func TestPlanStowUnfoldIgnored(t *testing.T) {
+	env := setupStowTestEnvironment(t)
+	for _, name := range []string{"nvim/.config/README.md", "nvim/.config/notes.txt", "nvim/.config/.git/HEAD"} {
+		path := filepath.Join(env.repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	opts := StowOptions{Ignore: append([]string{".config/notes.txt"}, DefaultStowIgnorePatterns...)}
+
+	env.stow(t, "nvim", opts)
+	env.stow(t, "fish", opts)
+
+	// Unfolding nvim's .config applies the ignore patterns relative to the nvim package
+	assertLinkTo(t, filepath.Join(env.home, ".config", "nvim"), filepath.Join(env.repo, "nvim", ".config", "nvim"))
+	for _, name := range []string{"README.md", "notes.txt", ".git"} {
+		if _, err := os.Lstat(filepath.Join(env.home, ".config", name)); !os.IsNotExist(err) {
+			t.Errorf("Ignored %s should not be linked when unfolding", name)
+		}
+	}
+}
+
+func TestPlanStowNoFolding(t *testing.T) {
+	env := setupStowTestEnvironment(t)
+
+	plan := env.stow(t, "nvim", StowOptions{NoFolding: true})
+	expected := []string{
+		filepath.Join(env.home, ".config", "nvim", "init.lua"),
+		filepath.Join(env.home, ".config", "nvim", "lua", "plugins.lua"),
+	}
+	if !reflect.DeepEqual(plan.Links, expected) {
+		t.Errorf("Expected per-file links %v, got %v", expected, plan.Links)
+	}
+	info, err := os.Lstat(filepath.Join(env.home, ".config", "nvim", "lua"))
+	if err != nil || !info.IsDir() {
+		t.Error("Expected lua to be a real directory without folding")
+	}
+}
+
+func TestPlanStowConflicts(t *testing.T) {
+	env := setupStowTestEnvironment(t)
+
+	unmanaged := filepath.Join(env.home, ".zshrc")
+	if err := os.WriteFile(unmanaged, []byte("local"), 0644); err != nil {
+		t.Fatalf("Failed to create unmanaged file: %v", err)
+	}
+	foreign := filepath.Join(t.TempDir(), "elsewhere")
+	if err := os.MkdirAll(filepath.Join(foreign, "fish"), 0755); err != nil {
+		t.Fatalf("Failed to create foreign directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(env.home, ".config"), 0755); err != nil {
+		t.Fatalf("Failed to create .config: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(foreign, "fish"), filepath.Join(env.home, ".config", "fish")); err != nil {
+		t.Fatalf("Failed to create foreign link: %v", err)
+	}
+
+	for pkg, conflict := range map[string]string{
+		"zsh":  unmanaged,
+		"fish": filepath.Join(env.home, ".config", "fish"),
+	} {
+		plan, err := PlanStow(filepath.Join(env.repo, pkg), env.home, StowOptions{Ignore: DefaultStowIgnorePatterns, OwnerRoot: env.repo})
+		if err != nil {
+			t.Fatalf("PlanStow(%s) failed: %v", pkg, err)
+		}
+		if !reflect.DeepEqual(plan.Conflicts, []string{conflict}) {
+			t.Errorf("Expected conflict %s for %s, got %v", conflict, pkg, plan.Conflicts)
+		}
+		if err := env.fm.ApplyStowPlan(plan); err == nil {
+			t.Errorf("Expected ApplyStowPlan(%s) to refuse a conflicting plan", pkg)
+		}
+	}
+
+	content, err := os.ReadFile(unmanaged)
+	if err != nil || string(content) != "local" {
+		t.Error("Unmanaged file must be left untouched")
+	}
+}
+
+func TestUnstowLinks(t *testing.T) {
+	env := setupStowTestEnvironment(t)
+	plan := env.stow(t, "nvim", StowOptions{NoFolding: true})
+
+	// A link that was repointed elsewhere must survive
+	repointed := plan.Links[0]
+	if err := os.Remove(repointed); err != nil {
+		t.Fatalf("Failed to remove link: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(env.repo, "zsh", ".zshrc"), repointed); err != nil {
+		t.Fatalf("Failed to repoint link: %v", err)
+	}
+
+	removed, err := env.fm.UnstowLinks(plan.Links, filepath.Join(env.repo, "nvim"))
+	if err != nil {
+		t.Fatalf("UnstowLinks failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, plan.Links[1:]) {
+		t.Errorf("Expected %v removed, got %v", plan.Links[1:], removed)
+	}
+	if _, err := os.Lstat(repointed); err != nil {
+		t.Error("Repointed link should not be removed")
+	}
+}
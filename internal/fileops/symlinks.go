@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CreateRelativeSymlink creates a symbolic link whose target is relative to the link's parent directory.
+// Parent directories are only created with createParents. An existing link at targetPath is
+// replaced, but a file or directory is not: callers back it up and remove it first, as they
+// do for CreateSymlink.
+func (fm *FileManager) CreateRelativeSymlink(sourcePath, targetPath string, createParents bool) error {
+	linkTarget, err := RelativeLinkTarget(sourcePath, targetPath)
+	if err != nil {
+		return err
+	}
+
+	if fm.dryRun {
+		fmt.Printf("DRY RUN: Would create relative symlink %s -> %s\n", targetPath, linkTarget)
+		return nil
+	}
+
+	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+		return fmt.Errorf("source file does not exist: %s", sourcePath)
+	}
+
+	parentDir := filepath.Dir(targetPath)
+	if createParents {
+		if err := os.MkdirAll(parentDir, 0755); err != nil {
+			return fmt.Errorf("failed to create parent directories: %w", err)
+		}
+	} else if _, err := os.Stat(parentDir); os.IsNotExist(err) {
+		return fmt.Errorf("parent directory does not exist: %s", parentDir)
+	}
+
+	if info, err := os.Lstat(targetPath); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("target exists and is not a symlink: %s", targetPath)
+		}
+		if err := os.Remove(targetPath); err != nil {
+			return fmt.Errorf("unable to remove existing symlink: %w", err)
+		}
+	}
+
+	if err := os.Symlink(linkTarget, targetPath); err != nil {
+		return fmt.Errorf("unable to create symlink: %w", err)
+	}
+
+	return nil
+}
+
+// RelativeLinkTarget computes the link target for sourcePath relative to the directory containing linkPath.
+// Symlinked parent directories are resolved first so the result is valid from the link's real location.
+func RelativeLinkTarget(sourcePath, linkPath string) (string, error) {
+	source, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute source path: %w", err)
+	}
+	linkDir, err := filepath.Abs(filepath.Dir(linkPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute link directory: %w", err)
+	}
+
+	realLinkDir, linkErr := filepath.EvalSymlinks(linkDir)
+	realSourceDir, sourceErr := filepath.EvalSymlinks(filepath.Dir(source))
+	if linkErr == nil && sourceErr == nil {
+		linkDir = realLinkDir
+		source = filepath.Join(realSourceDir, filepath.Base(source))
+	}
+
+	rel, err := filepath.Rel(linkDir, source)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute relative path from %s to %s: %w", linkDir, source, err)
+	}
+	return rel, nil
+}
+
+// ResolveLinkTarget returns the absolute path a link target refers to.
+// Relative targets are resolved against the directory containing linkPath.
+func ResolveLinkTarget(linkPath, linkTarget string) string {
+	if filepath.IsAbs(linkTarget) {
+		return filepath.Clean(linkTarget)
+	}
+	return filepath.Join(filepath.Dir(linkPath), linkTarget)
+}
+
+// SymlinkPointsTo reports whether a link target refers to expectedSource,
+// treating absolute and relative forms of the same path as equal.
+func SymlinkPointsTo(linkPath, linkTarget, expectedSource string) bool {
+	actual, err := filepath.Abs(ResolveLinkTarget(linkPath, linkTarget))
+	if err != nil {
+		return false
+	}
+	expected, err := filepath.Abs(expectedSource)
+	if err != nil {
+		return false
+	}
+	if actual == expected {
+		return true
+	}
+
+	// Fall back to real paths, e.g. when a parent directory is itself a symlink
+	realActual, actualErr := filepath.EvalSymlinks(actual)
+	realExpected, expectedErr := filepath.EvalSymlinks(expected)
+	return actualErr == nil && expectedErr == nil && realActual == realExpected
+}
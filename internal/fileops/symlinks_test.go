@@ -0,0 +1,162 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+)
+
+func TestRelativeLinkTarget(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "dotfiles", "fish", "config.fish")
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		linkPath string
+		expected string
+	}{
+		{
+			name:     "sibling directory",
+			linkPath: filepath.Join(tempDir, "home", ".config", "fish", "config.fish"),
+			expected: filepath.Join("..", "..", "..", "dotfiles", "fish", "config.fish"),
+		},
+		{
+			name:     "same directory",
+			linkPath: filepath.Join(tempDir, "dotfiles", "fish", "link.fish"),
+			expected: "config.fish",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RelativeLinkTarget(source, tc.linkPath)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCreateRelativeSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "dotfiles", "gitconfig")
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+	if err := os.WriteFile(source, []byte("[user]\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	fm := NewFileManager(platform.DetectPlatform(), false)
+	link := filepath.Join(tempDir, "home", ".gitconfig")
+
+	// Parent directories are only created on request
+	if err := fm.CreateRelativeSymlink(source, link, false); err == nil {
+		t.Error("Expected error for a missing parent directory without createParents")
+	}
+	if err := fm.CreateRelativeSymlink(source, link, true); err != nil {
+		t.Fatalf("CreateRelativeSymlink failed: %v", err)
+	}
+
+	// An existing link is replaced
+	if err := fm.CreateRelativeSymlink(source, link, false); err != nil {
+		t.Fatalf("CreateRelativeSymlink failed to replace the link: %v", err)
+	}
+
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("Failed to read link: %v", err)
+	}
+	if filepath.IsAbs(target) {
+		t.Errorf("Expected relative link target, got %s", target)
+	}
+
+	content, err := os.ReadFile(link)
+	if err != nil {
+		t.Fatalf("Failed to read through link: %v", err)
+	}
+	if string(content) != "[user]\n" {
+		t.Errorf("Unexpected content through link: %q", content)
+	}
+
+	t.Run("existing file or directory", func(t *testing.T) {
+		file := filepath.Join(tempDir, "home", ".zshrc")
+		if err := os.WriteFile(file, []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to create existing target: %v", err)
+		}
+		dir := filepath.Join(tempDir, "home", ".config")
+		if err := os.MkdirAll(filepath.Join(dir, "nvim"), 0755); err != nil {
+			t.Fatalf("Failed to create existing directory: %v", err)
+		}
+		for _, target := range []string{file, dir} {
+			if err := fm.CreateRelativeSymlink(source, target, true); err == nil {
+				t.Errorf("Expected error for existing %s", target)
+			}
+		}
+		if content, _ := os.ReadFile(file); string(content) != "old" {
+			t.Errorf("Expected the existing file to be kept, got %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "nvim")); err != nil {
+			t.Errorf("Expected the existing directory to be kept: %v", err)
+		}
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		err := fm.CreateRelativeSymlink(filepath.Join(tempDir, "missing"), filepath.Join(tempDir, "home", ".missing"), true)
+		if err == nil {
+			t.Error("Expected error for missing source")
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dryRunLink := filepath.Join(tempDir, "home", ".dry-run")
+		if err := NewFileManager(platform.DetectPlatform(), true).CreateRelativeSymlink(source, dryRunLink, true); err != nil {
+			t.Fatalf("Dry run failed: %v", err)
+		}
+		if _, err := os.Lstat(dryRunLink); !os.IsNotExist(err) {
+			t.Error("Dry run should not create a link")
+		}
+	})
+}
+
+func TestSymlinkPointsTo(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "dotfiles", "vimrc")
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		t.Fatalf("Failed to create source directory: %v", err)
+	}
+	if err := os.WriteFile(source, []byte("set nocompatible\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	link := filepath.Join(tempDir, "home", ".vimrc")
+
+	testCases := []struct {
+		name       string
+		linkTarget string
+		expected   bool
+	}{
+		{name: "absolute", linkTarget: source, expected: true},
+		{name: "relative", linkTarget: filepath.Join("..", "dotfiles", "vimrc"), expected: true},
+		{name: "unclean relative", linkTarget: filepath.Join("..", "home", "..", "dotfiles", "vimrc"), expected: true},
+		{name: "different file", linkTarget: filepath.Join("..", "dotfiles", "gvimrc"), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SymlinkPointsTo(link, tc.linkTarget, source); got != tc.expected {
+				t.Errorf("Expected %v for %s, got %v", tc.expected, tc.linkTarget, got)
+			}
+		})
+	}
+}
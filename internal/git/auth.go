@@ -0,0 +1,230 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// defaultSSHUser is used for SSH URLs that do not name a user, as GitHub and GitLab expect.
+const defaultSSHUser = "git"
+
+// authFor returns the authentication for a remote URL. Explicitly configured credentials
+// take precedence; otherwise SSH remotes use keys from ssh-agent and HTTPS remotes are
+// looked up in the netrc file and then the credential helper. Managers created without
+// an AuthConfig leave authentication to go-git's defaults.
+func (g *GitManager) authFor(repoURL string) (transport.AuthMethod, error) {
+	if g.config == nil {
+		return g.auth, nil
+	}
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return g.auth, nil
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		if _, ok := g.auth.(*ssh.PublicKeys); ok {
+			return g.auth, nil
+		}
+		return sshAgentAuth(endpoint.User, g.config)
+	case "http", "https":
+		if _, ok := g.auth.(*http.BasicAuth); ok {
+			return g.auth, nil
+		}
+		return lookupHTTPCredentials(endpoint, g.config)
+	default:
+		return nil, nil
+	}
+}
+
+// sshAgentAuth authenticates with the keys held by the agent listening on SSH_AUTH_SOCK,
+// including hardware-backed keys that cannot be read from disk.
+func sshAgentAuth(user string, authConfig *AuthConfig) (transport.AuthMethod, error) {
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		return nil, fmt.Errorf("no SSH credentials: set git_ssh_private_key_path or start ssh-agent (SSH_AUTH_SOCK is not set)")
+	}
+	if user == "" {
+		user = defaultSSHUser
+	}
+
+	agentAuth, err := ssh.NewSSHAgentAuth(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+	agentAuth.HostKeyCallback, err = hostKeyCallback(authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return agentAuth, nil
+}
+
+// hostKeyCallback verifies SSH host keys against known_hosts. The configured file is used
+// when set, otherwise SSH_KNOWN_HOSTS or the standard OpenSSH locations. Verification is
+// only skipped when explicitly requested.
+func hostKeyCallback(authConfig *AuthConfig) (gossh.HostKeyCallback, error) {
+	if authConfig.SSHSkipHostKeyVerification {
+		return gossh.InsecureIgnoreHostKey(), nil // #nosec G106 -- explicit opt-in
+	}
+
+	var files []string
+	if authConfig.SSHKnownHostsPath != "" {
+		files = append(files, authConfig.SSHKnownHostsPath)
+	}
+	callback, err := ssh.NewKnownHostsCallback(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// lookupHTTPCredentials finds credentials for an HTTP(S) remote in the netrc file, then
+// asks the configured credential helper. Remotes without credentials are accessed anonymously.
+func lookupHTTPCredentials(endpoint *transport.Endpoint, authConfig *AuthConfig) (transport.AuthMethod, error) {
+	if endpoint.User != "" && endpoint.Password != "" {
+		return &http.BasicAuth{Username: endpoint.User, Password: endpoint.Password}, nil
+	}
+
+	netrcPath := authConfig.NetrcPath
+	if netrcPath == "" {
+		netrcPath = defaultNetrcPath()
+	}
+	if netrcPath != "" {
+		auth, err := netrcCredentials(netrcPath, endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		if auth != nil {
+			return auth, nil
+		}
+	}
+
+	if authConfig.CredentialHelper != "" {
+		return helperCredentials(authConfig.CredentialHelper, endpoint)
+	}
+	return nil, nil
+}
+
+// defaultNetrcPath returns $NETRC, or ~/.netrc when it exists.
+func defaultNetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".netrc")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// netrcCredentials returns the login and password for host from a netrc file, falling back
+// to the default entry. It returns nil when the file has no matching entry.
+func netrcCredentials(path, host string) (transport.AuthMethod, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read netrc file %s: %w", path, err)
+	}
+
+	var match, fallback map[string]string
+	var current map[string]string
+	tokens := strings.Fields(string(content))
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = make(map[string]string)
+			if i+1 < len(tokens) {
+				i++
+				if tokens[i] == host && match == nil {
+					match = current
+				}
+			}
+		case "default":
+			current = make(map[string]string)
+			if fallback == nil {
+				fallback = current
+			}
+		case "login", "password", "account":
+			if current != nil && i+1 < len(tokens) {
+				current[tokens[i]] = tokens[i+1]
+			}
+			i++
+		case "macdef":
+			// Macro definitions run to the next blank line, which Fields has collapsed; stop parsing
+			i = len(tokens)
+		}
+	}
+
+	if match == nil {
+		match = fallback
+	}
+	if match == nil || match["password"] == "" {
+		return nil, nil
+	}
+	return &http.BasicAuth{Username: match["login"], Password: match["password"]}, nil
+}
+
+// helperCredentials asks a git credential helper for credentials using the
+// git-credential(1) protocol. Helpers are named as in git's credential.helper setting:
+// "store" runs git-credential-store, an absolute path runs that program, and a value
+// starting with "!" runs as a shell command.
+func helperCredentials(helper string, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	var cmd *exec.Cmd
+	switch {
+	case strings.HasPrefix(helper, "!"):
+		cmd = exec.Command("/bin/sh", "-c", helper[1:]+" get") // #nosec G204 -- helper is user configuration
+	default:
+		args := strings.Fields(helper)
+		if len(args) == 0 {
+			return nil, nil
+		}
+		if !filepath.IsAbs(args[0]) {
+			args[0] = "git-credential-" + args[0]
+		}
+		cmd = exec.Command(args[0], append(args[1:], "get")...) // #nosec G204 -- helper is user configuration
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\n\n", endpoint.Protocol, endpointHost(endpoint))
+	cmd.Stdin = &input
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %q failed: %w", helper, err)
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if key, value, found := strings.Cut(scanner.Text(), "="); found {
+			values[key] = value
+		}
+	}
+	if values["password"] == "" {
+		return nil, nil
+	}
+	return &http.BasicAuth{Username: values["username"], Password: values["password"]}, nil
+}
+
+// endpointHost returns the host of an endpoint, with the port when it is not the default.
+func endpointHost(endpoint *transport.Endpoint) string {
+	if endpoint.Port == 0 {
+		return endpoint.Host
+	}
+	return fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port)
+}
@@ -0,0 +1,250 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestAuthForSSH(t *testing.T) {
+	tempDir := t.TempDir()
+	startTestAgent(t, filepath.Join(tempDir, "agent.sock"))
+
+	hostKey := newTestPublicKey(t)
+	knownHostsPath := filepath.Join(tempDir, "known_hosts")
+	knownHosts := knownhosts.Line([]string{"github.com"}, hostKey) + "\n"
+	if err := os.WriteFile(knownHostsPath, []byte(knownHosts), 0600); err != nil {
+		t.Fatalf("Failed to write known_hosts: %v", err)
+	}
+	remoteAddr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+
+	t.Run("ssh-agent with known_hosts", func(t *testing.T) {
+		manager, err := NewGitManager(&AuthConfig{SSHKnownHostsPath: knownHostsPath})
+		if err != nil {
+			t.Fatalf("NewGitManager failed: %v", err)
+		}
+		auth, err := manager.authFor("git@github.com:user/dotfiles.git")
+		if err != nil {
+			t.Fatalf("authFor failed: %v", err)
+		}
+		agentAuth, ok := auth.(*ssh.PublicKeysCallback)
+		if !ok {
+			t.Fatalf("Expected ssh-agent auth, got %T", auth)
+		}
+		if agentAuth.User != "git" {
+			t.Errorf("Expected user git, got %s", agentAuth.User)
+		}
+
+		if err := agentAuth.HostKeyCallback("github.com:22", remoteAddr, hostKey); err != nil {
+			t.Errorf("Known host key was rejected: %v", err)
+		}
+		if err := agentAuth.HostKeyCallback("github.com:22", remoteAddr, newTestPublicKey(t)); err == nil {
+			t.Error("Mismatched host key should be rejected")
+		}
+		if err := agentAuth.HostKeyCallback("gitlab.com:22", remoteAddr, hostKey); err == nil {
+			t.Error("Unknown host should be rejected")
+		}
+	})
+
+	t.Run("insecure_ignore_host_key", func(t *testing.T) {
+		manager, err := NewGitManager(&AuthConfig{SSHSkipHostKeyVerification: true})
+		if err != nil {
+			t.Fatalf("NewGitManager failed: %v", err)
+		}
+		auth, err := manager.authFor("ssh://deploy@gitlab.com/user/dotfiles.git")
+		if err != nil {
+			t.Fatalf("authFor failed: %v", err)
+		}
+		agentAuth := auth.(*ssh.PublicKeysCallback)
+		if agentAuth.User != "deploy" {
+			t.Errorf("Expected user from URL, got %s", agentAuth.User)
+		}
+		if err := agentAuth.HostKeyCallback("gitlab.com:22", remoteAddr, hostKey); err != nil {
+			t.Errorf("Host key check should be skipped: %v", err)
+		}
+	})
+
+	t.Run("missing known_hosts", func(t *testing.T) {
+		manager, err := NewGitManager(&AuthConfig{SSHKnownHostsPath: filepath.Join(tempDir, "missing")})
+		if err != nil {
+			t.Fatalf("NewGitManager failed: %v", err)
+		}
+		if _, err := manager.authFor("git@github.com:user/dotfiles.git"); err == nil {
+			t.Error("Expected error for missing known_hosts file")
+		}
+	})
+
+	t.Run("no agent", func(t *testing.T) {
+		t.Setenv("SSH_AUTH_SOCK", "")
+		manager, err := NewGitManager(&AuthConfig{SSHKnownHostsPath: knownHostsPath})
+		if err != nil {
+			t.Fatalf("NewGitManager failed: %v", err)
+		}
+		_, err = manager.authFor("git@github.com:user/dotfiles.git")
+		if err == nil || !strings.Contains(err.Error(), "ssh-agent") {
+			t.Errorf("Expected error pointing at ssh-agent, got %v", err)
+		}
+	})
+}
+
+func TestAuthForHTTPS(t *testing.T) {
+	tempDir := t.TempDir()
+	netrcPath := filepath.Join(tempDir, "netrc")
+	netrc := "machine github.com\n  login octocat\n  password netrc-token\n\ndefault login anonymous password guest\n"
+	if err := os.WriteFile(netrcPath, []byte(netrc), 0600); err != nil {
+		t.Fatalf("Failed to write netrc: %v", err)
+	}
+	emptyNetrc := filepath.Join(tempDir, "empty-netrc")
+	if err := os.WriteFile(emptyNetrc, nil, 0600); err != nil {
+		t.Fatalf("Failed to write netrc: %v", err)
+	}
+	helperPath := filepath.Join(tempDir, "helper")
+	helper := "#!/bin/sh\ngrep -q '^host=git.example.com$' && printf 'username=helper\\npassword=helper-token\\n'\nexit 0\n"
+	if err := os.WriteFile(helperPath, []byte(helper), 0755); err != nil {
+		t.Fatalf("Failed to write credential helper: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		authConfig *AuthConfig
+		url        string
+		username   string
+		password   string
+	}{
+		{
+			name:       "netrc machine",
+			authConfig: &AuthConfig{NetrcPath: netrcPath},
+			url:        "https://github.com/user/dotfiles.git",
+			username:   "octocat",
+			password:   "netrc-token",
+		},
+		{
+			name:       "netrc default",
+			authConfig: &AuthConfig{NetrcPath: netrcPath},
+			url:        "https://git.example.com/user/dotfiles.git",
+			username:   "anonymous",
+			password:   "guest",
+		},
+		{
+			name:       "token takes precedence",
+			authConfig: &AuthConfig{NetrcPath: netrcPath, PersonalAccessToken: "pat", Username: "me"},
+			url:        "https://github.com/user/dotfiles.git",
+			username:   "me",
+			password:   "pat",
+		},
+		{
+			name:       "credential helper",
+			authConfig: &AuthConfig{NetrcPath: emptyNetrc, CredentialHelper: helperPath},
+			url:        "https://git.example.com/user/dotfiles.git",
+			username:   "helper",
+			password:   "helper-token",
+		},
+		{
+			name:       "shell credential helper",
+			authConfig: &AuthConfig{NetrcPath: emptyNetrc, CredentialHelper: "!" + helperPath},
+			url:        "https://git.example.com/user/dotfiles.git",
+			username:   "helper",
+			password:   "helper-token",
+		},
+		{
+			name:       "anonymous",
+			authConfig: &AuthConfig{NetrcPath: emptyNetrc, CredentialHelper: helperPath},
+			url:        "https://github.com/user/dotfiles.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := NewGitManager(tt.authConfig)
+			if err != nil {
+				t.Fatalf("NewGitManager failed: %v", err)
+			}
+			auth, err := manager.authFor(tt.url)
+			if err != nil {
+				t.Fatalf("authFor failed: %v", err)
+			}
+
+			if tt.password == "" {
+				if auth != nil {
+					t.Errorf("Expected anonymous access, got %v", auth)
+				}
+				return
+			}
+			basicAuth, ok := auth.(*http.BasicAuth)
+			if !ok {
+				t.Fatalf("Expected basic auth, got %T", auth)
+			}
+			if basicAuth.Username != tt.username || basicAuth.Password != tt.password {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.username, tt.password, basicAuth.Username, basicAuth.Password)
+			}
+		})
+	}
+
+	// Local repositories never need credentials
+	manager, err := NewGitManager(&AuthConfig{NetrcPath: netrcPath})
+	if err != nil {
+		t.Fatalf("NewGitManager failed: %v", err)
+	}
+	if auth, err := manager.authFor(tempDir); auth != nil || err != nil {
+		t.Errorf("Expected no auth for a local path, got %v, %v", auth, err)
+	}
+}
+
+// startTestAgent serves an in-memory ssh-agent holding one key on socketPath and points SSH_AUTH_SOCK at it.
+func startTestAgent(t *testing.T, socketPath string) {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: privateKey}); err != nil {
+		t.Fatalf("Failed to add key to agent: %v", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socketPath, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socketPath)
+}
+
+// newTestPublicKey generates a random ed25519 public key.
+func newTestPublicKey(t *testing.T) gossh.PublicKey {
+	t.Helper()
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := gossh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	return key
+}
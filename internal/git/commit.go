@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Author identifies who a commit is attributed to.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// CommitFiles stages files, relative to localPath, and commits them with message. localPath
+// may be a subdirectory of the repository's worktree. Other changes in the working tree are
+// left unstaged, and it refuses to commit when changes to other paths are already staged, so
+// the commit holds only the named files. When author is nil, user.name and user.email are
+// read from the repository and global Git configuration. It returns an empty hash when none
+// of the files differ from HEAD.
+func CommitFiles(localPath string, files []string, message string, author *Author) (string, error) {
+	repo, err := git.PlainOpenWithOptions(localPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	prefix, err := worktreePrefix(worktree.Filesystem.Root(), localPath)
+	if err != nil {
+		return "", err
+	}
+
+	paths := make(map[string]bool, len(files))
+	for _, file := range files {
+		paths[filepath.ToSlash(filepath.Join(prefix, file))] = true
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return "", fmt.Errorf("failed to get status: %w", err)
+	}
+	var foreign []string
+	for path, fileStatus := range status {
+		if isStaged(fileStatus) && !paths[path] {
+			foreign = append(foreign, path)
+		}
+	}
+	if len(foreign) > 0 {
+		sort.Strings(foreign)
+		return "", fmt.Errorf("changes to other files are already staged (%s): commit or unstage them first", strings.Join(foreign, ", "))
+	}
+
+	for path := range paths {
+		if _, err := worktree.Add(path); err != nil {
+			return "", fmt.Errorf("failed to stage %s: %w", path, err)
+		}
+	}
+
+	status, err = worktree.Status()
+	if err != nil {
+		return "", fmt.Errorf("failed to get status: %w", err)
+	}
+	staged := false
+	for path := range paths {
+		if isStaged(status.File(path)) {
+			staged = true
+			break
+		}
+	}
+	if !staged {
+		return "", nil
+	}
+
+	options := &git.CommitOptions{}
+	if author != nil {
+		options.Author = &object.Signature{Name: author.Name, Email: author.Email, When: time.Now()}
+	}
+	hash, err := worktree.Commit(message, options)
+	if errors.Is(err, git.ErrMissingAuthor) {
+		return "", fmt.Errorf("no commit author: set user.name and user.email in the Git configuration or configure an author explicitly")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+	return hash.String(), nil
+}
+
+// isStaged reports whether fileStatus has a change in the index.
+func isStaged(fileStatus *git.FileStatus) bool {
+	return fileStatus.Staging != git.Unmodified && fileStatus.Staging != git.Untracked
+}
+
+// worktreePrefix returns localPath relative to the worktree root, or "" when they are the same.
+func worktreePrefix(root, localPath string) (string, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve worktree root: %w", err)
+	}
+	resolvedPath, err := filepath.EvalSymlinks(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository path: %w", err)
+	}
+	prefix, err := filepath.Rel(resolvedRoot, resolvedPath)
+	if err != nil || prefix == ".." || strings.HasPrefix(prefix, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not inside the worktree %s", localPath, root)
+	}
+	if prefix == "." {
+		return "", nil
+	}
+	return prefix, nil
+}
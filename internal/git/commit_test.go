@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestCommitFiles(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := createTestRepository(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	initial := commitAll(t, repo, "initial")
+	author := &Author{Name: "Capture", Email: "capture@example.com"}
+
+	// Nothing to commit
+	hash, err := CommitFiles(repoPath, []string{"README.md"}, "no-op", author)
+	if err != nil || hash != "" {
+		t.Fatalf("Expected no commit for unchanged files, got %q, %v", hash, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("captured\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify README.md: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoPath, "zsh"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "zsh", "zshrc"), []byte("export EDITOR=vim\n"), 0644); err != nil {
+		t.Fatalf("Failed to write zshrc: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "unrelated.txt"), []byte("scratch\n"), 0644); err != nil {
+		t.Fatalf("Failed to write unrelated file: %v", err)
+	}
+
+	hash, err = CommitFiles(repoPath, []string{"README.md", filepath.Join("zsh", "zshrc")}, "capture local edits", author)
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	if hash == "" || hash == initial {
+		t.Fatalf("Expected a new commit, got %q", hash)
+	}
+
+	commit, err := HeadCommit(repoPath)
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	if commit.Hash != hash || commit.Message != "capture local edits" || commit.Author != "Capture" {
+		t.Errorf("Unexpected commit %+v", commit)
+	}
+
+	// Only the named files were committed
+	status, err := RepositoryStatus(repoPath)
+	if err != nil {
+		t.Fatalf("RepositoryStatus failed: %v", err)
+	}
+	if status.IsDirty() {
+		t.Errorf("Expected committed files to be clean, got %v", status.ModifiedFiles)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	fullStatus, err := worktree.Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if staging := fullStatus.File("unrelated.txt").Staging; staging != git.Untracked {
+		t.Errorf("Unrelated file should not be staged, got %c", staging)
+	}
+}
+
+func TestCommitFilesRefusesForeignStagedChanges(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := createTestRepository(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	initial := commitAll(t, repo, "initial")
+	author := &Author{Name: "Capture", Email: "capture@example.com"}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "staged.txt"), []byte("work in progress\n"), 0644); err != nil {
+		t.Fatalf("Failed to write staged file: %v", err)
+	}
+	if _, err := worktree.Add("staged.txt"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("captured\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify README.md: %v", err)
+	}
+
+	if _, err := CommitFiles(repoPath, []string{"README.md"}, "capture local edits", author); err == nil || !strings.Contains(err.Error(), "staged.txt") {
+		t.Fatalf("Expected CommitFiles to refuse a foreign staged change, got %v", err)
+	}
+	commit, err := HeadCommit(repoPath)
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	if commit.Hash != initial {
+		t.Errorf("Expected HEAD to stay at %s, got %s", initial, commit.Hash)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if staging := status.File("README.md").Staging; staging != git.Unmodified {
+		t.Errorf("README.md should not have been staged, got %c", staging)
+	}
+}
+
+func TestCommitFilesSubdirectory(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := createTestRepository(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	dotfiles := filepath.Join(repoPath, "dotfiles")
+	if err := os.MkdirAll(dotfiles, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dotfiles, "vimrc"), []byte("set number\n"), 0644); err != nil {
+		t.Fatalf("Failed to write vimrc: %v", err)
+	}
+	commitAll(t, repo, "initial")
+	author := &Author{Name: "Capture", Email: "capture@example.com"}
+
+	if err := os.WriteFile(filepath.Join(dotfiles, "vimrc"), []byte("set relativenumber\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify vimrc: %v", err)
+	}
+	hash, err := CommitFiles(dotfiles, []string{"vimrc"}, "capture local edits", author)
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	if hash == "" {
+		t.Fatal("Expected a new commit")
+	}
+	status, err := RepositoryStatus(repoPath)
+	if err != nil {
+		t.Fatalf("RepositoryStatus failed: %v", err)
+	}
+	if status.IsDirty() {
+		t.Errorf("Expected dotfiles/vimrc to be committed, got %v", status.ModifiedFiles)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// MirrorRemote is the remote checkouts backed by a mirror are fetched from. Origin keeps
+// the source URL, so status reports and pushes still go to the real remote.
+const MirrorRemote = "dotfiles-mirror"
+
+// lastFetchMarker is written inside a mirror after every successful fetch. A mirror
+// without it was interrupted while cloning and is recreated.
+const lastFetchMarker = "dotfiles-last-fetch"
+
+// Mirrors are shared between workspaces and concurrent runs, so each one is guarded by a
+// lock file next to it. The holder refreshes the lock while it works; a lock that has not
+// been refreshed within mirrorLockStale was left behind by a crashed process and is taken over.
+const (
+	mirrorLockSuffix    = ".lock"
+	mirrorLockPoll      = 100 * time.Millisecond
+	mirrorLockHeartbeat = 30 * time.Second
+	mirrorLockStale     = 2 * time.Minute
+)
+
+// MirrorCache keeps bare mirrors of remote repositories, keyed by normalized URL, that
+// checkouts are cloned from and updated against. Each mirror is fetched at most once per
+// run, and not at all while it is younger than the maximum age, so checkouts can be
+// created and updated without network access.
+type MirrorCache struct {
+	root   string
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	mirrors map[string]*mirrorEntry
+}
+
+// mirrorEntry serializes synchronization of one mirror and remembers its outcome for the run.
+type mirrorEntry struct {
+	mu     sync.Mutex
+	synced *MirrorInfo
+}
+
+// MirrorInfo describes a synchronized mirror.
+type MirrorInfo struct {
+	// Path is the bare mirror repository, usable as a clone URL
+	Path string
+	// LastFetch is when the mirror was last fetched from the remote
+	LastFetch time.Time
+	// FetchError is set when the remote could not be reached and the existing mirror is used as is
+	FetchError error
+}
+
+// NewMirrorCache creates a mirror cache under root. Mirrors fetched less than maxAge ago
+// are used without contacting the remote; zero fetches every mirror once per run.
+func NewMirrorCache(root string, maxAge time.Duration) *MirrorCache {
+	return &MirrorCache{
+		root:    root,
+		maxAge:  maxAge,
+		mirrors: make(map[string]*mirrorEntry),
+	}
+}
+
+// MirrorPath returns where the mirror of repoURL is kept. The directory is named after
+// the host and repository path, with a hash of the full URL to keep distinct URLs apart.
+func (c *MirrorCache) MirrorPath(repoURL string) (string, error) {
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	host := endpoint.Host
+	if host == "" {
+		host = "local"
+	}
+	name := strings.TrimSuffix(strings.Trim(filepath.ToSlash(endpoint.Path), "/"), ".git")
+	sum := sha256.Sum256([]byte(repoURL))
+
+	safeName := strings.NewReplacer(":", "_", "?", "_", "*", "_", "~", "_").Replace(name)
+	return filepath.Join(c.root, strings.ReplaceAll(host, ":", "_"), filepath.FromSlash(safeName)+"-"+hex.EncodeToString(sum[:4])+".git"), nil
+}
+
+// Sync ensures the mirror of repoURL exists and is current, cloning or fetching it as needed.
+// When a fetch fails but a mirror already exists, the mirror is returned with FetchError set.
+func (c *MirrorCache) Sync(ctx context.Context, gm *GitManager, repoURL string) (*MirrorInfo, error) {
+	normalizedURL, err := NormalizeGitURL(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize URL: %w", err)
+	}
+	mirrorPath, err := c.MirrorPath(normalizedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.mirrors[normalizedURL]
+	if !ok {
+		entry = &mirrorEntry{}
+		c.mirrors[normalizedURL] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.synced != nil {
+		return entry.synced, nil
+	}
+
+	info, err := c.sync(ctx, gm, normalizedURL, mirrorPath)
+	if err != nil {
+		return nil, err
+	}
+	entry.synced = info
+	return info, nil
+}
+
+// sync clones a missing mirror or fetches a stale one while holding the mirror's lock.
+func (c *MirrorCache) sync(ctx context.Context, gm *GitManager, repoURL, mirrorPath string) (*MirrorInfo, error) {
+	unlock, err := lockMirror(ctx, mirrorPath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	marker := filepath.Join(mirrorPath, lastFetchMarker)
+	stat, err := os.Stat(marker)
+	if err != nil {
+		if err := cloneMirror(ctx, gm, repoURL, mirrorPath); err != nil {
+			return nil, err
+		}
+		return touchMirror(mirrorPath)
+	}
+
+	info := &MirrorInfo{Path: mirrorPath, LastFetch: stat.ModTime()}
+	if c.maxAge > 0 && time.Since(info.LastFetch) < c.maxAge {
+		return info, nil
+	}
+
+	if err := fetchMirror(ctx, gm, repoURL, mirrorPath); err != nil {
+		info.FetchError = err
+		return info, nil
+	}
+	return touchMirror(mirrorPath)
+}
+
+// cloneMirror creates a bare mirror of repoURL, replacing any incomplete one.
+func cloneMirror(ctx context.Context, gm *GitManager, repoURL, mirrorPath string) error {
+	if err := os.RemoveAll(mirrorPath); err != nil {
+		return fmt.Errorf("failed to remove incomplete mirror: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(mirrorPath), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	auth, err := gm.authFor(repoURL)
+	if err != nil {
+		return err
+	}
+	_, err = git.PlainCloneContext(ctx, mirrorPath, true, &git.CloneOptions{
+		URL:    repoURL,
+		Auth:   auth,
+		Mirror: true,
+	})
+	if err != nil {
+		os.RemoveAll(mirrorPath)
+		return fmt.Errorf("failed to create mirror: %w", err)
+	}
+	return nil
+}
+
+// fetchMirror updates every ref of a mirror from its remote, pruning deleted branches and tags.
+func fetchMirror(ctx context.Context, gm *GitManager, repoURL, mirrorPath string) error {
+	repo, err := git.PlainOpen(mirrorPath)
+	if err != nil {
+		return fmt.Errorf("failed to open mirror: %w", err)
+	}
+	auth, err := gm.authFor(repoURL)
+	if err != nil {
+		return err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		Force:      true,
+		Prune:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch mirror: %w", err)
+	}
+	return nil
+}
+
+// lockMirror takes the lock guarding mirrorPath against other processes, waiting until it
+// is released, taken over as stale, or ctx is done. The returned function releases it.
+func lockMirror(ctx context.Context, mirrorPath string) (func(), error) {
+	lockPath := mirrorPath + mirrorLockSuffix
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	// The token identifies this holder, so only its own lock is refreshed and released
+	token := fmt.Sprintf("%d %d\n", os.Getpid(), time.Now().UnixNano())
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, writeErr := file.WriteString(token)
+			closeErr := file.Close()
+			if writeErr != nil || closeErr != nil {
+				_ = os.Remove(lockPath)
+				return nil, fmt.Errorf("failed to lock mirror: %w", errors.Join(writeErr, closeErr))
+			}
+			return holdMirrorLock(lockPath, token), nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock mirror: %w", err)
+		}
+
+		if holder, stale := staleMirrorLock(lockPath); stale {
+			takeOverMirrorLock(lockPath, holder, token)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for mirror lock %s: %w", lockPath, ctx.Err())
+		case <-time.After(mirrorLockPoll):
+		}
+	}
+}
+
+// staleMirrorLock returns the token of the lock at lockPath and whether it is stale.
+func staleMirrorLock(lockPath string) (string, bool) {
+	stat, err := os.Stat(lockPath)
+	if err != nil || time.Since(stat.ModTime()) <= mirrorLockStale {
+		return "", false
+	}
+	holder, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", false
+	}
+	return string(holder), true
+}
+
+// takeOverMirrorLock removes the stale lock held by holder. It is renamed aside first, so of
+// several waiters only one claims it. A lock that turns out to have been replaced by a live
+// holder in the meantime is put back.
+func takeOverMirrorLock(lockPath, holder, token string) {
+	claimed := fmt.Sprintf("%s.%s.stale", lockPath, strings.ReplaceAll(strings.TrimSpace(token), " ", "-"))
+	if err := os.Rename(lockPath, claimed); err != nil {
+		return
+	}
+	if current, stale := staleMirrorLock(claimed); !stale || current != holder {
+		_ = os.Link(claimed, lockPath)
+	}
+	_ = os.Remove(claimed)
+}
+
+// ownsMirrorLock reports whether the lock at lockPath is still the one taken with token.
+func ownsMirrorLock(lockPath, token string) bool {
+	holder, err := os.ReadFile(lockPath)
+	return err == nil && string(holder) == token
+}
+
+// holdMirrorLock keeps the lock at lockPath fresh until the returned release function is
+// called, which removes it unless another process has taken it over.
+func holdMirrorLock(lockPath, token string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(mirrorLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if ownsMirrorLock(lockPath, token) {
+					now := time.Now()
+					_ = os.Chtimes(lockPath, now, now)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if ownsMirrorLock(lockPath, token) {
+			_ = os.Remove(lockPath)
+		}
+	}
+}
+
+// touchMirror records a successful fetch.
+func touchMirror(mirrorPath string) (*MirrorInfo, error) {
+	now := time.Now()
+	if err := os.WriteFile(filepath.Join(mirrorPath, lastFetchMarker), []byte(now.UTC().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to record mirror fetch: %w", err)
+	}
+	return &MirrorInfo{Path: mirrorPath, LastFetch: now}, nil
+}
+
+// SetCheckoutRemotes points origin of the checkout in localPath at sourceURL and, when
+// mirrorPath is set, adds the MirrorRemote remote fetching from it into origin's tracking
+// branches. An empty mirrorPath removes the mirror remote, e.g. when the cache is disabled.
+func SetCheckoutRemotes(localPath, sourceURL, mirrorPath string) error {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	origin, ok := cfg.Remotes["origin"]
+	if !ok {
+		return fmt.Errorf("repository has no origin remote")
+	}
+	mirror, hasMirror := cfg.Remotes[MirrorRemote]
+	if len(origin.URLs) == 1 && origin.URLs[0] == sourceURL {
+		if mirrorPath == "" && !hasMirror {
+			return nil
+		}
+		if hasMirror && len(mirror.URLs) == 1 && mirror.URLs[0] == mirrorPath {
+			return nil
+		}
+	}
+
+	origin.URLs = []string{sourceURL}
+	if mirrorPath == "" {
+		delete(cfg.Remotes, MirrorRemote)
+	} else {
+		cfg.Remotes[MirrorRemote] = &config.RemoteConfig{
+			Name:  MirrorRemote,
+			URLs:  []string{mirrorPath},
+			Fetch: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+		}
+	}
+
+	if err := repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to update remotes: %w", err)
+	}
+	return nil
+}
+
+// fetchRemote returns the remote a checkout is updated from: the mirror remote when the
+// checkout is backed by a mirror, otherwise origin.
+func fetchRemote(repo *git.Repository) (*git.Remote, error) {
+	if remote, err := repo.Remote(MirrorRemote); err == nil {
+		return remote, nil
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote: %w", err)
+	}
+	return remote, nil
+}
+
+// remoteURL returns the first URL of remote, or "" when it has none.
+func remoteURL(remote *git.Remote) string {
+	if urls := remote.Config().URLs; len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+// originURL returns the URL of the origin remote, or "" when there is none.
+func originURL(repo *git.Repository) string {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return ""
+	}
+	return remoteURL(remote)
+}
@@ -0,0 +1,321 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestMirrorCache(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	commitAll(t, upstream, "initial")
+
+	manager, err := NewGitManager(&AuthConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	ctx := context.Background()
+	mirrorRoot := filepath.Join(tempDir, "mirrors")
+
+	cache := NewMirrorCache(mirrorRoot, 0)
+	mirror, err := cache.Sync(ctx, manager, upstreamDir)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if mirror.FetchError != nil {
+		t.Fatalf("Unexpected fetch error: %v", mirror.FetchError)
+	}
+	expectedPath, _ := cache.MirrorPath(upstreamDir)
+	if mirror.Path != expectedPath {
+		t.Errorf("Expected mirror at %s, got %s", expectedPath, mirror.Path)
+	}
+	if _, err := os.Stat(filepath.Join(mirror.Path, "HEAD")); err != nil {
+		t.Errorf("Expected a bare repository at %s: %v", mirror.Path, err)
+	}
+
+	// Checkouts clone from the mirror
+	localDir := filepath.Join(tempDir, "local")
+	info, err := manager.CloneRepository(ctx, mirror.Path, localDir, "")
+	if err != nil {
+		t.Fatalf("Failed to clone from mirror: %v", err)
+	}
+	initial := mirrorHead(t, mirror.Path)
+	if info.LastCommit != initial {
+		t.Errorf("Expected checkout at %s, got %s", initial, info.LastCommit)
+	}
+
+	// A second sync in the same run does not fetch again
+	if err := os.WriteFile(filepath.Join(upstreamDir, "zshrc"), []byte("zshrc"), 0644); err != nil {
+		t.Fatalf("Failed to write zshrc: %v", err)
+	}
+	commitAll(t, upstream, "add zshrc")
+	if _, err := cache.Sync(ctx, manager, upstreamDir); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if head := mirrorHead(t, mirror.Path); head != initial {
+		t.Errorf("Expected no fetch within a run, mirror moved to %s", head)
+	}
+
+	// A fresh mirror is used as is in the next run
+	if _, err := NewMirrorCache(mirrorRoot, time.Hour).Sync(ctx, manager, upstreamDir); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if head := mirrorHead(t, mirror.Path); head != initial {
+		t.Errorf("Expected fresh mirror not to be fetched, moved to %s", head)
+	}
+
+	// Without a maximum age the next run fetches
+	if _, err := NewMirrorCache(mirrorRoot, 0).Sync(ctx, manager, upstreamDir); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	latest := mirrorHead(t, mirror.Path)
+	if latest == initial {
+		t.Error("Expected stale mirror to be fetched")
+	}
+
+	// An unreachable remote falls back to the existing mirror
+	if err := os.RemoveAll(upstreamDir); err != nil {
+		t.Fatalf("Failed to remove upstream: %v", err)
+	}
+	offline, err := NewMirrorCache(mirrorRoot, 0).Sync(ctx, manager, upstreamDir)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if offline.FetchError == nil || offline.Path != mirror.Path {
+		t.Errorf("Expected stale mirror with fetch error, got %+v", offline)
+	}
+	if _, err := manager.UpdateRepository(ctx, localDir); err != nil {
+		t.Fatalf("Failed to update from mirror offline: %v", err)
+	}
+	if head, _ := HeadCommit(localDir); head.Hash != latest {
+		t.Errorf("Expected checkout at %s, got %s", latest, head.Hash)
+	}
+
+	// Without a mirror there is nothing to fall back to
+	if _, err := NewMirrorCache(filepath.Join(tempDir, "empty"), 0).Sync(ctx, manager, upstreamDir); err == nil {
+		t.Error("Expected error creating a mirror of an unreachable remote")
+	}
+}
+
+func TestMirrorCacheSharedBetweenProcesses(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	commitAll(t, upstream, "initial")
+
+	manager, err := NewGitManager(&AuthConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	mirrorRoot := filepath.Join(tempDir, "mirrors")
+
+	// Separate caches stand in for separate workspaces sharing the directory
+	const workers = 4
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			_, err := NewMirrorCache(mirrorRoot, 0).Sync(context.Background(), manager, upstreamDir)
+			errs <- err
+		}()
+	}
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Concurrent sync failed: %v", err)
+		}
+	}
+
+	mirrorPath, _ := NewMirrorCache(mirrorRoot, 0).MirrorPath(upstreamDir)
+	if _, err := git.PlainOpen(mirrorPath); err != nil {
+		t.Errorf("Expected an intact mirror after concurrent syncs: %v", err)
+	}
+	if _, err := os.Stat(mirrorPath + mirrorLockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the mirror lock to be released, got %v", err)
+	}
+}
+
+func TestLockMirror(t *testing.T) {
+	mirrorPath := filepath.Join(t.TempDir(), "host", "repo.git")
+
+	unlock, err := lockMirror(context.Background(), mirrorPath)
+	if err != nil {
+		t.Fatalf("Failed to take mirror lock: %v", err)
+	}
+
+	// A second holder waits until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 3*mirrorLockPoll)
+	defer cancel()
+	if _, err := lockMirror(ctx, mirrorPath); err == nil {
+		t.Fatal("Expected a held mirror lock to block other holders")
+	}
+
+	unlock()
+	unlock, err = lockMirror(context.Background(), mirrorPath)
+	if err != nil {
+		t.Fatalf("Failed to take released mirror lock: %v", err)
+	}
+
+	// A lock abandoned by a crashed process is taken over once stale
+	stale := time.Now().Add(-2 * mirrorLockStale)
+	if err := os.Chtimes(mirrorPath+mirrorLockSuffix, stale, stale); err != nil {
+		t.Fatalf("Failed to age mirror lock: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	takeover, err := lockMirror(ctx, mirrorPath)
+	if err != nil {
+		t.Fatalf("Expected a stale mirror lock to be taken over: %v", err)
+	}
+
+	// The holder whose lock was taken over does not release the new holder's lock
+	unlock()
+	if _, err := os.Stat(mirrorPath + mirrorLockSuffix); err != nil {
+		t.Errorf("Expected the new holder to keep its lock: %v", err)
+	}
+	takeover()
+	if _, err := os.Stat(mirrorPath + mirrorLockSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
+
+func TestLockMirrorStaleTakeoverIsExclusive(t *testing.T) {
+	mirrorPath := filepath.Join(t.TempDir(), "host", "repo.git")
+	lockPath := mirrorPath + mirrorLockSuffix
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		t.Fatalf("Failed to create mirror directory: %v", err)
+	}
+	if err := os.WriteFile(lockPath, []byte("1 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write mirror lock: %v", err)
+	}
+	stale := time.Now().Add(-2 * mirrorLockStale)
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatalf("Failed to age mirror lock: %v", err)
+	}
+
+	// Waiters that all see the stale lock hold the mirror one at a time
+	var holders, overlaps int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			unlock, err := lockMirror(ctx, mirrorPath)
+			if err != nil {
+				t.Errorf("Failed to take mirror lock: %v", err)
+				return
+			}
+			if atomic.AddInt32(&holders, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if overlaps != 0 {
+		t.Errorf("Expected one holder at a time, got %d overlapping holders", overlaps)
+	}
+	if matches, _ := filepath.Glob(lockPath + "*"); len(matches) != 0 {
+		t.Errorf("Expected no lock files to be left behind, got %v", matches)
+	}
+}
+
+func TestMirrorPath(t *testing.T) {
+	cache := NewMirrorCache("/cache", 0)
+
+	https, err := cache.MirrorPath("https://github.com/user/dotfiles.git")
+	if err != nil {
+		t.Fatalf("MirrorPath failed: %v", err)
+	}
+	if filepath.Dir(https) != filepath.Join("/cache", "github.com", "user") || filepath.Ext(https) != ".git" {
+		t.Errorf("Unexpected mirror path %s", https)
+	}
+
+	ssh, err := cache.MirrorPath("git@github.com:user/dotfiles.git")
+	if err != nil {
+		t.Fatalf("MirrorPath failed: %v", err)
+	}
+	if ssh == https {
+		t.Error("Distinct URLs should not share a mirror")
+	}
+	again, _ := cache.MirrorPath("https://github.com/user/dotfiles.git")
+	if again != https {
+		t.Errorf("Mirror path should be stable, got %s and %s", https, again)
+	}
+}
+
+func TestSetCheckoutRemotes(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := createTestRepository(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	sourceURL := "https://github.com/user/dotfiles.git"
+	if err := SetCheckoutRemotes(repoPath, sourceURL, "/mirror.git"); err == nil {
+		t.Error("Expected error for repository without origin")
+	}
+
+	// Origin is moved back to the source, the mirror gets its own remote
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"/mirror.git"}}); err != nil {
+		t.Fatalf("Failed to create remote: %v", err)
+	}
+	if err := SetCheckoutRemotes(repoPath, sourceURL, "/mirror.git"); err != nil {
+		t.Fatalf("SetCheckoutRemotes failed: %v", err)
+	}
+	remotes, err := RemoteURLs(repoPath)
+	if err != nil {
+		t.Fatalf("RemoteURLs failed: %v", err)
+	}
+	if remotes["origin"] != sourceURL || remotes[MirrorRemote] != "/mirror.git" {
+		t.Errorf("Expected origin %s and mirror /mirror.git, got %v", sourceURL, remotes)
+	}
+	opened, _ := git.PlainOpen(repoPath)
+	if remote, err := fetchRemote(opened); err != nil || remote.Config().Name != MirrorRemote {
+		t.Errorf("Expected updates to fetch from the mirror remote, got %v", err)
+	}
+
+	// Without a mirror the mirror remote is removed
+	if err := SetCheckoutRemotes(repoPath, sourceURL, ""); err != nil {
+		t.Fatalf("SetCheckoutRemotes failed: %v", err)
+	}
+	remotes, _ = RemoteURLs(repoPath)
+	if _, ok := remotes[MirrorRemote]; ok || remotes["origin"] != sourceURL {
+		t.Errorf("Expected only origin %s, got %v", sourceURL, remotes)
+	}
+}
+
+// mirrorHead returns the commit the default branch of a bare mirror points to.
+func mirrorHead(t *testing.T, mirrorPath string) string {
+	t.Helper()
+	repo, err := git.PlainOpen(mirrorPath)
+	if err != nil {
+		t.Fatalf("Failed to open mirror: %v", err)
+	}
+	ref, err := repo.Reference(plumbing.HEAD, true)
+	if err != nil {
+		t.Fatalf("Failed to resolve mirror HEAD: %v", err)
+	}
+	return ref.Hash().String()
+}
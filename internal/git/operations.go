@@ -48,10 +48,14 @@ type AuthConfig struct {
 	SSHPrivateKeyPath string
 	// SSH Private Key passphrase
 	SSHPassphrase string
-	// SSH Known Hosts file path
+	// SSH Known Hosts file path, defaults to SSH_KNOWN_HOSTS or ~/.ssh/known_hosts
 	SSHKnownHostsPath string
 	// Skip SSH host key verification (insecure)
 	SSHSkipHostKeyVerification bool
+	// netrc file consulted for HTTPS credentials, defaults to $NETRC or ~/.netrc
+	NetrcPath string
+	// Git credential helper consulted for HTTPS credentials, e.g. "osxkeychain" or "store"
+	CredentialHelper string
 	// Authentication method preference
 	AuthMethod string
 }
@@ -142,10 +146,15 @@ func (g *GitManager) CloneRepository(ctx context.Context, repoURL, localPath, br
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	auth, err := g.authFor(normalizedURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Clone options
 	cloneOptions := &git.CloneOptions{
 		URL:          normalizedURL,
-		Auth:         g.auth,
+		Auth:         auth,
 		RemoteName:   "origin",
 		SingleBranch: true,
 		Tags:         git.NoTags,
@@ -175,12 +184,26 @@ func (g *GitManager) CloneRepository(ctx context.Context, repoURL, localPath, br
 
 // UpdateRepository pulls the latest changes from the remote repository.
 func (g *GitManager) UpdateRepository(ctx context.Context, localPath string) (*RepositoryInfo, error) {
+	return g.pull(ctx, localPath, 0)
+}
+
+// pull fast-forwards the current branch, limiting the fetch to depth commits when non-zero.
+func (g *GitManager) pull(ctx context.Context, localPath string, depth int) (*RepositoryInfo, error) {
 	// Open existing repository
 	repo, err := git.PlainOpen(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open repository: %w", err)
 	}
 
+	remote, err := fetchRemote(repo)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := g.authFor(remoteURL(remote))
+	if err != nil {
+		return nil, err
+	}
+
 	// Get working tree
 	worktree, err := repo.Worktree()
 	if err != nil {
@@ -189,8 +212,9 @@ func (g *GitManager) UpdateRepository(ctx context.Context, localPath string) (*R
 
 	// Pull latest changes
 	pullOptions := &git.PullOptions{
-		RemoteName: "origin",
-		Auth:       g.auth,
+		RemoteName: remote.Config().Name,
+		Auth:       auth,
+		Depth:      depth,
 	}
 
 	err = worktree.PullContext(ctx, pullOptions)
@@ -198,19 +222,8 @@ func (g *GitManager) UpdateRepository(ctx context.Context, localPath string) (*R
 		return nil, fmt.Errorf("failed to pull updates: %w", err)
 	}
 
-	// Get remote URL
-	remote, err := repo.Remote("origin")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get remote: %w", err)
-	}
-
-	var remoteURL string
-	if len(remote.Config().URLs) > 0 {
-		remoteURL = remote.Config().URLs[0]
-	}
-
 	// Get repository info
-	info, err := g.getRepositoryInfo(repo, remoteURL, localPath)
+	info, err := g.getRepositoryInfo(repo, originURL(repo), localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repository info: %w", err)
 	}
@@ -226,17 +239,11 @@ func (g *GitManager) GetRepositoryInfo(localPath string) (*RepositoryInfo, error
 	}
 
 	// Get remote URL
-	remote, err := repo.Remote("origin")
-	if err != nil {
+	if _, err := repo.Remote("origin"); err != nil {
 		return nil, fmt.Errorf("failed to get remote: %w", err)
 	}
 
-	var remoteURL string
-	if len(remote.Config().URLs) > 0 {
-		remoteURL = remote.Config().URLs[0]
-	}
-
-	return g.getRepositoryInfo(repo, remoteURL, localPath)
+	return g.getRepositoryInfo(repo, originURL(repo), localPath)
 }
 
 // ValidateRepository checks if a local path contains a valid Git repository.
@@ -281,10 +288,14 @@ func (g *GitManager) getRepositoryInfo(repo *git.Repository, url, localPath stri
 func buildAuthMethod(authConfig *AuthConfig) (transport.AuthMethod, error) {
 	// SSH authentication
 	if authConfig.SSHPrivateKeyPath != "" {
-		sshAuth, err := ssh.NewPublicKeysFromFile("git", authConfig.SSHPrivateKeyPath, authConfig.SSHPassphrase)
+		sshAuth, err := ssh.NewPublicKeysFromFile(defaultSSHUser, authConfig.SSHPrivateKeyPath, authConfig.SSHPassphrase)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create SSH auth: %w", err)
 		}
+		sshAuth.HostKeyCallback, err = hostKeyCallback(authConfig)
+		if err != nil {
+			return nil, err
+		}
 		return sshAuth, nil
 	}
 
@@ -335,26 +346,58 @@ type CloneOptions struct {
 	LocalPath string
 	// Branch is the specific branch to clone (optional)
 	Branch string
+	// Ref pins the checkout to a tag, commit SHA or semver constraint (optional, overrides Branch)
+	Ref string
 	// Depth limits the clone depth (0 for full clone)
 	Depth int
 	// RecurseSubmodules indicates whether to clone submodules
 	RecurseSubmodules bool
 	// SingleBranch indicates whether to clone only the specified branch
 	SingleBranch bool
+	// SparsePaths limits the checkout to these directories, relative to the repository root
+	SparsePaths []string
 	// Progress callback for clone progress
 	Progress func(message string)
 }
 
 // CloneRepositoryWithOptions clones a repository with enhanced options.
 func (gm *GitManager) CloneRepositoryWithOptions(ctx context.Context, options CloneOptions) (*RepositoryInfo, error) {
+	normalizedURL, err := NormalizeGitURL(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize URL: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(options.LocalPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Resolve pinned refs before cloning so an unknown ref leaves nothing behind
+	var revision string
+	if options.Ref != "" {
+		revision, err = gm.ResolveRef(ctx, normalizedURL, options.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve git ref: %w", err)
+		}
+	}
+
+	auth, err := gm.authFor(normalizedURL)
+	if err != nil {
+		return nil, err
+	}
+
 	cloneOptions := &git.CloneOptions{
-		URL:      options.URL,
-		Auth:     gm.auth,
-		Progress: nil, // We'll handle progress separately
+		URL:        normalizedURL,
+		Auth:       auth,
+		RemoteName: "origin",
+		Progress:   nil, // We'll handle progress separately
+		// Pinned and sparse checkouts are done after the clone
+		NoCheckout: revision != "" || len(options.SparsePaths) > 0,
 	}
 
-	// Set branch if specified
-	if options.Branch != "" {
+	if revision != "" {
+		cloneOptions.Tags = git.AllTags
+	} else if options.Branch != "" {
+		// Set branch if specified
 		cloneOptions.ReferenceName = plumbing.ReferenceName("refs/heads/" + options.Branch)
 		cloneOptions.SingleBranch = options.SingleBranch
 	}
@@ -365,7 +408,7 @@ func (gm *GitManager) CloneRepositoryWithOptions(ctx context.Context, options Cl
 	}
 
 	// Set submodule recursion
-	if options.RecurseSubmodules {
+	if options.RecurseSubmodules && !cloneOptions.NoCheckout {
 		cloneOptions.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
 	}
 
@@ -375,8 +418,170 @@ func (gm *GitManager) CloneRepositoryWithOptions(ctx context.Context, options Cl
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
 
+	if cloneOptions.NoCheckout {
+		if revision != "" {
+			err = checkoutRevision(repo, revision, options.SparsePaths)
+		} else {
+			// Populate the cloned branch's working tree without detaching HEAD
+			var head *plumbing.Reference
+			head, err = repo.Head()
+			if err == nil {
+				err = resetBranch(repo, head.Hash().String(), options.SparsePaths)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if options.RecurseSubmodules {
+			if err := gm.UpdateSubmodules(ctx, options.LocalPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Get repository information
-	return gm.getRepositoryInfoFromRepo(repo, options.URL, options.LocalPath)
+	return gm.getRepositoryInfo(repo, normalizedURL, options.LocalPath)
+}
+
+// UpdateOptions contains options for updating a cloned repository.
+type UpdateOptions struct {
+	// LocalPath is the repository to update
+	LocalPath string
+	// Ref pins the checkout to a tag, commit SHA or semver constraint (optional)
+	Ref string
+	// Depth limits the fetch depth (0 for full history)
+	Depth int
+	// RecurseSubmodules indicates whether to update submodules
+	RecurseSubmodules bool
+	// SparsePaths limits the checkout to these directories, relative to the repository root
+	SparsePaths []string
+}
+
+// UpdateRepositoryWithOptions brings a clone up to date with its remote. Pinned refs are
+// re-resolved; otherwise the current branch is fast-forwarded, for sparse checkouts by a
+// reset to its upstream so directories outside SparsePaths stay absent.
+func (gm *GitManager) UpdateRepositoryWithOptions(ctx context.Context, options UpdateOptions) (*RepositoryInfo, error) {
+	var info *RepositoryInfo
+	var err error
+	switch {
+	case options.Ref != "":
+		info, err = gm.updateToRef(ctx, options.LocalPath, options.Ref, options.Depth, options.SparsePaths)
+	case len(options.SparsePaths) > 0:
+		info, err = gm.updateSparse(ctx, options)
+	default:
+		info, err = gm.pull(ctx, options.LocalPath, options.Depth)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if options.RecurseSubmodules {
+		if err := gm.UpdateSubmodules(ctx, options.LocalPath); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// updateSparse fetches origin and fast-forwards the current branch to its upstream within the sparse paths.
+func (gm *GitManager) updateSparse(ctx context.Context, options UpdateOptions) (*RepositoryInfo, error) {
+	repo, err := git.PlainOpen(options.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	remote, err := fetchRemote(repo)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := gm.authFor(remoteURL(remote))
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return nil, fmt.Errorf("cannot update detached HEAD without a git ref")
+	}
+	upstreamName := plumbing.NewRemoteReferenceName("origin", head.Name().Short())
+	var fetched plumbing.Hash
+	if previous, err := repo.Reference(upstreamName, true); err == nil {
+		fetched = previous.Hash()
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote.Config().Name,
+		Auth:       auth,
+		Depth:      options.Depth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch updates: %w", err)
+	}
+
+	upstream, err := repo.Reference(upstreamName, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upstream of %s: %w", head.Name().Short(), err)
+	}
+	if err := checkFastForward(repo, head.Hash(), fetched, upstream.Hash()); err != nil {
+		return nil, err
+	}
+
+	if err := resetBranch(repo, upstream.Hash().String(), options.SparsePaths); err != nil {
+		return nil, err
+	}
+
+	return gm.getRepositoryInfo(repo, originURL(repo), options.LocalPath)
+}
+
+// checkFastForward refuses, like a pull, to move a branch whose head has commits that are
+// not on origin. The head counts as pushed when it is reachable from the upstream commit
+// fetched now or, since a shallow fetch may not reach back to it, from the one fetched before.
+func checkFastForward(repo *git.Repository, head, previous, upstream plumbing.Hash) error {
+	if head == upstream || head == previous {
+		return nil
+	}
+	for _, from := range []plumbing.Hash{upstream, previous} {
+		if from.IsZero() {
+			continue
+		}
+		reachable, err := ancestors(repo, from)
+		if err != nil {
+			return err
+		}
+		if reachable[head] {
+			return nil
+		}
+	}
+	return fmt.Errorf("branch has local commits that are not on origin: %w", git.ErrNonFastForwardUpdate)
+}
+
+// resetBranch moves the checked-out branch to revision, updating only the sparse paths when set.
+func resetBranch(repo *git.Repository, revision string, sparsePaths []string) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	resetOptions := &git.ResetOptions{Commit: plumbing.NewHash(revision), Mode: git.MergeReset}
+	if err := worktree.ResetSparsely(resetOptions, sparseDirectories(sparsePaths)); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", revision, err)
+	}
+	return nil
+}
+
+// sparseDirectories converts sparse paths to the index prefixes go-git matches against,
+// so "dotfiles" selects "dotfiles/..." but not "dotfiles-old/...".
+func sparseDirectories(paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	dirs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		dirs = append(dirs, strings.TrimSuffix(filepath.ToSlash(filepath.Clean(p)), "/")+"/")
+	}
+	return dirs
 }
 
 // UpdateSubmodules updates all submodules in a repository.
@@ -399,9 +604,13 @@ func (gm *GitManager) UpdateSubmodules(ctx context.Context, localPath string) er
 
 	// Update each submodule
 	for _, submodule := range submodules {
+		auth, err := gm.authFor(submodule.Config().URL)
+		if err != nil {
+			return err
+		}
 		err = submodule.Update(&git.SubmoduleUpdateOptions{
 			Init: true,
-			Auth: gm.auth,
+			Auth: auth,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to update submodule %s: %w", submodule.Config().Name, err)
@@ -536,9 +745,14 @@ func (gm *GitManager) GetRemoteInfo(ctx context.Context, repoURL string) (*Remot
 		URLs: []string{repoURL},
 	})
 
+	auth, err := gm.authFor(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// List references
 	refs, err := remote.List(&git.ListOptions{
-		Auth: gm.auth,
+		Auth: auth,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list remote references: %w", err)
@@ -579,34 +793,3 @@ type RemoteInfo struct {
 	Branches      []string
 	Tags          []string
 }
-
-// Helper method to get repository info from a git.Repository.
-func (gm *GitManager) getRepositoryInfoFromRepo(repo *git.Repository, url, localPath string) (*RepositoryInfo, error) {
-	// Get HEAD reference
-	head, err := repo.Head()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get HEAD: %w", err)
-	}
-
-	// Get branch name
-	var branchName string
-	if head.Name().IsBranch() {
-		branchName = head.Name().Short()
-	} else {
-		branchName = "detached"
-	}
-
-	// Get last commit
-	commit, err := repo.CommitObject(head.Hash())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get commit: %w", err)
-	}
-
-	return &RepositoryInfo{
-		URL:        url,
-		LocalPath:  localPath,
-		Branch:     branchName,
-		LastCommit: commit.Hash.String(),
-		LastUpdate: time.Now(),
-	}, nil
-}
@@ -4,7 +4,15 @@
 package git
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 func TestIsGitURL(t *testing.T) {
@@ -375,3 +383,120 @@ func TestBuildAuthMethod(t *testing.T) {
 		})
 	}
 }
+
+func TestSparseShallowClone(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	writeFile := func(name, content string) {
+		path := filepath.Join(upstreamDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeFile("dotfiles/zsh/zshrc", "export EDITOR=vim\n")
+	writeFile("dotfiles-old/zshrc", "export EDITOR=nano\n")
+	writeFile("services/api/main.go", "package main\n")
+	commitAll(t, upstream, "initial")
+	writeFile("dotfiles/git/gitconfig", "[user]\n")
+	commitAll(t, upstream, "add gitconfig")
+
+	manager, err := NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	ctx := context.Background()
+	localDir := filepath.Join(tempDir, "local")
+	options := CloneOptions{
+		URL:         upstreamDir,
+		LocalPath:   localDir,
+		Depth:       1,
+		SparsePaths: []string{"dotfiles"},
+	}
+	info, err := manager.CloneRepositoryWithOptions(ctx, options)
+	if err != nil {
+		t.Fatalf("CloneRepositoryWithOptions failed: %v", err)
+	}
+	if info.Branch == "" {
+		t.Error("Sparse clone should keep the branch checked out")
+	}
+
+	assertCheckout := func(present, absent string) {
+		t.Helper()
+		if _, err := os.Stat(filepath.Join(localDir, present)); err != nil {
+			t.Errorf("Expected %s in checkout: %v", present, err)
+		}
+		if _, err := os.Stat(filepath.Join(localDir, absent)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s outside the sparse checkout to be absent", absent)
+		}
+	}
+	assertCheckout("dotfiles/git/gitconfig", "services")
+	assertCheckout("dotfiles/zsh/zshrc", "dotfiles-old")
+
+	repo, err := git.PlainOpen(localDir)
+	if err != nil {
+		t.Fatalf("Failed to open clone: %v", err)
+	}
+	shallow, err := repo.Storer.Shallow()
+	if err != nil || len(shallow) == 0 {
+		t.Errorf("Expected a shallow clone, got %v (%v)", shallow, err)
+	}
+
+	// Updates keep the sparse checkout, also past the shallow boundary
+	writeFile("dotfiles/zsh/zshenv", "export PATH\n")
+	writeFile("services/api/handler.go", "package main\n")
+	commitAll(t, upstream, "update")
+	writeFile("dotfiles/zsh/zprofile", "\n")
+	latest := commitAll(t, upstream, "update again")
+
+	info, err = manager.UpdateRepositoryWithOptions(ctx, UpdateOptions{
+		LocalPath:   localDir,
+		Depth:       1,
+		SparsePaths: options.SparsePaths,
+	})
+	if err != nil {
+		t.Fatalf("UpdateRepositoryWithOptions failed: %v", err)
+	}
+	if info.LastCommit != latest {
+		t.Errorf("Expected %s after update, got %s", latest, info.LastCommit)
+	}
+	assertCheckout("dotfiles/zsh/zshenv", "services")
+
+	// Local commits that are not on origin are never reset away
+	if err := os.WriteFile(filepath.Join(localDir, "dotfiles", "local"), []byte("local\n"), 0644); err != nil {
+		t.Fatalf("Failed to write local file: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if _, err := worktree.Add("dotfiles/local"); err != nil {
+		t.Fatalf("Failed to stage local file: %v", err)
+	}
+	local, err := worktree.Commit("local", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	writeFile("dotfiles/zsh/zlogin", "\n")
+	commitAll(t, upstream, "diverge")
+
+	_, err = manager.UpdateRepositoryWithOptions(ctx, UpdateOptions{
+		LocalPath:   localDir,
+		Depth:       1,
+		SparsePaths: options.SparsePaths,
+	})
+	if !errors.Is(err, git.ErrNonFastForwardUpdate) {
+		t.Fatalf("Expected a non-fast-forward error, got %v", err)
+	}
+	if head, err := repo.Head(); err != nil || head.Hash() != local {
+		t.Errorf("Expected HEAD to stay at the local commit %s, got %v", local, head)
+	}
+}
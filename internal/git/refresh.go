@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ParseUpdateInterval parses a repository update interval such as "1h" or "30m".
+// An empty value, "0" and "never" disable scheduled updates and return zero.
+func ParseUpdateInterval(value string) (time.Duration, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "never":
+		return 0, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid update interval %q: %w", value, err)
+	}
+	if interval < 0 {
+		return 0, fmt.Errorf("update interval must not be negative: %s", value)
+	}
+	return interval, nil
+}
+
+// UpdateDue reports whether a repository last updated at lastUpdate (RFC 3339) should be refreshed.
+// A zero interval never schedules updates; a missing or unparseable timestamp is always due.
+func UpdateDue(lastUpdate string, interval time.Duration, now time.Time) bool {
+	if interval <= 0 {
+		return false
+	}
+	last, err := time.Parse(time.RFC3339, lastUpdate)
+	if err != nil {
+		return true
+	}
+	return !now.Before(last.Add(interval))
+}
+
+// FetchUpstream fetches from origin without touching the working tree and returns the
+// commit the checked-out branch's upstream points to. Detached checkouts return HEAD.
+func (g *GitManager) FetchUpstream(ctx context.Context, localPath string) (string, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	remote, err := fetchRemote(repo)
+	if err != nil {
+		return "", err
+	}
+	auth, err := g.authFor(remoteURL(remote))
+	if err != nil {
+		return "", err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote.Config().Name,
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to fetch updates: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return head.Hash().String(), nil
+	}
+
+	upstream, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve upstream of %s: %w", head.Name().Short(), err)
+	}
+	return upstream.Hash().String(), nil
+}
+
+// UpstreamCache memoizes upstream lookups for the lifetime of a provider run, so many
+// resources depending on the same repository trigger at most one fetch.
+type UpstreamCache struct {
+	mu      sync.Mutex
+	entries map[string]*upstreamEntry
+}
+
+type upstreamEntry struct {
+	once   sync.Once
+	done   bool
+	commit string
+	err    error
+}
+
+// NewUpstreamCache creates an empty upstream cache.
+func NewUpstreamCache() *UpstreamCache {
+	return &UpstreamCache{entries: make(map[string]*upstreamEntry)}
+}
+
+// Lookup returns the cached result for key, calling fetch on first use.
+// Concurrent callers for the same key wait for a single fetch. A nil cache always fetches.
+func (c *UpstreamCache) Lookup(key string, fetch func() (string, error)) (string, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &upstreamEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.commit, entry.err = fetch()
+		c.mu.Lock()
+		entry.done = true
+		c.mu.Unlock()
+	})
+	return entry.commit, entry.err
+}
+
+// Fetched returns the result of a completed, successful lookup for key, without fetching.
+func (c *UpstreamCache) Fetched(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !entry.done || entry.err != nil {
+		return "", false
+	}
+	return entry.commit, true
+}
+
+// Forget drops the cached result for key, e.g. after the repository has been updated.
+func (c *UpstreamCache) Forget(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, key)
+	c.mu.Unlock()
+}
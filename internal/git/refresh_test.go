@@ -0,0 +1,188 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseUpdateInterval(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{value: "", expected: 0},
+		{value: "0", expected: 0},
+		{value: "never", expected: 0},
+		{value: "Never", expected: 0},
+		{value: "30m", expected: 30 * time.Minute},
+		{value: "1h30m", expected: 90 * time.Minute},
+		{value: "-1h", wantErr: true},
+		{value: "daily", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseUpdateInterval(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", tc.value, err)
+			}
+			if got != tc.expected {
+				t.Errorf("ParseUpdateInterval(%q) = %s, expected %s", tc.value, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		lastUpdate string
+		interval   time.Duration
+		expected   bool
+	}{
+		{name: "disabled", lastUpdate: "2020-01-01T00:00:00Z", interval: 0, expected: false},
+		{name: "within interval", lastUpdate: "2025-06-01T11:30:00Z", interval: time.Hour, expected: false},
+		{name: "interval elapsed", lastUpdate: "2025-06-01T11:00:00Z", interval: time.Hour, expected: true},
+		{name: "missing timestamp", lastUpdate: "", interval: time.Hour, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := UpdateDue(tc.lastUpdate, tc.interval, now); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFetchUpstream(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	commitAll(t, upstream, "initial")
+
+	manager, err := NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "local")
+	info, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, "")
+	if err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(upstreamDir, "zshrc"), []byte("export EDITOR=vim\n"), 0644); err != nil {
+		t.Fatalf("Failed to write upstream file: %v", err)
+	}
+	newCommit := commitAll(t, upstream, "add zshrc")
+
+	fetched, err := manager.FetchUpstream(context.Background(), localDir)
+	if err != nil {
+		t.Fatalf("FetchUpstream failed: %v", err)
+	}
+	if fetched != newCommit {
+		t.Errorf("Expected upstream commit %s, got %s", newCommit, fetched)
+	}
+
+	// The working tree must not move until the repository is updated
+	current, err := manager.GetRepositoryInfo(localDir)
+	if err != nil {
+		t.Fatalf("GetRepositoryInfo failed: %v", err)
+	}
+	if current.LastCommit != info.LastCommit {
+		t.Errorf("Fetch should not change HEAD: expected %s, got %s", info.LastCommit, current.LastCommit)
+	}
+}
+
+func TestUpstreamCache(t *testing.T) {
+	cache := NewUpstreamCache()
+	var calls int32
+	fetch := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "abc123", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if commit, err := cache.Lookup("repo", fetch); err != nil || commit != "abc123" {
+				t.Errorf("Unexpected lookup result %q, %v", commit, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected a single fetch, got %d", calls)
+	}
+	if commit, ok := cache.Fetched("repo"); !ok || commit != "abc123" {
+		t.Errorf("Expected the completed lookup to be reported, got %q (ok=%v)", commit, ok)
+	}
+	if _, ok := cache.Fetched("other"); ok {
+		t.Error("Keys never looked up should not be reported as fetched")
+	}
+	if _, err := cache.Lookup("failing", func() (string, error) { return "", errors.New("offline") }); err == nil {
+		t.Error("Expected the fetch error to be returned")
+	}
+	if _, ok := cache.Fetched("failing"); ok {
+		t.Error("Failed lookups should not be reported as fetched")
+	}
+
+	cache.Forget("repo")
+	if _, err := cache.Lookup("repo", fetch); err != nil {
+		t.Fatalf("Lookup after Forget failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected a new fetch after Forget, got %d calls", calls)
+	}
+
+	var nilCache *UpstreamCache
+	if _, err := nilCache.Lookup("repo", fetch); err != nil || calls != 3 {
+		t.Errorf("Nil cache should always fetch, got %d calls", calls)
+	}
+}
+
+// commitAll stages every file in the repository and commits it, returning the commit hash.
+func commitAll(t *testing.T, repo *git.Repository, message string) string {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		t.Fatalf("Failed to stage files: %v", err)
+	}
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return hash.String()
+}
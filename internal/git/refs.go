@@ -0,0 +1,197 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	version "github.com/hashicorp/go-version"
+)
+
+// commitSHAPattern matches full and abbreviated commit SHAs.
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// refFetchSpecs fetches all branches and tags so tags and commits off the default branch can be checked out.
+var refFetchSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/remotes/origin/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+// IsCommitSHA reports whether ref looks like a full or abbreviated commit SHA.
+func IsCommitSHA(ref string) bool {
+	return commitSHAPattern.MatchString(ref)
+}
+
+// ResolveRef resolves a git ref against the remote repository. Exact tag names are returned as-is,
+// commit SHAs are passed through, and anything else is treated as a semver constraint
+// (e.g. "~> 1.2", ">= 1.0, < 2.0") matched against the remote tags, returning the highest match.
+func (g *GitManager) ResolveRef(ctx context.Context, repoURL, ref string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("git ref must not be empty")
+	}
+
+	remote, err := g.GetRemoteInfo(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range remote.Tags {
+		if tag == ref {
+			return tag, nil
+		}
+	}
+	if IsCommitSHA(ref) {
+		return ref, nil
+	}
+
+	return MatchVersionTag(remote.Tags, ref)
+}
+
+// MatchVersionTag returns the highest tag satisfying a semver constraint.
+// Tags that are not versions (with or without a "v" prefix) are ignored.
+func MatchVersionTag(tags []string, constraint string) (string, error) {
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("git ref %q is not a tag, commit SHA or semver constraint: %w", constraint, err)
+	}
+
+	var bestTag string
+	var best *version.Version
+	for _, tag := range tags {
+		v, err := version.NewVersion(tag)
+		if err != nil || !constraints.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestTag = v, tag
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("no tag satisfies version constraint %q", constraint)
+	}
+	return bestTag, nil
+}
+
+// CloneAtRef clones a repository and checks out the given tag, commit SHA or semver constraint in detached HEAD.
+func (g *GitManager) CloneAtRef(ctx context.Context, repoURL, localPath, ref string) (*RepositoryInfo, error) {
+	return g.CloneRepositoryWithOptions(ctx, CloneOptions{URL: repoURL, LocalPath: localPath, Ref: ref})
+}
+
+// UpdateToRef fetches from origin and checks out the given ref in detached HEAD.
+// Semver constraints are re-resolved, so the checkout moves to the newest matching tag.
+func (g *GitManager) UpdateToRef(ctx context.Context, localPath, ref string) (*RepositoryInfo, error) {
+	return g.updateToRef(ctx, localPath, ref, 0, nil)
+}
+
+// updateToRef is UpdateToRef with an optional fetch depth and sparse checkout.
+func (g *GitManager) updateToRef(ctx context.Context, localPath, ref string, depth int, sparsePaths []string) (*RepositoryInfo, error) {
+	repo, remoteURL, err := g.fetchRefs(ctx, localPath, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := g.ResolveRef(ctx, remoteURL, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve git ref: %w", err)
+	}
+
+	if err := checkoutRevision(repo, revision, sparsePaths); err != nil {
+		return nil, err
+	}
+
+	return g.getRepositoryInfo(repo, originURL(repo), localPath)
+}
+
+// FetchRef fetches from origin without touching the working tree and returns the commit ref resolves to.
+func (g *GitManager) FetchRef(ctx context.Context, localPath, ref string) (string, error) {
+	repo, remoteURL, err := g.fetchRefs(ctx, localPath, 0)
+	if err != nil {
+		return "", err
+	}
+
+	revision, err := g.ResolveRef(ctx, remoteURL, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git ref: %w", err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+	return hash.String(), nil
+}
+
+// fetchRefs opens a repository and fetches all branches and tags from origin, limited to depth commits when non-zero.
+func (g *GitManager) fetchRefs(ctx context.Context, localPath string, depth int) (*git.Repository, string, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	remote, err := fetchRemote(repo)
+	if err != nil {
+		return nil, "", err
+	}
+	url := remoteURL(remote)
+	auth, err := g.authFor(url)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote.Config().Name,
+		RefSpecs:   refFetchSpecs,
+		Auth:       auth,
+		Tags:       git.AllTags,
+		Depth:      depth,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, "", fmt.Errorf("failed to fetch updates: %w", err)
+	}
+
+	return repo, url, nil
+}
+
+// checkoutRevision checks out a tag or commit SHA in detached HEAD, limited to sparsePaths when set.
+func checkoutRevision(repo *git.Repository, revision string, sparsePaths []string) error {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return fmt.Errorf("failed to resolve revision %s: %w", revision, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	checkoutOptions := &git.CheckoutOptions{Hash: *hash, Force: true, SparseCheckoutDirectories: sparseDirectories(sparsePaths)}
+	if err := worktree.Checkout(checkoutOptions); err != nil {
+		return fmt.Errorf("failed to checkout %s: %w", revision, err)
+	}
+	return nil
+}
+
+// ResetToCommit hard-resets the checkout in localPath, moving the current branch or detached HEAD to commit.
+func ResetToCommit(localPath, commit string) error {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: plumbing.NewHash(commit), Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", commit, err)
+	}
+	return nil
+}
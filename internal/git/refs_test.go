@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestIsCommitSHA(t *testing.T) {
+	testCases := map[string]bool{
+		"abc1234": true,
+		"0123456789abcdef0123456789abcdef01234567": true,
+		"abc123": false,
+		"v1.2.3": false,
+		"~> 1.2": false,
+		"main":   false,
+	}
+	for ref, expected := range testCases {
+		if got := IsCommitSHA(ref); got != expected {
+			t.Errorf("IsCommitSHA(%q) = %v, expected %v", ref, got, expected)
+		}
+	}
+}
+
+func TestMatchVersionTag(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1", "v2.0.0", "1.3.0", "stable"}
+
+	testCases := []struct {
+		constraint string
+		expected   string
+		wantErr    bool
+	}{
+		{constraint: "~> 1.0", expected: "1.3.0"},
+		{constraint: ">= 1.0, < 1.2", expected: "v1.1.0"},
+		{constraint: "1.0.0", expected: "v1.0.0"},
+		{constraint: ">= 1.0", expected: "v2.0.0"},
+		{constraint: ">= 3.0", wantErr: true},
+		{constraint: "not a constraint", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.constraint, func(t *testing.T) {
+			got, err := MatchVersionTag(tags, tc.constraint)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %s", tc.constraint, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("MatchVersionTag(%q) = %s, expected %s", tc.constraint, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCheckoutRefs(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+
+	first := commitAll(t, upstream, "initial")
+	tagHead(t, upstream, "v1.0.0", false)
+	writeAndCommit := func(name string) string {
+		if err := os.WriteFile(filepath.Join(upstreamDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return commitAll(t, upstream, "add "+name)
+	}
+	second := writeAndCommit("zshrc")
+	tagHead(t, upstream, "v1.1.0", true)
+	tagHead(t, upstream, "stable", false)
+	third := writeAndCommit("vimrc")
+	tagHead(t, upstream, "v2.0.0", true)
+	head := writeAndCommit("tmux.conf")
+
+	manager, err := NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	ctx := context.Background()
+	localDir := filepath.Join(tempDir, "local")
+
+	info, err := manager.CloneAtRef(ctx, upstreamDir, localDir, "~> 1.0")
+	if err != nil {
+		t.Fatalf("CloneAtRef failed: %v", err)
+	}
+	if info.LastCommit != second {
+		t.Errorf("Expected v1.1.0 commit %s, got %s", second, info.LastCommit)
+	}
+	if info.Branch != "" {
+		t.Errorf("Expected detached HEAD, got branch %s", info.Branch)
+	}
+
+	testCases := []struct {
+		ref      string
+		expected string
+	}{
+		{ref: "stable", expected: second},
+		{ref: first, expected: first},
+		{ref: third[:8], expected: third},
+		{ref: ">= 2.0", expected: third},
+		{ref: head, expected: head},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			fetched, err := manager.FetchRef(ctx, localDir, tc.ref)
+			if err != nil {
+				t.Fatalf("FetchRef failed: %v", err)
+			}
+			if fetched != tc.expected {
+				t.Errorf("FetchRef(%s) = %s, expected %s", tc.ref, fetched, tc.expected)
+			}
+
+			info, err := manager.UpdateToRef(ctx, localDir, tc.ref)
+			if err != nil {
+				t.Fatalf("UpdateToRef failed: %v", err)
+			}
+			if info.LastCommit != tc.expected {
+				t.Errorf("UpdateToRef(%s) checked out %s, expected %s", tc.ref, info.LastCommit, tc.expected)
+			}
+		})
+	}
+
+	if _, err := manager.UpdateToRef(ctx, localDir, "~> 5.0"); err == nil {
+		t.Error("Expected error for unsatisfiable constraint")
+	}
+}
+
+// tagHead creates a lightweight or annotated tag pointing to HEAD.
+func tagHead(t *testing.T, repo *git.Repository, name string, annotated bool) {
+	t.Helper()
+	hash, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		t.Fatalf("Failed to resolve HEAD: %v", err)
+	}
+
+	var opts *git.CreateTagOptions
+	if annotated {
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+			Message: "Release " + name,
+		}
+	}
+	if _, err := repo.CreateTag(name, *hash, opts); err != nil {
+		t.Fatalf("Failed to create tag %s: %v", name, err)
+	}
+}
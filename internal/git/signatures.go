@@ -0,0 +1,247 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signature formats recognised in commit headers.
+const (
+	SignatureFormatOpenPGP = "openpgp"
+	SignatureFormatSSH     = "ssh"
+)
+
+const (
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureNamespace = "git"
+	sshSignaturePEMType   = "SSH SIGNATURE"
+)
+
+// SignatureConfig holds the keys trusted to sign commits.
+type SignatureConfig struct {
+	// ArmoredKeyRing is an ASCII-armored OpenPGP public keyring
+	ArmoredKeyRing string
+	// AllowedSigners holds SSH allowed signers entries in ssh-keygen(1) format
+	AllowedSigners string
+}
+
+// SignatureVerification describes a successfully verified commit signature.
+type SignatureVerification struct {
+	Commit string
+	Format string
+	// Signer is the OpenPGP key ID or the SSH principal that matched
+	Signer string
+}
+
+// VerifyHeadSignature verifies the signature of the commit checked out in localPath.
+func VerifyHeadSignature(localPath string, trusted SignatureConfig) (*SignatureVerification, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	return VerifyCommitSignature(repo, head.Hash(), trusted)
+}
+
+// VerifyCommitSignature verifies a commit's OpenPGP or SSH signature against the trusted keys.
+// Unsigned commits and signatures made by keys outside the trusted set are rejected.
+func VerifyCommitSignature(repo *git.Repository, commitHash plumbing.Hash, trusted SignatureConfig) (*SignatureVerification, error) {
+	commit, err := repo.CommitObject(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	signature := strings.TrimSpace(commit.PGPSignature)
+	if signature == "" {
+		return nil, fmt.Errorf("commit %s is not signed", commitHash)
+	}
+
+	if strings.HasPrefix(signature, "-----BEGIN "+sshSignaturePEMType) {
+		if trusted.AllowedSigners == "" {
+			return nil, fmt.Errorf("commit %s has an SSH signature but no allowed signers are configured", commitHash)
+		}
+		principal, err := verifySSHCommitSignature(commit, trusted.AllowedSigners)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", commitHash, err)
+		}
+		return &SignatureVerification{Commit: commitHash.String(), Format: SignatureFormatSSH, Signer: principal}, nil
+	}
+
+	if trusted.ArmoredKeyRing == "" {
+		return nil, fmt.Errorf("commit %s has an OpenPGP signature but no keyring is configured", commitHash)
+	}
+	entity, err := commit.Verify(trusted.ArmoredKeyRing)
+	if err != nil {
+		return nil, fmt.Errorf("commit %s: OpenPGP signature verification failed: %w", commitHash, err)
+	}
+	return &SignatureVerification{
+		Commit: commitHash.String(),
+		Format: SignatureFormatOpenPGP,
+		Signer: entity.PrimaryKey.KeyIdString(),
+	}, nil
+}
+
+// sshSignature is the SSHSIG blob following the magic preamble.
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the structure an SSHSIG signature is computed over, after the magic preamble.
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// verifySSHCommitSignature verifies an SSHSIG commit signature and returns the matching principal.
+func verifySSHCommitSignature(commit *object.Commit, allowedSigners string) (string, error) {
+	block, _ := pem.Decode([]byte(commit.PGPSignature))
+	if block == nil || block.Type != sshSignaturePEMType {
+		return "", fmt.Errorf("malformed SSH signature")
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return "", fmt.Errorf("malformed SSH signature: missing %s preamble", sshSignatureMagic)
+	}
+
+	var sig sshSignature
+	if err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &sig); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if sig.Namespace != sshSignatureNamespace {
+		return "", fmt.Errorf("SSH signature namespace is %q, expected %q", sig.Namespace, sshSignatureNamespace)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid SSH signing key: %w", err)
+	}
+	principal, err := findAllowedSigner(allowedSigners, publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	message, err := encodeWithoutSignature(commit)
+	if err != nil {
+		return "", err
+	}
+	signedData, err := sshSignedMessage(message, sig.Namespace, sig.HashAlgorithm)
+	if err != nil {
+		return "", err
+	}
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &signature); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if err := publicKey.Verify(signedData, &signature); err != nil {
+		return "", fmt.Errorf("SSH signature verification failed: %w", err)
+	}
+	return principal, nil
+}
+
+// sshSignedMessage builds the data an SSHSIG signature covers for message.
+func sshSignedMessage(message []byte, namespace, hashAlgorithm string) ([]byte, error) {
+	var h hash.Hash
+	switch hashAlgorithm {
+	case "sha512":
+		h = sha512.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return nil, fmt.Errorf("unsupported SSH signature hash algorithm %q", hashAlgorithm)
+	}
+	h.Write(message)
+
+	data := ssh.Marshal(sshSignedData{
+		Namespace:     namespace,
+		HashAlgorithm: hashAlgorithm,
+		Hash:          h.Sum(nil),
+	})
+	return append([]byte(sshSignatureMagic), data...), nil
+}
+
+// findAllowedSigner returns the principals of the allowed signers entry matching key.
+// Entries follow the ssh-keygen(1) ALLOWED SIGNERS format: principals [options] key.
+func findAllowedSigner(allowedSigners string, key ssh.PublicKey) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(allowedSigners))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		separator := strings.IndexAny(line, " \t")
+		if separator < 0 {
+			continue
+		}
+		principals, rest := line[:separator], strings.TrimSpace(line[separator:])
+
+		allowed, _, options, _, err := ssh.ParseAuthorizedKey([]byte(rest))
+		if err != nil {
+			continue
+		}
+		if !bytes.Equal(allowed.Marshal(), key.Marshal()) || !allowsGitNamespace(options) {
+			continue
+		}
+		return principals, nil
+	}
+	return "", fmt.Errorf("SSH signing key %s is not in the allowed signers", ssh.FingerprintSHA256(key))
+}
+
+// allowsGitNamespace reports whether an allowed signers entry's namespaces option permits git signatures.
+func allowsGitNamespace(options []string) bool {
+	for _, option := range options {
+		name, value, found := strings.Cut(option, "=")
+		if !found || !strings.EqualFold(name, "namespaces") {
+			continue
+		}
+		for _, namespace := range strings.Split(strings.Trim(value, `"`), ",") {
+			if strings.TrimSpace(namespace) == sshSignatureNamespace {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// encodeWithoutSignature returns the commit object as it was signed.
+func encodeWithoutSignature(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, fmt.Errorf("failed to encode commit: %w", err)
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encoded commit: %w", err)
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, fmt.Errorf("failed to read encoded commit: %w", err)
+	}
+	return buf.Bytes(), nil
+}
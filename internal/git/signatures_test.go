@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshTestSigner produces SSHSIG commit signatures like ssh-keygen -Y sign -n <namespace>.
+type sshTestSigner struct {
+	signer    ssh.Signer
+	namespace string
+}
+
+func (s sshTestSigner) Sign(message io.Reader) ([]byte, error) {
+	content, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha512.Sum512(content)
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignedData{
+		Namespace:     s.namespace,
+		HashAlgorithm: "sha512",
+		Hash:          digest[:],
+	})...)
+
+	signature, err := s.signer.Sign(rand.Reader, signedData)
+	if err != nil {
+		return nil, err
+	}
+	blob := append([]byte(sshSignatureMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     s.namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: sshSignaturePEMType, Bytes: blob}), nil
+}
+
+func newSSHTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func newOpenPGPTestEntity(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create OpenPGP entity: %v", err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor keyring: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("Failed to serialize key: %v", err)
+	}
+	w.Close()
+	return entity, buf.String()
+}
+
+// signedCommit creates a commit in repo using the given commit options for signing.
+func signedCommit(t *testing.T, repo *git.Repository, opts git.CommitOptions) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	opts.Author = &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	opts.AllowEmptyCommits = true
+	hash, err := worktree.Commit("signed commit", &opts)
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return hash
+}
+
+func TestVerifyCommitSignature(t *testing.T) {
+	repo, err := createTestRepository(filepath.Join(t.TempDir(), "repo"))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	entity, keyring := newOpenPGPTestEntity(t)
+	_, otherKeyring := newOpenPGPTestEntity(t)
+	sshSigner := newSSHTestSigner(t)
+	otherSSHSigner := newSSHTestSigner(t)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshSigner.PublicKey())))
+
+	unsigned := signedCommit(t, repo, git.CommitOptions{})
+	pgpSigned := signedCommit(t, repo, git.CommitOptions{SignKey: entity})
+	sshSigned := signedCommit(t, repo, git.CommitOptions{Signer: sshTestSigner{signer: sshSigner, namespace: "git"}})
+	otherSSHSigned := signedCommit(t, repo, git.CommitOptions{Signer: sshTestSigner{signer: otherSSHSigner, namespace: "git"}})
+	wrongNamespace := signedCommit(t, repo, git.CommitOptions{Signer: sshTestSigner{signer: sshSigner, namespace: "file"}})
+
+	trusted := SignatureConfig{
+		ArmoredKeyRing: keyring,
+		AllowedSigners: "# team keys\ndev@example.com namespaces=\"git\" " + authorizedKey + "\n",
+	}
+
+	testCases := []struct {
+		name    string
+		commit  plumbing.Hash
+		trusted SignatureConfig
+		format  string
+		signer  string
+		wantErr string
+	}{
+		{name: "openpgp", commit: pgpSigned, trusted: trusted, format: SignatureFormatOpenPGP, signer: entity.PrimaryKey.KeyIdString()},
+		{name: "ssh", commit: sshSigned, trusted: trusted, format: SignatureFormatSSH, signer: "dev@example.com"},
+		{name: "unsigned", commit: unsigned, trusted: trusted, wantErr: "not signed"},
+		{name: "untrusted openpgp key", commit: pgpSigned, trusted: SignatureConfig{ArmoredKeyRing: otherKeyring}, wantErr: "verification failed"},
+		{name: "no keyring", commit: pgpSigned, trusted: SignatureConfig{AllowedSigners: trusted.AllowedSigners}, wantErr: "no keyring"},
+		{name: "untrusted ssh key", commit: otherSSHSigned, trusted: trusted, wantErr: "not in the allowed signers"},
+		{name: "ssh namespace", commit: wrongNamespace, trusted: trusted, wantErr: "namespace"},
+		{name: "namespace option", commit: sshSigned, trusted: SignatureConfig{AllowedSigners: "dev@example.com namespaces=\"file\" " + authorizedKey}, wantErr: "not in the allowed signers"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := VerifyCommitSignature(repo, tc.commit, tc.trusted)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Format != tc.format || result.Signer != tc.signer || result.Commit != tc.commit.String() {
+				t.Errorf("Unexpected verification result: %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// stashRoot is where stashed changes are kept, inside the repository's .git directory.
+const stashRoot = "dotfiles-stash"
+
+// WorkingTreeStatus describes uncommitted changes in a local clone and how far it has
+// diverged from its upstream branch.
+type WorkingTreeStatus struct {
+	// ModifiedFiles lists tracked files with staged or unstaged changes, relative to the repository root
+	ModifiedFiles []string
+	// Ahead counts local commits not on the upstream branch, as of the last fetch
+	Ahead int
+	// Behind counts upstream commits not yet checked out, as of the last fetch
+	Behind int
+}
+
+// IsDirty reports whether any tracked file has uncommitted changes.
+func (s *WorkingTreeStatus) IsDirty() bool {
+	return len(s.ModifiedFiles) > 0
+}
+
+// WorktreeRoot returns the root of the working tree containing path, which may be a
+// subdirectory of the checkout.
+func WorktreeRoot(path string) (string, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return worktree.Filesystem.Root(), nil
+}
+
+// RepositoryStatus returns the working tree status of the repository in localPath.
+// Untracked files are ignored since updates never overwrite them. Detached checkouts
+// have no upstream and report zero ahead and behind.
+func RepositoryStatus(localPath string) (*WorkingTreeStatus, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	modified, err := modifiedFiles(repo)
+	if err != nil {
+		return nil, err
+	}
+	status := &WorkingTreeStatus{ModifiedFiles: modified}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return status, nil
+	}
+
+	upstream, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Name().Short()), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upstream of %s: %w", head.Name().Short(), err)
+	}
+
+	status.Ahead, status.Behind, err = divergence(repo, head.Hash(), upstream.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// modifiedFiles returns the sorted paths of tracked files with staged or unstaged changes.
+func modifiedFiles(repo *git.Repository) ([]string, error) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	files := make([]string, 0, len(status))
+	for file, fileStatus := range status {
+		if fileStatus.Worktree == git.Untracked && fileStatus.Staging == git.Untracked {
+			continue
+		}
+		if fileStatus.Worktree != git.Unmodified || fileStatus.Staging != git.Unmodified {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// divergence counts the commits reachable only from local (ahead) and only from upstream (behind).
+func divergence(repo *git.Repository, local, upstream plumbing.Hash) (ahead, behind int, err error) {
+	if local == upstream {
+		return 0, 0, nil
+	}
+
+	localCommits, err := ancestors(repo, local)
+	if err != nil {
+		return 0, 0, err
+	}
+	upstreamCommits, err := ancestors(repo, upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for hash := range localCommits {
+		if !upstreamCommits[hash] {
+			ahead++
+		}
+	}
+	for hash := range upstreamCommits {
+		if !localCommits[hash] {
+			behind++
+		}
+	}
+	return ahead, behind, nil
+}
+
+// ancestors returns from and every commit reachable from it. Parents missing from a shallow clone are skipped.
+func ancestors(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	seen := make(map[plumbing.Hash]bool)
+	pending := []plumbing.Hash{from}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[hash] {
+			continue
+		}
+
+		commit, err := repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+		}
+		seen[hash] = true
+		pending = append(pending, commit.ParentHashes...)
+	}
+	return seen, nil
+}
+
+// CommitInfo describes a commit.
+type CommitInfo struct {
+	Hash        string
+	Message     string
+	Author      string
+	AuthorEmail string
+	Time        time.Time
+}
+
+// HeadCommit returns the commit checked out in localPath.
+func HeadCommit(localPath string) (*CommitInfo, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	return &CommitInfo{
+		Hash:        commit.Hash.String(),
+		Message:     strings.TrimSpace(commit.Message),
+		Author:      commit.Author.Name,
+		AuthorEmail: commit.Author.Email,
+		Time:        commit.Author.When,
+	}, nil
+}
+
+// RemoteURLs returns the first URL of each configured remote, keyed by remote name.
+func RemoteURLs(localPath string) (map[string]string, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remotes: %w", err)
+	}
+
+	urls := make(map[string]string, len(remotes))
+	for _, remote := range remotes {
+		if config := remote.Config(); len(config.URLs) > 0 {
+			urls[config.Name] = config.URLs[0]
+		}
+	}
+	return urls, nil
+}
+
+// Stash holds local changes set aside while a repository is updated.
+type Stash struct {
+	// Dir is where the changed files were saved
+	Dir string
+	// Files lists the stashed paths, relative to the repository root
+	Files []string
+
+	base    plumbing.Hash
+	deleted map[string]bool
+}
+
+// StashChanges copies tracked files with uncommitted changes into .git/dotfiles-stash and
+// resets them to HEAD so the repository can be updated. It returns nil when the working tree is clean.
+func StashChanges(localPath string) (*Stash, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	files, err := modifiedFiles(repo)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	stash := &Stash{
+		Dir:     filepath.Join(localPath, ".git", stashRoot, time.Now().UTC().Format("20060102T150405.000000000Z")),
+		Files:   files,
+		base:    head.Hash(),
+		deleted: make(map[string]bool),
+	}
+	for _, file := range files {
+		source := filepath.Join(localPath, filepath.FromSlash(file))
+		if _, err := os.Lstat(source); os.IsNotExist(err) {
+			stash.deleted[file] = true
+			continue
+		}
+		if err := copyFile(source, filepath.Join(stash.Dir, filepath.FromSlash(file))); err != nil {
+			return nil, fmt.Errorf("failed to stash %s: %w", file, err)
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Mode: git.HardReset, Files: files}); err != nil {
+		return nil, fmt.Errorf("failed to reset stashed files: %w", err)
+	}
+	return stash, nil
+}
+
+// Restore re-applies stashed changes to files the update left untouched. Files that also
+// changed upstream stay in the stash and are returned as conflicts; the stash directory is
+// removed once every file has been restored.
+func (s *Stash) Restore(localPath string) ([]string, error) {
+	repo, err := git.PlainOpen(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	baseTree, err := commitTree(repo, s.base)
+	if err != nil {
+		return nil, err
+	}
+	headTree, err := commitTree(repo, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	for _, file := range s.Files {
+		if fileHash(baseTree, file) != fileHash(headTree, file) {
+			conflicts = append(conflicts, file)
+			continue
+		}
+
+		target := filepath.Join(localPath, filepath.FromSlash(file))
+		if s.deleted[file] {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to restore deletion of %s: %w", file, err)
+			}
+			continue
+		}
+		if err := copyFile(filepath.Join(s.Dir, filepath.FromSlash(file)), target); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", file, err)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		if err := os.RemoveAll(s.Dir); err != nil {
+			return nil, fmt.Errorf("failed to remove stash: %w", err)
+		}
+	}
+	return conflicts, nil
+}
+
+// commitTree returns the tree of a commit.
+func commitTree(repo *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", hash, err)
+	}
+	return tree, nil
+}
+
+// fileHash returns the blob hash of a file in tree, or the zero hash when it does not exist.
+func fileHash(tree *object.Tree, file string) plumbing.Hash {
+	entry, err := tree.FindEntry(file)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
+
+// copyFile copies a regular file, preserving its permissions and creating parent directories.
+func copyFile(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
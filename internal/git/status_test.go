@@ -0,0 +1,167 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRepositoryStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	commitAll(t, upstream, "initial")
+
+	manager, err := NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "local")
+	if _, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, ""); err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+
+	status, err := RepositoryStatus(localDir)
+	if err != nil {
+		t.Fatalf("RepositoryStatus failed: %v", err)
+	}
+	if status.IsDirty() || status.Ahead != 0 || status.Behind != 0 {
+		t.Errorf("Expected clean, up-to-date clone, got %+v", status)
+	}
+
+	commit, err := HeadCommit(localDir)
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	if commit.Message != "initial" || commit.Author != "Test" || commit.AuthorEmail != "test@example.com" {
+		t.Errorf("Unexpected head commit %+v", commit)
+	}
+	remotes, err := RemoteURLs(localDir)
+	if err != nil {
+		t.Fatalf("RemoteURLs failed: %v", err)
+	}
+	if !reflect.DeepEqual(remotes, map[string]string{"origin": upstreamDir}) {
+		t.Errorf("Unexpected remotes %v", remotes)
+	}
+
+	// Local edits and untracked files
+	if err := os.WriteFile(filepath.Join(localDir, "README.md"), []byte("local tweak\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "scratch.txt"), []byte("notes\n"), 0644); err != nil {
+		t.Fatalf("Failed to create untracked file: %v", err)
+	}
+
+	// Two upstream commits, fetched but not pulled
+	for _, name := range []string{"zshrc", "vimrc"} {
+		if err := os.WriteFile(filepath.Join(upstreamDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		commitAll(t, upstream, "add "+name)
+	}
+	if _, err := manager.FetchUpstream(context.Background(), localDir); err != nil {
+		t.Fatalf("FetchUpstream failed: %v", err)
+	}
+
+	status, err = RepositoryStatus(localDir)
+	if err != nil {
+		t.Fatalf("RepositoryStatus failed: %v", err)
+	}
+	if !reflect.DeepEqual(status.ModifiedFiles, []string{"README.md"}) {
+		t.Errorf("Expected README.md to be modified, got %v", status.ModifiedFiles)
+	}
+	if status.Ahead != 0 || status.Behind != 2 {
+		t.Errorf("Expected 0 ahead and 2 behind, got %d ahead and %d behind", status.Ahead, status.Behind)
+	}
+}
+
+func TestStashChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := createTestRepository(upstreamDir)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	for _, name := range []string{"zshrc", "vimrc", "tmux.conf"} {
+		if err := os.WriteFile(filepath.Join(upstreamDir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	commitAll(t, upstream, "initial")
+
+	manager, err := NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "local")
+	if _, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, ""); err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+
+	if stash, err := StashChanges(localDir); err != nil || stash != nil {
+		t.Fatalf("Expected nothing to stash in a clean clone, got %v, %v", stash, err)
+	}
+
+	// zshrc is only changed locally, vimrc changes on both sides, tmux.conf is deleted locally
+	if err := os.WriteFile(filepath.Join(localDir, "zshrc"), []byte("local zshrc\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify zshrc: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "vimrc"), []byte("local vimrc\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify vimrc: %v", err)
+	}
+	if err := os.Remove(filepath.Join(localDir, "tmux.conf")); err != nil {
+		t.Fatalf("Failed to delete tmux.conf: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(upstreamDir, "vimrc"), []byte("upstream vimrc\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify upstream vimrc: %v", err)
+	}
+	commitAll(t, upstream, "update vimrc")
+
+	stash, err := StashChanges(localDir)
+	if err != nil {
+		t.Fatalf("StashChanges failed: %v", err)
+	}
+	if !reflect.DeepEqual(stash.Files, []string{"tmux.conf", "vimrc", "zshrc"}) {
+		t.Errorf("Unexpected stashed files %v", stash.Files)
+	}
+	if status, _ := RepositoryStatus(localDir); status.IsDirty() {
+		t.Fatalf("Expected clean working tree after stashing, got %v", status.ModifiedFiles)
+	}
+
+	if _, err := manager.UpdateRepository(context.Background(), localDir); err != nil {
+		t.Fatalf("UpdateRepository failed: %v", err)
+	}
+
+	conflicts, err := stash.Restore(localDir)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"vimrc"}) {
+		t.Errorf("Expected vimrc to conflict, got %v", conflicts)
+	}
+
+	expectContent := func(path, expected string) {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", path, expected, content)
+		}
+	}
+	expectContent(filepath.Join(localDir, "zshrc"), "local zshrc\n")
+	expectContent(filepath.Join(localDir, "vimrc"), "upstream vimrc\n")
+	expectContent(filepath.Join(stash.Dir, "vimrc"), "local vimrc\n")
+	if _, err := os.Stat(filepath.Join(localDir, "tmux.conf")); !os.IsNotExist(err) {
+		t.Error("Local deletion of tmux.conf should be restored")
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 )
 
 // OperationState represents the current state of an operation.
@@ -166,6 +169,12 @@ type DirectoryState struct {
 
 // GetDirectoryState captures the current state of a directory and its contents.
 func GetDirectoryState(ctx context.Context, path string, recursive bool) (*DirectoryState, error) {
+	return GetDirectoryStateWithConcurrency(ctx, path, recursive, nil)
+}
+
+// GetDirectoryStateWithConcurrency captures the state of a directory, hashing files
+// through the given concurrency manager. A nil manager hashes files sequentially.
+func GetDirectoryStateWithConcurrency(ctx context.Context, path string, recursive bool, cm *services.ConcurrencyManager) (*DirectoryState, error) {
 	// Initialize and validate directory
 	state, err := initializeDirectoryState(path)
 	if err != nil {
@@ -173,7 +182,7 @@ func GetDirectoryState(ctx context.Context, path string, recursive bool) (*Direc
 	}
 
 	// Walk the directory and populate file states
-	if err := populateDirectoryState(ctx, path, recursive, state); err != nil {
+	if err := populateDirectoryState(ctx, path, recursive, state, cm); err != nil {
 		return nil, fmt.Errorf("failed to walk directory %s: %w", path, err)
 	}
 
@@ -208,17 +217,47 @@ func initializeDirectoryState(path string) (*DirectoryState, error) {
 }
 
 // populateDirectoryState walks the directory and populates file states
-func populateDirectoryState(ctx context.Context, path string, recursive bool, state *DirectoryState) error {
-	walkFunc := createDirectoryWalkFunc(ctx, path, recursive, state)
+func populateDirectoryState(ctx context.Context, path string, recursive bool, state *DirectoryState, cm *services.ConcurrencyManager) error {
+	files := make(map[string]string)
+	walkFunc := createDirectoryWalkFunc(ctx, path, recursive, files)
 
+	var err error
 	if recursive {
-		return filepath.Walk(path, walkFunc)
+		err = filepath.Walk(path, walkFunc)
+	} else {
+		err = walkDirectoryNonRecursive(ctx, path, walkFunc)
+	}
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	tasks := make([]func() error, 0, len(files))
+	for relPath, filePath := range files {
+		tasks = append(tasks, func() error {
+			fileState, err := GetFileState(filePath)
+			if err != nil {
+				tflog.Warn(ctx, "Failed to get file state", map[string]interface{}{
+					"path":  filePath,
+					"error": err.Error(),
+				})
+				return err
+			}
+
+			mu.Lock()
+			state.Files[relPath] = fileState
+			state.FileCount++
+			mu.Unlock()
+			return nil
+		})
 	}
-	return walkDirectoryNonRecursive(ctx, path, walkFunc)
+
+	return cm.RunParallel(ctx, tasks)
 }
 
-// createDirectoryWalkFunc creates the walk function for directory traversal
-func createDirectoryWalkFunc(ctx context.Context, rootPath string, recursive bool, state *DirectoryState) filepath.WalkFunc {
+// createDirectoryWalkFunc creates the walk function for directory traversal.
+// It collects the files to hash, keyed by their path relative to rootPath.
+func createDirectoryWalkFunc(ctx context.Context, rootPath string, recursive bool, files map[string]string) filepath.WalkFunc {
 	return func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			tflog.Warn(ctx, "Error walking directory", map[string]interface{}{
@@ -238,6 +277,11 @@ func createDirectoryWalkFunc(ctx context.Context, rootPath string, recursive boo
 			return filepath.SkipDir
 		}
 
+		// Only include files in the state, not directories
+		if info.IsDir() {
+			return nil
+		}
+
 		// Get relative path for consistent keys
 		relPath, err := filepath.Rel(rootPath, filePath)
 		if err != nil {
@@ -249,22 +293,7 @@ func createDirectoryWalkFunc(ctx context.Context, rootPath string, recursive boo
 			return err
 		}
 
-		// Get file state
-		fileState, err := GetFileState(filePath)
-		if err != nil {
-			tflog.Warn(ctx, "Failed to get file state", map[string]interface{}{
-				"path":  filePath,
-				"error": err.Error(),
-			})
-			return err
-		}
-
-		// Only include files in the state, not directories
-		if !info.IsDir() {
-			state.Files[relPath] = fileState
-			state.FileCount++
-		}
-
+		files[relPath] = filePath
 		return nil
 	}
 }
@@ -399,9 +428,10 @@ func EnsureIdempotentFileOperation(ctx context.Context, targetPath string, opera
 }
 
 // EnsureIdempotentDirectoryOperation ensures a directory operation is idempotent.
-func EnsureIdempotentDirectoryOperation(ctx context.Context, targetPath string, recursive bool, operation func() error) error {
+// Directory states are hashed through cm; a nil manager hashes files sequentially.
+func EnsureIdempotentDirectoryOperation(ctx context.Context, targetPath string, recursive bool, cm *services.ConcurrencyManager, operation func() error) error {
 	// Get initial state
-	beforeState, err := GetDirectoryState(ctx, targetPath, recursive)
+	beforeState, err := GetDirectoryStateWithConcurrency(ctx, targetPath, recursive, cm)
 	if err != nil {
 		tflog.Debug(ctx, "Could not get initial directory state", map[string]interface{}{
 			"path":  targetPath,
@@ -417,7 +447,7 @@ func EnsureIdempotentDirectoryOperation(ctx context.Context, targetPath string,
 	}
 
 	// Get final state
-	afterState, err := GetDirectoryState(ctx, targetPath, recursive)
+	afterState, err := GetDirectoryStateWithConcurrency(ctx, targetPath, recursive, cm)
 	if err != nil {
 		tflog.Debug(ctx, "Could not get final directory state", map[string]interface{}{
 			"path":  targetPath,
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 )
 
 func TestGetFileState(t *testing.T) {
@@ -546,3 +548,97 @@ func TestEdgeCaseFilenames(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDirectoryStateWithConcurrency(t *testing.T) {
+	tempDir := t.TempDir()
+	numFiles := 50
+
+	for i := 0; i < numFiles; i++ {
+		subDir := filepath.Join(tempDir, fmt.Sprintf("dir%d", i%5))
+		if err := os.MkdirAll(subDir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		fileName := filepath.Join(subDir, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(fileName, []byte(fmt.Sprintf("content %d", i)), 0644); err != nil {
+			t.Fatalf("Failed to create file %d: %v", i, err)
+		}
+	}
+
+	ctx := context.Background()
+	sequential, err := GetDirectoryState(ctx, tempDir, true)
+	if err != nil {
+		t.Fatalf("Failed to get sequential directory state: %v", err)
+	}
+
+	cm := services.NewConcurrencyManager(4)
+	parallel, err := GetDirectoryStateWithConcurrency(ctx, tempDir, true, cm)
+	if err != nil {
+		t.Fatalf("Failed to get parallel directory state: %v", err)
+	}
+
+	if parallel.FileCount != int64(numFiles) {
+		t.Errorf("Expected file count %d, got %d", numFiles, parallel.FileCount)
+	}
+
+	if !CompareDirectoryStates(sequential, parallel) {
+		t.Error("Expected sequential and parallel directory states to be equal")
+	}
+}
+
+func TestEnsureIdempotentDirectoryOperationWithConcurrency(t *testing.T) {
+	tempDir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		if err := os.WriteFile(filepath.Join(tempDir, fmt.Sprintf("file%d.txt", i)), []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file %d: %v", i, err)
+		}
+	}
+
+	ctx := context.Background()
+	cm := services.NewConcurrencyManager(4)
+	called := false
+	err := EnsureIdempotentDirectoryOperation(ctx, tempDir, true, cm, func() error {
+		called = true
+		return os.WriteFile(filepath.Join(tempDir, "new.txt"), []byte("new"), 0644)
+	})
+	if err != nil {
+		t.Fatalf("EnsureIdempotentDirectoryOperation failed: %v", err)
+	}
+	if !called {
+		t.Error("Expected the operation to be called")
+	}
+
+	// Operation errors are returned
+	expected := fmt.Errorf("sync failed")
+	if err := EnsureIdempotentDirectoryOperation(ctx, tempDir, true, cm, func() error { return expected }); err != expected {
+		t.Errorf("Expected the operation error, got %v", err)
+	}
+}
+
+func BenchmarkGetDirectoryState(b *testing.B) {
+	tempDir := b.TempDir()
+	content := make([]byte, 64*1024)
+	for i := 0; i < 200; i++ {
+		fileName := filepath.Join(tempDir, fmt.Sprintf("file%d.bin", i))
+		if err := os.WriteFile(fileName, content, 0644); err != nil {
+			b.Fatalf("Failed to create file %d: %v", i, err)
+		}
+	}
+	ctx := context.Background()
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := GetDirectoryState(ctx, tempDir, true); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		cm := services.NewConcurrencyManager(services.DefaultMaxConcurrency)
+		for i := 0; i < b.N; i++ {
+			if _, err := GetDirectoryStateWithConcurrency(ctx, tempDir, true, cm); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,641 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package inifile edits INI-style configuration files such as .gitconfig, .npmrc and
+// .editorconfig key by key, keeping comments, layout and other keys as they are.
+package inifile
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Syntax identifies the dialect of an INI-style file.
+type Syntax string
+
+// Supported syntaxes.
+const (
+	// SyntaxGit follows git-config(1): `[section "subsection"]` headers, quoted values with
+	// escapes, inline comments, and case-insensitive section and key names.
+	SyntaxGit Syntax = "git"
+	// SyntaxINI is plain INI: values are the trimmed text after `=` and names are matched
+	// exactly.
+	SyntaxINI Syntax = "ini"
+)
+
+// Syntaxes lists the supported syntaxes.
+var Syntaxes = []Syntax{SyntaxGit, SyntaxINI}
+
+// DetectSyntax returns SyntaxGit for git configuration files and SyntaxINI otherwise.
+func DetectSyntax(path string) Syntax {
+	base := filepath.Base(path)
+	parent := filepath.Base(filepath.Dir(path))
+	switch {
+	case base == ".gitconfig", base == ".gitmodules", strings.HasSuffix(base, ".gitconfig"):
+		return SyntaxGit
+	case base == "config" && (parent == ".git" || parent == "git"):
+		return SyntaxGit
+	}
+	return SyntaxINI
+}
+
+var (
+	gitSectionName = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	gitKeyName     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+)
+
+// Key names a setting. Section is empty for keys before the first section header, which
+// only plain INI files have. Subsection is only supported by SyntaxGit.
+type Key struct {
+	Section    string
+	Subsection string
+	Name       string
+}
+
+// String returns the key in git's dotted form, e.g. `url.git@github.com:.insteadOf`.
+func (k Key) String() string {
+	parts := []string{}
+	if k.Section != "" {
+		parts = append(parts, k.Section)
+	}
+	if k.Subsection != "" {
+		parts = append(parts, k.Subsection)
+	}
+	return strings.Join(append(parts, k.Name), ".")
+}
+
+// Validate checks that the key can be written in the given syntax.
+func (k Key) Validate(syntax Syntax) error {
+	if syntax == SyntaxGit {
+		if !gitSectionName.MatchString(k.Section) {
+			return fmt.Errorf("section %q must be non-empty and contain only letters, digits and -", k.Section)
+		}
+		if strings.ContainsAny(k.Subsection, "\n\x00") {
+			return fmt.Errorf("subsection %q must not contain newlines", k.Subsection)
+		}
+		if !gitKeyName.MatchString(k.Name) {
+			return fmt.Errorf("key %q must start with a letter and contain only letters, digits and -", k.Name)
+		}
+		return nil
+	}
+
+	if strings.ContainsAny(k.Section, "\r\n") {
+		return fmt.Errorf("section %q must not contain newlines", k.Section)
+	}
+	if k.Subsection != "" {
+		return fmt.Errorf("subsections are only supported by the git syntax")
+	}
+	if k.Name == "" || k.Name != strings.TrimSpace(k.Name) || strings.ContainsAny(k.Name, "=\r\n") || strings.ContainsAny(k.Name[:1], "[#;") {
+		return fmt.Errorf("key %q must be non-empty, must not contain = or newlines and must not start with [, # or ;", k.Name)
+	}
+	return nil
+}
+
+type lineKind int
+
+const (
+	otherLine lineKind = iota
+	sectionLine
+	entryLine
+)
+
+// line is a logical line of the file. Entries continued with a trailing backslash span
+// several physical lines.
+type line struct {
+	kind lineKind
+	raw  string
+	// header is the section line an entry belongs to, nil before the first section
+	header *line
+
+	// Section header fields
+	section    string
+	subsection string
+	// legacy marks git's deprecated `[section.subsection]` form
+	legacy bool
+
+	// Entry fields
+	indent  string
+	name    string
+	sep     string
+	value   string
+	comment string
+	// implicit marks a key without `=`, which git reads as true
+	implicit bool
+}
+
+// File is a parsed INI-style file.
+type File struct {
+	syntax  Syntax
+	lines   []*line
+	newline string
+}
+
+// Parse parses data in the given syntax.
+func Parse(syntax Syntax, data string) (*File, error) {
+	f := &File{syntax: syntax, newline: "\n"}
+	if strings.Contains(data, "\r\n") {
+		f.newline = "\r\n"
+		data = strings.ReplaceAll(data, "\r\n", "\n")
+	}
+	physical := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	if data == "" {
+		physical = nil
+	}
+
+	var header *line
+	for i := 0; i < len(physical); i++ {
+		text := physical[i]
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+			f.lines = append(f.lines, &line{kind: otherLine, raw: text, header: header})
+		case trimmed[0] == '[':
+			l, err := f.parseHeader(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			l.raw = text
+			l.header = l
+			header = l
+			f.lines = append(f.lines, l)
+		default:
+			start := i
+			l, continued, err := f.parseEntry(text)
+			for err == nil && continued {
+				if i+1 == len(physical) {
+					err = fmt.Errorf("value continues past the end of the file")
+					break
+				}
+				i++
+				text += "\n" + physical[i]
+				l, continued, err = f.parseEntry(text)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", start+1, err)
+			}
+			l.header = header
+			f.lines = append(f.lines, l)
+		}
+	}
+	return f, nil
+}
+
+// parseHeader parses a trimmed section header line.
+func (f *File) parseHeader(text string) (*line, error) {
+	if f.syntax != SyntaxGit {
+		// The last bracket closes the header, so globs like [*.[ch]] keep theirs
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("section header %q is not closed", text)
+		}
+		return &line{kind: sectionLine, section: strings.TrimSpace(text[1 : len(text)-1])}, nil
+	}
+
+	l := &line{kind: sectionLine}
+	rest := text[1:]
+	end := strings.IndexAny(rest, "] \t\"")
+	if end < 0 {
+		return nil, fmt.Errorf("section header %q is not closed", text)
+	}
+	l.section = rest[:end]
+	rest = strings.TrimLeft(rest[end:], " \t")
+	if strings.HasPrefix(rest, "\"") {
+		var sub strings.Builder
+		closed := false
+		i := 1
+		for ; i < len(rest) && !closed; i++ {
+			switch rest[i] {
+			case '\\':
+				if i+1 < len(rest) {
+					i++
+					sub.WriteByte(rest[i])
+				}
+			case '"':
+				closed = true
+			default:
+				sub.WriteByte(rest[i])
+			}
+		}
+		if !closed {
+			return nil, fmt.Errorf("subsection in %q is not closed", text)
+		}
+		l.subsection = sub.String()
+		rest = rest[i:]
+	} else if dot := strings.Index(l.section, "."); dot >= 0 {
+		l.section, l.subsection, l.legacy = l.section[:dot], l.section[dot+1:], true
+	}
+	if !strings.HasPrefix(rest, "]") {
+		return nil, fmt.Errorf("section header %q is not closed", text)
+	}
+	if after := strings.TrimSpace(rest[1:]); after != "" && after[0] != '#' && after[0] != ';' {
+		return nil, fmt.Errorf("unexpected content after section header %q", text)
+	}
+	if !gitSectionName.MatchString(l.section) {
+		return nil, fmt.Errorf("invalid section name %q", l.section)
+	}
+	return l, nil
+}
+
+// parseEntry parses a key line. For SyntaxGit it reports whether the value continues on
+// the next physical line.
+func (f *File) parseEntry(text string) (*line, bool, error) {
+	l := &line{kind: entryLine, raw: text}
+	rest := strings.TrimLeft(text, " \t")
+	l.indent = text[:len(text)-len(rest)]
+
+	if f.syntax != SyntaxGit {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			l.name, l.implicit = strings.TrimSpace(rest), true
+			return l, false, nil
+		}
+		l.name = strings.TrimRight(rest[:eq], " \t")
+		value := rest[eq+1:]
+		l.value = strings.TrimSpace(value)
+		l.sep = rest[len(l.name):eq+1] + value[:len(value)-len(strings.TrimLeft(value, " \t"))]
+		if l.name == "" {
+			return nil, false, fmt.Errorf("missing key name in %q", text)
+		}
+		return l, false, nil
+	}
+
+	end := 0
+	for end < len(rest) && (isAlnum(rest[end]) || rest[end] == '-') {
+		end++
+	}
+	l.name = rest[:end]
+	if !gitKeyName.MatchString(l.name) {
+		return nil, false, fmt.Errorf("invalid key in %q", text)
+	}
+	after := strings.TrimLeft(rest[end:], " \t")
+	if after == "" || after[0] == '#' || after[0] == ';' {
+		l.implicit = true
+		l.comment = rest[end:]
+		return l, false, nil
+	}
+	if after[0] != '=' {
+		return nil, false, fmt.Errorf("expected = after key %q", l.name)
+	}
+	value := strings.TrimLeft(after[1:], " \t")
+	l.sep = rest[end : len(rest)-len(value)]
+
+	var err error
+	var continued bool
+	l.value, l.comment, continued, err = scanGitValue(value)
+	return l, continued, err
+}
+
+// scanGitValue decodes a git config value, returning the value, a trailing comment with the
+// whitespace before it, and whether a trailing backslash continues it on the next line.
+func scanGitValue(s string) (string, string, bool, error) {
+	var value strings.Builder
+	space := ""
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return value.String(), "", true, nil
+			}
+			i++
+			var decoded byte
+			switch s[i] {
+			case '\n':
+				continue
+			case 'n':
+				decoded = '\n'
+			case 't':
+				decoded = '\t'
+			case 'b':
+				decoded = '\b'
+			case '\\', '"':
+				decoded = s[i]
+			default:
+				return "", "", false, fmt.Errorf("invalid escape \\%c", s[i])
+			}
+			value.WriteString(space)
+			space = ""
+			value.WriteByte(decoded)
+		case c == '"':
+			value.WriteString(space)
+			space = ""
+			quoted = !quoted
+		case !quoted && (c == '#' || c == ';'):
+			return value.String(), space + s[i:], false, nil
+		case !quoted && (c == ' ' || c == '\t'):
+			space += string(c)
+		case c == '\n':
+			return "", "", false, fmt.Errorf("unexpected line break in value")
+		default:
+			value.WriteString(space)
+			space = ""
+			value.WriteByte(c)
+		}
+	}
+	if quoted {
+		return "", "", false, fmt.Errorf("unterminated quote in value")
+	}
+	return value.String(), "", false, nil
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// String returns the file content. A non-empty file ends with a newline.
+func (f *File) String() string {
+	if len(f.lines) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, l := range f.lines {
+		b.WriteString(strings.ReplaceAll(l.raw, "\n", f.newline))
+		b.WriteString(f.newline)
+	}
+	return b.String()
+}
+
+// Get returns the values of every occurrence of key, in file order. A git key without `=`
+// reads as true.
+func (f *File) Get(key Key) []string {
+	values := []string{}
+	for _, l := range f.lines {
+		if f.matchesEntry(l, key) {
+			values = append(values, f.entryValue(l))
+		}
+	}
+	return values
+}
+
+// Set makes values the values of key: existing occurrences are updated in place, extra
+// ones removed and missing ones added after the last occurrence, or at the end of the
+// section, which is created when missing. An empty list removes the key.
+func (f *File) Set(key Key, values []string) error {
+	if err := key.Validate(f.syntax); err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		f.Unset(key)
+		return nil
+	}
+	for _, value := range values {
+		if f.syntax != SyntaxGit && (value != strings.TrimSpace(value) || strings.ContainsAny(value, "\r\n")) {
+			return fmt.Errorf("value of %s cannot have leading or trailing whitespace or newlines in INI syntax", key)
+		}
+	}
+
+	occurrences := f.occurrences(key)
+	for i, index := range occurrences {
+		if i < len(values) && f.entryValue(f.lines[index]) != values[i] {
+			f.setValue(f.lines[index], values[i])
+		}
+	}
+	if len(occurrences) > len(values) {
+		f.removeLines(occurrences[len(values):])
+		return nil
+	}
+
+	var position int
+	var header *line
+	if len(occurrences) > 0 {
+		last := occurrences[len(occurrences)-1]
+		position, header = last+1, f.lines[last].header
+	} else {
+		position, header = f.sectionEnd(key)
+	}
+	template := f.entryTemplate(header)
+	added := make([]*line, 0, len(values)-len(occurrences))
+	for _, value := range values[len(occurrences):] {
+		l := &line{kind: entryLine, header: header, indent: template.indent, name: key.Name, sep: template.sep}
+		f.setValue(l, value)
+		added = append(added, l)
+	}
+	f.lines = slices.Insert(f.lines, position, added...)
+	return nil
+}
+
+// Unset removes every occurrence of key. Sections left without any lines but blank ones
+// are removed too.
+func (f *File) Unset(key Key) {
+	occurrences := f.occurrences(key)
+	if len(occurrences) == 0 {
+		return
+	}
+	headers := map[*line]bool{}
+	for _, index := range occurrences {
+		if header := f.lines[index].header; header != nil {
+			headers[header] = true
+		}
+	}
+	f.removeLines(occurrences)
+
+	var empty []int
+	for i := 0; i < len(f.lines); i++ {
+		if !headers[f.lines[i]] {
+			continue
+		}
+		block := []int{i}
+		for j := i + 1; j < len(f.lines) && f.lines[j].header == f.lines[i]; j++ {
+			if strings.TrimSpace(f.lines[j].raw) != "" {
+				block = nil
+				break
+			}
+			block = append(block, j)
+		}
+		empty = append(empty, block...)
+	}
+	if len(empty) == 0 {
+		return
+	}
+	f.removeLines(empty)
+	for len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1].raw) == "" {
+		f.lines = f.lines[:len(f.lines)-1]
+	}
+}
+
+// occurrences returns the indexes of the entries for key.
+func (f *File) occurrences(key Key) []int {
+	var indexes []int
+	for i, l := range f.lines {
+		if f.matchesEntry(l, key) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (f *File) matchesEntry(l *line, key Key) bool {
+	if l.kind != entryLine || !f.equalName(l.name, key.Name) {
+		return false
+	}
+	if l.header == nil {
+		return key.Section == "" && key.Subsection == ""
+	}
+	return f.matchesSection(l.header, key)
+}
+
+func (f *File) matchesSection(header *line, key Key) bool {
+	if !f.equalName(header.section, key.Section) {
+		return false
+	}
+	if header.legacy {
+		return strings.EqualFold(header.subsection, key.Subsection)
+	}
+	return header.subsection == key.Subsection
+}
+
+// equalName compares section and key names, which git treats case-insensitively.
+func (f *File) equalName(a, b string) bool {
+	if f.syntax == SyntaxGit {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func (f *File) entryValue(l *line) string {
+	if l.implicit && f.syntax == SyntaxGit {
+		return "true"
+	}
+	return l.value
+}
+
+// setValue replaces the value of an entry, keeping its indentation, separator and comment.
+func (f *File) setValue(l *line, value string) {
+	if l.implicit || l.sep == "" {
+		l.sep = " = "
+	}
+	l.implicit = false
+	l.value = value
+
+	formatted := value
+	if f.syntax == SyntaxGit {
+		formatted = formatGitValue(value)
+	}
+	sep := l.sep
+	if formatted == "" {
+		sep = strings.TrimRight(sep, " \t")
+	}
+	l.raw = l.indent + l.name + sep + formatted + l.comment
+}
+
+// formatGitValue quotes and escapes a value the way `git config` writes it.
+func formatGitValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\b", `\b`).Replace(value)
+	if value != "" && (value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;")) {
+		return `"` + escaped + `"`
+	}
+	return escaped
+}
+
+// sectionEnd returns where a new key of the section belongs, after its last entry, adding
+// the section header at the end of the file when the section is missing.
+func (f *File) sectionEnd(key Key) (int, *line) {
+	if key.Section == "" && key.Subsection == "" {
+		end := -1
+		for i, l := range f.lines {
+			if l.kind == sectionLine {
+				break
+			}
+			if l.kind == entryLine {
+				end = i + 1
+			}
+		}
+		if end >= 0 {
+			return end, nil
+		}
+		// Before the blank lines leading up to the first section
+		for i, l := range f.lines {
+			if l.kind == sectionLine {
+				for i > 0 && strings.TrimSpace(f.lines[i-1].raw) == "" {
+					i--
+				}
+				return i, nil
+			}
+		}
+		return len(f.lines), nil
+	}
+
+	var header *line
+	end := -1
+	for i, l := range f.lines {
+		if l.kind == sectionLine {
+			if f.matchesSection(l, key) {
+				header, end = l, i+1
+			}
+			continue
+		}
+		if header != nil && l.header == header && l.kind == entryLine {
+			end = i + 1
+		}
+	}
+	if header != nil {
+		return end, header
+	}
+
+	header = &line{kind: sectionLine, section: key.Section, subsection: key.Subsection}
+	header.header = header
+	header.raw = "[" + key.Section + "]"
+	if key.Subsection != "" {
+		subsection := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key.Subsection)
+		header.raw = "[" + key.Section + ` "` + subsection + `"]`
+	}
+	var added []*line
+	if len(f.lines) > 0 && f.blankBetweenSections() && strings.TrimSpace(f.lines[len(f.lines)-1].raw) != "" {
+		added = append(added, &line{kind: otherLine, header: f.lines[len(f.lines)-1].header})
+	}
+	f.lines = append(f.lines, append(added, header)...)
+	return len(f.lines), header
+}
+
+// blankBetweenSections reports whether the file separates sections with blank lines.
+func (f *File) blankBetweenSections() bool {
+	for i, l := range f.lines {
+		if l.kind == sectionLine && i > 0 {
+			return strings.TrimSpace(f.lines[i-1].raw) == ""
+		}
+	}
+	return f.syntax != SyntaxGit
+}
+
+// entryTemplate returns an entry whose indentation and separator new keys copy: the last
+// entry of the section, or of the file, or the syntax's default layout.
+func (f *File) entryTemplate(header *line) *line {
+	var inFile *line
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		l := f.lines[i]
+		if l.kind != entryLine || l.implicit {
+			continue
+		}
+		if l.header == header {
+			return l
+		}
+		if inFile == nil && l.header != nil && header != nil {
+			inFile = l
+		}
+	}
+	if inFile != nil {
+		return inFile
+	}
+	if f.syntax == SyntaxGit {
+		return &line{indent: "\t", sep: " = "}
+	}
+	return &line{sep: " = "}
+}
+
+// removeLines removes the lines at the given ascending indexes.
+func (f *File) removeLines(indexes []int) {
+	remove := make(map[int]bool, len(indexes))
+	for _, index := range indexes {
+		remove[index] = true
+	}
+	kept := f.lines[:0]
+	for i, l := range f.lines {
+		if !remove[i] {
+			kept = append(kept, l)
+		}
+	}
+	f.lines = kept
+}
@@ -0,0 +1,235 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package inifile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, syntax Syntax, data string) *File {
+	t.Helper()
+	f, err := Parse(syntax, data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return f
+}
+
+func mustSet(t *testing.T, f *File, key Key, values ...string) {
+	t.Helper()
+	if err := f.Set(key, values); err != nil {
+		t.Fatalf("Set %s failed: %v", key, err)
+	}
+}
+
+const gitconfig = `# user settings
+[user]
+	name = Jane Doe
+	email = jane@example.com ; work address
+[core]
+	bare
+	pager = "less -R"
+[url "git@github.com:"]
+	insteadOf = https://github.com/
+[remote "origin"]
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+[alias]
+	lg = log --graph \
+	  --oneline
+`
+
+func TestGetGit(t *testing.T) {
+	f := mustParse(t, SyntaxGit, gitconfig)
+	tests := []struct {
+		key      Key
+		expected []string
+	}{
+		{Key{Section: "user", Name: "name"}, []string{"Jane Doe"}},
+		{Key{Section: "USER", Name: "Email"}, []string{"jane@example.com"}},
+		{Key{Section: "core", Name: "bare"}, []string{"true"}},
+		{Key{Section: "core", Name: "pager"}, []string{"less -R"}},
+		{Key{Section: "url", Subsection: "git@github.com:", Name: "insteadOf"}, []string{"https://github.com/"}},
+		{Key{Section: "url", Subsection: "GIT@github.com:", Name: "insteadOf"}, []string{}},
+		{Key{Section: "remote", Subsection: "origin", Name: "fetch"}, []string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}},
+		{Key{Section: "alias", Name: "lg"}, []string{"log --graph \t  --oneline"}},
+		{Key{Section: "user", Name: "signingkey"}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := f.Get(tt.key); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Get(%s) = %q, expected %q", tt.key, got, tt.expected)
+		}
+	}
+	if f.String() != gitconfig {
+		t.Errorf("Expected an unchanged file to round-trip, got:\n%s", f.String())
+	}
+}
+
+func TestSetGit(t *testing.T) {
+	f := mustParse(t, SyntaxGit, gitconfig)
+	mustSet(t, f, Key{Section: "user", Name: "email"}, "jane@personal.dev")
+	mustSet(t, f, Key{Section: "user", Name: "signingKey"}, "ABC123")
+	mustSet(t, f, Key{Section: "core", Name: "bare"}, "false")
+	mustSet(t, f, Key{Section: "remote", Subsection: "origin", Name: "fetch"}, "+refs/heads/main:refs/remotes/origin/main")
+	mustSet(t, f, Key{Section: "includeIf", Subsection: `gitdir:~/work/`, Name: "path"}, "~/.gitconfig-work", "~/.gitconfig-#2")
+	mustSet(t, f, Key{Section: "alias", Name: "lg"}, "log --graph --oneline")
+
+	expected := `# user settings
+[user]
+	name = Jane Doe
+	email = jane@personal.dev ; work address
+	signingKey = ABC123
+[core]
+	bare = false
+	pager = "less -R"
+[url "git@github.com:"]
+	insteadOf = https://github.com/
+[remote "origin"]
+	fetch = +refs/heads/main:refs/remotes/origin/main
+[alias]
+	lg = log --graph --oneline
+[includeIf "gitdir:~/work/"]
+	path = ~/.gitconfig-work
+	path = "~/.gitconfig-#2"
+`
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	reparsed := mustParse(t, SyntaxGit, f.String())
+	paths := reparsed.Get(Key{Section: "includeIf", Subsection: "gitdir:~/work/", Name: "path"})
+	if !reflect.DeepEqual(paths, []string{"~/.gitconfig-work", "~/.gitconfig-#2"}) {
+		t.Errorf("Expected written values to read back, got %q", paths)
+	}
+}
+
+func TestSetGitQuoting(t *testing.T) {
+	f := mustParse(t, SyntaxGit, "")
+	mustSet(t, f, Key{Section: "core", Name: "editor"}, `vim -c "set tw=72"`)
+	mustSet(t, f, Key{Section: "core", Name: "comment"}, " padded ")
+	mustSet(t, f, Key{Section: "core", Name: "empty"}, "")
+
+	expected := "[core]\n\teditor = vim -c \\\"set tw=72\\\"\n\tcomment = \" padded \"\n\tempty =\n"
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	reparsed := mustParse(t, SyntaxGit, f.String())
+	for name, value := range map[string]string{"editor": `vim -c "set tw=72"`, "comment": " padded ", "empty": ""} {
+		if got := reparsed.Get(Key{Section: "core", Name: name}); !reflect.DeepEqual(got, []string{value}) {
+			t.Errorf("Expected %s to read back as %q, got %q", name, value, got)
+		}
+	}
+}
+
+func TestUnset(t *testing.T) {
+	f := mustParse(t, SyntaxGit, gitconfig)
+	f.Unset(Key{Section: "url", Subsection: "git@github.com:", Name: "insteadOf"})
+	f.Unset(Key{Section: "user", Name: "email"})
+	f.Unset(Key{Section: "alias", Name: "lg"})
+	f.Unset(Key{Section: "missing", Name: "key"})
+
+	expected := `# user settings
+[user]
+	name = Jane Doe
+[core]
+	bare
+	pager = "less -R"
+[remote "origin"]
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+`
+	if got := f.String(); got != expected {
+		t.Errorf("Expected emptied sections to be removed:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestPlainINI(t *testing.T) {
+	npmrc := "registry=https://registry.npmjs.org/\n//npm.pkg.github.com/:_authToken=${GH_TOKEN}\n"
+	f := mustParse(t, SyntaxINI, npmrc)
+	if got := f.Get(Key{Name: "//npm.pkg.github.com/:_authToken"}); !reflect.DeepEqual(got, []string{"${GH_TOKEN}"}) {
+		t.Errorf("Unexpected value %q", got)
+	}
+	mustSet(t, f, Key{Name: "save-exact"}, "true")
+	if got := f.String(); got != npmrc+"save-exact=true\n" {
+		t.Errorf("Expected the existing layout to be copied, got:\n%s", got)
+	}
+
+	editorconfig := "root = true\n\n[*]\nindent_style = space\n\n[*.[ch]]\nindent_size = 8\n"
+	f = mustParse(t, SyntaxINI, editorconfig)
+	mustSet(t, f, Key{Section: "*.[ch]", Name: "indent_size"}, "4")
+	mustSet(t, f, Key{Section: "Makefile", Name: "indent_style"}, "tab")
+	mustSet(t, f, Key{Name: "charset"}, "utf-8")
+	expected := "root = true\ncharset = utf-8\n\n[*]\nindent_style = space\n\n[*.[ch]]\nindent_size = 4\n\n[Makefile]\nindent_style = tab\n"
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := f.Get(Key{Section: "makefile", Name: "indent_style"}); len(got) != 0 {
+		t.Errorf("Expected INI names to be case-sensitive, got %q", got)
+	}
+	if err := f.Set(Key{Section: "*", Name: "x"}, []string{"two\nlines"}); err == nil {
+		t.Error("Expected an error for a multi-line INI value")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"[user\nname = x\n",
+		"[remote \"origin]\n",
+		"[user]\n\tname = \"unterminated\n",
+		"[user]\n\tname = a \\q\n",
+		"[user]\n\tname = continued \\\n",
+		"[user]\n\t= value\n",
+	} {
+		if _, err := Parse(SyntaxGit, data); err == nil {
+			t.Errorf("Expected an error parsing %q", data)
+		}
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	f := mustParse(t, SyntaxINI, "[a]\r\nx = 1\r\n")
+	mustSet(t, f, Key{Section: "a", Name: "y"}, "2")
+	if got := f.String(); got != "[a]\r\nx = 1\r\ny = 2\r\n" {
+		t.Errorf("Expected line endings to be kept, got %q", got)
+	}
+}
+
+func TestKeyValidate(t *testing.T) {
+	tests := []struct {
+		key    Key
+		syntax Syntax
+		valid  bool
+	}{
+		{Key{Section: "user", Name: "name"}, SyntaxGit, true},
+		{Key{Section: "url", Subsection: "git@github.com:", Name: "insteadOf"}, SyntaxGit, true},
+		{Key{Name: "name"}, SyntaxGit, false},
+		{Key{Section: "user", Name: "first_name"}, SyntaxGit, false},
+		{Key{Section: "a.b", Name: "c"}, SyntaxGit, false},
+		{Key{Name: "//registry/:_authToken"}, SyntaxINI, true},
+		{Key{Section: "x", Subsection: "y", Name: "z"}, SyntaxINI, false},
+		{Key{Section: "x", Name: "a=b"}, SyntaxINI, false},
+		{Key{Section: "x", Name: "#a"}, SyntaxINI, false},
+	}
+	for _, tt := range tests {
+		if err := tt.key.Validate(tt.syntax); (err == nil) != tt.valid {
+			t.Errorf("Validate(%s, %s) = %v, expected valid %v", tt.key, tt.syntax, err, tt.valid)
+		}
+	}
+}
+
+func TestDetectSyntax(t *testing.T) {
+	for path, expected := range map[string]Syntax{
+		"/home/u/.gitconfig":         SyntaxGit,
+		"/home/u/work.gitconfig":     SyntaxGit,
+		"/home/u/.config/git/config": SyntaxGit,
+		"/repo/.git/config":          SyntaxGit,
+		"/home/u/.npmrc":             SyntaxINI,
+		"/home/u/.editorconfig":      SyntaxINI,
+	} {
+		if got := DetectSyntax(path); got != expected {
+			t.Errorf("DetectSyntax(%s) = %s, expected %s", path, got, expected)
+		}
+	}
+}
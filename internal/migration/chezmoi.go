@@ -0,0 +1,607 @@
+package migration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	filePermissionsResourceType = "dotfiles_file_permissions"
+	chezmoiTemplateEngine       = "chezmoi"
+)
+
+// chezmoiUnsupportedFunctions are chezmoi template functions without a shim in the
+// provider's chezmoi template engine, with the reason they are missing.
+var chezmoiUnsupportedFunctions = map[string]string{
+	"output":          "runs commands",
+	"include":         "reads files relative to the chezmoi source directory",
+	"includeTemplate": "reads files relative to the chezmoi source directory",
+	"template":        "uses .chezmoitemplates",
+	"promptString":    "prompts interactively",
+	"promptBool":      "prompts interactively",
+	"promptInt":       "prompts interactively",
+	"promptChoice":    "prompts interactively",
+	"bitwarden":       "reads a password manager",
+	"bitwardenFields": "reads a password manager",
+	"gopass":          "reads a password manager",
+	"keepassxc":       "reads a password manager",
+	"lastpass":        "reads a password manager",
+	"onepassword":     "reads a password manager",
+	"onepasswordRead": "reads a password manager",
+	"pass":            "reads a password manager",
+	"passRaw":         "reads a password manager",
+	"secret":          "reads a password manager",
+	"secretJSON":      "reads a password manager",
+	"vault":           "reads a password manager",
+	"keyring":         "reads the system keyring",
+	"decrypt":         "decrypts files",
+	"encrypt":         "encrypts files",
+	"fromYaml":        "parses YAML",
+	"toYaml":          "serialises YAML",
+	"fromToml":        "parses TOML",
+	"toToml":          "serialises TOML",
+}
+
+// chezmoiVariables are the .chezmoi fields set by the provider's chezmoi template engine.
+var chezmoiVariables = map[string]bool{
+	"os":           true,
+	"arch":         true,
+	"hostname":     true,
+	"fqdnHostname": true,
+	"homeDir":      true,
+	"username":     true,
+}
+
+var (
+	templateActionPattern = regexp.MustCompile(`(?s)\{\{(.*?)\}\}`)
+	identifierPattern     = regexp.MustCompile(`(^|[^.$\w])([A-Za-z][A-Za-z0-9]*)\b`)
+	chezmoiFieldPattern   = regexp.MustCompile(`\.chezmoi\.([A-Za-z]+)`)
+	stringLiteralPattern  = regexp.MustCompile(`"(\\.|[^"\\])*"|` + "`[^`]*`")
+)
+
+// ChezmoiOptions configures translation of a chezmoi source directory.
+type ChezmoiOptions struct {
+	// SourceDir is the chezmoi source directory, usually ~/.local/share/chezmoi
+	SourceDir string
+	// RepositoryName names the dotfiles_repository resource; defaults to "dotfiles"
+	RepositoryName string
+	// SourcePath is the source_path of the repository, e.g. a Git URL; defaults to the source directory
+	SourcePath string
+}
+
+// ChezmoiResult is the outcome of translating a chezmoi source directory.
+type ChezmoiResult struct {
+	// Config is the generated Terraform configuration
+	Config    []byte
+	Resources []GeneratedResource
+	// Issues lists source state that could not be translated, keyed by source path
+	Issues []ValidationIssue
+}
+
+// chezmoiEntry is a source state entry decoded from its name.
+type chezmoiEntry struct {
+	// target is the name in the target directory, e.g. .gitconfig for private_dot_gitconfig.tmpl
+	target     string
+	kind       string
+	encrypted  bool
+	private    bool
+	readonly   bool
+	executable bool
+	exact      bool
+	template   bool
+}
+
+// Source state entry kinds.
+const (
+	chezmoiFile     = "file"
+	chezmoiCreate   = "create"
+	chezmoiModify   = "modify"
+	chezmoiRemove   = "remove"
+	chezmoiScript   = "script"
+	chezmoiSymlink  = "symlink"
+	chezmoiDir      = "directory"
+	chezmoiExternal = "external"
+)
+
+// ImportChezmoiDirectory translates a chezmoi source directory and writes the resulting
+// configuration to outputFile.
+func ImportChezmoiDirectory(opts ChezmoiOptions, outputFile string) (*ChezmoiResult, error) {
+	result, err := ImportChezmoi(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(outputFile, result.Config, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return result, nil
+}
+
+// ImportChezmoi translates a chezmoi source directory into provider resources. Files become
+// dotfiles_file resources copied to their target, with private_, readonly_ and executable_
+// mapped to file modes and .tmpl files rendered by the chezmoi template engine. Private and
+// read-only directories get a dotfiles_file_permissions resource. Scripts, encrypted files,
+// symlinks, externals and modify_ or remove_ entries are reported as issues.
+func ImportChezmoi(opts ChezmoiOptions) (*ChezmoiResult, error) {
+	root, err := chezmoiRoot(opts.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SourcePath == "" {
+		opts.SourcePath = opts.SourceDir
+	}
+
+	c := &chezmoiImporter{
+		root: root,
+		generator: &generator{
+			opts: GenerateOptions{
+				RepositoryPath: root,
+				RepositoryName: opts.RepositoryName,
+				SourcePath:     opts.SourcePath,
+			},
+			names: make(map[string]bool),
+			file:  hclwrite.NewEmptyFile(),
+		},
+	}
+	if c.generator.opts.RepositoryName == "" {
+		c.generator.opts.RepositoryName = "dotfiles"
+	}
+	if err := c.readSpecialFiles(); err != nil {
+		return nil, err
+	}
+
+	c.generator.writeRepository()
+	if _, err := c.walk("", ""); err != nil {
+		return nil, err
+	}
+	if len(c.generator.resources) == 0 {
+		return nil, fmt.Errorf("no chezmoi source state found in %s", root)
+	}
+
+	return &ChezmoiResult{
+		Config:    hclwrite.Format(c.generator.file.Bytes()),
+		Resources: c.generator.resources,
+		Issues:    c.issues,
+	}, nil
+}
+
+// chezmoiRoot returns the directory holding the source state, following .chezmoiroot.
+func chezmoiRoot(sourceDir string) (string, error) {
+	info, err := os.Stat(sourceDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chezmoi source directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("chezmoi source directory %s is not a directory", sourceDir)
+	}
+
+	content, err := os.ReadFile(filepath.Join(sourceDir, ".chezmoiroot"))
+	if os.IsNotExist(err) {
+		return sourceDir, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read .chezmoiroot: %w", err)
+	}
+	return filepath.Join(sourceDir, filepath.FromSlash(strings.TrimSpace(string(content)))), nil
+}
+
+// chezmoiImporter accumulates the translation of a source directory.
+type chezmoiImporter struct {
+	root      string
+	generator *generator
+	// data holds the string values of .chezmoidata.json, passed to every template
+	data map[string]string
+	// ignore holds the target patterns of .chezmoiignore
+	ignore []string
+	issues []ValidationIssue
+}
+
+// readSpecialFiles reads .chezmoidata.json and .chezmoiignore and reports the special files
+// that have no equivalent.
+func (c *chezmoiImporter) readSpecialFiles() error {
+	entries, err := os.ReadDir(c.root)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", c.root, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, ".chezmoi") {
+			continue
+		}
+		switch {
+		case name == ".chezmoiroot" || name == ".chezmoiversion":
+		case name == ".chezmoidata.json":
+			if err := c.readData(name); err != nil {
+				return err
+			}
+		case name == ".chezmoiignore":
+			if err := c.readIgnore(name); err != nil {
+				return err
+			}
+		case name == ".chezmoiscripts":
+			c.addIssue(name, 0, "Scripts in .chezmoiscripts are not run",
+				"Run them with a terraform_data resource and a local-exec provisioner")
+		case name == ".chezmoitemplates":
+			c.addIssue(name, 0, "Shared templates in .chezmoitemplates are not supported",
+				"Inline the shared templates into the files that use them")
+		case name == ".chezmoiremove":
+			c.addIssue(name, 0, "Targets listed in .chezmoiremove are not removed",
+				"Remove the listed files by hand")
+		case strings.HasPrefix(name, ".chezmoiexternal"):
+			c.addIssue(name, 0, "External archives and repositories are not fetched",
+				"Manage each external Git repository with its own dotfiles_repository")
+		case strings.HasPrefix(name, ".chezmoidata"):
+			c.addIssue(name, 0, "Template data in "+name+" is not read; only .chezmoidata.json is",
+				"Convert the data to .chezmoidata.json or set template_vars by hand")
+		case strings.HasPrefix(name, ".chezmoi."):
+			c.addIssue(name, 0, "The chezmoi configuration template is not translated; its data and prompts are dropped",
+				"Set the values your templates read from it in template_vars")
+		default:
+			c.addIssue(name, 0, "Unknown chezmoi special file "+name+" was skipped", "")
+		}
+	}
+	return nil
+}
+
+// readData reads the top-level string values of a .chezmoidata.json file.
+func (c *chezmoiImporter) readData(name string) error {
+	content, err := os.ReadFile(filepath.Join(c.root, name))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	c.data = make(map[string]string, len(data))
+	var nested []string
+	for key, value := range data {
+		switch v := value.(type) {
+		case string:
+			c.data[key] = v
+		case bool, float64:
+			c.data[key] = fmt.Sprint(v)
+		default:
+			nested = append(nested, key)
+		}
+	}
+	if len(nested) > 0 {
+		sort.Strings(nested)
+		c.addIssue(name, 0, "Nested template data is not supported: "+strings.Join(nested, ", "),
+			"template_vars only holds strings; flatten these values by hand")
+	}
+	return nil
+}
+
+// readIgnore reads the target patterns of .chezmoiignore. Templated ignore files are
+// reported, as their patterns depend on the machine.
+func (c *chezmoiImporter) readIgnore(name string) error {
+	content, err := os.ReadFile(filepath.Join(c.root, name))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if bytes.Contains(content, []byte("{{")) {
+		c.addIssue(name, 0, "Templated .chezmoiignore entries are not evaluated; every file is translated",
+			"Remove the resources for files that should be ignored on some machines, or guard them with count")
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		pattern, _, _ := strings.Cut(scanner.Text(), "#")
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+		case strings.HasPrefix(pattern, "!"):
+			c.addIssue(name, line, "Negated ignore pattern "+pattern+" is not supported", "")
+		default:
+			c.ignore = append(c.ignore, strings.TrimPrefix(pattern, "/"))
+		}
+	}
+	return scanner.Err()
+}
+
+// walk translates the source state below dir into resources for targets below target,
+// returning the addresses of the resources it wrote.
+func (c *chezmoiImporter) walk(dir, target string) ([]string, error) {
+	children, err := os.ReadDir(filepath.Join(c.root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+
+	var addresses []string
+	for _, child := range children {
+		// chezmoi ignores other files beginning with a dot, such as .git
+		if strings.HasPrefix(child.Name(), ".") {
+			continue
+		}
+		source := path.Join(dir, child.Name())
+		entry := parseChezmoiName(child.Name(), child.IsDir())
+		targetPath := path.Join(target, entry.target)
+		if c.ignored(targetPath) {
+			continue
+		}
+
+		written, err := c.translate(source, targetPath, entry)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, written...)
+	}
+	return addresses, nil
+}
+
+// translate writes the resources for one source state entry.
+func (c *chezmoiImporter) translate(source, target string, entry chezmoiEntry) ([]string, error) {
+	switch entry.kind {
+	case chezmoiScript:
+		c.addIssue(source, 0, "Script "+source+" is not run",
+			"Run it with a terraform_data resource and a local-exec provisioner")
+		return nil, nil
+	case chezmoiExternal:
+		c.addIssue(source, 0, "External directory "+source+" is not fetched",
+			"Manage it with its own dotfiles_repository")
+		return nil, nil
+	case chezmoiModify:
+		c.addIssue(source, 0, "Modify script for ~/"+target+" is not translated",
+			"Manage the whole file with dotfiles_file, or the changed lines with a managed block")
+		return nil, nil
+	case chezmoiRemove:
+		c.addIssue(source, 0, "Removal of ~/"+target+" is not translated", "Remove the file by hand")
+		return nil, nil
+	case chezmoiSymlink:
+		c.addIssue(source, 0, "Symlink ~/"+target+" is not translated: its target is not a repository file",
+			"Move the link target into the repository and manage it with dotfiles_symlink")
+		return nil, nil
+	}
+	if entry.encrypted {
+		c.addIssue(source, 0, "Encrypted file "+source+" is not translated",
+			"Decrypt it into the repository with a secret manager, or render it from template_vars")
+		return nil, nil
+	}
+
+	if entry.kind == chezmoiDir {
+		addresses, err := c.walk(source, target)
+		if err != nil {
+			return nil, err
+		}
+		if entry.exact {
+			c.addIssue(source, 0, "Files in ~/"+target+" that are not in the repository are not removed (exact_)", "")
+		}
+		if mode := chezmoiMode(entry, true); mode != "" {
+			addresses = append(addresses, c.writeDirectoryPermissions(target, mode, addresses))
+		}
+		return addresses, nil
+	}
+
+	if entry.template {
+		if err := c.checkTemplate(source); err != nil {
+			return nil, err
+		}
+	}
+	return []string{c.writeFile(source, target, entry)}, nil
+}
+
+// writeFile writes the dotfiles_file resource for a regular file.
+func (c *chezmoiImporter) writeFile(source, target string, entry chezmoiEntry) string {
+	g := c.generator
+	name := g.uniqueName(fileResourceType, resourceName(target))
+	g.file.Body().AppendNewline()
+	body := g.file.Body().AppendNewBlock("resource", []string{fileResourceType, name}).Body()
+	g.setRepository(body)
+	body.SetAttributeValue("name", cty.StringVal(name))
+	body.SetAttributeValue("source_path", cty.StringVal(source))
+	body.SetAttributeValue("target_path", cty.StringVal("~/"+target))
+	if entry.template {
+		body.SetAttributeValue("is_template", cty.True)
+		body.SetAttributeValue("template_engine", cty.StringVal(chezmoiTemplateEngine))
+		if len(c.data) > 0 {
+			vars := make(map[string]cty.Value, len(c.data))
+			for key, value := range c.data {
+				vars[key] = cty.StringVal(value)
+			}
+			body.SetAttributeValue("template_vars", cty.MapVal(vars))
+		}
+	}
+	if mode := chezmoiMode(entry, false); mode != "" {
+		body.AppendNewBlock("permissions", nil).Body().SetAttributeValue("files", cty.StringVal(mode))
+	}
+	if entry.kind == chezmoiCreate {
+		// create_ files are only written when missing; later edits are left alone
+		lifecycle := body.AppendNewBlock("lifecycle", nil).Body()
+		lifecycle.SetAttributeRaw("ignore_changes", hclwrite.TokensForIdentifier("all"))
+	}
+
+	address := fileResourceType + "." + name
+	g.resources = append(g.resources, GeneratedResource{Address: address, Source: source, Target: "~/" + target})
+	return address
+}
+
+// writeDirectoryPermissions writes a dotfiles_file_permissions resource applying the mode
+// of a private or read-only directory once the files inside it exist.
+func (c *chezmoiImporter) writeDirectoryPermissions(target, mode string, dependencies []string) string {
+	g := c.generator
+	name := g.uniqueName(filePermissionsResourceType, resourceName(target))
+	g.file.Body().AppendNewline()
+	body := g.file.Body().AppendNewBlock("resource", []string{filePermissionsResourceType, name}).Body()
+	body.SetAttributeValue("path", cty.StringVal("~/"+target))
+	body.SetAttributeValue("mode", cty.StringVal(mode))
+	if len(dependencies) > 0 {
+		tokens := make([]hclwrite.Tokens, 0, len(dependencies))
+		for _, dependency := range dependencies {
+			tokens = append(tokens, hclwrite.TokensForTraversal(addressTraversal(dependency)))
+		}
+		body.SetAttributeRaw("depends_on", hclwrite.TokensForTuple(tokens))
+	}
+
+	address := filePermissionsResourceType + "." + name
+	g.resources = append(g.resources, GeneratedResource{Address: address, Target: "~/" + target})
+	return address
+}
+
+// checkTemplate reports the functions and .chezmoi fields of a template that the chezmoi
+// template engine does not provide.
+func (c *chezmoiImporter) checkTemplate(source string) error {
+	content, err := os.ReadFile(filepath.Join(c.root, filepath.FromSlash(source)))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	reported := make(map[string]bool)
+	for _, match := range templateActionPattern.FindAllSubmatchIndex(content, -1) {
+		action := content[match[2]:match[3]]
+		line := bytes.Count(content[:match[0]], []byte("\n")) + 1
+
+		// Drop string literals so quoted words are not mistaken for functions
+		action = stringLiteralPattern.ReplaceAll(action, nil)
+		for _, ident := range identifierPattern.FindAllSubmatch(action, -1) {
+			function := string(ident[2])
+			reason, unsupported := chezmoiUnsupportedFunctions[function]
+			if !unsupported || reported[function] {
+				continue
+			}
+			reported[function] = true
+			c.addIssue(source, line, fmt.Sprintf("Template function %s is not supported: it %s", function, reason),
+				"Replace the call with a value in template_vars")
+		}
+		for _, field := range chezmoiFieldPattern.FindAllSubmatch(action, -1) {
+			name := string(field[1])
+			if chezmoiVariables[name] || reported["."+name] {
+				continue
+			}
+			reported["."+name] = true
+			c.addIssue(source, line, fmt.Sprintf("Template variable .chezmoi.%s is not set by the chezmoi template engine", name),
+				"Replace it with a value in template_vars")
+		}
+	}
+	return nil
+}
+
+// ignored reports whether a target matches a .chezmoiignore pattern.
+func (c *chezmoiImporter) ignored(target string) bool {
+	for _, pattern := range c.ignore {
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(target)); matched && !strings.Contains(pattern, "/") {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/**") && strings.HasPrefix(target, strings.TrimSuffix(pattern, "**")) {
+			return true
+		}
+	}
+	return false
+}
+
+// addIssue records source state that could not be translated.
+func (c *chezmoiImporter) addIssue(source string, line int, description, suggestion string) {
+	c.issues = append(c.issues, ValidationIssue{
+		File:        source,
+		LineNumber:  line,
+		Description: description,
+		Suggestion:  suggestion,
+	})
+}
+
+// parseChezmoiName decodes the attributes chezmoi encodes in a source state name, such as
+// private_executable_dot_local or dot_gitconfig.tmpl.
+func parseChezmoiName(name string, isDir bool) chezmoiEntry {
+	entry := chezmoiEntry{kind: chezmoiFile}
+	if isDir {
+		entry.kind = chezmoiDir
+	}
+
+	consume := func(prefix string) bool {
+		if strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			return true
+		}
+		return false
+	}
+
+	literal := consume("literal_")
+	if !literal {
+		if isDir {
+			switch {
+			case consume("remove_"):
+				entry.kind = chezmoiRemove
+			case consume("external_"):
+				entry.kind = chezmoiExternal
+			}
+			entry.exact = consume("exact_")
+			entry.private = consume("private_")
+			entry.readonly = consume("readonly_")
+		} else {
+			switch {
+			case consume("run_"):
+				entry.kind = chezmoiScript
+			case consume("symlink_"):
+				entry.kind = chezmoiSymlink
+			case consume("create_"):
+				entry.kind = chezmoiCreate
+			case consume("modify_"):
+				entry.kind = chezmoiModify
+			case consume("remove_"):
+				entry.kind = chezmoiRemove
+			}
+			entry.encrypted = consume("encrypted_")
+			entry.private = consume("private_")
+			entry.readonly = consume("readonly_")
+			consume("empty_")
+			entry.executable = consume("executable_")
+		}
+		literal = consume("literal_")
+		if !literal && consume("dot_") {
+			name = "." + name
+		}
+	}
+
+	if !isDir {
+		switch {
+		case strings.HasSuffix(name, ".literal"):
+			name = strings.TrimSuffix(name, ".literal")
+		case strings.HasSuffix(name, ".tmpl"):
+			name = strings.TrimSuffix(name, ".tmpl")
+			entry.template = true
+		}
+		if entry.encrypted {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, ".age"), ".asc")
+		}
+	}
+	entry.target = name
+	return entry
+}
+
+// chezmoiMode returns the mode chezmoi gives a file or directory with the attributes of
+// entry, or an empty string for the default 0644 and 0755.
+func chezmoiMode(entry chezmoiEntry, isDir bool) string {
+	mode := os.FileMode(0644)
+	if isDir || entry.executable {
+		mode = 0755
+	}
+	if entry.private {
+		mode &= 0700
+	}
+	if entry.readonly {
+		mode &^= 0222
+	}
+	if (isDir && mode == 0755) || (!isDir && !entry.executable && mode == 0644) {
+		return ""
+	}
+	return fmt.Sprintf("%04o", mode)
+}
@@ -0,0 +1,214 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeChezmoiSource creates a chezmoi source directory from file contents keyed by
+// slash-separated source path.
+func writeChezmoiSource(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestParseChezmoiName(t *testing.T) {
+	tests := []struct {
+		name     string
+		isDir    bool
+		expected chezmoiEntry
+	}{
+		{"dot_gitconfig", false, chezmoiEntry{target: ".gitconfig", kind: chezmoiFile}},
+		{"private_dot_netrc.tmpl", false, chezmoiEntry{target: ".netrc", kind: chezmoiFile, private: true, template: true}},
+		{"private_readonly_executable_dot_script", false, chezmoiEntry{target: ".script", kind: chezmoiFile, private: true, readonly: true, executable: true}},
+		{"empty_dot_hushlogin", false, chezmoiEntry{target: ".hushlogin", kind: chezmoiFile}},
+		{"create_dot_viminfo", false, chezmoiEntry{target: ".viminfo", kind: chezmoiCreate}},
+		{"run_once_before_install.sh.tmpl", false, chezmoiEntry{target: "once_before_install.sh", kind: chezmoiScript, template: true}},
+		{"symlink_dot_vim", false, chezmoiEntry{target: ".vim", kind: chezmoiSymlink}},
+		{"encrypted_private_dot_token.age", false, chezmoiEntry{target: ".token", kind: chezmoiFile, encrypted: true, private: true}},
+		{"literal_dot_not_a_dotfile", false, chezmoiEntry{target: "dot_not_a_dotfile", kind: chezmoiFile}},
+		{"dot_config.tmpl.literal", false, chezmoiEntry{target: ".config.tmpl", kind: chezmoiFile}},
+		{"exact_private_dot_ssh", true, chezmoiEntry{target: ".ssh", kind: chezmoiDir, exact: true, private: true}},
+		{"external_dot_oh-my-zsh", true, chezmoiEntry{target: ".oh-my-zsh", kind: chezmoiExternal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChezmoiName(tt.name, tt.isDir); got != tt.expected {
+				t.Errorf("parseChezmoiName(%q) = %+v, expected %+v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestChezmoiMode(t *testing.T) {
+	tests := []struct {
+		entry    chezmoiEntry
+		isDir    bool
+		expected string
+	}{
+		{chezmoiEntry{}, false, ""},
+		{chezmoiEntry{private: true}, false, "0600"},
+		{chezmoiEntry{executable: true}, false, "0755"},
+		{chezmoiEntry{private: true, executable: true}, false, "0700"},
+		{chezmoiEntry{readonly: true}, false, "0444"},
+		{chezmoiEntry{}, true, ""},
+		{chezmoiEntry{private: true}, true, "0700"},
+		{chezmoiEntry{readonly: true}, true, "0555"},
+	}
+	for _, tt := range tests {
+		if got := chezmoiMode(tt.entry, tt.isDir); got != tt.expected {
+			t.Errorf("chezmoiMode(%+v, %v) = %q, expected %q", tt.entry, tt.isDir, got, tt.expected)
+		}
+	}
+}
+
+func TestImportChezmoi(t *testing.T) {
+	source := writeChezmoiSource(t, map[string]string{
+		"dot_bashrc":                         "export EDITOR=vim\n",
+		"private_dot_ssh/config":             "Host *\n",
+		"private_dot_ssh/private_id_ed25519": "key\n",
+		"dot_gitconfig.tmpl":                 "[user]\n  email = {{ .email }}\n  name = {{ output \"whoami\" }}\n  dir = {{ .chezmoi.sourceDir }}\n",
+		"dot_local/bin/executable_hello":     "#!/bin/sh\n",
+		"create_dot_viminfo":                 "",
+		"run_once_install.sh":                "#!/bin/sh\n",
+		"symlink_dot_vim":                    ".config/vim\n",
+		"encrypted_dot_token.age":            "secret\n",
+		"README.md":                          "# dotfiles\n",
+		".chezmoiignore":                     "README.md\n",
+		".chezmoidata.json":                  `{"email": "me@example.com", "work": true, "hosts": {"a": 1}}`,
+		".chezmoiexternal.toml":              "",
+		".git/HEAD":                          "ref: refs/heads/main\n",
+	})
+
+	result, err := ImportChezmoi(ChezmoiOptions{SourceDir: source, SourcePath: "https://github.com/me/dotfiles.git"})
+	if err != nil {
+		t.Fatalf("ImportChezmoi failed: %v", err)
+	}
+	blocks, _ := generatedBlocks(t, result.Config)
+	config := string(result.Config)
+
+	bashrc := blocks["dotfiles_file.bashrc"]
+	if bashrc == nil || stringAttr(t, bashrc, "source_path") != "dot_bashrc" || stringAttr(t, bashrc, "target_path") != "~/.bashrc" {
+		t.Errorf("Expected dot_bashrc to be copied to ~/.bashrc:\n%s", config)
+	}
+	if blocks["dotfiles_file.readme_md"] != nil {
+		t.Error("Expected README.md to be ignored through .chezmoiignore")
+	}
+
+	key := blocks["dotfiles_file.ssh_id_ed25519"]
+	if key == nil || stringAttr(t, key, "target_path") != "~/.ssh/id_ed25519" {
+		t.Fatalf("Expected the private key to be translated:\n%s", config)
+	}
+	if len(key.Body.Blocks) != 1 || stringAttr(t, key.Body.Blocks[0], "files") != "0600" {
+		t.Errorf("Expected private_ to map to 0600 permissions:\n%s", config)
+	}
+	hello := blocks["dotfiles_file.local_bin_hello"]
+	if hello == nil || len(hello.Body.Blocks) != 1 || stringAttr(t, hello.Body.Blocks[0], "files") != "0755" {
+		t.Errorf("Expected executable_ to map to 0755 permissions:\n%s", config)
+	}
+
+	ssh := blocks["dotfiles_file_permissions.ssh"]
+	if ssh == nil || stringAttr(t, ssh, "path") != "~/.ssh" || stringAttr(t, ssh, "mode") != "0700" {
+		t.Fatalf("Expected the private directory to get 0700 permissions:\n%s", config)
+	}
+	if !strings.Contains(config, "depends_on = [dotfiles_file.ssh_config, dotfiles_file.ssh_id_ed25519]") {
+		t.Errorf("Expected the directory permissions to follow its files:\n%s", config)
+	}
+
+	gitconfig := blocks["dotfiles_file.gitconfig"]
+	if gitconfig == nil || stringAttr(t, gitconfig, "template_engine") != "chezmoi" {
+		t.Fatalf("Expected the template to use the chezmoi engine:\n%s", config)
+	}
+	if _, ok := gitconfig.Body.Attributes["is_template"]; !ok {
+		t.Error("Expected is_template on the template")
+	}
+	for _, want := range []string{`email = "me@example.com"`, `work  = "true"`} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected template_vars from .chezmoidata.json to contain %s:\n%s", want, config)
+		}
+	}
+
+	viminfo := blocks["dotfiles_file.viminfo"]
+	if viminfo == nil || !strings.Contains(config, "ignore_changes = all") {
+		t.Errorf("Expected create_ to ignore later changes:\n%s", config)
+	}
+
+	expectedIssues := map[string]string{
+		"run_once_install.sh":     "is not run",
+		"symlink_dot_vim":         "Symlink ~/.vim",
+		"encrypted_dot_token.age": "Encrypted file",
+		".chezmoiexternal.toml":   "not fetched",
+		".chezmoidata.json":       "hosts",
+	}
+	for file, description := range expectedIssues {
+		found := false
+		for _, issue := range result.Issues {
+			if issue.File == file && strings.Contains(issue.Description, description) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected an issue for %s mentioning %q, got %+v", file, description, result.Issues)
+		}
+	}
+
+	templateIssues := make(map[int]string)
+	for _, issue := range result.Issues {
+		if issue.File == "dot_gitconfig.tmpl" {
+			templateIssues[issue.LineNumber] = issue.Description
+		}
+	}
+	if !strings.Contains(templateIssues[3], "output") || !strings.Contains(templateIssues[4], ".chezmoi.sourceDir") || len(templateIssues) != 2 {
+		t.Errorf("Expected issues for output on line 3 and .chezmoi.sourceDir on line 4, got %v", templateIssues)
+	}
+}
+
+func TestImportChezmoiRoot(t *testing.T) {
+	source := writeChezmoiSource(t, map[string]string{
+		".chezmoiroot":        "home\n",
+		"home/dot_zshrc":      "setopt autocd\n",
+		"install.sh":          "#!/bin/sh\n",
+		"home/.chezmoiignore": "{{ if ne .chezmoi.os \"darwin\" }}.config/karabiner{{ end }}\n",
+	})
+
+	result, err := ImportChezmoi(ChezmoiOptions{SourceDir: source})
+	if err != nil {
+		t.Fatalf("ImportChezmoi failed: %v", err)
+	}
+	blocks, _ := generatedBlocks(t, result.Config)
+	if stringAttr(t, blocks["provider"], "dotfiles_root") != filepath.Join(source, "home") {
+		t.Errorf("Expected dotfiles_root to follow .chezmoiroot:\n%s", result.Config)
+	}
+	if stringAttr(t, blocks["dotfiles_repository.dotfiles"], "source_path") != source {
+		t.Errorf("Expected the repository to be the source directory:\n%s", result.Config)
+	}
+	if len(result.Resources) != 1 || result.Resources[0].Address != "dotfiles_file.zshrc" {
+		t.Errorf("Expected only dot_zshrc below the root, got %+v", result.Resources)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].File != ".chezmoiignore" {
+		t.Errorf("Expected the templated .chezmoiignore to be reported, got %+v", result.Issues)
+	}
+
+	output := filepath.Join(t.TempDir(), "dotfiles.tf")
+	if _, err := ImportChezmoiDirectory(ChezmoiOptions{SourceDir: source}, output); err != nil {
+		t.Fatalf("ImportChezmoiDirectory failed: %v", err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("Expected the configuration to be written: %v", err)
+	}
+	if _, err := ImportChezmoi(ChezmoiOptions{SourceDir: writeChezmoiSource(t, map[string]string{".chezmoiversion": "2.0.0"})}); err == nil {
+		t.Error("Expected an error for a source directory without source state")
+	}
+}
@@ -0,0 +1,560 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+)
+
+// Layout describes how a dotfiles repository maps its files into the home directory.
+type Layout string
+
+const (
+	// LayoutAuto detects the layout from the top level of the repository.
+	LayoutAuto Layout = "auto"
+	// LayoutPlain stores files without their leading dot: vimrc → ~/.vimrc.
+	LayoutPlain Layout = "plain"
+	// LayoutDotted mirrors the home directory: .vimrc → ~/.vimrc.
+	LayoutDotted Layout = "dotted"
+	// LayoutStow keeps one GNU Stow package per application: vim/.vimrc → ~/.vimrc.
+	LayoutStow Layout = "stow"
+)
+
+const (
+	repositoryResourceType  = "dotfiles_repository"
+	directoryResourceType   = "dotfiles_directory"
+	applicationResourceType = "dotfiles_application"
+)
+
+// templateEngines maps template file extensions to the engine that renders them.
+var templateEngines = map[string]string{
+	".tmpl":       "go",
+	".tpl":        "go",
+	".gotmpl":     "go",
+	".hbs":        "handlebars",
+	".handlebars": "handlebars",
+	".mustache":   "mustache",
+}
+
+// ignoredNames are repository files that are not dotfiles.
+var ignoredNames = map[string]bool{
+	".git":               true,
+	".github":            true,
+	".gitignore":         true,
+	".gitmodules":        true,
+	".gitattributes":     true,
+	".stow-local-ignore": true,
+	".terraform":         true,
+	".DS_Store":          true,
+	"Makefile":           true,
+	"LICENSE":            true,
+	"README":             true,
+}
+
+// GenerateOptions configures Terraform generation from a dotfiles repository.
+type GenerateOptions struct {
+	// RepositoryPath is the local checkout to scan
+	RepositoryPath string
+	// RepositoryName names the dotfiles_repository resource; defaults to "dotfiles"
+	RepositoryName string
+	// SourcePath is the source_path of the repository, e.g. a Git URL; defaults to RepositoryPath
+	SourcePath string
+	// Layout selects how files map to targets; defaults to LayoutAuto
+	Layout Layout
+	// HomeDir is checked for targets already in place; defaults to the user's home directory
+	HomeDir string
+}
+
+// GeneratedResource describes a resource written by Generate.
+type GeneratedResource struct {
+	Address string
+	Source  string
+	Target  string
+	// Imported is set when an import block was written because the target is already in place
+	Imported bool
+}
+
+// GenerateResult is the outcome of generating configuration for a repository.
+type GenerateResult struct {
+	// Layout is the layout the repository was read with
+	Layout Layout
+	// Config is the generated Terraform configuration
+	Config    []byte
+	Resources []GeneratedResource
+}
+
+// dotfileEntry is a file or directory of the repository deployed to one target.
+type dotfileEntry struct {
+	// source is the slash-separated path relative to the repository root
+	source string
+	// target is the slash-separated path relative to the home directory
+	target      string
+	application string
+	isDir       bool
+	// engine is the template engine of a template file, or of the templates in a directory
+	engine string
+	// templateExt is the template extension of a file, or the one used inside a directory
+	templateExt string
+}
+
+// GenerateFromRepository scans a dotfiles repository and writes Terraform configuration
+// managing its files to outputFile.
+func GenerateFromRepository(opts GenerateOptions, outputFile string) (*GenerateResult, error) {
+	result, err := Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(outputFile, result.Config, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return result, nil
+}
+
+// Generate scans a dotfiles repository and builds configuration for it: a
+// dotfiles_repository, dotfiles_symlink resources for plain files, dotfiles_file resources
+// for templates, dotfiles_directory resources for directories and a dotfiles_application
+// for each application with several files. Targets already in place get an import block.
+func Generate(opts GenerateOptions) (*GenerateResult, error) {
+	info, err := os.Stat(opts.RepositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("repository %s is not a directory", opts.RepositoryPath)
+	}
+	if opts.RepositoryName == "" {
+		opts.RepositoryName = "dotfiles"
+	}
+	if opts.SourcePath == "" {
+		opts.SourcePath = opts.RepositoryPath
+	}
+	if opts.HomeDir == "" {
+		if opts.HomeDir, err = os.UserHomeDir(); err != nil {
+			return nil, fmt.Errorf("failed to determine home directory: %w", err)
+		}
+	}
+
+	layout := opts.Layout
+	switch layout {
+	case "", LayoutAuto:
+		if layout, err = detectLayout(opts.RepositoryPath); err != nil {
+			return nil, err
+		}
+	case LayoutPlain, LayoutDotted, LayoutStow:
+	default:
+		return nil, fmt.Errorf("unknown layout %q: expected auto, plain, dotted or stow", layout)
+	}
+
+	entries, err := scanRepository(opts.RepositoryPath, layout)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no dotfiles found in %s", opts.RepositoryPath)
+	}
+
+	g := &generator{opts: opts, names: make(map[string]bool), file: hclwrite.NewEmptyFile()}
+	g.writeRepository()
+	for _, group := range groupEntries(entries) {
+		if len(group) > 1 {
+			g.writeApplication(group)
+			continue
+		}
+		g.writeEntry(group[0])
+	}
+	g.writeImports()
+
+	return &GenerateResult{Layout: layout, Config: hclwrite.Format(g.file.Bytes()), Resources: g.resources}, nil
+}
+
+// detectLayout picks the layout of a repository from its top-level entries: dot-prefixed
+// files mean the repository mirrors the home directory, and directories holding
+// dot-prefixed files are Stow packages.
+func detectLayout(root string) (Layout, error) {
+	entries, err := readEntries(root)
+	if err != nil {
+		return "", err
+	}
+
+	packages := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			return LayoutDotted, nil
+		}
+		if !entry.IsDir() {
+			return LayoutPlain, nil
+		}
+		children, err := readEntries(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		for _, child := range children {
+			if strings.HasPrefix(child.Name(), ".") {
+				packages++
+				break
+			}
+		}
+	}
+	if packages > 0 && packages*2 >= len(entries) {
+		return LayoutStow, nil
+	}
+	return LayoutPlain, nil
+}
+
+// scanRepository lists the entries of a repository in a stable order.
+func scanRepository(root string, layout Layout) ([]dotfileEntry, error) {
+	if layout != LayoutStow {
+		return scanTree(root, "", "", layout)
+	}
+
+	packages, err := readEntries(root)
+	if err != nil {
+		return nil, err
+	}
+	var entries []dotfileEntry
+	for _, pkg := range packages {
+		if !pkg.IsDir() {
+			continue
+		}
+		pkgEntries, err := scanTree(root, pkg.Name(), pkg.Name(), LayoutDotted)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, pkgEntries...)
+	}
+	return entries, nil
+}
+
+// scanTree lists the entries below dir, a slash-separated path relative to root. Each
+// top-level file or directory becomes one entry, except that the applications inside
+// .config are listed separately. Entries are grouped under application, or under a name
+// derived from their target when it is empty.
+func scanTree(root, dir, application string, layout Layout) ([]dotfileEntry, error) {
+	children, err := readEntries(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []dotfileEntry
+	for _, child := range children {
+		target := child.Name()
+		if layout == LayoutPlain && !strings.HasPrefix(target, ".") {
+			target = "." + target
+		}
+		source := path.Join(dir, child.Name())
+
+		if target == ".config" && child.IsDir() {
+			apps, err := readEntries(filepath.Join(root, filepath.FromSlash(source)))
+			if err != nil {
+				return nil, err
+			}
+			for _, app := range apps {
+				entry, err := newEntry(root, path.Join(source, app.Name()), path.Join(target, app.Name()), app.IsDir())
+				if err != nil {
+					return nil, err
+				}
+				entry.application = application
+				if entry.application == "" {
+					entry.application = applicationName(app.Name())
+				}
+				entries = append(entries, entry)
+			}
+			continue
+		}
+
+		entry, err := newEntry(root, source, target, child.IsDir())
+		if err != nil {
+			return nil, err
+		}
+		entry.application = application
+		if entry.application == "" {
+			entry.application = applicationName(target)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// newEntry describes a repository file or directory, detecting templates by extension.
+func newEntry(root, source, target string, isDir bool) (dotfileEntry, error) {
+	entry := dotfileEntry{source: source, target: target, isDir: isDir}
+	if !isDir {
+		ext := path.Ext(source)
+		if engine, ok := templateEngines[ext]; ok {
+			entry.engine = engine
+			entry.templateExt = ext
+			entry.target = strings.TrimSuffix(target, ext)
+		}
+		return entry, nil
+	}
+
+	ext, err := directoryTemplateExt(filepath.Join(root, filepath.FromSlash(source)))
+	if err != nil {
+		return entry, err
+	}
+	if ext != "" {
+		entry.engine = templateEngines[ext]
+		entry.templateExt = ext
+	}
+	return entry, nil
+}
+
+// directoryTemplateExt returns the most common template extension in a directory tree,
+// or an empty string when it holds no templates.
+func directoryTemplateExt(dir string) (string, error) {
+	counts := make(map[string]int)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && ignoredNames[d.Name()] {
+			return filepath.SkipDir
+		}
+		if _, ok := templateEngines[filepath.Ext(p)]; ok && !d.IsDir() {
+			counts[filepath.Ext(p)]++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	best := ""
+	for ext, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && ext < best) {
+			best = ext
+		}
+	}
+	return best, nil
+}
+
+// groupEntries groups the entries of each application in first-seen order. Applications
+// with several plain files are managed together; templates and directories keep a
+// resource of their own.
+func groupEntries(entries []dotfileEntry) [][]dotfileEntry {
+	files := make(map[string][]dotfileEntry)
+	for _, entry := range entries {
+		if !entry.isDir && entry.engine == "" {
+			files[entry.application] = append(files[entry.application], entry)
+		}
+	}
+
+	var groups [][]dotfileEntry
+	emitted := make(map[string]bool)
+	for _, entry := range entries {
+		group := files[entry.application]
+		if entry.isDir || entry.engine != "" || len(group) < 2 {
+			groups = append(groups, []dotfileEntry{entry})
+			continue
+		}
+		if !emitted[entry.application] {
+			emitted[entry.application] = true
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// generator accumulates the generated configuration.
+type generator struct {
+	opts      GenerateOptions
+	file      *hclwrite.File
+	names     map[string]bool
+	resources []GeneratedResource
+}
+
+// writeRepository writes the provider block, which resolves source paths against the
+// scanned checkout, and the dotfiles_repository every other resource refers to.
+func (g *generator) writeRepository() {
+	provider := g.file.Body().AppendNewBlock("provider", []string{"dotfiles"}).Body()
+	provider.SetAttributeValue("dotfiles_root", cty.StringVal(g.opts.RepositoryPath))
+
+	g.file.Body().AppendNewline()
+	body := g.file.Body().AppendNewBlock("resource", []string{repositoryResourceType, g.opts.RepositoryName}).Body()
+	body.SetAttributeValue("name", cty.StringVal(g.opts.RepositoryName))
+	body.SetAttributeValue("source_path", cty.StringVal(g.opts.SourcePath))
+	g.names[repositoryResourceType+"."+g.opts.RepositoryName] = true
+}
+
+// writeEntry writes the resource managing a single entry.
+func (g *generator) writeEntry(entry dotfileEntry) {
+	resourceType := symlinkResourceType
+	switch {
+	case entry.isDir:
+		resourceType = directoryResourceType
+	case entry.engine != "":
+		resourceType = fileResourceType
+	}
+
+	name := g.uniqueName(resourceType, resourceName(entry.target))
+	g.file.Body().AppendNewline()
+	body := g.file.Body().AppendNewBlock("resource", []string{resourceType, name}).Body()
+	g.setRepository(body)
+	body.SetAttributeValue("name", cty.StringVal(name))
+	body.SetAttributeValue("source_path", cty.StringVal(entry.source))
+	body.SetAttributeValue("target_path", cty.StringVal("~/"+entry.target))
+
+	switch {
+	case resourceType == fileResourceType:
+		body.SetAttributeValue("is_template", cty.True)
+		if entry.engine != "go" {
+			body.SetAttributeValue("template_engine", cty.StringVal(entry.engine))
+		}
+	case resourceType == directoryResourceType && entry.engine != "":
+		body.SetAttributeValue("template_pattern", cty.StringVal("*"+entry.templateExt))
+		if entry.engine != "go" {
+			body.SetAttributeValue("template_engine", cty.StringVal(entry.engine))
+		}
+	case resourceType == symlinkResourceType:
+		body.SetAttributeValue("create_parents", cty.True)
+	}
+
+	g.resources = append(g.resources, GeneratedResource{
+		Address:  resourceType + "." + name,
+		Source:   entry.source,
+		Target:   "~/" + entry.target,
+		Imported: g.inPlace(resourceType, entry),
+	})
+}
+
+// writeApplication writes a dotfiles_application linking the files of one application.
+// Applications are not importable, so existing targets are replaced on apply.
+func (g *generator) writeApplication(entries []dotfileEntry) {
+	application := entries[0].application
+	name := g.uniqueName(applicationResourceType, resourceName(application))
+	g.file.Body().AppendNewline()
+	body := g.file.Body().AppendNewBlock("resource", []string{applicationResourceType, name}).Body()
+	body.SetAttributeValue("application_name", cty.StringVal(application))
+
+	mappings := make(map[string]cty.Value, len(entries))
+	for _, entry := range entries {
+		mappings[entry.source] = cty.ObjectVal(map[string]cty.Value{
+			"target_path": cty.StringVal("~/" + entry.target),
+		})
+		g.resources = append(g.resources, GeneratedResource{
+			Address: applicationResourceType + "." + name,
+			Source:  entry.source,
+			Target:  "~/" + entry.target,
+		})
+	}
+	body.SetAttributeValue("config_mappings", cty.MapVal(mappings))
+	body.SetAttributeRaw("depends_on", hclwrite.TokensForTuple([]hclwrite.Tokens{
+		hclwrite.TokensForTraversal(addressTraversal(repositoryResourceType + "." + g.opts.RepositoryName)),
+	}))
+}
+
+// writeImports writes an import block for each resource whose target is already in place.
+func (g *generator) writeImports() {
+	for _, res := range g.resources {
+		if !res.Imported {
+			continue
+		}
+		g.file.Body().AppendNewline()
+		body := g.file.Body().AppendNewBlock("import", nil).Body()
+		body.SetAttributeTraversal("to", addressTraversal(res.Address))
+		body.SetAttributeValue("id", cty.StringVal(res.Target))
+	}
+}
+
+// setRepository points a resource at the generated repository.
+func (g *generator) setRepository(body *hclwrite.Body) {
+	body.SetAttributeTraversal("repository", addressTraversal(repositoryResourceType+"."+g.opts.RepositoryName+".id"))
+}
+
+// inPlace reports whether the target of an entry already holds what the resource would
+// put there: a link to the source, a file or a directory.
+func (g *generator) inPlace(resourceType string, entry dotfileEntry) bool {
+	target := filepath.Join(g.opts.HomeDir, filepath.FromSlash(entry.target))
+	info, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+
+	switch resourceType {
+	case symlinkResourceType:
+		if info.Mode()&os.ModeSymlink == 0 {
+			return false
+		}
+		linkTarget, err := os.Readlink(target)
+		if err != nil {
+			return false
+		}
+		source := filepath.Join(g.opts.RepositoryPath, filepath.FromSlash(entry.source))
+		return fileops.SymlinkPointsTo(target, linkTarget, source)
+	case directoryResourceType:
+		return info.IsDir()
+	default:
+		return info.Mode().IsRegular()
+	}
+}
+
+// uniqueName returns name, suffixed with a number when the address is already taken.
+func (g *generator) uniqueName(resourceType, name string) string {
+	candidate := name
+	for i := 2; g.names[resourceType+"."+candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	g.names[resourceType+"."+candidate] = true
+	return candidate
+}
+
+// applicationName derives an application name from a target such as .vimrc or .config/nvim.
+func applicationName(target string) string {
+	name := strings.TrimPrefix(path.Base(target), ".")
+	if ext := path.Ext(name); ext != "" && ext != name {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// resourceName converts a target path to a Terraform identifier, e.g. .config/nvim →
+// config_nvim.
+func resourceName(target string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(target) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "dotfile_" + name
+	}
+	return strings.TrimSuffix(name, "_")
+}
+
+// readEntries lists a directory without repository metadata, sorted by name.
+func readEntries(dir string) ([]os.DirEntry, error) {
+	all, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	entries := make([]os.DirEntry, 0, len(all))
+	for _, entry := range all {
+		name := entry.Name()
+		if ignoredNames[name] || strings.HasPrefix(strings.ToUpper(name), "README") ||
+			strings.HasPrefix(strings.ToUpper(name), "LICENSE") || filepath.Ext(name) == ".tf" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
@@ -0,0 +1,259 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// writeRepository creates the given files, keyed by slash-separated path, below a temporary
+// repository directory.
+func writeRepository(t *testing.T, files ...string) string {
+	t.Helper()
+	repo := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return repo
+}
+
+// generatedBlocks parses generated configuration and returns its resources by address,
+// other blocks by type and the import blocks.
+func generatedBlocks(t *testing.T, config []byte) (map[string]*hclsyntax.Block, []*hclsyntax.Block) {
+	t.Helper()
+	file, diags := hclsyntax.ParseConfig(config, "generated.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("Generated configuration does not parse: %s\n%s", diags.Error(), config)
+	}
+
+	blocks := make(map[string]*hclsyntax.Block)
+	var imports []*hclsyntax.Block
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		switch block.Type {
+		case "resource":
+			blocks[block.Labels[0]+"."+block.Labels[1]] = block
+		case "import":
+			imports = append(imports, block)
+		default:
+			blocks[block.Type] = block
+		}
+	}
+	return blocks, imports
+}
+
+// stringAttr returns the literal value of a block attribute.
+func stringAttr(t *testing.T, block *hclsyntax.Block, name string) string {
+	t.Helper()
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		t.Fatalf("Attribute %s is not a literal: %s", name, diags.Error())
+	}
+	return value.AsString()
+}
+
+func TestDetectLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected Layout
+	}{
+		{"plain", []string{"vimrc", "gitconfig", "config/nvim/init.lua"}, LayoutPlain},
+		{"dotted", []string{".vimrc", ".config/nvim/init.lua", "README.md"}, LayoutDotted},
+		{"stow", []string{"vim/.vimrc", "git/.gitconfig", "README.md"}, LayoutStow},
+		{"directories without dotfiles", []string{"vim/vimrc", "git/gitconfig"}, LayoutPlain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := detectLayout(writeRepository(t, tt.files...))
+			if err != nil {
+				t.Fatalf("detectLayout failed: %v", err)
+			}
+			if layout != tt.expected {
+				t.Errorf("Expected layout %s, got %s", tt.expected, layout)
+			}
+		})
+	}
+}
+
+func TestGeneratePlainLayout(t *testing.T) {
+	repo := writeRepository(t, "vimrc", "gitconfig.tmpl", "config/nvim/init.lua", "config/starship.toml", "LICENSE", "main.tf")
+	result, err := Generate(GenerateOptions{RepositoryPath: repo, HomeDir: t.TempDir(), SourcePath: "https://github.com/me/dotfiles.git"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Layout != LayoutPlain {
+		t.Errorf("Expected plain layout, got %s", result.Layout)
+	}
+	blocks, _ := generatedBlocks(t, result.Config)
+
+	if got := stringAttr(t, blocks["provider"], "dotfiles_root"); got != repo {
+		t.Errorf("Expected dotfiles_root %s, got %s", repo, got)
+	}
+	if got := stringAttr(t, blocks["dotfiles_repository.dotfiles"], "source_path"); got != "https://github.com/me/dotfiles.git" {
+		t.Errorf("Expected the repository source_path option, got %s", got)
+	}
+
+	vimrc := blocks["dotfiles_symlink.vimrc"]
+	if vimrc == nil || stringAttr(t, vimrc, "source_path") != "vimrc" || stringAttr(t, vimrc, "target_path") != "~/.vimrc" {
+		t.Errorf("Expected vimrc to be linked to ~/.vimrc:\n%s", result.Config)
+	}
+	gitconfig := blocks["dotfiles_file.gitconfig"]
+	if gitconfig == nil || stringAttr(t, gitconfig, "target_path") != "~/.gitconfig" {
+		t.Fatalf("Expected gitconfig.tmpl to be a template rendered to ~/.gitconfig:\n%s", result.Config)
+	}
+	if _, ok := gitconfig.Body.Attributes["is_template"]; !ok {
+		t.Error("Expected is_template on the template file")
+	}
+	nvim := blocks["dotfiles_directory.config_nvim"]
+	if nvim == nil || stringAttr(t, nvim, "source_path") != "config/nvim" || stringAttr(t, nvim, "target_path") != "~/.config/nvim" {
+		t.Errorf("Expected config/nvim to be a directory at ~/.config/nvim:\n%s", result.Config)
+	}
+	if blocks["dotfiles_symlink.config_starship_toml"] == nil {
+		t.Errorf("Expected config/starship.toml to be linked:\n%s", result.Config)
+	}
+	if strings.Contains(string(result.Config), "LICENSE") || strings.Contains(string(result.Config), "main.tf") {
+		t.Errorf("Repository metadata should not be managed:\n%s", result.Config)
+	}
+}
+
+func TestGenerateStowLayout(t *testing.T) {
+	repo := writeRepository(t,
+		"zsh/.zshrc", "zsh/.zshenv",
+		"git/.gitconfig.hbs",
+		"nvim/.config/nvim/init.lua", "nvim/.config/nvim/lua/plugins.lua.tmpl",
+	)
+	result, err := Generate(GenerateOptions{RepositoryPath: repo, HomeDir: t.TempDir(), RepositoryName: "personal"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Layout != LayoutStow {
+		t.Errorf("Expected stow layout, got %s", result.Layout)
+	}
+	blocks, _ := generatedBlocks(t, result.Config)
+
+	zsh := blocks["dotfiles_application.zsh"]
+	if zsh == nil {
+		t.Fatalf("Expected the zsh package to be grouped into an application:\n%s", result.Config)
+	}
+	if stringAttr(t, zsh, "application_name") != "zsh" {
+		t.Error("Expected application_name to be the package name")
+	}
+	for _, want := range []string{`"zsh/.zshrc"`, `target_path = "~/.zshenv"`, "depends_on = [dotfiles_repository.personal]"} {
+		if !strings.Contains(string(result.Config), want) {
+			t.Errorf("Expected %s in the application:\n%s", want, result.Config)
+		}
+	}
+
+	gitconfig := blocks["dotfiles_file.gitconfig"]
+	if gitconfig == nil || stringAttr(t, gitconfig, "template_engine") != "handlebars" || stringAttr(t, gitconfig, "target_path") != "~/.gitconfig" {
+		t.Errorf("Expected a handlebars template for ~/.gitconfig:\n%s", result.Config)
+	}
+	nvim := blocks["dotfiles_directory.config_nvim"]
+	if nvim == nil || stringAttr(t, nvim, "template_pattern") != "*.tmpl" || stringAttr(t, nvim, "source_path") != "nvim/.config/nvim" {
+		t.Errorf("Expected a templated directory for ~/.config/nvim:\n%s", result.Config)
+	}
+}
+
+func TestGenerateImportsTargetsInPlace(t *testing.T) {
+	repo := writeRepository(t, ".vimrc", ".bashrc", ".profile.tmpl", ".config/nvim/init.lua")
+	home := t.TempDir()
+	if err := os.Symlink(filepath.Join(repo, ".vimrc"), filepath.Join(home, ".vimrc")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	// A regular file where a link is expected is not in place
+	if err := os.WriteFile(filepath.Join(home, ".bashrc"), []byte("local"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".profile"), []byte("rendered"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".config", "nvim"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	result, err := Generate(GenerateOptions{RepositoryPath: repo, HomeDir: home})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	imported := make(map[string]string)
+	_, imports := generatedBlocks(t, result.Config)
+	for _, block := range imports {
+		traversal, diags := hcl.AbsTraversalForExpr(block.Body.Attributes["to"].Expr)
+		if diags.HasErrors() {
+			t.Fatalf("import target is not an address: %s", diags.Error())
+		}
+		imported[traversal.RootName()+"."+traversal[1].(hcl.TraverseAttr).Name] = stringAttr(t, block, "id")
+	}
+
+	expected := map[string]string{
+		"dotfiles_symlink.vimrc":         "~/.vimrc",
+		"dotfiles_file.profile":          "~/.profile",
+		"dotfiles_directory.config_nvim": "~/.config/nvim",
+	}
+	if len(imported) != len(expected) {
+		t.Errorf("Expected %d import blocks, got %v", len(expected), imported)
+	}
+	for address, id := range expected {
+		if imported[address] != id {
+			t.Errorf("Expected import of %s with id %s, got %q", address, id, imported[address])
+		}
+	}
+	for _, resource := range result.Resources {
+		if resource.Imported != (expected[resource.Address] != "") {
+			t.Errorf("Unexpected Imported=%v for %s", resource.Imported, resource.Address)
+		}
+	}
+}
+
+func TestGenerateFromRepository(t *testing.T) {
+	repo := writeRepository(t, ".vimrc")
+	output := filepath.Join(t.TempDir(), "dotfiles.tf")
+	if _, err := GenerateFromRepository(GenerateOptions{RepositoryPath: repo, HomeDir: t.TempDir()}, output); err != nil {
+		t.Fatalf("GenerateFromRepository failed: %v", err)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if !strings.Contains(string(content), `resource "dotfiles_symlink" "vimrc"`) {
+		t.Errorf("Expected the generated configuration to be written:\n%s", content)
+	}
+
+	if _, err := Generate(GenerateOptions{RepositoryPath: repo, Layout: "flat"}); err == nil {
+		t.Error("Expected an error for an unknown layout")
+	}
+	if _, err := Generate(GenerateOptions{RepositoryPath: writeRepository(t, "README.md")}); err == nil {
+		t.Error("Expected an error for a repository without dotfiles")
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	tests := map[string]string{
+		".vimrc":             "vimrc",
+		".config/nvim":       "config_nvim",
+		".ssh/config":        "ssh_config",
+		".gitignore_global":  "gitignore_global",
+		".1password":         "dotfile_1password",
+		".config/Code/User/": "config_code_user",
+	}
+	for target, expected := range tests {
+		if got := resourceName(target); got != expected {
+			t.Errorf("resourceName(%q) = %q, expected %q", target, got, expected)
+		}
+	}
+}
@@ -1,295 +1,483 @@
 package migration
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	fileResourceType    = "dotfiles_file"
+	symlinkResourceType = "dotfiles_symlink"
 )
 
+// symlinkArguments are the dotfiles_file arguments and nested blocks that dotfiles_symlink
+// also accepts, including Terraform meta-arguments. Others are dropped when converting.
+var symlinkArguments = map[string]bool{
+	"repository":       true,
+	"name":             true,
+	"source_path":      true,
+	"target_path":      true,
+	"permissions":      true,
+	"permission_rules": true,
+	"count":            true,
+	"for_each":         true,
+	"depends_on":       true,
+	"provider":         true,
+	"lifecycle":        true,
+}
+
 // ValidationIssue represents a compatibility issue found in a configuration.
 type ValidationIssue struct {
+	File        string
 	LineNumber  int
 	Description string
 	Suggestion  string
 }
 
+// MovedResource records a resource whose type changed, so state can follow it.
+type MovedResource struct {
+	// File is the configuration file declaring the resource
+	File string
+	From string
+	To   string
+}
+
+// Result is the outcome of migrating a module.
+type Result struct {
+	// Files holds the migrated content of every input file, keyed by file name
+	Files map[string][]byte
+	// Moved lists converted resources; a moved block is emitted for each
+	Moved []MovedResource
+	// Issues lists resources that could not be migrated automatically
+	Issues []ValidationIssue
+}
+
+// blockEdit collects the comments to add above a migrated resource.
+type blockEdit struct {
+	resourceType string
+	name         string
+	notes        []string
+}
+
 // MigrateConfigFile migrates a Terraform configuration file from the old format to the new format.
-func MigrateConfigFile(inputFile, outputFile string) error {
-	input, err := os.Open(inputFile)
+func MigrateConfigFile(inputFile, outputFile string) (*Result, error) {
+	src, err := os.ReadFile(inputFile)
 	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
+		return nil, fmt.Errorf("failed to read input file: %w", err)
 	}
-	defer func() {
-		if cerr := input.Close(); cerr != nil && err == nil {
-			err = cerr
-		}
-	}()
 
-	output, err := os.Create(outputFile)
+	name := filepath.Base(inputFile)
+	result, err := MigrateModule(map[string][]byte{name: src})
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return nil, err
+	}
+	if err := os.WriteFile(outputFile, result.Files[name], 0644); err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	return result, nil
+}
+
+// MigrateDirectory migrates every .tf file of the module in inputDir and writes the results
+// to outputDir, which may be inputDir to migrate in place. References between files are
+// updated together.
+func MigrateDirectory(inputDir, outputDir string) (*Result, error) {
+	files, err := readModule(inputDir)
+	if err != nil {
+		return nil, err
+	}
+	result, err := MigrateModule(files)
+	if err != nil {
+		return nil, err
 	}
-	defer func() {
-		if cerr := output.Close(); cerr != nil && err == nil {
-			err = cerr
-		}
-	}()
 
-	return migrateConfig(input, output)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for name, content := range result.Files {
+		if err := os.WriteFile(filepath.Join(outputDir, name), content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return result, nil
 }
 
 // ValidateConfigFile checks a Terraform configuration file for compatibility issues.
 func ValidateConfigFile(inputFile string) ([]ValidationIssue, error) {
-	input, err := os.Open(inputFile)
+	src, err := os.ReadFile(inputFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open input file: %w", err)
+		return nil, fmt.Errorf("failed to read input file: %w", err)
 	}
-	defer func() {
-		_ = input.Close() // Best effort close, errors are not critical for validation
-	}()
-
-	return validateConfig(input)
+	return validateFile(inputFile, src)
 }
 
-// migrateConfig performs the actual migration from input to output.
-func migrateConfig(input io.Reader, output io.Writer) error {
-	scanner := bufio.NewScanner(input)
-	writer := bufio.NewWriter(output)
-	defer func() {
-		_ = writer.Flush() // Best effort flush, errors handled elsewhere
-	}()
-
-	var currentResource *ResourceContext
-	lineNumber := 0
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineNumber++
-
-		// Check if we're starting a new dotfiles_file resource
-		if match := fileResourceRegex.FindStringSubmatch(line); match != nil {
-			currentResource = &ResourceContext{
-				ResourceType: "dotfiles_file",
-				ResourceName: match[1],
-				StartLine:    lineNumber,
-				Lines:        []string{line},
-			}
-			continue
-		}
+// ValidateDirectory checks every .tf file of the module in dir for compatibility issues.
+func ValidateDirectory(dir string) ([]ValidationIssue, error) {
+	files, err := readModule(dir)
+	if err != nil {
+		return nil, err
+	}
 
-		// If we're inside a resource, collect lines
-		if currentResource != nil {
-			currentResource.Lines = append(currentResource.Lines, line)
+	var issues []ValidationIssue
+	for _, name := range sortedNames(files) {
+		fileIssues, err := validateFile(name, files[name])
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, fileIssues...)
+	}
+	return issues, nil
+}
 
-			// Check for strategy field
-			if match := strategyFieldRegex.FindStringSubmatch(line); match != nil {
-				currentResource.Strategy = strings.Trim(match[1], `"`)
+// MigrateModule migrates the files of one module, keyed by file name. dotfiles_file
+// resources lose their strategy argument; those with strategy = "symlink" become
+// dotfiles_symlink resources, references to them are renamed in every file and a moved
+// block is added after them. Formatting and comments outside migrated resources are kept.
+func MigrateModule(files map[string][]byte) (*Result, error) {
+	result := &Result{Files: make(map[string][]byte, len(files))}
+	parsed := make(map[string]*hclwrite.File, len(files))
+	edits := make(map[string][]*blockEdit, len(files))
+
+	names := sortedNames(files)
+	for _, name := range names {
+		file, diags := hclwrite.ParseConfig(files[name], name, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, diags.Error())
+		}
+		parsed[name] = file
+
+		for _, block := range file.Body().Blocks() {
+			edit, issue := migrateBlock(block)
+			if issue != nil {
+				issue.File = name
+				issue.LineNumber = resourceLine(files[name], name, block.Labels())
+				result.Issues = append(result.Issues, *issue)
 			}
-
-			// Check if we've reached the end of the resource
-			if strings.TrimSpace(line) == "}" && isResourceEnd(currentResource.Lines) {
-				// Process the resource
-				migratedLines, err := migrateResource(currentResource)
-				if err != nil {
-					return fmt.Errorf("failed to migrate resource %s at line %d: %w",
-						currentResource.ResourceName, currentResource.StartLine, err)
-				}
-
-				// Write migrated resource
-				for _, migratedLine := range migratedLines {
-					if _, err := writer.WriteString(migratedLine + "\n"); err != nil {
-						return err
-					}
-				}
-
-				currentResource = nil
+			if edit == nil {
 				continue
 			}
-		}
-
-		// If not inside a resource or resource doesn't need migration, write as-is
-		if currentResource == nil {
-			if _, err := writer.WriteString(line + "\n"); err != nil {
-				return err
+			edits[name] = append(edits[name], edit)
+			if edit.resourceType == symlinkResourceType {
+				result.Moved = append(result.Moved, MovedResource{
+					File: name,
+					From: fileResourceType + "." + edit.name,
+					To:   symlinkResourceType + "." + edit.name,
+				})
 			}
 		}
 	}
 
-	return scanner.Err()
-}
+	for _, name := range names {
+		for _, moved := range result.Moved {
+			renameReferences(parsed[name].Body(), strings.Split(moved.From, "."), strings.Split(moved.To, "."))
+		}
+	}
 
-// validateConfig checks for compatibility issues without modifying the config.
-func validateConfig(input io.Reader) ([]ValidationIssue, error) {
-	scanner := bufio.NewScanner(input)
-	var issues []ValidationIssue
-	lineNumber := 0
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineNumber++
-
-		// Check for dotfiles_file resources with strategy field
-		if fileResourceRegex.MatchString(line) {
-			resourceName := fileResourceRegex.FindStringSubmatch(line)[1]
-
-			// Look ahead to find strategy field in this resource
-			resourceLines := []string{line}
-			braceCount := strings.Count(line, "{") - strings.Count(line, "}")
-
-			for braceCount > 0 && scanner.Scan() {
-				lineNumber++
-				nextLine := scanner.Text()
-				resourceLines = append(resourceLines, nextLine)
-				braceCount += strings.Count(nextLine, "{") - strings.Count(nextLine, "}")
-
-				// Check for strategy field
-				if match := strategyFieldRegex.FindStringSubmatch(nextLine); match != nil {
-					strategy := strings.Trim(match[1], `"`)
-					issue := ValidationIssue{
-						LineNumber:  lineNumber,
-						Description: fmt.Sprintf("Resource '%s' uses deprecated strategy field with value '%s'", resourceName, strategy),
-					}
-
-					switch strategy {
-					case "symlink":
-						issue.Suggestion = "Convert to dotfiles_symlink resource"
-					case "copy":
-						issue.Suggestion = "Remove strategy field (dotfiles_file defaults to copy)"
-					case "template":
-						issue.Suggestion = "Remove strategy field and set is_template = true"
-					default:
-						issue.Suggestion = "Consider using dotfiles_application for complex strategies"
-					}
-
-					issues = append(issues, issue)
-				}
-			}
+	for _, name := range names {
+		content, err := finishFile(name, parsed[name].Bytes(), edits[name], result.Moved)
+		if err != nil {
+			return nil, err
 		}
+		result.Files[name] = content
 	}
+	return result, nil
+}
 
-	return issues, scanner.Err()
+// migrateConfig migrates a single configuration read from input.
+func migrateConfig(input io.Reader, output io.Writer) error {
+	src, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	result, err := MigrateModule(map[string][]byte{"main.tf": src})
+	if err != nil {
+		return err
+	}
+	_, err = output.Write(result.Files["main.tf"])
+	return err
 }
 
-// ResourceContext holds information about a resource being processed.
-type ResourceContext struct {
-	ResourceType string
-	ResourceName string
-	Strategy     string
-	StartLine    int
-	Lines        []string
+// validateConfig checks a single configuration read from input for compatibility issues.
+func validateConfig(input io.Reader) ([]ValidationIssue, error) {
+	src, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return validateFile("main.tf", src)
 }
 
-// migrateResource converts a dotfiles_file resource to the appropriate new format.
-func migrateResource(ctx *ResourceContext) ([]string, error) {
-	if ctx.Strategy == "" {
-		// No strategy field, just remove any strategy references and keep as dotfiles_file
-		return removeStrategyField(ctx.Lines), nil
+// migrateBlock rewrites a dotfiles_file resource according to its strategy. It returns
+// nil when the block needs no changes, and an issue when the strategy is not a literal.
+func migrateBlock(block *hclwrite.Block) (*blockEdit, *ValidationIssue) {
+	labels := block.Labels()
+	if block.Type() != "resource" || len(labels) != 2 || labels[0] != fileResourceType {
+		return nil, nil
+	}
+	body := block.Body()
+	attr := body.GetAttribute("strategy")
+	if attr == nil {
+		return nil, nil
 	}
 
-	switch ctx.Strategy {
+	strategy, ok := literalString(attr.Expr().BuildTokens(nil))
+	if !ok {
+		return nil, &ValidationIssue{
+			Description: fmt.Sprintf("Resource '%s' sets the deprecated strategy field with an expression", labels[1]),
+			Suggestion:  "Replace the expression with dotfiles_file, dotfiles_symlink or is_template = true by hand",
+		}
+	}
+
+	edit := &blockEdit{resourceType: fileResourceType, name: labels[1]}
+	switch strategy {
 	case "symlink":
-		return convertToSymlinkResource(ctx)
+		edit.resourceType = symlinkResourceType
+		edit.notes = append(edit.notes, "# MIGRATED: dotfiles_file with strategy=symlink → dotfiles_symlink")
+		body.RemoveAttribute("strategy")
+		block.SetLabels([]string{symlinkResourceType, labels[1]})
+		if removed := removeUnsupported(body); len(removed) > 0 {
+			edit.notes = append(edit.notes, fmt.Sprintf("# MIGRATION NOTE: removed %s, not supported by dotfiles_symlink", strings.Join(removed, ", ")))
+		}
 	case "copy":
-		// Convert to dotfiles_file without strategy field
-		return removeStrategyField(ctx.Lines), nil
+		body.RemoveAttribute("strategy")
 	case "template":
-		return convertToTemplateFile(ctx)
+		edit.notes = append(edit.notes, "# MIGRATED: strategy=template → is_template=true")
+		if body.RenameAttribute("strategy", "is_template") {
+			body.SetAttributeValue("is_template", cty.True)
+		} else {
+			body.RemoveAttribute("strategy")
+			edit.notes = append(edit.notes, "# MIGRATION NOTE: is_template was already set, check it is true")
+		}
 	default:
-		// Unknown strategy, add comment and keep as dotfiles_file
-		lines := removeStrategyField(ctx.Lines)
-		lines[0] = "# MIGRATION NOTE: Unknown strategy '" + ctx.Strategy + "' converted to copy operation\n" + lines[0]
-		return lines, nil
+		body.RemoveAttribute("strategy")
+		edit.notes = append(edit.notes, fmt.Sprintf("# MIGRATION NOTE: Unknown strategy '%s' converted to copy operation", strategy))
 	}
+	return edit, nil
 }
 
-// removeStrategyField removes strategy field lines from the resource.
-func removeStrategyField(lines []string) []string {
-	var result []string
-	for _, line := range lines {
-		if !strategyFieldRegex.MatchString(line) {
-			result = append(result, line)
+// removeUnsupported drops the arguments and blocks dotfiles_symlink does not accept,
+// returning their names.
+func removeUnsupported(body *hclwrite.Body) []string {
+	var removed []string
+	for name := range body.Attributes() {
+		if !symlinkArguments[name] {
+			body.RemoveAttribute(name)
+			removed = append(removed, name)
+		}
+	}
+	for _, nested := range body.Blocks() {
+		if !symlinkArguments[nested.Type()] {
+			body.RemoveBlock(nested)
+			removed = append(removed, nested.Type())
 		}
 	}
-	return result
+	sort.Strings(removed)
+	return removed
 }
 
-// convertToSymlinkResource converts dotfiles_file with strategy=symlink to dotfiles_symlink.
-func convertToSymlinkResource(ctx *ResourceContext) ([]string, error) {
-	result := make([]string, 0, len(ctx.Lines)+1)
+// renameReferences renames references with the prefix from to the prefix to in every
+// expression of body. Existing moved blocks keep their addresses so move chains stay intact.
+func renameReferences(body *hclwrite.Body, from, to []string) {
+	for _, attr := range body.Attributes() {
+		attr.Expr().RenameVariablePrefix(from, to)
+	}
+	for _, block := range body.Blocks() {
+		if block.Type() == "moved" {
+			continue
+		}
+		renameReferences(block.Body(), from, to)
+	}
+}
 
-	// Add migration comment
-	result = append(result, "# MIGRATED: dotfiles_file with strategy=symlink → dotfiles_symlink")
+// finishFile formats the migrated resources, adds their comments and appends moved blocks
+// for the resources converted in this file.
+func finishFile(name string, src []byte, edits []*blockEdit, moved []MovedResource) ([]byte, error) {
+	if len(edits) > 0 {
+		syntaxFile, diags := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse migrated %s: %s", name, diags.Error())
+		}
+		body := syntaxFile.Body.(*hclsyntax.Body)
 
-	for i, line := range ctx.Lines {
-		if i == 0 {
-			// Replace resource type
-			line = strings.Replace(line, "dotfiles_file", "dotfiles_symlink", 1)
-		} else if strategyFieldRegex.MatchString(line) {
-			// Skip strategy field
-			continue
+		// Splice from the end so earlier byte offsets stay valid
+		blocks := body.Blocks
+		for i := len(blocks) - 1; i >= 0; i-- {
+			edit := findEdit(edits, blocks[i])
+			if edit == nil {
+				continue
+			}
+			blockRange := blocks[i].Range()
+			formatted := hclwrite.Format(src[blockRange.Start.Byte:blockRange.End.Byte])
+			var replacement bytes.Buffer
+			for _, note := range edit.notes {
+				replacement.WriteString(note + "\n")
+			}
+			replacement.Write(formatted)
+			src = append(src[:blockRange.Start.Byte], append(replacement.Bytes(), src[blockRange.End.Byte:]...)...)
 		}
-		result = append(result, line)
 	}
 
-	return result, nil
+	var movedBlocks []MovedResource
+	for _, move := range moved {
+		if move.File == name {
+			movedBlocks = append(movedBlocks, move)
+		}
+	}
+	if len(movedBlocks) == 0 {
+		return src, nil
+	}
+
+	out := bytes.TrimRight(src, "\n")
+	for _, move := range movedBlocks {
+		block := hclwrite.NewEmptyFile()
+		movedBody := block.Body().AppendNewBlock("moved", nil).Body()
+		movedBody.SetAttributeTraversal("from", addressTraversal(move.From))
+		movedBody.SetAttributeTraversal("to", addressTraversal(move.To))
+		out = append(out, "\n\n"...)
+		out = append(out, bytes.TrimRight(hclwrite.Format(block.Bytes()), "\n")...)
+	}
+	return append(out, '\n'), nil
 }
 
-// convertToTemplateFile converts dotfiles_file with strategy=template to dotfiles_file with is_template=true.
-func convertToTemplateFile(ctx *ResourceContext) ([]string, error) {
-	result := make([]string, 0, len(ctx.Lines)+2)
-	var hasIsTemplate bool
+// findEdit returns the edit for a migrated resource block.
+func findEdit(edits []*blockEdit, block *hclsyntax.Block) *blockEdit {
+	if block.Type != "resource" || len(block.Labels) != 2 {
+		return nil
+	}
+	for _, edit := range edits {
+		if block.Labels[0] == edit.resourceType && block.Labels[1] == edit.name {
+			return edit
+		}
+	}
+	return nil
+}
 
-	// Add migration comment
-	result = append(result, "# MIGRATED: strategy=template → is_template=true")
+// validateFile reports the dotfiles_file resources of a file that still use the strategy field.
+func validateFile(name string, src []byte) ([]ValidationIssue, error) {
+	file, diags := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %s", name, diags.Error())
+	}
 
-	for _, line := range ctx.Lines {
-		if strategyFieldRegex.MatchString(line) {
-			// Replace strategy field with is_template
-			indentation := getIndentation(line)
-			result = append(result, indentation+"is_template = true")
+	var issues []ValidationIssue
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 || block.Labels[0] != fileResourceType {
+			continue
+		}
+		attr, ok := block.Body.Attributes["strategy"]
+		if !ok {
 			continue
 		}
 
-		if strings.Contains(line, "is_template") {
-			hasIsTemplate = true
+		issue := ValidationIssue{File: name, LineNumber: attr.SrcRange.Start.Line}
+		value, valueDiags := attr.Expr.Value(nil)
+		if valueDiags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+			issue.Description = fmt.Sprintf("Resource '%s' sets the deprecated strategy field with an expression", block.Labels[1])
+			issue.Suggestion = "Replace the expression with dotfiles_file, dotfiles_symlink or is_template = true by hand"
+			issues = append(issues, issue)
+			continue
 		}
 
-		result = append(result, line)
+		strategy := value.AsString()
+		issue.Description = fmt.Sprintf("Resource '%s' uses deprecated strategy field with value '%s'", block.Labels[1], strategy)
+		switch strategy {
+		case "symlink":
+			issue.Suggestion = "Convert to dotfiles_symlink resource"
+		case "copy":
+			issue.Suggestion = "Remove strategy field (dotfiles_file defaults to copy)"
+		case "template":
+			issue.Suggestion = "Remove strategy field and set is_template = true"
+		default:
+			issue.Suggestion = "Consider using dotfiles_application for complex strategies"
+		}
+		issues = append(issues, issue)
 	}
+	return issues, nil
+}
 
-	// If is_template wasn't already set, we've added it above
-	if hasIsTemplate {
-		// Add note that there might be a conflict
-		result[1] += " # NOTE: Check for is_template conflicts"
+// literalString returns the value of an expression consisting of a single quoted string
+// without interpolation.
+func literalString(tokens hclwrite.Tokens) (string, bool) {
+	var parts []*hclwrite.Token
+	for _, token := range tokens {
+		if token.Type != hclsyntax.TokenNewline && token.Type != hclsyntax.TokenComment {
+			parts = append(parts, token)
+		}
 	}
+	switch {
+	case len(parts) == 2 && parts[0].Type == hclsyntax.TokenOQuote && parts[1].Type == hclsyntax.TokenCQuote:
+		return "", true
+	case len(parts) == 3 && parts[0].Type == hclsyntax.TokenOQuote && parts[1].Type == hclsyntax.TokenQuotedLit &&
+		parts[2].Type == hclsyntax.TokenCQuote:
+		return string(parts[1].Bytes), true
+	default:
+		return "", false
+	}
+}
 
-	return result, nil
+// resourceLine returns the line a resource is declared on, or 0 when it cannot be found.
+func resourceLine(src []byte, name string, labels []string) int {
+	file, diags := hclsyntax.ParseConfig(src, name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return 0
+	}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type == "resource" && len(block.Labels) == 2 && len(labels) == 2 &&
+			block.Labels[0] == labels[0] && block.Labels[1] == labels[1] {
+			return block.DefRange().Start.Line
+		}
+	}
+	return 0
 }
 
-// isResourceEnd checks if we've reached the end of a resource block.
-func isResourceEnd(lines []string) bool {
-	braceCount := 0
-	for _, line := range lines {
-		braceCount += strings.Count(line, "{") - strings.Count(line, "}")
+// addressTraversal converts a resource address such as dotfiles_file.vimrc to a traversal.
+func addressTraversal(address string) hcl.Traversal {
+	parts := strings.Split(address, ".")
+	traversal := hcl.Traversal{hcl.TraverseRoot{Name: parts[0]}}
+	for _, part := range parts[1:] {
+		traversal = append(traversal, hcl.TraverseAttr{Name: part})
 	}
-	return braceCount == 0
+	return traversal
 }
 
-// getIndentation returns the indentation (whitespace) from the beginning of a line.
-func getIndentation(line string) string {
-	for i, char := range line {
-		if char != ' ' && char != '\t' {
-			return line[:i]
+// readModule reads the .tf files of a module directory, keyed by file name.
+func readModule(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module directory: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tf" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
 		}
+		files[entry.Name()] = content
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tf files found in %s", dir)
 	}
-	return line
+	return files, nil
 }
 
-// Regular expressions for parsing Terraform configuration.
-var (
-	fileResourceRegex  = regexp.MustCompile(`resource\s+"dotfiles_file"\s+"([^"]+)"`)
-	strategyFieldRegex = regexp.MustCompile(`\s*strategy\s*=\s*"([^"]*)"`)
-)
+// sortedNames returns the file names of a module in a stable order.
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
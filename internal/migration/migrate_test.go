@@ -2,6 +2,8 @@ package migration
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -23,12 +25,17 @@ func TestMigrateConfig(t *testing.T) {
   file_mode   = "0644"
 }`,
 			expected: `# MIGRATED: dotfiles_file with strategy=symlink → dotfiles_symlink
+# MIGRATION NOTE: removed file_mode, not supported by dotfiles_symlink
 resource "dotfiles_symlink" "test_symlink" {
   repository  = dotfiles_repository.main.id
   name        = "test-config"
   source_path = "config.json"
   target_path = "~/.config/app/config.json"
-  file_mode   = "0644"
+}
+
+moved {
+  from = dotfiles_file.test_symlink
+  to   = dotfiles_symlink.test_symlink
 }`,
 		},
 		{
@@ -189,116 +196,208 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
-func TestConvertToSymlinkResource(t *testing.T) {
-	ctx := &ResourceContext{
-		ResourceType: "dotfiles_file",
-		ResourceName: "test",
-		Strategy:     "symlink",
-		Lines: []string{
-			`resource "dotfiles_file" "test" {`,
-			`  source_path = "config"`,
-			`  strategy    = "symlink"`,
-			`}`,
-		},
-	}
-
-	result, err := convertToSymlinkResource(ctx)
-	if err != nil {
-		t.Fatalf("convertToSymlinkResource failed: %v", err)
-	}
-
-	expected := []string{
-		`# MIGRATED: dotfiles_file with strategy=symlink → dotfiles_symlink`,
-		`resource "dotfiles_symlink" "test" {`,
-		`  source_path = "config"`,
-		`}`,
-	}
+func TestMigrateConfigSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "nested blocks, heredocs and comments",
+			input: `# Shell configuration
+resource "dotfiles_file" "zshrc" {
+  # Managed by terraform
+  source_path = "zshrc"
+  target_path = "~/.zshrc"
+  strategy    = "copy"
 
-	if len(result) != len(expected) {
-		t.Fatalf("Expected %d lines, got %d", len(expected), len(result))
-	}
+  permissions {
+    files = "0644"
+  }
 
-	for i, line := range result {
-		if line != expected[i] {
-			t.Errorf("Line %d: expected '%s', got '%s'", i, expected[i], line)
-		}
-	}
+  post_create_commands = [<<-EOT
+    }
+    echo done
+  EOT
+  ]
 }
 
-func TestRemoveStrategyField(t *testing.T) {
-	lines := []string{
-		`resource "dotfiles_file" "test" {`,
-		`  source_path = "config"`,
-		`  strategy    = "copy"`,
-		`  file_mode   = "0644"`,
-		`}`,
-	}
-
-	result := removeStrategyField(lines)
-	expected := []string{
-		`resource "dotfiles_file" "test" {`,
-		`  source_path = "config"`,
-		`  file_mode   = "0644"`,
-		`}`,
-	}
+resource "dotfiles_file" "unchanged" {
+  source_path = "bashrc"
+}
+`,
+			expected: `# Shell configuration
+resource "dotfiles_file" "zshrc" {
+  # Managed by terraform
+  source_path = "zshrc"
+  target_path = "~/.zshrc"
 
-	if len(result) != len(expected) {
-		t.Fatalf("Expected %d lines, got %d", len(expected), len(result))
-	}
+  permissions {
+    files = "0644"
+  }
 
-	for i, line := range result {
-		if line != expected[i] {
-			t.Errorf("Line %d: expected '%s', got '%s'", i, expected[i], line)
-		}
-	}
+  post_create_commands = [<<-EOT
+    }
+    echo done
+  EOT
+  ]
 }
 
-func TestRegexPatterns(t *testing.T) {
-	tests := []struct {
-		name    string
-		regex   string
-		input   string
-		matches bool
-	}{
-		{
-			name:    "file resource regex matches",
-			input:   `resource "dotfiles_file" "test" {`,
-			matches: true,
-		},
-		{
-			name:    "file resource regex doesn't match other resources",
-			input:   `resource "dotfiles_symlink" "test" {`,
-			matches: false,
+resource "dotfiles_file" "unchanged" {
+  source_path = "bashrc"
+}`,
 		},
 		{
-			name:    "strategy field regex matches",
-			input:   `  strategy = "symlink"`,
-			matches: true,
+			name:  "single-line resource",
+			input: `resource "dotfiles_file" "vimrc" { strategy = "template" }`,
+			expected: `# MIGRATED: strategy=template → is_template=true
+resource "dotfiles_file" "vimrc" { is_template = true }`,
 		},
 		{
-			name:    "strategy field regex matches with different quotes",
-			input:   `  strategy    =    "copy"`,
-			matches: true,
+			name: "for_each resource keeps meta-arguments",
+			input: `resource "dotfiles_file" "configs" {
+  for_each    = toset(["a", "b"])
+  source_path = each.key
+  target_path = "~/.config/${each.key}"
+  strategy    = "symlink"
+
+  lifecycle {
+    ignore_changes = [target_path]
+  }
+}`,
+			expected: `# MIGRATED: dotfiles_file with strategy=symlink → dotfiles_symlink
+resource "dotfiles_symlink" "configs" {
+  for_each    = toset(["a", "b"])
+  source_path = each.key
+  target_path = "~/.config/${each.key}"
+
+  lifecycle {
+    ignore_changes = [target_path]
+  }
+}
+
+moved {
+  from = dotfiles_file.configs
+  to   = dotfiles_symlink.configs
+}`,
 		},
 		{
-			name:    "strategy field regex doesn't match other fields",
-			input:   `  source_path = "test"`,
-			matches: false,
+			name: "unknown strategy",
+			input: `resource "dotfiles_file" "custom" {
+  source_path = "x"
+  strategy    = "hardlink"
+}`,
+			expected: `# MIGRATION NOTE: Unknown strategy 'hardlink' converted to copy operation
+resource "dotfiles_file" "custom" {
+  source_path = "x"
+}`,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var matched bool
-			if strings.Contains(tt.name, "file resource") {
-				matched = fileResourceRegex.MatchString(tt.input)
-			} else {
-				matched = strategyFieldRegex.MatchString(tt.input)
+			var output bytes.Buffer
+			if err := migrateConfig(strings.NewReader(tt.input), &output); err != nil {
+				t.Fatalf("migrateConfig failed: %v", err)
 			}
-
-			if matched != tt.matches {
-				t.Errorf("Expected match=%v, got match=%v for input: %s", tt.matches, matched, tt.input)
+			result := strings.TrimSpace(output.String())
+			if result != strings.TrimSpace(tt.expected) {
+				t.Errorf("Migration result mismatch\nExpected:\n%s\nGot:\n%s", tt.expected, result)
 			}
 		})
 	}
 }
+
+func TestMigrateModule(t *testing.T) {
+	files := map[string][]byte{
+		"main.tf": []byte(`resource "dotfiles_file" "gitconfig" {
+  source_path = "gitconfig"
+  target_path = "~/.gitconfig"
+  strategy    = "symlink"
+}
+
+resource "dotfiles_file" "dynamic" {
+  source_path = "zshrc"
+  strategy    = var.strategy
+}
+`),
+		"outputs.tf": []byte(`output "gitconfig" {
+  value = "${dotfiles_file.gitconfig.target_path} (${dotfiles_file.dynamic.id})"
+}
+
+moved {
+  from = dotfiles_file.old
+  to   = dotfiles_file.gitconfig
+}
+`),
+	}
+
+	result, err := MigrateModule(files)
+	if err != nil {
+		t.Fatalf("MigrateModule failed: %v", err)
+	}
+
+	if len(result.Moved) != 1 || result.Moved[0] != (MovedResource{File: "main.tf", From: "dotfiles_file.gitconfig", To: "dotfiles_symlink.gitconfig"}) {
+		t.Errorf("Unexpected moved resources %+v", result.Moved)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].File != "main.tf" || result.Issues[0].LineNumber != 7 {
+		t.Errorf("Expected an issue for the non-literal strategy, got %+v", result.Issues)
+	}
+
+	main := string(result.Files["main.tf"])
+	if !strings.Contains(main, "strategy    = var.strategy") {
+		t.Errorf("Non-literal strategy should be left for manual migration:\n%s", main)
+	}
+	if !strings.HasSuffix(main, "moved {\n  from = dotfiles_file.gitconfig\n  to   = dotfiles_symlink.gitconfig\n}\n") {
+		t.Errorf("Expected moved block at the end of main.tf:\n%s", main)
+	}
+
+	outputs := string(result.Files["outputs.tf"])
+	if !strings.Contains(outputs, "${dotfiles_symlink.gitconfig.target_path} (${dotfiles_file.dynamic.id})") {
+		t.Errorf("Expected references in other files to be renamed:\n%s", outputs)
+	}
+	if !strings.Contains(outputs, "to   = dotfiles_file.gitconfig") {
+		t.Errorf("Existing moved blocks should keep their addresses:\n%s", outputs)
+	}
+}
+
+func TestMigrateDirectory(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := filepath.Join(t.TempDir(), "migrated")
+	config := `resource "dotfiles_file" "test" {
+  strategy = "copy"
+}
+`
+	if err := os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "README.md"), []byte("strategy"), 0644); err != nil {
+		t.Fatalf("Failed to write readme: %v", err)
+	}
+
+	issues, err := ValidateDirectory(inputDir)
+	if err != nil || len(issues) != 1 || issues[0].File != "main.tf" || issues[0].LineNumber != 2 {
+		t.Fatalf("Unexpected validation result %+v, %v", issues, err)
+	}
+
+	if _, err := MigrateDirectory(inputDir, outputDir); err != nil {
+		t.Fatalf("MigrateDirectory failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "main.tf"))
+	if err != nil || strings.Contains(string(content), "strategy") {
+		t.Errorf("Expected migrated main.tf, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "README.md")); !os.IsNotExist(err) {
+		t.Error("Only .tf files should be written")
+	}
+
+	if _, err := MigrateDirectory(t.TempDir(), outputDir); err == nil {
+		t.Error("Expected error for a directory without .tf files")
+	}
+}
+
+func TestValidateConfigInvalidSyntax(t *testing.T) {
+	if _, err := validateConfig(strings.NewReader(`resource "dotfiles_file" "test" {`)); err == nil {
+		t.Error("Expected error for invalid HCL")
+	}
+}
@@ -0,0 +1,515 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+)
+
+var _ resource.Resource = &AdoptResource{}
+var _ resource.ResourceWithValidateConfig = &AdoptResource{}
+
+func NewAdoptResource() resource.Resource {
+	return &AdoptResource{}
+}
+
+// AdoptResource moves an unmanaged file into the repository and replaces it with a link
+// to, or a copy of, the adopted file.
+type AdoptResource struct {
+	client *DotfilesClient
+}
+
+// AdoptResourceModel describes the resource data model.
+type AdoptResourceModel struct {
+	ID                 types.String `tfsdk:"id"`
+	Repository         types.String `tfsdk:"repository"`
+	SourcePath         types.String `tfsdk:"source_path"`
+	TargetPath         types.String `tfsdk:"target_path"`
+	Strategy           types.String `tfsdk:"strategy"`
+	ConflictResolution types.String `tfsdk:"conflict_resolution"`
+
+	AbsoluteSourcePath types.String `tfsdk:"absolute_source_path"`
+	Adopted            types.Bool   `tfsdk:"adopted"`
+	BackupPath         types.String `tfsdk:"backup_path"`
+	SourceBackupPath   types.String `tfsdk:"source_backup_path"`
+}
+
+// adoptPaths are the resolved locations of an adopted file.
+type adoptPaths struct {
+	source string
+	target string
+}
+
+func (r *AdoptResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_adopt"
+}
+
+func (r *AdoptResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Adopts an existing, unmanaged file into the repository: the file is backed up, moved to `source_path` " +
+			"and replaced with a symlink to or a copy of the adopted file. An existing source that differs from the file is " +
+			"never overwritten unless `conflict_resolution` says so. Destroying the resource leaves a regular copy at the target.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Adoption identifier",
+			},
+			"repository": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "ID of the `dotfiles_repository` the file is adopted into (default: `dotfiles_root`)",
+			},
+			"source_path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Path of the adopted file in the repository",
+			},
+			"target_path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Path of the existing file to adopt",
+			},
+			"strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How the original location is replaced: `symlink` or `copy` (default: symlink)",
+			},
+			"conflict_resolution": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "What to do when `source_path` already exists with different content: `backup` backs up the " +
+					"source and replaces it, `overwrite` replaces it, `skip` leaves both files alone. Unset, adoption fails",
+			},
+			"absolute_source_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Absolute path of the adopted file in the repository",
+			},
+			"adopted": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the target links to or matches the adopted file",
+			},
+			"backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Backup of the original file taken before it was replaced",
+			},
+			"source_backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Backup of a conflicting source replaced with `conflict_resolution = \"backup\"`",
+			},
+		},
+	}
+}
+
+func (r *AdoptResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig checks the strategy and conflict resolution values.
+func (r *AdoptResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data AdoptResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if strategy := data.Strategy.ValueString(); !data.Strategy.IsNull() && !data.Strategy.IsUnknown() &&
+		strategy != StrategySymlink && strategy != StrategyCopy {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("strategy"),
+			"Invalid Adoption Strategy",
+			fmt.Sprintf("strategy must be %s or %s, got %q.", StrategySymlink, StrategyCopy, strategy),
+		)
+	}
+
+	// Adoption runs unattended, so conflicts cannot be prompted for
+	if resolution := data.ConflictResolution.ValueString(); !data.ConflictResolution.IsNull() && !data.ConflictResolution.IsUnknown() &&
+		resolution != ConflictResolutionBackup && resolution != ConflictResolutionOverwrite && resolution != ConflictResolutionSkip {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("conflict_resolution"),
+			"Invalid Conflict Resolution",
+			fmt.Sprintf("conflict_resolution must be one of %s, %s or %s, got %q.",
+				ConflictResolutionBackup, ConflictResolutionOverwrite, ConflictResolutionSkip, resolution),
+		)
+	}
+}
+
+func (r *AdoptResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data AdoptResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = types.StringNull()
+	data.SourceBackupPath = types.StringNull()
+	if err := r.adopt(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to adopt file")
+		return
+	}
+	r.warnNotAdopted(ctx, &data, resp)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AdoptResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data AdoptResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	paths, err := r.resolvePaths(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to resolve adopted file")
+		return
+	}
+
+	// The adopted file was removed from the repository
+	if _, err := os.Stat(paths.source); os.IsNotExist(err) {
+		tflog.Info(ctx, "Adopted file no longer exists, removing from state", map[string]interface{}{
+			"source_path": paths.source,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	adopted := r.isAdopted(&data, paths)
+	data.Adopted = types.BoolValue(adopted)
+
+	// The target was replaced since; adopt it again, unless conflicts are skipped anyway
+	if !adopted && data.ConflictResolution.ValueString() != ConflictResolutionSkip {
+		tflog.Info(ctx, "Target no longer matches adopted file, removing from state", map[string]interface{}{
+			"target_path": paths.target,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AdoptResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state AdoptResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = state.BackupPath
+	data.SourceBackupPath = state.SourceBackupPath
+	if err := r.adopt(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to adopt file")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete replaces a symlink to the adopted file with a regular copy, so the file keeps
+// working once it is no longer managed. The adopted file stays in the repository.
+func (r *AdoptResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data AdoptResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	paths, err := r.resolvePaths(&data)
+	if err != nil || !isLinkTo(paths.target, paths.source) || r.dryRun() {
+		return
+	}
+	if err := replaceWithCopy(paths.source, paths.target); err != nil {
+		releaseErr := errors.IOError("release", "adopt", "Failed to replace symlink with a copy of the adopted file", err).
+			WithPath(paths.target)
+		errors.AddWarningToDiagnostics(ctx, &resp.Diagnostics, "Symlink left in place", releaseErr.Error())
+	}
+}
+
+// adopt moves the target into the repository when needed and replaces it according to the
+// strategy. It is idempotent: targets that already link to or match the source are left alone.
+func (r *AdoptResource) adopt(ctx context.Context, data *AdoptResourceModel) error {
+	paths, err := r.resolvePaths(data)
+	if err != nil {
+		return err
+	}
+	data.ID = types.StringValue(paths.target)
+	data.AbsoluteSourcePath = types.StringValue(paths.source)
+	data.Adopted = types.BoolValue(r.isAdopted(data, paths))
+	if data.Adopted.ValueBool() {
+		return nil
+	}
+
+	if r.dryRun() {
+		tflog.Info(ctx, "DRY RUN: Skipping adoption", map[string]interface{}{
+			"source_path": paths.source,
+			"target_path": paths.target,
+		})
+		return nil
+	}
+
+	proceed, err := r.adoptSource(ctx, data, paths)
+	if err != nil || !proceed {
+		return err
+	}
+
+	if err := r.replaceTarget(data, paths); err != nil {
+		return errors.IOError("replace_target", "adopt", "Failed to replace the original file", err).
+			WithPath(paths.target).
+			WithContext("source_path", paths.source)
+	}
+	data.Adopted = types.BoolValue(true)
+
+	tflog.Info(ctx, "Adopted file into repository", map[string]interface{}{
+		"source_path": paths.source,
+		"target_path": paths.target,
+		"strategy":    r.strategy(data),
+	})
+	return nil
+}
+
+// adoptSource backs up the target and moves it into the repository, resolving conflicts
+// with an existing source. It returns false when a conflict is skipped.
+func (r *AdoptResource) adoptSource(ctx context.Context, data *AdoptResourceModel, paths *adoptPaths) (bool, error) {
+	targetInfo, targetErr := os.Lstat(paths.target)
+	_, sourceErr := os.Stat(paths.source)
+
+	switch {
+	case targetErr != nil && sourceErr != nil:
+		return false, errors.ValidationError("adopt", "adopt", "Nothing to adopt: neither the target nor the source exists", targetErr).
+			WithPath(paths.target).
+			WithContext("source_path", paths.source)
+	case targetErr != nil:
+		// Only the source exists, e.g. on a new machine: restore the target from it
+		return true, nil
+	case isLinkTo(paths.target, paths.source):
+		// A link to the source that should become a copy; there is nothing to move
+		return true, nil
+	case !targetInfo.Mode().IsRegular():
+		return false, errors.ValidationError("adopt", "adopt", "Only regular files can be adopted", nil).
+			WithPath(paths.target)
+	}
+
+	if sourceErr == nil && !sameContent(paths.target, paths.source) {
+		proceed, err := r.resolveSourceConflict(ctx, data, paths)
+		if err != nil || !proceed {
+			return proceed, err
+		}
+	}
+
+	if err := r.backupTarget(data, paths); err != nil {
+		return false, err
+	}
+	if err := moveFile(paths.target, paths.source); err != nil {
+		return false, errors.IOError("move_into_repository", "adopt", "Failed to move file into the repository", err).
+			WithPath(paths.target).
+			WithContext("source_path", paths.source)
+	}
+	return true, nil
+}
+
+// resolveSourceConflict applies conflict_resolution to a source that differs from the target.
+func (r *AdoptResource) resolveSourceConflict(ctx context.Context, data *AdoptResourceModel, paths *adoptPaths) (bool, error) {
+	strategy := data.ConflictResolution.ValueString()
+	if data.ConflictResolution.IsNull() {
+		return false, errors.ValidationError("adopt", "adopt", "The repository already has a different file at source_path; set conflict_resolution to replace it", nil).
+			WithPath(paths.source).
+			WithContext("target_path", paths.target)
+	}
+
+	resolution, err := r.fileManager().ResolveConflict(paths.source, r.client.Config.BackupDirectory, strategy)
+	if err != nil {
+		return false, errors.IOError("resolve_conflict", "adopt", "Failed to resolve conflict with existing source", err).
+			WithPath(paths.source)
+	}
+	if resolution.BackupPath != "" {
+		data.SourceBackupPath = types.StringValue(resolution.BackupPath)
+	}
+	if !resolution.ShouldProceed {
+		tflog.Info(ctx, "Skipping adoption of file conflicting with existing source", map[string]interface{}{
+			"source_path": paths.source,
+			"target_path": paths.target,
+		})
+	}
+	return resolution.ShouldProceed, nil
+}
+
+// backupTarget records a backup of the original file before it is moved.
+func (r *AdoptResource) backupTarget(data *AdoptResourceModel, paths *adoptPaths) error {
+	backupPath, err := r.fileManager().CreateEnhancedBackup(paths.target, &fileops.EnhancedBackupConfig{
+		Enabled:        true,
+		Directory:      r.client.Config.BackupDirectory,
+		BackupFormat:   "timestamped",
+		BackupMetadata: true,
+		BackupIndex:    true,
+	})
+	if err != nil {
+		return errors.IOError("backup", "adopt", "Failed to back up the original file", err).
+			WithPath(paths.target).
+			WithContext("backup_directory", r.client.Config.BackupDirectory)
+	}
+	data.BackupPath = types.StringValue(backupPath)
+	return nil
+}
+
+// replaceTarget puts a symlink to or a copy of the source at the target.
+func (r *AdoptResource) replaceTarget(data *AdoptResourceModel, paths *adoptPaths) error {
+	if r.strategy(data) == StrategyCopy {
+		return replaceWithCopy(paths.source, paths.target)
+	}
+	if err := os.Remove(paths.target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return r.fileManager().CreateSymlinkWithParents(paths.source, paths.target)
+}
+
+// isAdopted reports whether the target already links to, or for copies matches, the source.
+func (r *AdoptResource) isAdopted(data *AdoptResourceModel, paths *adoptPaths) bool {
+	if r.strategy(data) == StrategySymlink {
+		return isLinkTo(paths.target, paths.source)
+	}
+	info, err := os.Lstat(paths.target)
+	return err == nil && info.Mode().IsRegular() && sameContent(paths.target, paths.source)
+}
+
+// resolvePaths resolves the adopted file in the repository and the expanded target. A
+// configured repository that cannot be resolved is an error, never dotfiles_root, since
+// adopting moves the user's file into whichever tree is returned.
+func (r *AdoptResource) resolvePaths(data *AdoptResourceModel) (*adoptPaths, error) {
+	if r.client == nil {
+		return nil, errors.ConfigurationError("resolve_repository", "adopt", "Provider is not configured", nil)
+	}
+	repoPath, err := r.client.RepositoryRoot(data.Repository.ValueString())
+	if err != nil {
+		return nil, errors.ConfigurationError("resolve_repository", "adopt", "No repository to adopt into", err).
+			WithContext("repository", data.Repository.ValueString())
+	}
+	source, err := repositoryFile(repoPath, data.SourcePath.ValueString())
+	if err != nil {
+		return nil, errors.ValidationError("resolve_source", "adopt", "Invalid source path", err).
+			WithPath(data.SourcePath.ValueString())
+	}
+	target, err := platform.DetectPlatform().ExpandPath(data.TargetPath.ValueString())
+	if err != nil {
+		return nil, errors.ValidationError("expand_target_path", "adopt", "Could not expand target path", err).
+			WithPath(data.TargetPath.ValueString())
+	}
+	return &adoptPaths{source: source, target: target}, nil
+}
+
+// strategy returns the configured strategy, defaulting to symlink.
+func (r *AdoptResource) strategy(data *AdoptResourceModel) string {
+	if data.Strategy.IsNull() || data.Strategy.ValueString() == "" {
+		return StrategySymlink
+	}
+	return data.Strategy.ValueString()
+}
+
+func (r *AdoptResource) dryRun() bool {
+	return r.client.Config != nil && r.client.Config.DryRun
+}
+
+func (r *AdoptResource) fileManager() *fileops.FileManager {
+	return fileops.NewFileManager(platform.DetectPlatform(), r.dryRun())
+}
+
+// warnNotAdopted reports a conflict that was skipped.
+func (r *AdoptResource) warnNotAdopted(ctx context.Context, data *AdoptResourceModel, resp *resource.CreateResponse) {
+	if data.Adopted.ValueBool() || r.dryRun() {
+		return
+	}
+	errors.AddWarningToDiagnostics(ctx, &resp.Diagnostics, "File was not adopted",
+		fmt.Sprintf("%s already exists in the repository with different content and conflict_resolution is skip.", data.SourcePath.ValueString()))
+}
+
+// isLinkTo reports whether linkPath is a symlink to source.
+func isLinkTo(linkPath, source string) bool {
+	linkTarget, err := os.Readlink(linkPath)
+	return err == nil && fileops.SymlinkPointsTo(linkPath, linkTarget, source)
+}
+
+// sameContent reports whether two files have identical content.
+func sameContent(a, b string) bool {
+	contentA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	contentB, err := os.ReadFile(b)
+	return err == nil && bytes.Equal(contentA, contentB)
+}
+
+// replaceWithCopy replaces target, which may be a symlink, with a copy of source.
+func replaceWithCopy(source, target string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return copyFileMode(source, target, info.Mode().Perm())
+}
+
+// moveFile moves a file, falling back to copying across filesystems. The destination's
+// parent directories are created.
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	if err := copyFileMode(from, to, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
+
+// copyFileMode copies a file, creating the destination with mode.
+func copyFileMode(from, to string, mode os.FileMode) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,275 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestAdoptResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewAdoptResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_adopt" {
+			t.Errorf("Expected TypeName dotfiles_adopt, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewAdoptResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		for _, attr := range []string{"source_path", "target_path"} {
+			if !resp.Schema.Attributes[attr].IsRequired() {
+				t.Errorf("Attribute %s should be required", attr)
+			}
+		}
+		for _, attr := range []string{"id", "absolute_source_path", "adopted", "backup_path", "source_backup_path"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		r := NewAdoptResource().(*AdoptResource)
+		ctx := context.Background()
+
+		resp := &resource.ConfigureResponse{}
+		r.Configure(ctx, resource.ConfigureRequest{ProviderData: &DotfilesClient{}}, resp)
+		if resp.Diagnostics.HasError() || r.client == nil {
+			t.Errorf("Configure with valid client failed: %v", resp.Diagnostics)
+		}
+
+		resp = &resource.ConfigureResponse{}
+		r.Configure(ctx, resource.ConfigureRequest{ProviderData: "invalid"}, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Error("Configure with invalid provider data should error")
+		}
+	})
+}
+
+func TestAdoptResourceAdopt(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "dotfiles")
+	homeDir := filepath.Join(tempDir, "home")
+	for _, dir := range []string{repoDir, homeDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	r := &AdoptResource{client: &DotfilesClient{Config: &DotfilesConfig{
+		DotfilesRoot:    repoDir,
+		BackupDirectory: filepath.Join(tempDir, "backups"),
+	}}}
+	ctx := context.Background()
+
+	newData := func(source, target string) *AdoptResourceModel {
+		return &AdoptResourceModel{
+			Repository:         types.StringNull(),
+			SourcePath:         types.StringValue(source),
+			TargetPath:         types.StringValue(filepath.Join(homeDir, target)),
+			Strategy:           types.StringNull(),
+			ConflictResolution: types.StringNull(),
+			BackupPath:         types.StringNull(),
+			SourceBackupPath:   types.StringNull(),
+		}
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(content)
+	}
+
+	t.Run("move and link", func(t *testing.T) {
+		target := filepath.Join(homeDir, ".vimrc")
+		write(target, "set number\n")
+		data := newData(filepath.Join("vim", "vimrc"), ".vimrc")
+
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		source := filepath.Join(repoDir, "vim", "vimrc")
+		if read(source) != "set number\n" {
+			t.Error("Expected file to be moved into the repository")
+		}
+		if info, err := os.Stat(source); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected adopted file to keep its mode, got %v, %v", info, err)
+		}
+		if !isLinkTo(target, source) {
+			t.Error("Expected target to link to the adopted file")
+		}
+		if !data.Adopted.ValueBool() || data.AbsoluteSourcePath.ValueString() != source {
+			t.Errorf("Unexpected state adopted=%v absolute_source_path=%s", data.Adopted, data.AbsoluteSourcePath)
+		}
+		if backup := data.BackupPath.ValueString(); backup == "" || read(backup) != "set number\n" {
+			t.Errorf("Expected backup of the original file, got %q", backup)
+		}
+
+		// Adopting again is a no-op
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+	})
+
+	t.Run("copy strategy", func(t *testing.T) {
+		target := filepath.Join(homeDir, ".inputrc")
+		write(target, "set editing-mode vi\n")
+		data := newData("inputrc", ".inputrc")
+		data.Strategy = types.StringValue(StrategyCopy)
+
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		info, err := os.Lstat(target)
+		if err != nil || !info.Mode().IsRegular() || read(target) != "set editing-mode vi\n" {
+			t.Errorf("Expected a regular copy at the target, got %v, %v", info, err)
+		}
+		if read(filepath.Join(repoDir, "inputrc")) != "set editing-mode vi\n" {
+			t.Error("Expected file in the repository")
+		}
+	})
+
+	t.Run("refuses differing source", func(t *testing.T) {
+		target := filepath.Join(homeDir, ".zshrc")
+		source := filepath.Join(repoDir, "zshrc")
+		write(target, "local\n")
+		write(source, "repository\n")
+		data := newData("zshrc", ".zshrc")
+
+		if err := r.adopt(ctx, data); err == nil {
+			t.Fatal("Expected adoption to refuse a differing source")
+		}
+		if read(source) != "repository\n" || read(target) != "local\n" {
+			t.Error("Neither file should change when adoption is refused")
+		}
+
+		data.ConflictResolution = types.StringValue(ConflictResolutionSkip)
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		if data.Adopted.ValueBool() || read(target) != "local\n" {
+			t.Error("Skipped conflict should leave the target alone")
+		}
+
+		data.ConflictResolution = types.StringValue(ConflictResolutionBackup)
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		if read(source) != "local\n" || !isLinkTo(target, source) {
+			t.Error("Expected local file to replace the source")
+		}
+		if backup := data.SourceBackupPath.ValueString(); backup == "" || read(backup) != "repository\n" {
+			t.Errorf("Expected backup of the replaced source, got %q", backup)
+		}
+	})
+
+	t.Run("identical source", func(t *testing.T) {
+		target := filepath.Join(homeDir, ".tmux.conf")
+		source := filepath.Join(repoDir, "tmux.conf")
+		write(target, "set -g mouse on\n")
+		write(source, "set -g mouse on\n")
+
+		if err := r.adopt(ctx, newData("tmux.conf", ".tmux.conf")); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		if !isLinkTo(target, source) {
+			t.Error("Expected target to link to the existing source")
+		}
+	})
+
+	t.Run("restore from source", func(t *testing.T) {
+		source := filepath.Join(repoDir, "gitignore")
+		write(source, "*.swp\n")
+		target := filepath.Join(homeDir, ".config", "git", "ignore")
+
+		data := newData("gitignore", filepath.Join(".config", "git", "ignore"))
+		if err := r.adopt(ctx, data); err != nil {
+			t.Fatalf("adopt failed: %v", err)
+		}
+		if !isLinkTo(target, source) || !data.BackupPath.IsNull() {
+			t.Errorf("Expected link without backup, got backup_path=%s", data.BackupPath)
+		}
+
+		if err := r.adopt(ctx, newData("missing", ".missing")); err == nil {
+			t.Error("Expected error when neither file exists")
+		}
+	})
+
+	t.Run("rejects", func(t *testing.T) {
+		if err := r.adopt(ctx, newData(filepath.Join("..", "outside"), ".outside")); err == nil {
+			t.Error("Expected error for a source path outside the repository")
+		}
+		if err := os.MkdirAll(filepath.Join(homeDir, ".config", "nvim"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := r.adopt(ctx, newData("nvim", filepath.Join(".config", "nvim"))); err == nil {
+			t.Error("Expected error adopting a directory")
+		}
+	})
+
+	t.Run("unregistered repository", func(t *testing.T) {
+		target := filepath.Join(homeDir, ".curlrc")
+		write(target, "silent\n")
+		data := newData("curlrc", ".curlrc")
+		data.Repository = types.StringValue("work")
+
+		if err := r.adopt(ctx, data); err == nil {
+			t.Fatal("Expected error adopting into a repository that is not registered")
+		}
+		if read(target) != "silent\n" {
+			t.Error("Target should stay in place")
+		}
+		if _, err := os.Stat(filepath.Join(repoDir, "curlrc")); !os.IsNotExist(err) {
+			t.Error("Target should not be moved into dotfiles_root")
+		}
+	})
+}
+
+func TestAdoptResourceRelease(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "vimrc")
+	target := filepath.Join(tempDir, ".vimrc")
+	if err := os.WriteFile(source, []byte("set number\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	if err := os.Symlink(source, target); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	if err := replaceWithCopy(source, target); err != nil {
+		t.Fatalf("replaceWithCopy failed: %v", err)
+	}
+	info, err := os.Lstat(target)
+	if err != nil || !info.Mode().IsRegular() {
+		t.Fatalf("Expected a regular file, got %v, %v", info, err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "set number\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+}
@@ -11,11 +11,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
@@ -48,6 +54,7 @@ type ApplicationResourceModel struct {
 type ConfigMappingValue struct {
 	TargetPath types.String `tfsdk:"target_path"`
 	Strategy   types.String `tfsdk:"strategy"`
+	FileMode   types.String `tfsdk:"file_mode"`
 }
 
 // Metadata sets the resource type name.
@@ -84,6 +91,13 @@ func (r *ApplicationResource) Schema(ctx context.Context, req resource.SchemaReq
 							Default:             stringdefault.StaticString("symlink"),
 							MarkdownDescription: "Deployment strategy: 'symlink' or 'copy'",
 						},
+						"file_mode": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "File permission mode for the deployed file (e.g., '0600'). With the symlink strategy the mode is applied to the linked source file",
+							Validators: []validator.String{
+								validators.ValidFileMode(),
+							},
+						},
 					},
 				},
 			},
@@ -280,11 +294,12 @@ func (r *ApplicationResource) deployApplicationConfig(ctx context.Context, data
 		}
 
 		// Deploy based on strategy
+		fileMode := mappingFileMode(mappingAttrs)
 		switch strategy {
 		case "symlink":
 			err = r.createSymlinkForConfig(ctx, sourcePath, expandedTargetPath)
 		case "copy":
-			err = r.copyConfigFile(ctx, sourcePath, expandedTargetPath)
+			err = r.copyConfigFile(sourcePath, expandedTargetPath, fileMode)
 		default:
 			err = fmt.Errorf("unsupported strategy: %s", strategy)
 		}
@@ -293,6 +308,13 @@ func (r *ApplicationResource) deployApplicationConfig(ctx context.Context, data
 			return types.ListNull(types.StringType), fmt.Errorf("failed to deploy %s using %s strategy: %w", sourceFile, strategy, err)
 		}
 
+		// Copies are written with their mode; a symlink's mode is that of the repository file
+		if strategy == "symlink" && fileMode != "" {
+			if err := chmodPermission(expandedTargetPath, fileMode); err != nil {
+				return types.ListNull(types.StringType), fmt.Errorf("failed to apply file mode for %s: %w", sourceFile, err)
+			}
+		}
+
 		configuredFiles = append(configuredFiles, expandedTargetPath)
 		tflog.Debug(ctx, "Configuration file deployed", map[string]interface{}{
 			"source":   sourcePath,
@@ -306,6 +328,15 @@ func (r *ApplicationResource) deployApplicationConfig(ctx context.Context, data
 	return configuredFilesList, nil
 }
 
+// mappingFileMode returns the optional file_mode of a config mapping.
+func mappingFileMode(mappingAttrs map[string]attr.Value) string {
+	fileMode, ok := mappingAttrs["file_mode"].(types.String)
+	if !ok || fileMode.IsNull() || fileMode.IsUnknown() {
+		return ""
+	}
+	return fileMode.ValueString()
+}
+
 // expandTargetPathTemplate expands template variables in target paths.
 func (r *ApplicationResource) expandTargetPathTemplate(targetPath, applicationName string) (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -358,48 +389,23 @@ func (r *ApplicationResource) createSymlinkForConfig(ctx context.Context, source
 	return nil
 }
 
-// copyConfigFile copies a configuration file to the target location.
-func (r *ApplicationResource) copyConfigFile(ctx context.Context, sourcePath, targetPath string) error {
-	// Create target directory if it doesn't exist
-	targetDir := filepath.Dir(targetPath)
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory %s: %w", targetDir, err)
-	}
-
-	// Open source file
-	sourceFile, err := os.Open(sourcePath)
+// copyConfigFile copies a configuration file to the target location. With a file mode the
+// copy is written with that mode, also over an existing file, before it is renamed into place.
+func (r *ApplicationResource) copyConfigFile(sourcePath, targetPath, fileMode string) error {
+	content, err := os.ReadFile(sourcePath)
 	if err != nil {
-		return fmt.Errorf("failed to open source file %s: %w", sourcePath, err)
+		return fmt.Errorf("failed to read source file %s: %w", sourcePath, err)
 	}
-	defer func() {
-		if err := sourceFile.Close(); err != nil {
-			tflog.Warn(ctx, "Failed to close source file", map[string]interface{}{
-				"file":  sourcePath,
-				"error": err.Error(),
-			})
-		}
-	}()
 
-	// Create target file
-	targetFile, err := os.Create(targetPath)
-	if err != nil {
-		return fmt.Errorf("failed to create target file %s: %w", targetPath, err)
+	fileManager := fileops.NewFileManager(platform.DetectPlatform(), false)
+	if fileMode == "" {
+		return fileManager.WriteFileAtomic(targetPath, content, 0644)
 	}
-	defer func() {
-		if err := targetFile.Close(); err != nil {
-			tflog.Warn(ctx, "Failed to close target file", map[string]interface{}{
-				"file":  targetPath,
-				"error": err.Error(),
-			})
-		}
-	}()
-
-	// Copy file contents
-	if _, err := sourceFile.WriteTo(targetFile); err != nil {
-		return fmt.Errorf("failed to copy file contents from %s to %s: %w", sourcePath, targetPath, err)
+	mode, err := parsePermission(fileMode)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return fileManager.WriteFileAtomicWithMode(targetPath, content, os.FileMode(mode))
 }
 
 // verifyConfigurationFiles verifies that configuration files are still properly configured.
@@ -8,6 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 func TestApplicationResourceUnit(t *testing.T) {
@@ -25,6 +28,10 @@ func TestApplicationResourceUnit(t *testing.T) {
 	t.Run("copyConfigFile", func(t *testing.T) {
 		testCopyConfigFile(t, testEnv)
 	})
+
+	t.Run("deployApplicationConfigFileMode", func(t *testing.T) {
+		testDeployApplicationConfigFileMode(t, testEnv)
+	})
 }
 
 // applicationResourceTestEnv holds the test environment setup
@@ -118,3 +125,56 @@ func testCopyConfigFile(t *testing.T, env *applicationResourceTestEnv) {
 		t.Error("Copy source file should exist")
 	}
 }
+
+// testDeployApplicationConfigFileMode tests per-mapping file modes for both strategies
+func testDeployApplicationConfigFileMode(t *testing.T, env *applicationResourceTestEnv) {
+	for _, name := range []string{"ssh_config", "id_ed25519"} {
+		if err := os.WriteFile(filepath.Join(env.tempDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create source file %s: %v", name, err)
+		}
+	}
+
+	mappingType := types.ObjectType{AttrTypes: map[string]attr.Type{
+		"target_path": types.StringType,
+		"strategy":    types.StringType,
+		"file_mode":   types.StringType,
+	}}
+	targetDir := filepath.Join(env.tempDir, "target")
+	mapping := func(target, strategy, mode string) attr.Value {
+		fileMode := types.StringNull()
+		if mode != "" {
+			fileMode = types.StringValue(mode)
+		}
+		return types.ObjectValueMust(mappingType.AttrTypes, map[string]attr.Value{
+			"target_path": types.StringValue(filepath.Join(targetDir, target)),
+			"strategy":    types.StringValue(strategy),
+			"file_mode":   fileMode,
+		})
+	}
+
+	data := &ApplicationResourceModel{
+		ApplicationName: types.StringValue("ssh"),
+		ConfigMappings: types.MapValueMust(mappingType, map[string]attr.Value{
+			"ssh_config": mapping("config", "copy", "0600"),
+			"id_ed25519": mapping("id_ed25519", "symlink", "0400"),
+		}),
+	}
+
+	if _, err := env.appResource.deployApplicationConfig(env.ctx, data); err != nil {
+		t.Fatalf("deployApplicationConfig failed: %v", err)
+	}
+
+	expected := map[string]os.FileMode{
+		filepath.Join(targetDir, "config"):       0600,
+		filepath.Join(env.tempDir, "id_ed25519"): 0400,
+	}
+	for path, mode := range expected {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", path, err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to have permission %o, got %o", path, mode, info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+var _ resource.Resource = &BlockResource{}
+var _ resource.ResourceWithValidateConfig = &BlockResource{}
+var _ resource.ResourceWithModifyPlan = &BlockResource{}
+
+func NewBlockResource() resource.Resource {
+	return &BlockResource{}
+}
+
+// BlockResource manages a marker-delimited block inside a file that is shared with other
+// tools, leaving the rest of the file alone.
+type BlockResource struct {
+	client *DotfilesClient
+}
+
+// BlockResourceModel describes the resource data model.
+type BlockResourceModel struct {
+	ID             types.String `tfsdk:"id"`
+	TargetPath     types.String `tfsdk:"target_path"`
+	Name           types.String `tfsdk:"name"`
+	Content        types.String `tfsdk:"content"`
+	Repository     types.String `tfsdk:"repository"`
+	SourcePath     types.String `tfsdk:"source_path"`
+	IsTemplate     types.Bool   `tfsdk:"is_template"`
+	TemplateEngine types.String `tfsdk:"template_engine"`
+	TemplateVars   types.Map    `tfsdk:"template_vars"`
+	Position       types.String `tfsdk:"position"`
+	Anchor         types.String `tfsdk:"anchor"`
+	CommentPrefix  types.String `tfsdk:"comment_prefix"`
+	FileMode       types.String `tfsdk:"file_mode"`
+	BackupEnabled  types.Bool   `tfsdk:"backup_enabled"`
+
+	ContentHash types.String `tfsdk:"content_hash"`
+	BackupPath  types.String `tfsdk:"backup_path"`
+}
+
+// validBlockPositions are the accepted values of position.
+var validBlockPositions = []string{
+	fileops.BlockPositionEnd,
+	fileops.BlockPositionBeginning,
+	fileops.BlockPositionBefore,
+	fileops.BlockPositionAfter,
+}
+
+func (r *BlockResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_block"
+}
+
+func (r *BlockResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manages a block of lines inside a file co-owned with other tools, such as shell rc files or " +
+			"`~/.ssh/config`. The block is delimited by `# BEGIN dotfiles:<name>` and `# END dotfiles:<name>` markers; " +
+			"only its content is replaced on change and only the block is removed on destroy. Edits made inside the " +
+			"block are detected as drift and reverted on the next apply.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Block identifier",
+			},
+			"target_path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "File the block is managed in. It is created when missing; a symlink is followed",
+				Validators: []validator.String{
+					validators.ValidPath(),
+					validators.EnvironmentVariableExpansion(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Block name used in the markers; unique per file",
+			},
+			"content": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Content of the block. Conflicts with `source_path`",
+			},
+			"repository": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "ID of the `dotfiles_repository` holding `source_path` (default: `dotfiles_root`)",
+			},
+			"source_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Repository file holding the content of the block. Conflicts with `content`",
+			},
+			"is_template": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Render the content as a template",
+			},
+			"template_engine": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Template engine: go, handlebars, mustache, or chezmoi. Defaults to the provider's `template_engine`",
+				Validators: []validator.String{
+					validators.ValidTemplateEngine(),
+				},
+			},
+			"template_vars": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Variables available to the template",
+			},
+			"position": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Where a new block is inserted: `end`, `beginning`, or `before`/`after` the first line " +
+					"matching `anchor` (default: end). An existing block is updated in place",
+			},
+			"anchor": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Regular expression selecting the line for `before` and `after`; without a match the block is appended",
+			},
+			"comment_prefix": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Comment prefix of the marker lines, e.g. `\"` for vimrc or `;` for INI files (default: `#`)",
+			},
+			"file_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Permissions of a file created for the block (default: 0644). Existing files keep their permissions",
+				Validators: []validator.String{
+					validators.ValidFileMode(),
+				},
+			},
+			"backup_enabled": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Back up the file before changing it. Defaults to the provider's `backup_enabled`",
+			},
+			"content_hash": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SHA-256 of the block content; empty when the block is missing from the file",
+			},
+			"backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Most recent backup of the file taken before it was changed",
+			},
+		},
+	}
+}
+
+func (r *BlockResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig checks the content source, the block name and the placement.
+func (r *BlockResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data BlockResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Content.IsUnknown() && !data.SourcePath.IsUnknown() && data.Content.IsNull() == data.SourcePath.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("content"),
+			"Invalid Block Content",
+			"Exactly one of content or source_path must be set.",
+		)
+	}
+
+	if name := data.Name.ValueString(); !data.Name.IsUnknown() && (strings.TrimSpace(name) == "" || strings.ContainsAny(name, "\r\n")) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"Invalid Block Name",
+			"name must be a non-empty, single-line string.",
+		)
+	}
+	if prefix := data.CommentPrefix.ValueString(); strings.ContainsAny(prefix, "\r\n") {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("comment_prefix"),
+			"Invalid Comment Prefix",
+			"comment_prefix must be a single-line string.",
+		)
+	}
+
+	position := data.Position.ValueString()
+	if !data.Position.IsNull() && !data.Position.IsUnknown() && !contains(validBlockPositions, position) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("position"),
+			"Invalid Block Position",
+			fmt.Sprintf("position must be one of %s, got %q.", strings.Join(validBlockPositions, ", "), position),
+		)
+	}
+	if data.Anchor.IsUnknown() {
+		return
+	}
+	needsAnchor := position == fileops.BlockPositionBefore || position == fileops.BlockPositionAfter
+	if needsAnchor && data.Anchor.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("anchor"),
+			"Missing Block Anchor",
+			fmt.Sprintf("anchor is required when position is %q.", position),
+		)
+	}
+	if _, err := regexp.Compile(data.Anchor.ValueString()); !data.Anchor.IsNull() && err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("anchor"),
+			"Invalid Block Anchor",
+			fmt.Sprintf("anchor is not a valid regular expression: %s", err),
+		)
+	}
+}
+
+// ModifyPlan plans the hash of the rendered content, so a block edited or removed outside
+// Terraform, or a changed source file, shows up as an update.
+func (r *BlockResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan BlockResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || !blockContentKnown(&plan) {
+		return
+	}
+
+	// A source in a repository that is not cloned yet is rendered on apply
+	plan.ContentHash = types.StringUnknown()
+	if body, err := r.renderBody(&plan); err == nil {
+		plan.ContentHash = types.StringValue(blockHash(body))
+	} else {
+		tflog.Debug(ctx, "Block content cannot be rendered at plan time", map[string]interface{}{
+			"name":  plan.Name.ValueString(),
+			"error": err.Error(),
+		})
+	}
+
+	// Rewriting the file on drift may take a new backup
+	if !req.State.Raw.IsNull() {
+		var state BlockResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if !plan.ContentHash.Equal(state.ContentHash) {
+			plan.BackupPath = types.StringUnknown()
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *BlockResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data BlockResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = types.StringNull()
+	if err := r.apply(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write block")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BlockResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data BlockResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	hash, err := r.currentHash(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read block")
+		return
+	}
+	if hash != data.ContentHash.ValueString() {
+		tflog.Info(ctx, "Block differs from the applied content", map[string]interface{}{
+			"target_path": data.TargetPath.ValueString(),
+			"name":        data.Name.ValueString(),
+			"present":     hash != "",
+		})
+	}
+	data.ContentHash = types.StringValue(hash)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *BlockResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state BlockResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// A renamed or moved block leaves its old location first
+	if !data.TargetPath.Equal(state.TargetPath) || !data.Name.Equal(state.Name) || !data.CommentPrefix.Equal(state.CommentPrefix) {
+		if err := r.remove(ctx, &state); err != nil {
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove block from its previous location")
+			return
+		}
+	}
+
+	data.BackupPath = state.BackupPath
+	if err := r.apply(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write block")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the block and its markers, leaving the rest of the file in place.
+func (r *BlockResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data BlockResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.remove(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove block")
+	}
+}
+
+// apply inserts or updates the block in the target file. The file is only rewritten when
+// its content changes.
+func (r *BlockResource) apply(ctx context.Context, data *BlockResourceModel) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	body, err := r.renderBody(data)
+	if err != nil {
+		return errors.IOError("render", "block", "Failed to render block content", err).WithPath(target)
+	}
+	placement, err := blockPlacement(data)
+	if err != nil {
+		return err
+	}
+	data.ID = types.StringValue(target + "#" + data.Name.ValueString())
+	data.ContentHash = types.StringValue(blockHash(body))
+
+	existing, existed, err := readShared("block", target)
+	if err != nil {
+		return err
+	}
+	updated, err := managedBlock(data).Upsert(existing, body, placement)
+	if err != nil {
+		return errors.ValidationError("update_block", "block", "File has damaged block markers", err).WithPath(target)
+	}
+	if updated == existing {
+		return nil
+	}
+
+	mode, err := sharedFileMode("block", data.FileMode)
+	if err != nil {
+		return err
+	}
+	if err := r.write(ctx, data, target, updated, existed, mode); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Wrote managed block", map[string]interface{}{
+		"target_path": target,
+		"name":        data.Name.ValueString(),
+	})
+	return nil
+}
+
+// remove deletes the block from the target file, if both still exist.
+func (r *BlockResource) remove(ctx context.Context, data *BlockResourceModel) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	existing, existed, err := readShared("block", target)
+	if err != nil || !existed {
+		return err
+	}
+	updated, err := managedBlock(data).Remove(existing)
+	if err != nil {
+		return errors.ValidationError("remove_block", "block", "File has damaged block markers", err).WithPath(target)
+	}
+	if updated == existing {
+		return nil
+	}
+	if err := r.write(ctx, data, target, updated, true, 0644); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Removed managed block", map[string]interface{}{
+		"target_path": target,
+		"name":        data.Name.ValueString(),
+	})
+	return nil
+}
+
+// write writes the updated file, recording the backup taken before the change.
+func (r *BlockResource) write(ctx context.Context, data *BlockResourceModel, target, content string, existed bool, mode os.FileMode) error {
+	backupPath, err := r.client.writeShared(ctx, "block", target, content, existed, mode, data.BackupEnabled)
+	if err != nil {
+		return err
+	}
+	if backupPath != "" {
+		data.BackupPath = types.StringValue(backupPath)
+	}
+	return nil
+}
+
+// currentHash returns the hash of the block as it is in the target file, or an empty
+// string when the file or the block is missing.
+func (r *BlockResource) currentHash(data *BlockResourceModel) (string, error) {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return "", err
+	}
+	existing, _, err := readShared("block", target)
+	if err != nil {
+		return "", err
+	}
+	body, found, err := managedBlock(data).Find(existing)
+	if err != nil {
+		return "", errors.ValidationError("read_block", "block", "File has damaged block markers", err).WithPath(target)
+	}
+	if !found {
+		return "", nil
+	}
+	return blockHash(body), nil
+}
+
+// renderBody returns the block content from content or source_path, rendered when it is a
+// template. The content always ends with a newline, matching how it is read back.
+func (r *BlockResource) renderBody(data *BlockResourceModel) (string, error) {
+	body := data.Content.ValueString()
+	if !data.SourcePath.IsNull() {
+		content, _, err := r.client.readRepositoryFile("block", data.Repository.ValueString(), data.SourcePath.ValueString())
+		if err != nil {
+			return "", err
+		}
+		body = string(content)
+	}
+
+	if data.IsTemplate.ValueBool() {
+		var err error
+		if body, err = r.client.renderTemplateContent(body, data.TemplateEngine, data.TemplateVars); err != nil {
+			return "", err
+		}
+	}
+
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	return body, nil
+}
+
+// targetPath returns the expanded path of the file holding the block.
+func (r *BlockResource) targetPath(data *BlockResourceModel) (string, error) {
+	if r.client == nil {
+		return "", errors.ConfigurationError("resolve_target", "block", "Provider is not configured", nil)
+	}
+	target, err := platform.DetectPlatform().ExpandPath(data.TargetPath.ValueString())
+	if err != nil {
+		return "", errors.ValidationError("expand_target_path", "block", "Could not expand target path", err).
+			WithPath(data.TargetPath.ValueString())
+	}
+	return target, nil
+}
+
+// managedBlock returns the block described by the model.
+func managedBlock(data *BlockResourceModel) fileops.ManagedBlock {
+	return fileops.ManagedBlock{Name: data.Name.ValueString(), CommentPrefix: data.CommentPrefix.ValueString()}
+}
+
+// blockPlacement returns where a new block is inserted.
+func blockPlacement(data *BlockResourceModel) (fileops.BlockPlacement, error) {
+	placement := fileops.BlockPlacement{Position: data.Position.ValueString()}
+	if data.Anchor.IsNull() || data.Anchor.ValueString() == "" {
+		return placement, nil
+	}
+	anchor, err := regexp.Compile(data.Anchor.ValueString())
+	if err != nil {
+		return placement, errors.ValidationError("compile_anchor", "block", "Invalid anchor", err)
+	}
+	placement.Anchor = anchor
+	return placement, nil
+}
+
+// blockContentKnown reports whether everything the block content depends on is known.
+func blockContentKnown(data *BlockResourceModel) bool {
+	return !data.Content.IsUnknown() && !data.Repository.IsUnknown() && !data.SourcePath.IsUnknown() &&
+		!data.IsTemplate.IsUnknown() && !data.TemplateEngine.IsUnknown() && !data.TemplateVars.IsUnknown()
+}
+
+// blockHash returns the SHA-256 of block content.
+func blockHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
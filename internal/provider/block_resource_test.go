@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestBlockResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewBlockResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_block" {
+			t.Errorf("Expected TypeName dotfiles_block, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewBlockResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		for _, attr := range []string{"target_path", "name"} {
+			if !resp.Schema.Attributes[attr].IsRequired() {
+				t.Errorf("Attribute %s should be required", attr)
+			}
+		}
+		for _, attr := range []string{"id", "content_hash", "backup_path"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+	})
+}
+
+func TestBlockResourceLifecycle(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "dotfiles")
+	target := filepath.Join(tempDir, "home", ".zshrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("Failed to create home: %v", err)
+	}
+	original := "# added by installer\nexport PATH=/opt/tool/bin:$PATH\n"
+	if err := os.WriteFile(target, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &BlockResource{client: &DotfilesClient{Config: &DotfilesConfig{
+		DotfilesRoot:    repoDir,
+		BackupEnabled:   true,
+		BackupDirectory: filepath.Join(tempDir, "backups"),
+	}}}
+	ctx := context.Background()
+	data := &BlockResourceModel{
+		TargetPath:    types.StringValue(target),
+		Name:          types.StringValue("aliases"),
+		Content:       types.StringValue("alias g={{ .tool }}"),
+		IsTemplate:    types.BoolValue(true),
+		TemplateVars:  types.MapValueMust(types.StringType, map[string]attr.Value{"tool": types.StringValue("git")}),
+		Position:      types.StringValue("beginning"),
+		BackupEnabled: types.BoolNull(),
+	}
+
+	if err := r.apply(ctx, data); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	content, _ := os.ReadFile(target)
+	expected := "# BEGIN dotfiles:aliases\nalias g=git\n# END dotfiles:aliases\n" + original
+	if string(content) != expected {
+		t.Errorf("Expected the rendered block before the existing content, got:\n%s", content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to keep mode 0600, got %v", info.Mode().Perm())
+	}
+	if data.BackupPath.IsNull() {
+		t.Error("Expected the file to be backed up before it was changed")
+	}
+
+	hash, err := r.currentHash(data)
+	if err != nil || hash != data.ContentHash.ValueString() {
+		t.Errorf("Expected the applied block to match content_hash, got %s (%v)", hash, err)
+	}
+
+	// Edits inside the block are drift, edits outside are not
+	edited := strings.Replace(string(content), "alias g=git", "alias g=hub", 1) + "export FOO=1\n"
+	if err := os.WriteFile(target, []byte(edited), 0600); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	if hash, _ := r.currentHash(data); hash == data.ContentHash.ValueString() {
+		t.Error("Expected an edit inside the block to change the hash")
+	}
+	if err := r.apply(ctx, data); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	content, _ = os.ReadFile(target)
+	if !strings.Contains(string(content), "alias g=git\n") || !strings.HasSuffix(string(content), "export FOO=1\n") {
+		t.Errorf("Expected the block to be restored and other edits kept, got:\n%s", content)
+	}
+
+	if err := r.remove(ctx, data); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	content, _ = os.ReadFile(target)
+	if string(content) != original+"export FOO=1\n" {
+		t.Errorf("Expected only the block to be removed, got:\n%s", content)
+	}
+	if hash, _ := r.currentHash(data); hash != "" {
+		t.Errorf("Expected an empty hash for a missing block, got %s", hash)
+	}
+}
+
+func TestBlockResourceSourceFile(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "dotfiles")
+	if err := os.MkdirAll(filepath.Join(repoDir, "ssh"), 0755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "ssh", "hosts"), []byte("Host work\n  User me\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	r := &BlockResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: repoDir}}}
+	target := filepath.Join(tempDir, "home", ".ssh", "config")
+	data := &BlockResourceModel{
+		TargetPath: types.StringValue(target),
+		Name:       types.StringValue("hosts"),
+		SourcePath: types.StringValue("ssh/hosts"),
+		FileMode:   types.StringValue("0600"),
+	}
+	if err := r.apply(context.Background(), data); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil || string(content) != "# BEGIN dotfiles:hosts\nHost work\n  User me\n# END dotfiles:hosts\n" {
+		t.Errorf("Expected a new file holding the block, got %q (%v)", content, err)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the new file to use file_mode 0600, got %v", info.Mode().Perm())
+	}
+	if !data.BackupPath.IsNull() {
+		t.Error("Expected no backup of a file that did not exist")
+	}
+
+	data.SourcePath = types.StringValue("../outside")
+	if err := r.apply(context.Background(), data); err == nil {
+		t.Error("Expected an error for a source outside the repository")
+	}
+}
@@ -0,0 +1,560 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+var _ resource.Resource = &CaptureResource{}
+var _ resource.ResourceWithModifyPlan = &CaptureResource{}
+var _ resource.ResourceWithValidateConfig = &CaptureResource{}
+
+func NewCaptureResource() resource.Resource {
+	return &CaptureResource{}
+}
+
+// CaptureResource copies local edits of copied dotfiles back into the repository working
+// tree and optionally commits them.
+type CaptureResource struct {
+	client *DotfilesClient
+}
+
+// CaptureResourceModel describes the resource data model.
+type CaptureResourceModel struct {
+	ID            types.String       `tfsdk:"id"`
+	Repository    types.String       `tfsdk:"repository"`
+	Commit        types.Bool         `tfsdk:"commit"`
+	CommitMessage types.String       `tfsdk:"commit_message"`
+	AuthorName    types.String       `tfsdk:"author_name"`
+	AuthorEmail   types.String       `tfsdk:"author_email"`
+	Files         []CaptureFileModel `tfsdk:"file"`
+
+	RepositoryPath types.String `tfsdk:"repository_path"`
+	PendingFiles   types.List   `tfsdk:"pending_files"`
+	SkippedFiles   types.List   `tfsdk:"skipped_files"`
+	CapturedFiles  types.List   `tfsdk:"captured_files"`
+	LastCommit     types.String `tfsdk:"last_commit"`
+	LastCapture    types.String `tfsdk:"last_capture"`
+}
+
+// CaptureFileModel maps a file in the repository to the target it is copied to.
+type CaptureFileModel struct {
+	SourcePath           types.String `tfsdk:"source_path"`
+	TargetPath           types.String `tfsdk:"target_path"`
+	IsTemplate           types.Bool   `tfsdk:"is_template"`
+	TemplateEngine       types.String `tfsdk:"template_engine"`
+	TemplateVars         types.Map    `tfsdk:"template_vars"`
+	PlatformTemplateVars types.Map    `tfsdk:"platform_template_vars"`
+	TemplateFunctions    types.Map    `tfsdk:"template_functions"`
+	TemplateStrict       types.Bool   `tfsdk:"template_strict"`
+	TemplateDataFiles    types.List   `tfsdk:"template_data_files"`
+}
+
+// captureScan is the outcome of comparing targets with their sources.
+type captureScan struct {
+	// pending lists source paths whose targets were edited and can be copied back
+	pending []string
+	// skipped lists template source paths whose rendered output was edited by hand
+	skipped []string
+	// unrendered maps template source paths that could not be rendered to the reason
+	unrendered map[string]string
+}
+
+func (r *CaptureResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_capture"
+}
+
+func (r *CaptureResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	templateAttributes := GetEnhancedTemplateAttributes()
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Copies local edits of copied dotfiles back into the repository working tree and optionally commits them. " +
+			"Targets are compared with their sources on every refresh; edited targets are captured on the next apply. " +
+			"Templates cannot be captured automatically and are reported in `skipped_files` instead.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Capture identifier",
+			},
+			"repository": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "ID of the `dotfiles_repository` whose working tree receives the edits (default: `dotfiles_root`)",
+			},
+			"commit": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commit captured files to the repository (default: false)",
+			},
+			"commit_message": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Subject line of capture commits. The captured files are always listed in the body",
+			},
+			"author_name": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Author name of capture commits (default: `user.name` from the Git configuration)",
+			},
+			"author_email": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Author email of capture commits (default: `user.email` from the Git configuration)",
+			},
+			"repository_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Working tree the edits are copied into",
+			},
+			"pending_files": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Source paths whose targets were edited and will be captured on the next apply",
+			},
+			"skipped_files": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Template source paths whose rendered targets were edited and must be updated by hand",
+			},
+			"captured_files": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Source paths captured by the last apply",
+			},
+			"last_commit": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SHA of the last capture commit",
+			},
+			"last_capture": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Timestamp of the last capture (RFC 3339)",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"file": schema.ListNestedBlock{
+				MarkdownDescription: "A copied dotfile, as configured on its `dotfiles_file`. Templates take the same template settings, so the expected output can be rendered",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"source_path": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Path of the file in the repository",
+						},
+						"target_path": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Path the file is copied to",
+						},
+						"is_template": schema.BoolAttribute{
+							Optional:            true,
+							MarkdownDescription: "Whether the source is a template. Edited templates are reported, never captured",
+						},
+						"template_engine": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Template engine used to render the source: go (default), handlebars, mustache or chezmoi",
+							Validators: []validator.String{
+								validators.ValidTemplateEngine(),
+							},
+						},
+						"template_vars": schema.MapAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Variables used to render the source",
+						},
+						"platform_template_vars": templateAttributes["platform_template_vars"],
+						"template_functions":     templateAttributes["template_functions"],
+						"template_strict":        templateAttributes["template_strict"],
+						"template_data_files":    templateAttributes["template_data_files"],
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *CaptureResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig requires the commit author to be configured completely or not at all.
+func (r *CaptureResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data CaptureResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.AuthorName.IsUnknown() && !data.AuthorEmail.IsUnknown() && data.AuthorName.IsNull() != data.AuthorEmail.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("author_name"),
+			"Incomplete Commit Author",
+			"author_name and author_email must be set together.",
+		)
+	}
+}
+
+func (r *CaptureResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data CaptureResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.LastCommit = types.StringNull()
+	data.LastCapture = types.StringNull()
+	scan, err := r.capture(ctx, &data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to capture local edits")
+		return
+	}
+	r.warnSkipped(ctx, &data, &resp.Diagnostics)
+	warnUnrendered(ctx, scan, &resp.Diagnostics)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *CaptureResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data CaptureResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	repoPath, err := r.repositoryPath(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to locate repository")
+		return
+	}
+	scan, err := r.scan(ctx, &data, repoPath)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to compare targets with the repository")
+		return
+	}
+	warnUnrendered(ctx, scan, &resp.Diagnostics)
+
+	data.RepositoryPath = types.StringValue(repoPath)
+	data.PendingFiles = stringList(scan.pending)
+	data.SkippedFiles = stringList(scan.skipped)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *CaptureResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state CaptureResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Captures that find nothing new keep reporting the previous one
+	data.LastCommit = state.LastCommit
+	data.LastCapture = state.LastCapture
+	scan, err := r.capture(ctx, &data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to capture local edits")
+		return
+	}
+	r.warnSkipped(ctx, &data, &resp.Diagnostics)
+	warnUnrendered(ctx, scan, &resp.Diagnostics)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// ModifyPlan plans a capture when the refresh found edited targets.
+func (r *CaptureResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Creation captures anyway; nothing to do on destroy
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state CaptureResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.PendingFiles.IsNull() || len(state.PendingFiles.Elements()) == 0 {
+		return
+	}
+
+	tflog.Info(ctx, "Planning capture of edited targets", map[string]interface{}{
+		"pending_files": state.PendingFiles.String(),
+	})
+	// Targets may be edited again before apply, or left pending in dry run mode
+	plan.PendingFiles = types.ListUnknown(types.StringType)
+	plan.SkippedFiles = types.ListUnknown(types.StringType)
+	plan.CapturedFiles = types.ListUnknown(types.StringType)
+	plan.LastCapture = types.StringUnknown()
+	plan.LastCommit = types.StringUnknown()
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *CaptureResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Captured edits belong to the repository; nothing to undo
+	tflog.Debug(ctx, "Removing capture resource from state")
+}
+
+// capture copies edited targets into the repository and commits them when configured,
+// filling the computed attributes. It returns the scan the capture was based on.
+func (r *CaptureResource) capture(ctx context.Context, data *CaptureResourceModel) (*captureScan, error) {
+	repoPath, err := r.repositoryPath(data)
+	if err != nil {
+		return nil, err
+	}
+	scan, err := r.scan(ctx, data, repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data.ID = types.StringValue(repoPath)
+	data.RepositoryPath = types.StringValue(repoPath)
+	data.PendingFiles = stringList(nil)
+	data.SkippedFiles = stringList(scan.skipped)
+	data.CapturedFiles = stringList(scan.pending)
+	if len(scan.pending) == 0 {
+		return scan, nil
+	}
+
+	if r.client.Config != nil && r.client.Config.DryRun {
+		tflog.Info(ctx, "DRY RUN: Skipping capture of edited targets", map[string]interface{}{
+			"repository_path": repoPath,
+			"files":           scan.pending,
+		})
+		data.PendingFiles = stringList(scan.pending)
+		data.CapturedFiles = stringList(nil)
+		return scan, nil
+	}
+
+	targets := r.targetPaths(data)
+	for _, sourcePath := range scan.pending {
+		source := filepath.Join(repoPath, sourcePath)
+		if err := copyBack(targets[sourcePath], source); err != nil {
+			return nil, errors.IOError("capture_file", "capture", "Failed to copy target back into the repository", err).
+				WithPath(source).
+				WithContext("target_path", targets[sourcePath])
+		}
+	}
+	tflog.Info(ctx, "Captured edited targets", map[string]interface{}{
+		"repository_path": repoPath,
+		"files":           scan.pending,
+	})
+	data.LastCapture = types.StringValue(time.Now().Format(time.RFC3339))
+
+	if !data.Commit.ValueBool() {
+		return scan, nil
+	}
+	var author *git.Author
+	if !data.AuthorName.IsNull() {
+		author = &git.Author{Name: data.AuthorName.ValueString(), Email: data.AuthorEmail.ValueString()}
+	}
+	hash, err := git.CommitFiles(repoPath, scan.pending, captureCommitMessage(data.CommitMessage.ValueString(), scan.pending), author)
+	if err != nil {
+		return nil, errors.GitError("commit", "capture", "Failed to commit captured files", err).
+			WithPath(repoPath)
+	}
+	if hash != "" {
+		data.LastCommit = types.StringValue(hash)
+	}
+	return scan, nil
+}
+
+// scan compares every target with its source. Missing targets have nothing to capture, and
+// templates that cannot be rendered are recorded rather than failing the comparison.
+func (r *CaptureResource) scan(ctx context.Context, data *CaptureResourceModel, repoPath string) (*captureScan, error) {
+	result := &captureScan{unrendered: make(map[string]string)}
+	targets := r.targetPaths(data)
+
+	for _, file := range data.Files {
+		sourcePath := file.SourcePath.ValueString()
+		source, err := repositoryFile(repoPath, sourcePath)
+		if err != nil {
+			return nil, errors.ValidationError("scan", "capture", "Invalid source path", err).WithPath(sourcePath)
+		}
+
+		targetContent, err := os.ReadFile(targets[sourcePath])
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.IOError("scan", "capture", "Failed to read target", err).WithPath(targets[sourcePath])
+		}
+
+		if file.IsTemplate.ValueBool() {
+			expected, err := r.renderedTemplate(ctx, data, file, source)
+			if err != nil {
+				result.unrendered[sourcePath] = err.Error()
+				continue
+			}
+			if !bytes.Equal(expected, targetContent) {
+				result.skipped = append(result.skipped, sourcePath)
+			}
+			continue
+		}
+
+		// A missing source is treated as empty so new files can be captured
+		expected, err := os.ReadFile(source)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.IOError("scan", "capture", "Failed to read source", err).WithPath(source)
+		}
+		if !bytes.Equal(expected, targetContent) {
+			result.pending = append(result.pending, sourcePath)
+		}
+	}
+	return result, nil
+}
+
+// renderedTemplate renders a template source the way dotfiles_file does, with the same
+// engine, variables, data files, functions and strictness.
+func (r *CaptureResource) renderedTemplate(ctx context.Context, data *CaptureResourceModel, file CaptureFileModel, source string) ([]byte, error) {
+	model := &EnhancedFileResourceModelWithTemplate{
+		TemplateEngine:       file.TemplateEngine,
+		PlatformTemplateVars: file.PlatformTemplateVars,
+		TemplateFunctions:    file.TemplateFunctions,
+		TemplateStrict:       file.TemplateStrict,
+		TemplateDataFiles:    file.TemplateDataFiles,
+	}
+	model.Repository = data.Repository
+	model.SourcePath = file.SourcePath
+	model.IsTemplate = file.IsTemplate
+	model.TemplateVars = file.TemplateVars
+
+	fileResource := &FileResource{client: r.client}
+	config, err := buildEnhancedTemplateConfig(model)
+	if err != nil {
+		return nil, err
+	}
+	if err := fileResource.loadTemplateData(ctx, model, config); err != nil {
+		return nil, err
+	}
+	rendered, err := fileResource.renderTemplate(source, config)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(rendered), nil
+}
+
+// targetPaths returns the expanded target path of each file, keyed by source path.
+func (r *CaptureResource) targetPaths(data *CaptureResourceModel) map[string]string {
+	platformProvider := platform.DetectPlatform()
+	targets := make(map[string]string, len(data.Files))
+	for _, file := range data.Files {
+		target := file.TargetPath.ValueString()
+		if expanded, err := platformProvider.ExpandPath(target); err == nil {
+			target = expanded
+		}
+		targets[file.SourcePath.ValueString()] = target
+	}
+	return targets
+}
+
+// repositoryPath returns the working tree edits are copied into.
+func (r *CaptureResource) repositoryPath(data *CaptureResourceModel) (string, error) {
+	if r.client == nil {
+		return "", errors.ConfigurationError("resolve_repository", "capture", "Provider is not configured", nil)
+	}
+	repoPath, err := r.client.RepositoryRoot(data.Repository.ValueString())
+	if err != nil {
+		return "", errors.ConfigurationError("resolve_repository", "capture", "No repository to capture into", err)
+	}
+	return repoPath, nil
+}
+
+// warnSkipped reports edited templates, which have to be updated by hand.
+func (r *CaptureResource) warnSkipped(ctx context.Context, data *CaptureResourceModel, diags *diag.Diagnostics) {
+	if len(data.SkippedFiles.Elements()) == 0 {
+		return
+	}
+	errors.AddWarningToDiagnostics(ctx, diags, "Edited templates were not captured",
+		fmt.Sprintf("The rendered targets of %s differ from their templates. Port the edits to the templates by hand.", data.SkippedFiles.String()))
+}
+
+// warnUnrendered reports templates whose expected output could not be rendered, so edits to
+// their targets cannot be detected.
+func warnUnrendered(ctx context.Context, scan *captureScan, diags *diag.Diagnostics) {
+	if scan == nil || len(scan.unrendered) == 0 {
+		return
+	}
+	sources := make([]string, 0, len(scan.unrendered))
+	for sourcePath := range scan.unrendered {
+		sources = append(sources, sourcePath)
+	}
+	sort.Strings(sources)
+	details := make([]string, 0, len(sources))
+	for _, sourcePath := range sources {
+		details = append(details, fmt.Sprintf("%s: %s", sourcePath, scan.unrendered[sourcePath]))
+	}
+	errors.AddWarningToDiagnostics(ctx, diags, "Templates could not be rendered",
+		fmt.Sprintf("Edits to the targets of these templates cannot be detected until they render with the settings of their dotfiles_file: %s", strings.Join(details, "; ")))
+}
+
+// repositoryFile joins a source path onto the repository root, rejecting paths that escape it.
+func repositoryFile(repoPath, sourcePath string) (string, error) {
+	if filepath.IsAbs(sourcePath) {
+		return "", fmt.Errorf("source path %s must be relative to the repository", sourcePath)
+	}
+	source := filepath.Join(repoPath, sourcePath)
+	rel, err := filepath.Rel(repoPath, source)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("source path %s is outside the repository", sourcePath)
+	}
+	return source, nil
+}
+
+// copyBack replaces source with the content of target, keeping the source's permissions.
+func copyBack(target, source string) error {
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(source); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(source, content, mode)
+}
+
+// captureCommitMessage builds the message of a capture commit, listing the captured files.
+func captureCommitMessage(subject string, files []string) string {
+	if subject == "" {
+		noun := "files"
+		if len(files) == 1 {
+			noun = "file"
+		}
+		subject = fmt.Sprintf("Capture local edits to %d %s", len(files), noun)
+	}
+
+	var message strings.Builder
+	message.WriteString(subject)
+	message.WriteString("\n\n")
+	for _, file := range files {
+		fmt.Fprintf(&message, "- %s\n", filepath.ToSlash(file))
+	}
+	return message.String()
+}
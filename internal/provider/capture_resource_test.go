@@ -0,0 +1,378 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
+)
+
+func TestCaptureResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewCaptureResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_capture" {
+			t.Errorf("Expected TypeName dotfiles_capture, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewCaptureResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		for _, attr := range []string{"repository", "commit", "commit_message", "author_name", "author_email"} {
+			if !resp.Schema.Attributes[attr].IsOptional() {
+				t.Errorf("Attribute %s should be optional", attr)
+			}
+		}
+		for _, attr := range []string{"id", "repository_path", "pending_files", "skipped_files", "captured_files", "last_commit", "last_capture"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+		file, ok := resp.Schema.Blocks["file"].(schema.ListNestedBlock)
+		if !ok {
+			t.Fatal("Schema should have a file block")
+		}
+		engine := file.NestedObject.Attributes["template_engine"].(schema.StringAttribute)
+		if len(engine.Validators) == 0 {
+			t.Error("template_engine should be validated against the known template engines")
+		}
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		r := NewCaptureResource().(*CaptureResource)
+		ctx := context.Background()
+
+		resp := &resource.ConfigureResponse{}
+		r.Configure(ctx, resource.ConfigureRequest{ProviderData: &DotfilesClient{}}, resp)
+		if resp.Diagnostics.HasError() || r.client == nil {
+			t.Errorf("Configure with valid client failed: %v", resp.Diagnostics)
+		}
+
+		resp = &resource.ConfigureResponse{}
+		r.Configure(ctx, resource.ConfigureRequest{ProviderData: "invalid"}, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Error("Configure with invalid provider data should error")
+		}
+	})
+}
+
+func TestCaptureResourceCapture(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "dotfiles")
+	repo, err := gogit.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commitUpstreamFile(t, repo, "gitconfig.tmpl", "[user]\n  name = {{.name}}\n")
+	initial := commitUpstreamFile(t, repo, "zshrc", "export EDITOR=vim\n")
+
+	homeDir := filepath.Join(tempDir, "home")
+	if err := os.MkdirAll(homeDir, 0755); err != nil {
+		t.Fatalf("Failed to create home directory: %v", err)
+	}
+	writeTarget := func(name, content string) string {
+		target := filepath.Join(homeDir, name)
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return target
+	}
+	zshrc := writeTarget(".zshrc", "export EDITOR=vim\n")
+	gitconfig := writeTarget(".gitconfig", "[user]\n  name = Test\n")
+
+	r := &CaptureResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: repoDir}, HomeDir: homeDir}}
+	ctx := context.Background()
+	data := &CaptureResourceModel{
+		Repository:    types.StringNull(),
+		Commit:        types.BoolValue(true),
+		CommitMessage: types.StringNull(),
+		AuthorName:    types.StringValue("Capture"),
+		AuthorEmail:   types.StringValue("capture@example.com"),
+		LastCommit:    types.StringNull(),
+		Files: []CaptureFileModel{
+			{SourcePath: types.StringValue("zshrc"), TargetPath: types.StringValue(zshrc), IsTemplate: types.BoolNull(), TemplateEngine: types.StringNull(), TemplateVars: types.MapNull(types.StringType)},
+			{
+				SourcePath:     types.StringValue("gitconfig.tmpl"),
+				TargetPath:     types.StringValue(gitconfig),
+				IsTemplate:     types.BoolValue(true),
+				TemplateEngine: types.StringNull(),
+				TemplateVars:   types.MapValueMust(types.StringType, map[string]attr.Value{"name": types.StringValue("Test")}),
+			},
+			{SourcePath: types.StringValue("vimrc"), TargetPath: types.StringValue(filepath.Join(homeDir, ".vimrc")), IsTemplate: types.BoolNull(), TemplateEngine: types.StringNull(), TemplateVars: types.MapNull(types.StringType)},
+		},
+	}
+
+	// Unchanged targets and missing targets have nothing to capture
+	scan, err := r.scan(ctx, data, repoDir)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if len(scan.pending) != 0 || len(scan.skipped) != 0 {
+		t.Fatalf("Expected nothing to capture, got %+v", scan)
+	}
+
+	writeTarget(".zshrc", "export EDITOR=nvim\n")
+	writeTarget(".gitconfig", "[user]\n  name = Edited\n")
+	scan, err = r.scan(ctx, data, repoDir)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if !reflect.DeepEqual(scan.pending, []string{"zshrc"}) || !reflect.DeepEqual(scan.skipped, []string{"gitconfig.tmpl"}) {
+		t.Fatalf("Expected zshrc pending and gitconfig.tmpl skipped, got %+v", scan)
+	}
+
+	if _, err := r.capture(ctx, data); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(repoDir, "zshrc"))
+	if err != nil || string(content) != "export EDITOR=nvim\n" {
+		t.Errorf("Expected edit to be copied back, got %q, %v", content, err)
+	}
+	tmpl, err := os.ReadFile(filepath.Join(repoDir, "gitconfig.tmpl"))
+	if err != nil || !strings.Contains(string(tmpl), "{{.name}}") {
+		t.Errorf("Template should not be overwritten, got %q, %v", tmpl, err)
+	}
+
+	head, err := git.HeadCommit(repoDir)
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	if head.Hash == initial || data.LastCommit.ValueString() != head.Hash {
+		t.Errorf("Expected capture commit in last_commit, got %s (HEAD %s)", data.LastCommit, head.Hash)
+	}
+	if head.Author != "Capture" || !strings.Contains(head.Message, "- zshrc") {
+		t.Errorf("Unexpected capture commit %+v", head)
+	}
+	var captured []string
+	data.CapturedFiles.ElementsAs(ctx, &captured, false)
+	if !reflect.DeepEqual(captured, []string{"zshrc"}) || len(data.PendingFiles.Elements()) != 0 {
+		t.Errorf("Expected zshrc captured and nothing pending, got captured=%v pending=%v", captured, data.PendingFiles)
+	}
+
+	// A second capture finds nothing new and keeps the last commit
+	if _, err := r.capture(ctx, data); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if data.LastCommit.ValueString() != head.Hash || len(data.CapturedFiles.Elements()) != 0 {
+		t.Errorf("Expected no new capture, got last_commit=%s captured=%v", data.LastCommit, data.CapturedFiles)
+	}
+}
+
+func TestCaptureResourceSubdirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	checkout := filepath.Join(tempDir, "dotfiles")
+	repo, err := gogit.PlainInit(checkout, false)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(checkout, "home"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	initial := commitUpstreamFile(t, repo, filepath.Join("home", "zshrc"), "export EDITOR=vim\n")
+
+	repository := &RepositoryResource{}
+	repoData := &RepositoryResourceModel{
+		LocalPath:    types.StringValue(checkout),
+		Subdirectory: types.StringValue("home"),
+	}
+	if err := repository.setRootPath(repoData); err != nil {
+		t.Fatalf("setRootPath failed: %v", err)
+	}
+
+	target := filepath.Join(tempDir, ".zshrc")
+	if err := os.WriteFile(target, []byte("export EDITOR=nvim\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+	r := &CaptureResource{client: &DotfilesClient{Config: &DotfilesConfig{}, HomeDir: tempDir}}
+	ctx := context.Background()
+	data := &CaptureResourceModel{
+		Repository:  repoData.RootPath,
+		Commit:      types.BoolValue(true),
+		AuthorName:  types.StringValue("Capture"),
+		AuthorEmail: types.StringValue("capture@example.com"),
+		LastCommit:  types.StringNull(),
+		Files: []CaptureFileModel{
+			{SourcePath: types.StringValue("zshrc"), TargetPath: types.StringValue(target), IsTemplate: types.BoolNull(), TemplateEngine: types.StringNull(), TemplateVars: types.MapNull(types.StringType)},
+		},
+	}
+
+	if _, err := r.capture(ctx, data); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	head, err := git.HeadCommit(checkout)
+	if err != nil {
+		t.Fatalf("HeadCommit failed: %v", err)
+	}
+	if head.Hash == initial || data.LastCommit.ValueString() != head.Hash {
+		t.Errorf("Expected capture commit in last_commit, got %s (HEAD %s)", data.LastCommit, head.Hash)
+	}
+	status, err := git.RepositoryStatus(checkout)
+	if err != nil {
+		t.Fatalf("RepositoryStatus failed: %v", err)
+	}
+	if status.IsDirty() {
+		t.Errorf("Expected home/zshrc to be committed, got %v", status.ModifiedFiles)
+	}
+}
+
+func TestCaptureResourceDryRun(t *testing.T) {
+	repoDir := t.TempDir()
+	source := filepath.Join(repoDir, "zshrc")
+	if err := os.WriteFile(source, []byte("original\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	target := filepath.Join(t.TempDir(), ".zshrc")
+	if err := os.WriteFile(target, []byte("edited\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &CaptureResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: repoDir, DryRun: true}}}
+	data := &CaptureResourceModel{
+		Repository: types.StringNull(),
+		Files:      []CaptureFileModel{{SourcePath: types.StringValue("zshrc"), TargetPath: types.StringValue(target)}},
+	}
+	if _, err := r.capture(context.Background(), data); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if content, _ := os.ReadFile(source); string(content) != "original\n" {
+		t.Errorf("Dry run should not modify the repository, got %q", content)
+	}
+	if len(data.PendingFiles.Elements()) != 1 {
+		t.Errorf("Expected edit to remain pending, got %v", data.PendingFiles)
+	}
+}
+
+func TestCaptureResourceRenderedTemplate(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "gitconfig.tmpl")
+	if err := os.WriteFile(source, []byte("os = {{ .chezmoi.os }}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	r := &CaptureResource{client: &DotfilesClient{Config: &DotfilesConfig{}, Platform: "linux"}}
+	file := CaptureFileModel{
+		SourcePath:     types.StringValue("gitconfig.tmpl"),
+		IsTemplate:     types.BoolValue(true),
+		TemplateEngine: types.StringValue(TemplateEngineChezmoi),
+		TemplateVars:   types.MapNull(types.StringType),
+	}
+	rendered, err := r.renderedTemplate(context.Background(), &CaptureResourceModel{Repository: types.StringNull()}, file, source)
+	if err != nil {
+		t.Fatalf("renderedTemplate failed: %v", err)
+	}
+	if string(rendered) != "os = linux\n" {
+		t.Errorf("Expected the chezmoi template to render, got %q", rendered)
+	}
+}
+
+func TestRepositoryFile(t *testing.T) {
+	for _, sourcePath := range []string{"zshrc", filepath.Join("config", "nvim", "init.lua")} {
+		if _, err := repositoryFile("/dotfiles", sourcePath); err != nil {
+			t.Errorf("Expected %s to be accepted: %v", sourcePath, err)
+		}
+	}
+	for _, sourcePath := range []string{"/etc/passwd", filepath.Join("..", "secrets"), filepath.Join("config", "..", "..", "x")} {
+		if _, err := repositoryFile("/dotfiles", sourcePath); err == nil {
+			t.Errorf("Expected %s to be rejected", sourcePath)
+		}
+	}
+}
+
+func TestCaptureCommitMessage(t *testing.T) {
+	message := captureCommitMessage("", []string{"zshrc", filepath.Join("config", "git")})
+	expected := "Capture local edits to 2 files\n\n- zshrc\n- config/git\n"
+	if message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+
+	message = captureCommitMessage("Upstream laptop tweaks", []string{"zshrc"})
+	if !strings.HasPrefix(message, "Upstream laptop tweaks\n\n- zshrc") {
+		t.Errorf("Expected custom subject, got %q", message)
+	}
+}
+
+func TestCaptureResourceTemplateSettings(t *testing.T) {
+	repoDir := t.TempDir()
+	homeDir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(filepath.Join(repoDir, "data", "team.yaml"), "editor: nvim\n")
+	write(filepath.Join(repoDir, "profile.tmpl"), "export EDITOR={{ .editor }}\nexport SHELL={{ .shell }}\n")
+	write(filepath.Join(repoDir, "strict.tmpl"), "name = {{ .missing }}\n")
+	write(filepath.Join(homeDir, ".profile"), "export EDITOR=nvim\nexport SHELL=/bin/zsh\n")
+	write(filepath.Join(homeDir, ".strict"), "name = edited\n")
+
+	r := &CaptureResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: repoDir}, Platform: "linux"}}
+	ctx := context.Background()
+	data := &CaptureResourceModel{
+		Repository: types.StringNull(),
+		Files: []CaptureFileModel{
+			{
+				SourcePath:        types.StringValue("profile.tmpl"),
+				TargetPath:        types.StringValue(filepath.Join(homeDir, ".profile")),
+				IsTemplate:        types.BoolValue(true),
+				TemplateEngine:    types.StringNull(),
+				TemplateVars:      types.MapNull(types.StringType),
+				TemplateDataFiles: types.ListValueMust(types.StringType, []attr.Value{types.StringValue("data/team.yaml")}),
+				PlatformTemplateVars: types.MapValueMust(types.ObjectType{AttrTypes: map[string]attr.Type{"shell": types.StringType}},
+					map[string]attr.Value{
+						"linux": types.ObjectValueMust(map[string]attr.Type{"shell": types.StringType}, map[string]attr.Value{"shell": types.StringValue("/bin/zsh")}),
+					}),
+			},
+			{
+				SourcePath:     types.StringValue("strict.tmpl"),
+				TargetPath:     types.StringValue(filepath.Join(homeDir, ".strict")),
+				IsTemplate:     types.BoolValue(true),
+				TemplateEngine: types.StringNull(),
+				TemplateVars:   types.MapNull(types.StringType),
+				TemplateStrict: types.BoolValue(true),
+			},
+		},
+	}
+
+	// Templates render with the settings of their dotfiles_file, and failures do not fail the scan
+	scan, err := r.scan(ctx, data, repoDir)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if len(scan.pending) != 0 || len(scan.skipped) != 0 {
+		t.Errorf("Expected an unedited template with nothing to report, got %+v", scan)
+	}
+	if _, ok := scan.unrendered["strict.tmpl"]; !ok || len(scan.unrendered) != 1 {
+		t.Errorf("Expected strict.tmpl to be reported as unrendered, got %v", scan.unrendered)
+	}
+
+	var diags diag.Diagnostics
+	warnUnrendered(ctx, scan, &diags)
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Errorf("Expected a single warning for unrendered templates, got %v", diags)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 )
 
@@ -25,6 +27,48 @@ type DotfilesClient struct {
 
 	// Concurrency management
 	ConcurrencyManager *services.ConcurrencyManager
+
+	// Upstream commits fetched during this run, keyed by local repository path
+	UpstreamCache *git.UpstreamCache
+
+	// Shared bare mirrors of remote repositories, nil unless git_mirror_directory is set
+	MirrorCache *git.MirrorCache
+
+	// Root paths of repositories read or applied in this process, keyed by repository name,
+	// for dependent state recorded before repository IDs carried the root path
+	repositories sync.Map
+}
+
+// RegisterRepository records the root path of a repository by name, so dependent state
+// recorded before repository IDs carried the root path can still be resolved in this process.
+func (c *DotfilesClient) RegisterRepository(name, rootPath string) {
+	c.repositories.Store(name, rootPath)
+}
+
+// ForgetRepository removes a repository recorded with RegisterRepository.
+func (c *DotfilesClient) ForgetRepository(name string) {
+	c.repositories.Delete(name)
+}
+
+// RepositoryRoot returns the working tree files of a repository are read from. The ID of a
+// dotfiles_repository is its root path, which honours its subdirectory, so it is used as is.
+// An empty ID selects dotfiles_root. Any other ID must name a repository registered in this
+// process; otherwise it is an error rather than a silent fallback, so files are never read
+// from or written to the wrong tree.
+func (c *DotfilesClient) RepositoryRoot(id string) (string, error) {
+	if id == "" {
+		if c.Config == nil || c.Config.DotfilesRoot == "" {
+			return "", fmt.Errorf("no repository is set and dotfiles_root is not set")
+		}
+		return c.Config.DotfilesRoot, nil
+	}
+	if filepath.IsAbs(id) {
+		return id, nil
+	}
+	if rootPath, ok := c.repositories.Load(id); ok {
+		return rootPath.(string), nil
+	}
+	return "", fmt.Errorf("repository %q is not the ID of a dotfiles_repository: reference dotfiles_repository.<name>.id", id)
 }
 
 // NewDotfilesClient creates a new dotfiles client with the provided configuration.
@@ -52,7 +96,19 @@ func NewDotfilesClient(config *DotfilesConfig) (*DotfilesClient, error) {
 	client.ConfigDir = getConfigDir(client.Platform, homeDir)
 
 	// Initialize concurrency manager
-	client.ConcurrencyManager = services.NewConcurrencyManager(DefaultMaxConcurrency)
+	maxConcurrency := config.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+	client.ConcurrencyManager = services.NewConcurrencyManager(maxConcurrency)
+	client.UpstreamCache = git.NewUpstreamCache()
+	if config.GitMirrorDirectory != "" {
+		maxAge, err := config.MirrorMaxAge()
+		if err != nil {
+			return nil, err
+		}
+		client.MirrorCache = git.NewMirrorCache(config.GitMirrorDirectory, maxAge)
+	}
 
 	// Initialize services
 	serviceConfig := services.ServiceConfig{
@@ -4,6 +4,7 @@
 package provider
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
@@ -143,3 +144,37 @@ func testPlatformValidation(t *testing.T, env *platformDetectionTestEnv) {
 		t.Error("Provider should be detected")
 	}
 }
+
+func TestDotfilesClientRepositoryRoot(t *testing.T) {
+	client := &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: "/dotfiles"}}
+	root := filepath.Join(t.TempDir(), "work")
+
+	// Repository IDs carry their root path, so another process resolves them unregistered
+	if resolved, err := client.RepositoryRoot(root); err != nil || resolved != root {
+		t.Errorf("Expected the root path carried by the ID, got %q, %v", resolved, err)
+	}
+	if resolved, err := client.RepositoryRoot(""); err != nil || resolved != "/dotfiles" {
+		t.Errorf("Expected dotfiles_root without a repository, got %q, %v", resolved, err)
+	}
+
+	// Names recorded as IDs by older state resolve in the process that read the repository
+	client.RegisterRepository("work", root)
+	if resolved, err := client.RepositoryRoot("work"); err != nil || resolved != root {
+		t.Errorf("Expected registered repository root, got %q, %v", resolved, err)
+	}
+
+	// Unknown names never fall back to dotfiles_root
+	client.ForgetRepository("work")
+	if resolved, err := client.RepositoryRoot("work"); err == nil {
+		t.Errorf("Expected error for an unregistered repository, got %q", resolved)
+	}
+	if _, err := (&DotfilesClient{}).RepositoryRoot(""); err == nil {
+		t.Error("Expected error without dotfiles_root")
+	}
+}
+
+// withRepository registers a repository named id with root path root and returns client.
+func withRepository(client *DotfilesClient, id, root string) *DotfilesClient {
+	client.RegisterRepository(id, root)
+	return client
+}
@@ -39,14 +39,14 @@ func TestProviderIntegration(t *testing.T) {
 
 		// Test resource registration
 		resources := p.Resources(ctx)
-		if len(resources) != 6 {
-			t.Errorf("Expected 6 resources, got %d", len(resources))
+		if len(resources) != 13 {
+			t.Errorf("Expected 13 resources, got %d", len(resources))
 		}
 
 		// Test data source registration
 		dataSources := p.DataSources(ctx)
-		if len(dataSources) != 2 {
-			t.Errorf("Expected 2 data sources, got %d", len(dataSources))
+		if len(dataSources) != 3 {
+			t.Errorf("Expected 3 data sources, got %d", len(dataSources))
 		}
 
 		// Test functions registration (available in DotfilesProvider interface)
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // DotfilesConfig holds the provider configuration.
@@ -23,6 +24,9 @@ type DotfilesConfig struct {
 	TargetPlatform     string
 	TemplateEngine     string
 	LogLevel           string
+	MaxConcurrency     int
+	GitMirrorDirectory string
+	GitMirrorMaxAge    string
 }
 
 // SetDefaults sets default values for the provider configuration.
@@ -61,6 +65,10 @@ func (c *DotfilesConfig) SetDefaults() error {
 	if c.LogLevel == "" {
 		c.LogLevel = DefaultLogLevel
 	}
+	// max_concurrency is only zero when unset; the schema rejects an explicit 0
+	if c.MaxConcurrency == 0 {
+		c.MaxConcurrency = DefaultMaxConcurrency
+	}
 
 	return nil
 }
@@ -100,6 +108,13 @@ func (c *DotfilesConfig) validatePaths(errs *[]string) {
 			*errs = append(*errs, err.Error())
 		}
 	}
+
+	// The mirror directory is created on first use
+	if c.GitMirrorDirectory != "" {
+		if err := c.validateAndExpandPath(&c.GitMirrorDirectory, "git_mirror_directory", false); err != nil {
+			*errs = append(*errs, err.Error())
+		}
+	}
 }
 
 // validateEnumFields validates enum-type configuration fields
@@ -128,6 +143,18 @@ func (c *DotfilesConfig) validateEnumFields(errs *[]string) {
 	if !contains(ValidLogLevels, c.LogLevel) {
 		*errs = append(*errs, fmt.Sprintf("invalid log_level '%s', must be one of: %v", c.LogLevel, ValidLogLevels))
 	}
+
+	// Validate mirror freshness window
+	if c.GitMirrorMaxAge != "" {
+		if _, err := c.MirrorMaxAge(); err != nil {
+			*errs = append(*errs, err.Error())
+		}
+	}
+
+	// Validate concurrency limit (zero is only left behind when SetDefaults was not called)
+	if c.MaxConcurrency != 0 && (c.MaxConcurrency < MinConcurrency || c.MaxConcurrency > MaxConcurrency) {
+		*errs = append(*errs, fmt.Sprintf("invalid max_concurrency %d, must be between %d and %d", c.MaxConcurrency, MinConcurrency, MaxConcurrency))
+	}
 }
 
 // validateAndExpandPath validates and expands a path configuration
@@ -158,6 +185,18 @@ func (c *DotfilesConfig) validateAndExpandPath(pathPtr *string, fieldName string
 	return nil
 }
 
+// MirrorMaxAge parses git_mirror_max_age, returning zero when it is unset.
+func (c *DotfilesConfig) MirrorMaxAge() (time.Duration, error) {
+	if c.GitMirrorMaxAge == "" {
+		return 0, nil
+	}
+	maxAge, err := time.ParseDuration(c.GitMirrorMaxAge)
+	if err != nil || maxAge < 0 {
+		return 0, fmt.Errorf("invalid git_mirror_max_age '%s', must be a non-negative duration such as '1h' or '30m'", c.GitMirrorMaxAge)
+	}
+	return maxAge, nil
+}
+
 // contains checks if a slice contains a string.
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
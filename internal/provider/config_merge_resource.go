@@ -0,0 +1,750 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/configmerge"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+var _ resource.Resource = &ConfigMergeResource{}
+var _ resource.ResourceWithValidateConfig = &ConfigMergeResource{}
+var _ resource.ResourceWithModifyPlan = &ConfigMergeResource{}
+
+// Merge strategies of dotfiles_config_merge.
+const (
+	mergeStrategyOverlay = "overlay"
+	mergeStrategyOwned   = "owned"
+)
+
+var validMergeStrategies = []string{mergeStrategyOverlay, mergeStrategyOwned}
+
+var validArrayMerges = []string{string(configmerge.ArrayReplace), string(configmerge.ArrayAppend)}
+
+func NewConfigMergeResource() resource.Resource {
+	return &ConfigMergeResource{}
+}
+
+// ConfigMergeResource merges a fragment into a structured configuration file that the
+// application owning it also writes to.
+type ConfigMergeResource struct {
+	client *DotfilesClient
+}
+
+// ConfigMergeResourceModel describes the resource data model.
+type ConfigMergeResourceModel struct {
+	ID              types.String  `tfsdk:"id"`
+	TargetPath      types.String  `tfsdk:"target_path"`
+	Format          types.String  `tfsdk:"format"`
+	Values          types.Dynamic `tfsdk:"values"`
+	Repository      types.String  `tfsdk:"repository"`
+	SourcePath      types.String  `tfsdk:"source_path"`
+	IsTemplate      types.Bool    `tfsdk:"is_template"`
+	TemplateEngine  types.String  `tfsdk:"template_engine"`
+	TemplateVars    types.Map     `tfsdk:"template_vars"`
+	MergeStrategy   types.String  `tfsdk:"merge_strategy"`
+	ArrayMerge      types.String  `tfsdk:"array_merge"`
+	FileMode        types.String  `tfsdk:"file_mode"`
+	BackupEnabled   types.Bool    `tfsdk:"backup_enabled"`
+	DiscardComments types.Bool    `tfsdk:"discard_comments"`
+
+	ManagedKeys types.List   `tfsdk:"managed_keys"`
+	ContentHash types.String `tfsdk:"content_hash"`
+	BackupPath  types.String `tfsdk:"backup_path"`
+}
+
+func (r *ConfigMergeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_config_merge"
+}
+
+func (r *ConfigMergeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Deep-merges a fragment into a JSON, YAML or TOML file that its application also writes to, such as " +
+			"VS Code's `settings.json` or `starship.toml`. Keys the fragment does not set are preserved, and only the keys it " +
+			"sets are checked for drift. Key order and comments are kept in JSON and YAML files. TOML files are written with " +
+			"sorted keys and without comments, so a TOML file with comments is only rewritten with `discard_comments`.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Merge identifier",
+			},
+			"target_path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Configuration file to merge into. It is created when missing; a symlink is followed",
+				Validators: []validator.String{
+					validators.ValidPath(),
+					validators.EnvironmentVariableExpansion(),
+				},
+			},
+			"format": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Format of the target: `json`, `yaml` or `toml` (default: detected from the extension)",
+			},
+			"values": schema.DynamicAttribute{
+				Optional:            true,
+				MarkdownDescription: "Object merged into the file. Conflicts with `source_path`",
+			},
+			"repository": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "ID of the `dotfiles_repository` holding `source_path` (default: `dotfiles_root`)",
+			},
+			"source_path": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Repository file holding the fragment, in any supported format (detected from the extension, " +
+					"falling back to the target's format). Conflicts with `values`",
+			},
+			"is_template": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Render `source_path` as a template before parsing it",
+			},
+			"template_engine": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Template engine: go, handlebars, mustache, or chezmoi. Defaults to the provider's `template_engine`",
+				Validators: []validator.String{
+					validators.ValidTemplateEngine(),
+				},
+			},
+			"template_vars": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Variables available to the template",
+			},
+			"merge_strategy": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "`overlay` merges the fragment and leaves the merged keys in place on destroy. `owned` also " +
+					"removes the keys it set on destroy, and keys dropped from the fragment on update (default: overlay)",
+			},
+			"array_merge": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "`replace` replaces existing arrays; `append` adds the fragment's items an array lacks, so " +
+					"items added by the application are kept (default: replace)",
+			},
+			"file_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Permissions of a file created for the merge (default: 0644). Existing files keep their permissions",
+				Validators: []validator.String{
+					validators.ValidFileMode(),
+				},
+			},
+			"backup_enabled": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Back up the file before changing it. Defaults to the provider's `backup_enabled`",
+			},
+			"discard_comments": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Rewrite a TOML file that has comments, losing them. Without it, changing such a file is an error (default: false)",
+			},
+			"managed_keys": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "JSON pointers of the values the fragment sets",
+			},
+			"content_hash": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SHA-256 of the file's values at the managed keys",
+			},
+			"backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Most recent backup of the file taken before it was changed",
+			},
+		},
+	}
+}
+
+func (r *ConfigMergeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig checks the fragment source, the format and the merge options.
+func (r *ConfigMergeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Values.IsUnknown() && !data.SourcePath.IsUnknown() && data.Values.IsNull() == data.SourcePath.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("values"),
+			"Invalid Merge Fragment",
+			"Exactly one of values or source_path must be set.",
+		)
+	}
+	if !data.Values.IsNull() && !data.Values.IsUnknown() && !data.Values.IsUnderlyingValueUnknown() {
+		switch data.Values.UnderlyingValue().(type) {
+		case types.Object, types.Map:
+		default:
+			resp.Diagnostics.AddAttributeError(
+				path.Root("values"),
+				"Invalid Merge Fragment",
+				"values must be an object.",
+			)
+		}
+	}
+
+	if !data.Format.IsNull() && !data.Format.IsUnknown() {
+		if format := data.Format.ValueString(); !contains(configFormatNames(), format) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("format"),
+				"Invalid Format",
+				fmt.Sprintf("format must be one of %s, got %q.", strings.Join(configFormatNames(), ", "), format),
+			)
+		}
+	} else if !data.Format.IsUnknown() && !data.TargetPath.IsUnknown() {
+		if _, err := configmerge.DetectFormat(data.TargetPath.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("format"),
+				"Missing Format",
+				fmt.Sprintf("Set format explicitly: %s.", err),
+			)
+		}
+	}
+
+	for name, option := range map[string]struct {
+		value types.String
+		valid []string
+	}{
+		"merge_strategy": {data.MergeStrategy, validMergeStrategies},
+		"array_merge":    {data.ArrayMerge, validArrayMerges},
+	} {
+		if value := option.value.ValueString(); !option.value.IsNull() && !option.value.IsUnknown() && !contains(option.valid, value) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Invalid Merge Option",
+				fmt.Sprintf("%s must be one of %s, got %q.", name, strings.Join(option.valid, ", "), value),
+			)
+		}
+	}
+}
+
+// ModifyPlan plans the managed keys and the hash of the fragment, so owned keys changed
+// outside Terraform, or a changed fragment, show up as an update.
+func (r *ConfigMergeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || !configMergeKnown(&plan) {
+		return
+	}
+
+	// A source in a repository that is not cloned yet is read on apply
+	plan.ContentHash = types.StringUnknown()
+	plan.ManagedKeys = types.ListUnknown(types.StringType)
+	if fragment, err := r.fragment(&plan); err == nil {
+		hash, keys, err := r.expected(fragment, &plan)
+		if err != nil {
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to plan merge")
+			return
+		}
+		plan.ContentHash = types.StringValue(hash)
+		plan.ManagedKeys = keys
+	} else {
+		tflog.Debug(ctx, "Merge fragment cannot be read at plan time", map[string]interface{}{
+			"target_path": plan.TargetPath.ValueString(),
+			"error":       err.Error(),
+		})
+	}
+
+	// Rewriting the file on drift may take a new backup
+	if !req.State.Raw.IsNull() {
+		var state ConfigMergeResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if !plan.ContentHash.Equal(state.ContentHash) || !plan.ManagedKeys.Equal(state.ManagedKeys) {
+			plan.BackupPath = types.StringUnknown()
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *ConfigMergeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = types.StringNull()
+	if err := r.apply(ctx, &data, nil); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to merge configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ConfigMergeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	fragment, err := r.fragment(&data)
+	if err != nil {
+		// Without the fragment there is nothing to compare against; the next apply reports the error
+		tflog.Warn(ctx, "Cannot read merge fragment, skipping drift detection", map[string]interface{}{
+			"target_path": data.TargetPath.ValueString(),
+			"error":       err.Error(),
+		})
+		return
+	}
+	target, format, err := r.target(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read configuration")
+		return
+	}
+	doc, _, err := readConfigDocument(target, format)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read configuration")
+		return
+	}
+	hash, err := ownedHash(doc, fragment, r.options(&data))
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read configuration")
+		return
+	}
+	if hash != data.ContentHash.ValueString() {
+		tflog.Info(ctx, "Managed keys differ from the merged fragment", map[string]interface{}{
+			"target_path": target,
+		})
+	}
+	data.ContentHash = types.StringValue(hash)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *ConfigMergeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keys owned in another file are released there first
+	previousKeys := managedKeyPaths(state.ManagedKeys)
+	if !data.TargetPath.Equal(state.TargetPath) {
+		if err := r.release(ctx, &state); err != nil {
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove keys from the previous file")
+			return
+		}
+		previousKeys = nil
+	}
+
+	data.BackupPath = state.BackupPath
+	if err := r.apply(ctx, &data, previousKeys); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to merge configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the managed keys when merge_strategy is owned. Overlaid values stay.
+func (r *ConfigMergeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data ConfigMergeResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.release(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove managed keys")
+	}
+}
+
+// apply merges the fragment into the target. With the owned strategy, keys in previousKeys
+// the fragment no longer sets are removed first. The file is only rewritten when its values
+// change.
+func (r *ConfigMergeResource) apply(ctx context.Context, data *ConfigMergeResourceModel, previousKeys []configmerge.Path) error {
+	target, format, err := r.target(data)
+	if err != nil {
+		return err
+	}
+	fragment, err := r.fragment(data)
+	if err != nil {
+		return err
+	}
+	hash, keys, err := r.expected(fragment, data)
+	if err != nil {
+		return err
+	}
+	data.ID = types.StringValue(target)
+	data.ContentHash = types.StringValue(hash)
+	data.ManagedKeys = keys
+
+	doc, existed, err := readConfigDocument(target, format)
+	if err != nil {
+		return err
+	}
+	before, err := doc.Value()
+	if err != nil {
+		return errors.IOError("merge", "config_merge", "Failed to read configuration values", err).WithPath(target)
+	}
+
+	opts := r.options(data)
+	if r.strategy(data) == mergeStrategyOwned {
+		doc.Remove(droppedKeys(previousKeys, fragment.Leaves()), nil, opts)
+	}
+	doc.Merge(fragment, opts)
+
+	after, err := doc.Value()
+	if err != nil {
+		return errors.IOError("merge", "config_merge", "Failed to merge configuration", err).WithPath(target)
+	}
+	if existed && reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	mode, err := sharedFileMode("config_merge", data.FileMode)
+	if err != nil {
+		return err
+	}
+	if err := r.write(ctx, data, target, doc, existed, mode); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Merged configuration", map[string]interface{}{
+		"target_path": target,
+		"keys":        len(keys.Elements()),
+	})
+	return nil
+}
+
+// release removes the managed keys from the target when they are owned.
+func (r *ConfigMergeResource) release(ctx context.Context, data *ConfigMergeResourceModel) error {
+	if r.strategy(data) != mergeStrategyOwned {
+		return nil
+	}
+	target, format, err := r.target(data)
+	if err != nil {
+		return err
+	}
+	doc, existed, err := readConfigDocument(target, format)
+	if err != nil || !existed {
+		return err
+	}
+	before, err := doc.Value()
+	if err != nil {
+		return errors.IOError("remove_keys", "config_merge", "Failed to read configuration values", err).WithPath(target)
+	}
+
+	// Appended array items can only be told apart with the fragment
+	fragment, _ := r.fragment(data)
+	doc.Remove(managedKeyPaths(data.ManagedKeys), fragment, r.options(data))
+
+	after, err := doc.Value()
+	if err != nil || reflect.DeepEqual(before, after) {
+		return err
+	}
+	if err := r.write(ctx, data, target, doc, true, 0644); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Removed managed keys", map[string]interface{}{
+		"target_path": target,
+	})
+	return nil
+}
+
+// write encodes the document and writes it, recording the backup taken before the change.
+// A file whose comments would be lost is only written with discard_comments.
+func (r *ConfigMergeResource) write(ctx context.Context, data *ConfigMergeResourceModel, target string, doc *configmerge.Document, existed bool, mode os.FileMode) error {
+	if doc.DropsComments() && !data.DiscardComments.ValueBool() {
+		return errors.ValidationError("encode", "config_merge", "The configuration file has comments that would be lost",
+			fmt.Errorf("%s files are written without comments; set discard_comments = true to rewrite it anyway", strings.ToUpper(string(doc.Format())))).
+			WithPath(target)
+	}
+	encoded, err := doc.Encode()
+	if err != nil {
+		return errors.IOError("encode", "config_merge", "Failed to encode configuration", err).WithPath(target)
+	}
+	backupPath, err := r.client.writeShared(ctx, "config_merge", target, string(encoded), existed, mode, data.BackupEnabled)
+	if err != nil {
+		return err
+	}
+	if backupPath != "" {
+		data.BackupPath = types.StringValue(backupPath)
+	}
+	return nil
+}
+
+// fragment returns the document merged into the target, from values or source_path.
+func (r *ConfigMergeResource) fragment(data *ConfigMergeResourceModel) (*configmerge.Document, error) {
+	if r.client == nil {
+		return nil, errors.ConfigurationError("read_fragment", "config_merge", "Provider is not configured", nil)
+	}
+	if data.SourcePath.IsNull() {
+		values, err := dynamicObject(data.Values)
+		if err != nil {
+			return nil, errors.ValidationError("read_fragment", "config_merge", "Invalid values", err)
+		}
+		return configmerge.FromValue(values)
+	}
+
+	content, source, err := r.client.readRepositoryFile("config_merge", data.Repository.ValueString(), data.SourcePath.ValueString())
+	if err != nil {
+		return nil, err
+	}
+	if data.IsTemplate.ValueBool() {
+		rendered, err := r.client.renderTemplateContent(string(content), data.TemplateEngine, data.TemplateVars)
+		if err != nil {
+			return nil, errors.IOError("render", "config_merge", "Failed to render fragment", err).WithPath(source)
+		}
+		content = []byte(rendered)
+	}
+
+	format, err := configmerge.DetectFormat(source)
+	if err != nil {
+		if _, format, err = r.target(data); err != nil {
+			return nil, err
+		}
+	}
+	fragment, err := configmerge.Parse(format, content)
+	if err != nil {
+		return nil, errors.ValidationError("parse_fragment", "config_merge", "Invalid fragment", err).WithPath(source)
+	}
+	return fragment, nil
+}
+
+// expected returns the content hash and managed keys of a target in sync with fragment.
+func (r *ConfigMergeResource) expected(fragment *configmerge.Document, data *ConfigMergeResourceModel) (string, types.List, error) {
+	hash, err := ownedHash(fragment, fragment, r.options(data))
+	if err != nil {
+		return "", types.ListNull(types.StringType), errors.ValidationError("hash_fragment", "config_merge", "Invalid fragment", err)
+	}
+	var keys []attr.Value
+	for _, leaf := range fragment.Leaves() {
+		keys = append(keys, types.StringValue(leaf.String()))
+	}
+	list, _ := types.ListValue(types.StringType, keys)
+	return hash, list, nil
+}
+
+// target returns the expanded target path and its format.
+func (r *ConfigMergeResource) target(data *ConfigMergeResourceModel) (string, configmerge.Format, error) {
+	target, err := platform.DetectPlatform().ExpandPath(data.TargetPath.ValueString())
+	if err != nil {
+		return "", "", errors.ValidationError("expand_target_path", "config_merge", "Could not expand target path", err).
+			WithPath(data.TargetPath.ValueString())
+	}
+	if !data.Format.IsNull() && data.Format.ValueString() != "" {
+		return target, configmerge.Format(data.Format.ValueString()), nil
+	}
+	format, err := configmerge.DetectFormat(target)
+	if err != nil {
+		return "", "", errors.ValidationError("detect_format", "config_merge", "Unknown configuration format", err).WithPath(target)
+	}
+	return target, format, nil
+}
+
+func (r *ConfigMergeResource) options(data *ConfigMergeResourceModel) configmerge.Options {
+	if data.ArrayMerge.ValueString() == string(configmerge.ArrayAppend) {
+		return configmerge.Options{Arrays: configmerge.ArrayAppend}
+	}
+	return configmerge.Options{Arrays: configmerge.ArrayReplace}
+}
+
+// strategy returns the configured merge strategy, defaulting to overlay.
+func (r *ConfigMergeResource) strategy(data *ConfigMergeResourceModel) string {
+	if data.MergeStrategy.IsNull() || data.MergeStrategy.ValueString() == "" {
+		return mergeStrategyOverlay
+	}
+	return data.MergeStrategy.ValueString()
+}
+
+// readConfigDocument parses the target, returning an empty document when it is missing.
+func readConfigDocument(target string, format configmerge.Format) (*configmerge.Document, bool, error) {
+	content, existed, err := readShared("config_merge", target)
+	if err != nil {
+		return nil, false, err
+	}
+	doc, err := configmerge.Parse(format, []byte(content))
+	if err != nil {
+		return nil, false, errors.ValidationError("parse", "config_merge", "The configuration file cannot be parsed", err).WithPath(target)
+	}
+	return doc, existed, nil
+}
+
+// ownedHash returns the SHA-256 of the values doc holds at the keys fragment sets.
+func ownedHash(doc, fragment *configmerge.Document, opts configmerge.Options) (string, error) {
+	owned, err := doc.Owned(fragment, opts)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(owned)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// droppedKeys returns the previous keys that are not managed any more.
+func droppedKeys(previous, current []configmerge.Path) []configmerge.Path {
+	kept := make(map[string]bool, len(current))
+	for _, key := range current {
+		kept[key.String()] = true
+	}
+	var dropped []configmerge.Path
+	for _, key := range previous {
+		if !kept[key.String()] {
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
+
+// managedKeyPaths parses the JSON pointers in managed_keys.
+func managedKeyPaths(keys types.List) []configmerge.Path {
+	var paths []configmerge.Path
+	for _, element := range keys.Elements() {
+		if pointer, ok := element.(types.String); ok {
+			if path, err := configmerge.ParsePath(pointer.ValueString()); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// configMergeKnown reports whether everything the fragment depends on is known.
+func configMergeKnown(data *ConfigMergeResourceModel) bool {
+	if data.Values.IsUnknown() || data.TargetPath.IsUnknown() || data.Format.IsUnknown() || data.ArrayMerge.IsUnknown() {
+		return false
+	}
+	if !data.Values.IsNull() {
+		value, err := data.Values.ToTerraformValue(context.Background())
+		if err != nil || !value.IsFullyKnown() {
+			return false
+		}
+	}
+	return !data.Repository.IsUnknown() && !data.SourcePath.IsUnknown() && !data.IsTemplate.IsUnknown() &&
+		!data.TemplateEngine.IsUnknown() && !data.TemplateVars.IsUnknown()
+}
+
+func configFormatNames() []string {
+	names := make([]string, 0, len(configmerge.Formats))
+	for _, format := range configmerge.Formats {
+		names = append(names, string(format))
+	}
+	return names
+}
+
+// dynamicObject converts an object or map given in the configuration into plain values.
+func dynamicObject(value types.Dynamic) (map[string]interface{}, error) {
+	terraformValue, err := value.ToTerraformValue(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	converted, err := plainValue(terraformValue)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := converted.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object")
+	}
+	return object, nil
+}
+
+// plainValue converts a known Terraform value into strings, numbers, booleans, maps and slices.
+func plainValue(value tftypes.Value) (interface{}, error) {
+	if !value.IsKnown() {
+		return nil, fmt.Errorf("value is not known yet")
+	}
+	if value.IsNull() {
+		return nil, nil
+	}
+
+	switch value.Type().(type) {
+	case tftypes.Object, tftypes.Map:
+		var elements map[string]tftypes.Value
+		if err := value.As(&elements); err != nil {
+			return nil, err
+		}
+		result := make(map[string]interface{}, len(elements))
+		for key, element := range elements {
+			converted, err := plainValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case tftypes.List, tftypes.Set, tftypes.Tuple:
+		var elements []tftypes.Value
+		if err := value.As(&elements); err != nil {
+			return nil, err
+		}
+		result := make([]interface{}, 0, len(elements))
+		for _, element := range elements {
+			converted, err := plainValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, converted)
+		}
+		return result, nil
+	}
+
+	switch {
+	case value.Type().Is(tftypes.String):
+		var s string
+		err := value.As(&s)
+		return s, err
+	case value.Type().Is(tftypes.Bool):
+		var b bool
+		err := value.As(&b)
+		return b, err
+	case value.Type().Is(tftypes.Number):
+		number := new(big.Float)
+		if err := value.As(&number); err != nil {
+			return nil, err
+		}
+		if number.IsInt() {
+			if i, accuracy := number.Int64(); accuracy == big.Exact {
+				return i, nil
+			}
+		}
+		f, _ := number.Float64()
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", value.Type())
+}
@@ -0,0 +1,296 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/configmerge"
+)
+
+func TestConfigMergeResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewConfigMergeResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_config_merge" {
+			t.Errorf("Expected TypeName dotfiles_config_merge, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewConfigMergeResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		if !resp.Schema.Attributes["target_path"].IsRequired() {
+			t.Error("Attribute target_path should be required")
+		}
+		for _, attr := range []string{"id", "managed_keys", "content_hash", "backup_path"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+	})
+}
+
+// readJSON returns the decoded content of a JSON file.
+func readJSON(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	// Comments are kept, so the file is read as JSONC and its values compared as plain JSON
+	doc, err := configmerge.Parse(configmerge.FormatJSON, content)
+	if err != nil {
+		t.Fatalf("Invalid JSON in %s: %v\n%s", path, err, content)
+	}
+	parsed, err := doc.Value()
+	if err != nil {
+		t.Fatalf("Failed to read values of %s: %v", path, err)
+	}
+	encoded, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatalf("Failed to encode values of %s: %v", path, err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		t.Fatalf("Invalid JSON in %s: %v\n%s", path, err, content)
+	}
+	return values
+}
+
+func TestConfigMergeResourceValues(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "Code", "User", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("{\n  // set by VS Code\n  \"window.zoomLevel\": 1,\n  \"editor.fontSize\": 12,\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &ConfigMergeResource{client: &DotfilesClient{Config: &DotfilesConfig{
+		BackupEnabled:   true,
+		BackupDirectory: filepath.Join(tempDir, "backups"),
+	}}}
+	ctx := context.Background()
+	data := &ConfigMergeResourceModel{
+		TargetPath: types.StringValue(target),
+		Values: types.DynamicValue(types.ObjectValueMust(
+			map[string]attr.Type{"editor.fontSize": types.NumberType, "files.exclude": types.ObjectType{AttrTypes: map[string]attr.Type{"**/.git": types.BoolType}}},
+			map[string]attr.Value{
+				"editor.fontSize": types.NumberValue(big.NewFloat(14)),
+				"files.exclude":   types.ObjectValueMust(map[string]attr.Type{"**/.git": types.BoolType}, map[string]attr.Value{"**/.git": types.BoolValue(true)}),
+			},
+		)),
+		MergeStrategy: types.StringValue(mergeStrategyOwned),
+	}
+
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	expected := map[string]interface{}{
+		"window.zoomLevel": float64(1),
+		"editor.fontSize":  float64(14),
+		"files.exclude":    map[string]interface{}{"**/.git": true},
+	}
+	if values := readJSON(t, target); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+	if content, _ := os.ReadFile(target); !strings.Contains(string(content), "// set by VS Code\n  \"window.zoomLevel\": 1") {
+		t.Errorf("Expected the comment to be kept, got:\n%s", content)
+	}
+	if data.BackupPath.IsNull() {
+		t.Error("Expected the file to be backed up before it was changed")
+	}
+	var keys []string
+	data.ManagedKeys.ElementsAs(ctx, &keys, false)
+	if !reflect.DeepEqual(keys, []string{"/editor.fontSize", "/files.exclude/**~1.git"}) {
+		t.Errorf("Unexpected managed keys %v", keys)
+	}
+
+	// The application changes an unrelated key and an owned key
+	if err := os.WriteFile(target, []byte(`{"window.zoomLevel": 2, "editor.fontSize": 12, "files.exclude": {"**/.git": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	fragment, err := r.fragment(data)
+	if err != nil {
+		t.Fatalf("fragment failed: %v", err)
+	}
+	doc, _, _ := readConfigDocument(target, "json")
+	if hash, _ := ownedHash(doc, fragment, r.options(data)); hash == data.ContentHash.ValueString() {
+		t.Error("Expected a changed owned key to be drift")
+	}
+	if err := os.WriteFile(target, []byte(`{"window.zoomLevel": 3, "editor.fontSize": 14, "files.exclude": {"**/.git": true}}`), 0644); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	doc, _, _ = readConfigDocument(target, "json")
+	if hash, _ := ownedHash(doc, fragment, r.options(data)); hash != data.ContentHash.ValueString() {
+		t.Error("Expected an unrelated key not to be drift")
+	}
+
+	// Owned keys are removed on destroy, unrelated keys stay
+	if err := r.release(ctx, data); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if values := readJSON(t, target); !reflect.DeepEqual(values, map[string]interface{}{"window.zoomLevel": float64(3)}) {
+		t.Errorf("Expected only the unrelated key to remain, got %v", values)
+	}
+}
+
+func TestConfigMergeResourceSourceFile(t *testing.T) {
+	tempDir := t.TempDir()
+	repoDir := filepath.Join(tempDir, "dotfiles")
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	source := filepath.Join(repoDir, "starship.yaml")
+	if err := os.WriteFile(source, []byte("add_newline: false\ncustom:\n  modules: [git, \"{{ .lang }}\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write fragment: %v", err)
+	}
+	target := filepath.Join(tempDir, "starship.toml")
+	if err := os.WriteFile(target, []byte("scan_timeout = 10\n[custom]\nmodules = [\"aws\"]\n"), 0600); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &ConfigMergeResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: repoDir}}}
+	ctx := context.Background()
+	data := &ConfigMergeResourceModel{
+		TargetPath:   types.StringValue(target),
+		SourcePath:   types.StringValue("starship.yaml"),
+		IsTemplate:   types.BoolValue(true),
+		TemplateVars: types.MapValueMust(types.StringType, map[string]attr.Value{"lang": types.StringValue("golang")}),
+		ArrayMerge:   types.StringValue("append"),
+	}
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	doc, _, err := readConfigDocument(target, "toml")
+	if err != nil {
+		t.Fatalf("Merged TOML does not parse: %v", err)
+	}
+	values, _ := doc.Value()
+	expected := map[string]interface{}{
+		"scan_timeout": 10,
+		"add_newline":  false,
+		"custom":       map[string]interface{}{"modules": []interface{}{"aws", "git", "golang"}},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to keep mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// Overlay merges leave the file alone on destroy
+	before, _ := os.ReadFile(target)
+	if err := r.release(ctx, data); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if after, _ := os.ReadFile(target); string(after) != string(before) {
+		t.Error("Expected an overlay merge to leave the file on destroy")
+	}
+
+	// TOML comments cannot be written back, so a commented file is only rewritten on request
+	commented := "# prompt\nscan_timeout = 10\n"
+	if err := os.WriteFile(target, []byte(commented), 0600); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+	if err := r.apply(ctx, data, nil); err == nil || !strings.Contains(err.Error(), "discard_comments") {
+		t.Errorf("Expected a commented TOML file to be refused, got %v", err)
+	}
+	if after, _ := os.ReadFile(target); string(after) != commented {
+		t.Errorf("Expected the refused file to be left alone, got:\n%s", after)
+	}
+	data.DiscardComments = types.BoolValue(true)
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply with discard_comments failed: %v", err)
+	}
+	if after, _ := os.ReadFile(target); strings.Contains(string(after), "# prompt") || !strings.Contains(string(after), "add_newline") {
+		t.Errorf("Expected the file to be rewritten without comments, got:\n%s", after)
+	}
+}
+
+func TestConfigMergeResourceDroppedKeys(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(target, []byte("# app config\ntheme: dark\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+	r := &ConfigMergeResource{client: &DotfilesClient{Config: &DotfilesConfig{}}}
+	ctx := context.Background()
+	object := func(values map[string]attr.Value) types.Dynamic {
+		attrTypes := make(map[string]attr.Type)
+		for key := range values {
+			attrTypes[key] = types.StringType
+		}
+		return types.DynamicValue(types.ObjectValueMust(attrTypes, values))
+	}
+
+	data := &ConfigMergeResourceModel{
+		TargetPath:    types.StringValue(target),
+		Values:        object(map[string]attr.Value{"font": types.StringValue("Hack"), "shell": types.StringValue("zsh")}),
+		MergeStrategy: types.StringValue(mergeStrategyOwned),
+	}
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	previous := managedKeyPaths(data.ManagedKeys)
+
+	data.Values = object(map[string]attr.Value{"font": types.StringValue("Hack")})
+	if err := r.apply(ctx, data, previous); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	content, _ := os.ReadFile(target)
+	if string(content) != "# app config\ntheme: dark\nfont: Hack\n" {
+		t.Errorf("Expected the dropped key to be removed and comments kept, got:\n%s", content)
+	}
+}
+
+func TestDynamicObject(t *testing.T) {
+	value := types.DynamicValue(types.ObjectValueMust(
+		map[string]attr.Type{
+			"name":  types.StringType,
+			"size":  types.NumberType,
+			"ratio": types.NumberType,
+			"tags":  types.TupleType{ElemTypes: []attr.Type{types.StringType, types.BoolType}},
+			"none":  types.StringType,
+		},
+		map[string]attr.Value{
+			"name":  types.StringValue("x"),
+			"size":  types.NumberValue(big.NewFloat(3)),
+			"ratio": types.NumberValue(big.NewFloat(0.5)),
+			"tags":  types.TupleValueMust([]attr.Type{types.StringType, types.BoolType}, []attr.Value{types.StringValue("a"), types.BoolValue(true)}),
+			"none":  types.StringNull(),
+		},
+	))
+	object, err := dynamicObject(value)
+	if err != nil {
+		t.Fatalf("dynamicObject failed: %v", err)
+	}
+	expected := map[string]interface{}{"name": "x", "size": int64(3), "ratio": 0.5, "tags": []interface{}{"a", true}, "none": nil}
+	if !reflect.DeepEqual(object, expected) {
+		t.Errorf("Expected %v, got %v", expected, object)
+	}
+	if _, err := dynamicObject(types.DynamicValue(types.StringValue("x"))); err == nil {
+		t.Error("Expected an error for a value that is not an object")
+	}
+}
@@ -54,6 +54,7 @@ const (
 	TemplateEngineGo         = "go"
 	TemplateEngineHandlebars = "handlebars"
 	TemplateEngineMustache   = "mustache"
+	TemplateEngineChezmoi    = "chezmoi"
 	TemplateEngineNone       = "none"
 )
 
@@ -62,6 +63,7 @@ var ValidTemplateEngines = []string{
 	TemplateEngineGo,
 	TemplateEngineHandlebars,
 	TemplateEngineMustache,
+	TemplateEngineChezmoi,
 	TemplateEngineNone,
 }
 
@@ -187,6 +189,7 @@ const (
 	ResourceTypeSymlink     = "dotfiles_symlink"
 	ResourceTypeDirectory   = "dotfiles_directory"
 	ResourceTypeApplication = "dotfiles_application"
+	ResourceTypePackage     = "dotfiles_package"
 )
 
 // Data source type constants.
@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+)
+
+// directorySyncOptions holds the per-sync settings shared by all file tasks of a directory.
+type directorySyncOptions struct {
+	template    *directoryTemplateConfig
+	permissions *directoryPermissions
+	files       *fileops.FileManager
+}
+
+// directoryPermissions holds the permission modes applied while syncing a directory.
+type directoryPermissions struct {
+	targetRoot    string
+	directoryMode string
+	fileMode      string
+	recursive     bool
+	rules         types.Map
+}
+
+// buildDirectoryPermissions resolves the permissions block and permission rules for a directory sync.
+// It returns nil when neither is configured, leaving copied modes untouched.
+func buildDirectoryPermissions(data *DirectoryResourceModel, targetRoot string) (*directoryPermissions, error) {
+	if err := ValidatePermissionsModel(data.Permissions); err != nil {
+		return nil, err
+	}
+	if err := ValidatePermissionRules(data.PermissionRules); err != nil {
+		return nil, err
+	}
+
+	hasRules := !data.PermissionRules.IsNull() && !data.PermissionRules.IsUnknown()
+	if data.Permissions == nil && !hasRules {
+		return nil, nil
+	}
+
+	perms := &directoryPermissions{
+		targetRoot: targetRoot,
+		recursive:  true,
+		rules:      data.PermissionRules,
+	}
+	if data.Permissions != nil {
+		if !data.Permissions.Directory.IsNull() && !data.Permissions.Directory.IsUnknown() {
+			perms.directoryMode = data.Permissions.Directory.ValueString()
+		}
+		if !data.Permissions.Files.IsNull() && !data.Permissions.Files.IsUnknown() {
+			perms.fileMode = data.Permissions.Files.ValueString()
+		}
+		if !data.Permissions.Recursive.IsNull() && !data.Permissions.Recursive.IsUnknown() {
+			perms.recursive = data.Permissions.Recursive.ValueBool()
+		}
+	}
+
+	return perms, nil
+}
+
+// appliesTo reports whether the permissions block modes apply to path.
+// Without recursion only the target directory and its direct entries are affected.
+func (p *directoryPermissions) appliesTo(path string) bool {
+	return p.recursive || path == p.targetRoot || filepath.Dir(path) == p.targetRoot
+}
+
+// applyToDirectory sets the configured directory mode on a synced directory.
+func (p *directoryPermissions) applyToDirectory(path string) error {
+	if p == nil || p.directoryMode == "" || !p.appliesTo(path) {
+		return nil
+	}
+	return chmodPermission(path, p.directoryMode)
+}
+
+// modeFor returns the permission a synced file is written with, preferring the most
+// specific permission rule, or "" when neither a rule nor the permissions block applies.
+func (p *directoryPermissions) modeFor(path string) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	defaultMode := ""
+	if p.appliesTo(path) {
+		defaultMode = p.fileMode
+	}
+	return ApplyPermissionRules(filepath.Base(path), p.rules, defaultMode)
+}
+
+// chmodPermission applies a permission string (e.g., "0600") to path.
+func chmodPermission(path, perm string) error {
+	mode, err := parsePermission(perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+		return fmt.Errorf("failed to set permissions %s on %s: %w", perm, path, err)
+	}
+	return nil
+}
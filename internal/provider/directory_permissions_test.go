@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestBuildDirectoryPermissions(t *testing.T) {
+	t.Run("nothing configured", func(t *testing.T) {
+		perms, err := buildDirectoryPermissions(&DirectoryResourceModel{PermissionRules: types.MapNull(types.StringType)}, "/tmp/target")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if perms != nil {
+			t.Error("Expected nil permissions when neither block nor rules are set")
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		data := &DirectoryResourceModel{
+			PermissionRules: types.MapValueMust(types.StringType, map[string]attr.Value{
+				"id_*": types.StringValue("999"),
+			}),
+		}
+		if _, err := buildDirectoryPermissions(data, "/tmp/target"); err == nil {
+			t.Error("Expected error for invalid permission rule")
+		}
+	})
+
+	t.Run("invalid block", func(t *testing.T) {
+		data := &DirectoryResourceModel{
+			PermissionRules: types.MapNull(types.StringType),
+			Permissions: &PermissionsModel{
+				Directory: types.StringValue("abc"),
+				Files:     types.StringValue("0644"),
+				Recursive: types.BoolValue(true),
+			},
+		}
+		if _, err := buildDirectoryPermissions(data, "/tmp/target"); err == nil {
+			t.Error("Expected error for invalid directory permission")
+		}
+	})
+}
+
+func TestDirectoryResourceSyncWithPermissions(t *testing.T) {
+	sourceDir := t.TempDir()
+	files := []string{"config", "id_ed25519", "id_ed25519.pub", "keys/id_rsa", "keys/notes.txt"}
+	for _, name := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	rules := types.MapValueMust(types.StringType, map[string]attr.Value{
+		"id_*":  types.StringValue("0600"),
+		"*.pub": types.StringValue("0644"),
+	})
+
+	t.Run("rules only", func(t *testing.T) {
+		targetDir := filepath.Join(t.TempDir(), ".ssh")
+		r := &DirectoryResource{}
+		data := &DirectoryResourceModel{
+			Recursive:           types.BoolValue(true),
+			PreservePermissions: types.BoolValue(true),
+			PermissionRules:     rules,
+		}
+		if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+			t.Fatalf("syncDirectory failed: %v", err)
+		}
+
+		assertModes(t, targetDir, map[string]os.FileMode{
+			"config":         0644,
+			"id_ed25519":     0600,
+			"id_ed25519.pub": 0644,
+			"keys/id_rsa":    0600,
+			"keys/notes.txt": 0644,
+		})
+	})
+
+	t.Run("permissions block without recursion", func(t *testing.T) {
+		targetDir := filepath.Join(t.TempDir(), ".ssh")
+		r := &DirectoryResource{}
+		data := &DirectoryResourceModel{
+			Recursive:           types.BoolValue(true),
+			PreservePermissions: types.BoolValue(true),
+			PermissionRules:     rules,
+			Permissions: &PermissionsModel{
+				Directory: types.StringValue("0700"),
+				Files:     types.StringValue("0640"),
+				Recursive: types.BoolValue(false),
+			},
+		}
+		if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+			t.Fatalf("syncDirectory failed: %v", err)
+		}
+
+		assertModes(t, targetDir, map[string]os.FileMode{
+			".":              0700,
+			"keys":           0700,
+			"config":         0640,
+			"id_ed25519":     0600,
+			"id_ed25519.pub": 0644,
+			"keys/id_rsa":    0600,
+			"keys/notes.txt": 0644,
+		})
+	})
+
+	t.Run("existing targets are replaced with the rule mode", func(t *testing.T) {
+		targetDir := filepath.Join(t.TempDir(), ".ssh")
+		if err := os.MkdirAll(targetDir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(targetDir, "id_ed25519"), []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to write existing key: %v", err)
+		}
+		r := &DirectoryResource{}
+		data := &DirectoryResourceModel{
+			Recursive:       types.BoolValue(true),
+			PermissionRules: rules,
+		}
+		if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+			t.Fatalf("syncDirectory failed: %v", err)
+		}
+
+		assertModes(t, targetDir, map[string]os.FileMode{
+			"id_ed25519":  0600,
+			"keys/id_rsa": 0600,
+		})
+		if content, _ := os.ReadFile(filepath.Join(targetDir, "id_ed25519")); string(content) != "id_ed25519" {
+			t.Errorf("Expected the key to be replaced, got %q", content)
+		}
+	})
+}
+
+// assertModes checks the permission bits of paths relative to root.
+func assertModes(t *testing.T, root string, expected map[string]os.FileMode) {
+	t.Helper()
+	for name, mode := range expected {
+		info, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", name, err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to have permission %o, got %o", name, mode, info.Mode().Perm())
+		}
+	}
+}
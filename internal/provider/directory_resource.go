@@ -18,11 +18,16 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/idempotency"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/utils"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
 )
 
 var _ resource.Resource = &DirectoryResource{}
+var _ resource.ResourceWithImportState = &DirectoryResource{}
 
 func NewDirectoryResource() resource.Resource {
 	return &DirectoryResource{}
@@ -40,6 +45,14 @@ type DirectoryResourceModel struct {
 	TargetPath          types.String `tfsdk:"target_path"`
 	Recursive           types.Bool   `tfsdk:"recursive"`
 	PreservePermissions types.Bool   `tfsdk:"preserve_permissions"`
+	TemplatePattern     types.String `tfsdk:"template_pattern"`
+	TemplateVars        types.Map    `tfsdk:"template_vars"`
+	TemplateEngine      types.String `tfsdk:"template_engine"`
+	ValidateAs          types.String `tfsdk:"validate_as"`
+
+	// Permission management
+	Permissions     *PermissionsModel `tfsdk:"permissions"`
+	PermissionRules types.Map         `tfsdk:"permission_rules"`
 
 	// Computed attributes
 	DirectoryExists types.Bool   `tfsdk:"directory_exists"`
@@ -95,6 +108,24 @@ func (r *DirectoryResource) Schema(ctx context.Context, req resource.SchemaReque
 				Default:             booldefault.StaticBool(true),
 				MarkdownDescription: "Preserve file permissions. Defaults to true",
 			},
+			"template_pattern": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Glob matched against file names (e.g. `*.tmpl`). Matching files are rendered as templates and the pattern's suffix is stripped from the target name",
+			},
+			"template_vars": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Variables available to templates matched by `template_pattern`",
+			},
+			"template_engine": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Template engine for matched files: go, handlebars, mustache, or chezmoi. Defaults to the provider's `template_engine`",
+				Validators: []validator.String{
+					validators.ValidTemplateEngine(),
+				},
+			},
+			"permission_rules": GetPermissionRulesAttribute(),
+			"validate_as":      GetValidateAsAttribute("Format every rendered or copied file must parse as before it is written. Files that fail are not written; the others are still synced"),
 			"directory_exists": schema.BoolAttribute{
 				Computed:            true,
 				MarkdownDescription: "Whether the target directory exists",
@@ -108,6 +139,9 @@ func (r *DirectoryResource) Schema(ctx context.Context, req resource.SchemaReque
 				MarkdownDescription: "Timestamp when the directory was last synced",
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"permissions": GetPermissionsSchemaBlock(),
+		},
 	}
 }
 
@@ -123,6 +157,11 @@ func (r *DirectoryResource) Configure(ctx context.Context, req resource.Configur
 	r.client = client
 }
 
+// ImportState imports an existing directory by its target path.
+func (r *DirectoryResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importTargetPath(ctx, req, resp)
+}
+
 func (r *DirectoryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data DirectoryResourceModel
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -184,7 +223,7 @@ func (r *DirectoryResource) Read(ctx context.Context, req resource.ReadRequest,
 	}
 
 	// Resolve target path to check current state
-	_, targetPath, err := r.resolvePaths(&data)
+	targetPath, err := r.resolveTargetPath(&data)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to resolve paths",
@@ -230,7 +269,9 @@ func (r *DirectoryResource) Update(ctx context.Context, req resource.UpdateReque
 	}
 
 	// Re-sync the directory with updated configuration
-	err = r.syncDirectory(ctx, sourcePath, targetPath, &data)
+	err = idempotency.EnsureIdempotentDirectoryOperation(ctx, targetPath, data.Recursive.ValueBool(), r.concurrencyManager(), func() error {
+		return r.syncDirectory(ctx, sourcePath, targetPath, &data)
+	})
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to sync directory",
@@ -271,7 +312,7 @@ func (r *DirectoryResource) Delete(ctx context.Context, req resource.DeleteReque
 	})
 
 	// Resolve target path
-	_, targetPath, err := r.resolvePaths(&data)
+	targetPath, err := r.resolveTargetPath(&data)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to resolve paths",
@@ -320,26 +361,16 @@ func (r *DirectoryResource) Delete(ctx context.Context, req resource.DeleteReque
 
 // resolvePaths resolves the source and target paths for the directory.
 func (r *DirectoryResource) resolvePaths(data *DirectoryResourceModel) (string, string, error) {
-	// Get repository local path
-	repositoryLocalPath := r.getRepositoryLocalPath(data.Repository.ValueString())
 	sourcePath := data.SourcePath.ValueString()
 
 	// Resolve full source path
-	var fullSourcePath string
-	if strings.HasPrefix(sourcePath, "/") {
-		fullSourcePath = sourcePath
-	} else {
-		fullSourcePath = filepath.Join(repositoryLocalPath, sourcePath)
-	}
-
-	// Resolve target path
-	targetPath := data.TargetPath.ValueString()
-	if strings.HasPrefix(targetPath, "~") {
-		homeDir, err := os.UserHomeDir()
+	fullSourcePath := sourcePath
+	if !strings.HasPrefix(sourcePath, "/") {
+		repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
 		if err != nil {
-			return "", "", fmt.Errorf("unable to get home directory: %w", err)
+			return "", "", err
 		}
-		targetPath = strings.Replace(targetPath, "~", homeDir, 1)
+		fullSourcePath = filepath.Join(repositoryLocalPath, sourcePath)
 	}
 
 	// Convert to absolute paths
@@ -348,14 +379,33 @@ func (r *DirectoryResource) resolvePaths(data *DirectoryResourceModel) (string,
 		return "", "", fmt.Errorf("failed to get absolute source path: %w", err)
 	}
 
-	targetPath, err = filepath.Abs(targetPath)
+	targetPath, err := r.resolveTargetPath(data)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get absolute target path: %w", err)
+		return "", "", err
 	}
 
 	return fullSourcePath, targetPath, nil
 }
 
+// resolveTargetPath resolves the target path for the directory. Reading and deleting the
+// directory only needs the target, so it does not depend on the repository being resolvable.
+func (r *DirectoryResource) resolveTargetPath(data *DirectoryResourceModel) (string, error) {
+	targetPath := data.TargetPath.ValueString()
+	if strings.HasPrefix(targetPath, "~") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to get home directory: %w", err)
+		}
+		targetPath = strings.Replace(targetPath, "~", homeDir, 1)
+	}
+
+	targetPath, err := filepath.Abs(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute target path: %w", err)
+	}
+	return targetPath, nil
+}
+
 // syncDirectory synchronizes the source directory to the target location.
 func (r *DirectoryResource) syncDirectory(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel) error {
 	// Check if source exists
@@ -368,17 +418,36 @@ func (r *DirectoryResource) syncDirectory(ctx context.Context, sourcePath, targe
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
+	tmpl, err := r.buildDirectoryTemplateConfig(data)
+	if err != nil {
+		return err
+	}
+
+	perms, err := buildDirectoryPermissions(data, targetPath)
+	if err != nil {
+		return err
+	}
+	if err := perms.applyToDirectory(targetPath); err != nil {
+		return err
+	}
+
+	opts := &directorySyncOptions{
+		template:    tmpl,
+		permissions: perms,
+		files:       fileops.NewFileManager(platform.DetectPlatform(), false),
+	}
 	if data.Recursive.ValueBool() {
-		return r.syncDirectoryRecursive(ctx, sourcePath, targetPath, data)
+		return r.syncDirectoryRecursive(ctx, sourcePath, targetPath, data, opts)
 	} else {
-		return r.syncDirectoryShallow(ctx, sourcePath, targetPath, data)
+		return r.syncDirectoryShallow(ctx, sourcePath, targetPath, data, opts)
 	}
 }
 
 // syncDirectoryRecursive recursively syncs directories.
-func (r *DirectoryResource) syncDirectoryRecursive(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel) error {
-	_ = ctx // Context reserved for future logging
-	return filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+// Directories are created while walking; file copies are run through the client's concurrency manager.
+func (r *DirectoryResource) syncDirectoryRecursive(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel, opts *directorySyncOptions) error {
+	var tasks []func() error
+	err := filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -396,25 +465,30 @@ func (r *DirectoryResource) syncDirectoryRecursive(ctx context.Context, sourcePa
 			if err := os.MkdirAll(targetFile, info.Mode()); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", targetFile, err)
 			}
-		} else {
-			// Copy file
-			if err := r.copyFile(ctx, path, targetFile, data); err != nil {
-				return fmt.Errorf("failed to copy file %s: %w", path, err)
+			if err := opts.permissions.applyToDirectory(targetFile); err != nil {
+				return err
 			}
+		} else {
+			tasks = append(tasks, r.fileTask(ctx, path, targetFile, data, opts))
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	return r.runFileTasks(ctx, tasks)
 }
 
 // syncDirectoryShallow syncs only the top-level directory contents.
-func (r *DirectoryResource) syncDirectoryShallow(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel) error {
-	_ = ctx // Context reserved for future logging
+func (r *DirectoryResource) syncDirectoryShallow(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel, opts *directorySyncOptions) error {
 	entries, err := os.ReadDir(sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to read source directory: %w", err)
 	}
 
+	var tasks []func() error
 	for _, entry := range entries {
 		sourceFile := filepath.Join(sourcePath, entry.Name())
 		targetFile := filepath.Join(targetPath, entry.Name())
@@ -429,67 +503,96 @@ func (r *DirectoryResource) syncDirectoryShallow(ctx context.Context, sourcePath
 			if err := os.MkdirAll(targetFile, info.Mode()); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", targetFile, err)
 			}
-		} else {
-			// Copy file
-			if err := r.copyFile(ctx, sourceFile, targetFile, data); err != nil {
-				return fmt.Errorf("failed to copy file %s: %w", sourceFile, err)
+			if err := opts.permissions.applyToDirectory(targetFile); err != nil {
+				return err
 			}
+		} else {
+			tasks = append(tasks, r.fileTask(ctx, sourceFile, targetFile, data, opts))
 		}
 	}
 
-	return nil
+	return r.runFileTasks(ctx, tasks)
 }
 
-// copyFile copies a single file with optional permission preservation.
-func (r *DirectoryResource) copyFile(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel) error {
-	sourceFile, err := os.Open(sourcePath)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer func() {
-		if err := sourceFile.Close(); err != nil {
-			tflog.Warn(ctx, "Failed to close source file", map[string]interface{}{
-				"error": err.Error(),
-			})
+// fileTask wraps a single file sync as a task for runFileTasks.
+// Files matching the template pattern are rendered; all others are copied. Either way the
+// target is replaced atomically, already carrying its final mode.
+func (r *DirectoryResource) fileTask(ctx context.Context, sourcePath, targetPath string, data *DirectoryResourceModel, opts *directorySyncOptions) func() error {
+	return func() error {
+		var content []byte
+		var err error
+		if opts.template.matches(sourcePath) {
+			targetPath = opts.template.targetPath(targetPath)
+			content, err = opts.template.render(sourcePath)
+		} else if content, err = os.ReadFile(sourcePath); err != nil {
+			err = fmt.Errorf("failed to read source file %s: %w", sourcePath, err)
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateOutput(data.ValidateAs, content); err != nil {
+			return fmt.Errorf("not writing %s: %w", targetPath, err)
 		}
-	}()
 
-	// Create target directory if needed
-	targetDir := filepath.Dir(targetPath)
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory: %w", err)
+		mode, replaceMode, err := syncedFileMode(sourcePath, targetPath, data.PreservePermissions.ValueBool(), opts.permissions)
+		if err != nil {
+			return err
+		}
+		write := opts.files.WriteFileAtomic
+		if replaceMode {
+			write = opts.files.WriteFileAtomicWithMode
+		}
+		if err := write(targetPath, content, mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", targetPath, err)
+		}
+		return nil
 	}
+}
 
-	targetFile, err := os.Create(targetPath)
+// syncedFileMode returns the mode a synced file is written with and whether it also replaces
+// the mode of an existing target: a permission rule or the permissions block, otherwise the
+// source mode with preserve_permissions, otherwise 0644 for new files.
+func syncedFileMode(sourcePath, targetPath string, preservePermissions bool, perms *directoryPermissions) (os.FileMode, bool, error) {
+	perm, err := perms.modeFor(targetPath)
 	if err != nil {
-		return fmt.Errorf("failed to create target file: %w", err)
+		return 0, false, err
 	}
-	defer func() {
-		if err := targetFile.Close(); err != nil {
-			tflog.Warn(ctx, "Failed to close target file", map[string]interface{}{
-				"error": err.Error(),
-			})
+	if perm != "" {
+		mode, err := parsePermission(perm)
+		if err != nil {
+			return 0, false, err
 		}
-	}()
-
-	// Copy content
-	if _, err := targetFile.ReadFrom(sourceFile); err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
+		return os.FileMode(mode), true, nil
 	}
 
-	// Preserve permissions if requested
-	if data.PreservePermissions.ValueBool() {
-		sourceInfo, err := sourceFile.Stat()
+	if preservePermissions {
+		info, err := os.Stat(sourcePath)
 		if err != nil {
-			return fmt.Errorf("failed to get source file permissions: %w", err)
+			return 0, false, fmt.Errorf("failed to get source file permissions: %w", err)
 		}
+		return info.Mode().Perm(), true, nil
+	}
+	return 0644, false, nil
+}
 
-		if err := targetFile.Chmod(sourceInfo.Mode()); err != nil {
-			return fmt.Errorf("failed to set target file permissions: %w", err)
-		}
+// concurrencyManager returns the client's concurrency manager, or nil without a client.
+func (r *DirectoryResource) concurrencyManager() *services.ConcurrencyManager {
+	if r.client == nil {
+		return nil
 	}
+	return r.client.ConcurrencyManager
+}
 
-	return nil
+// runFileTasks runs file tasks in parallel, falling back to sequential execution without a client.
+func (r *DirectoryResource) runFileTasks(ctx context.Context, tasks []func() error) error {
+	cm := r.concurrencyManager()
+
+	tflog.Debug(ctx, "Processing directory files", map[string]interface{}{
+		"file_count": len(tasks),
+		"parallel":   cm != nil,
+	})
+
+	return cm.RunParallel(ctx, tasks)
 }
 
 // updateComputedAttributes updates computed attributes based on current directory state.
@@ -588,10 +691,8 @@ func (r *DirectoryResource) validateDeletionSafety(targetPath string) error {
 	return nil
 }
 
-// getRepositoryLocalPath returns the local path for a repository.
-func (r *DirectoryResource) getRepositoryLocalPath(repositoryID string) string {
-	// For now, assume repository ID maps to the dotfiles root
-	// NOTE: Repository lookup feature planned for v0.2.0 - will enable proper multi-repository support
-	_ = repositoryID // Placeholder for future repository lookup implementation
-	return r.client.Config.DotfilesRoot
+// getRepositoryLocalPath returns the root source_path is resolved against: the root path
+// of the repository, which honours its subdirectory.
+func (r *DirectoryResource) getRepositoryLocalPath(repositoryID string) (string, error) {
+	return r.client.RepositoryRoot(repositoryID)
 }
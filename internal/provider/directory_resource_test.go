@@ -5,10 +5,15 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/services"
 )
 
 func TestDirectoryResource(t *testing.T) {
@@ -167,3 +172,96 @@ func TestDirectoryResourceModel(t *testing.T) {
 		t.Error("PreservePermissions field not working correctly")
 	}
 }
+
+func TestDirectoryResourceSyncParallel(t *testing.T) {
+	sourceDir := t.TempDir()
+	for i := 0; i < 25; i++ {
+		subDir := filepath.Join(sourceDir, fmt.Sprintf("sub%d", i%5))
+		if err := os.MkdirAll(subDir, 0755); err != nil {
+			t.Fatalf("Failed to create source directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(subDir, fmt.Sprintf("file%d", i)), []byte(fmt.Sprintf("content %d", i)), 0644); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "top.conf"), []byte("top"), 0600); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	for _, recursive := range []bool{true, false} {
+		t.Run(fmt.Sprintf("recursive=%v", recursive), func(t *testing.T) {
+			targetDir := filepath.Join(t.TempDir(), "target")
+			r := &DirectoryResource{client: &DotfilesClient{ConcurrencyManager: services.NewConcurrencyManager(4)}}
+			data := &DirectoryResourceModel{
+				Recursive:           types.BoolValue(recursive),
+				PreservePermissions: types.BoolValue(true),
+			}
+
+			if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+				t.Fatalf("syncDirectory failed: %v", err)
+			}
+
+			info, err := os.Stat(filepath.Join(targetDir, "top.conf"))
+			if err != nil {
+				t.Fatalf("Top-level file was not copied: %v", err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("Expected preserved permission 0600, got %o", info.Mode().Perm())
+			}
+
+			for i := 0; i < 25; i++ {
+				target := filepath.Join(targetDir, fmt.Sprintf("sub%d", i%5), fmt.Sprintf("file%d", i))
+				content, err := os.ReadFile(target)
+				if !recursive {
+					if err == nil {
+						t.Errorf("Shallow sync should not copy nested file %s", target)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Nested file was not copied: %v", err)
+				}
+				if string(content) != fmt.Sprintf("content %d", i) {
+					t.Errorf("Unexpected content in %s: %q", target, content)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDirectoryResourceSync(b *testing.B) {
+	sourceDir := b.TempDir()
+	for i := 0; i < 200; i++ {
+		subDir := filepath.Join(sourceDir, fmt.Sprintf("sub%d", i%10))
+		if err := os.MkdirAll(subDir, 0755); err != nil {
+			b.Fatalf("Failed to create source directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(subDir, fmt.Sprintf("file%d", i)), []byte("content"), 0644); err != nil {
+			b.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+	data := &DirectoryResourceModel{
+		Recursive:           types.BoolValue(true),
+		PreservePermissions: types.BoolValue(false),
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		r := &DirectoryResource{}
+		targetDir := b.TempDir()
+		for i := 0; i < b.N; i++ {
+			if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		r := &DirectoryResource{client: &DotfilesClient{ConcurrencyManager: services.NewConcurrencyManager(DefaultMaxConcurrency)}}
+		targetDir := b.TempDir()
+		for i := 0; i < b.N; i++ {
+			if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/template"
+)
+
+// directoryTemplateConfig holds the template settings resolved once per directory sync.
+type directoryTemplateConfig struct {
+	pattern string
+	suffix  string
+	engine  template.TemplateEngine
+	context map[string]interface{}
+}
+
+// buildDirectoryTemplateConfig resolves template settings for a directory sync.
+// It returns nil when no template_pattern is configured.
+func (r *DirectoryResource) buildDirectoryTemplateConfig(data *DirectoryResourceModel) (*directoryTemplateConfig, error) {
+	if data.TemplatePattern.IsNull() || data.TemplatePattern.IsUnknown() || data.TemplatePattern.ValueString() == "" {
+		return nil, nil
+	}
+
+	pattern := data.TemplatePattern.ValueString()
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid template_pattern '%s': %w", pattern, err)
+	}
+
+	engineName := r.directoryTemplateEngine(data)
+	engine, err := template.CreateTemplateEngineWithFunctions(engineName, map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template engine: %w", err)
+	}
+
+	userVars, err := directoryTemplateVars(data.TemplateVars)
+	if err != nil {
+		return nil, err
+	}
+
+	systemInfo := map[string]interface{}{}
+	if r.client != nil {
+		systemInfo = r.client.GetPlatformInfo()
+	}
+
+	return &directoryTemplateConfig{
+		pattern: pattern,
+		suffix:  templatePatternSuffix(pattern),
+		engine:  engine,
+		context: template.BuildPlatformAwareTemplateContext(systemInfo, userVars, nil, nil),
+	}, nil
+}
+
+// directoryTemplateEngine returns the engine for the directory, falling back to the provider setting.
+func (r *DirectoryResource) directoryTemplateEngine(data *DirectoryResourceModel) string {
+	if !data.TemplateEngine.IsNull() && !data.TemplateEngine.IsUnknown() && data.TemplateEngine.ValueString() != "" {
+		return data.TemplateEngine.ValueString()
+	}
+	if r.client != nil && r.client.Config != nil && contains(ValidTemplateEngines, r.client.Config.TemplateEngine) {
+		return r.client.Config.TemplateEngine
+	}
+	return TemplateEngineGo
+}
+
+// directoryTemplateVars converts the template_vars map into template variables.
+func directoryTemplateVars(vars types.Map) (map[string]interface{}, error) {
+	userVars := make(map[string]interface{})
+	if vars.IsNull() || vars.IsUnknown() {
+		return userVars, nil
+	}
+
+	for key, value := range vars.Elements() {
+		strValue, ok := value.(types.String)
+		if !ok {
+			return nil, fmt.Errorf("template variable '%s' must be a string", key)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("template variable name cannot be empty")
+		}
+		userVars[key] = strValue.ValueString()
+	}
+
+	return userVars, nil
+}
+
+// templatePatternSuffix returns the literal suffix of a template pattern, e.g. ".tmpl" for "*.tmpl".
+// Patterns without wildcards use their file extension.
+func templatePatternSuffix(pattern string) string {
+	if idx := strings.LastIndexAny(pattern, "*?]"); idx >= 0 {
+		return pattern[idx+1:]
+	}
+	return filepath.Ext(pattern)
+}
+
+// matches reports whether the file at path should be rendered as a template.
+func (c *directoryTemplateConfig) matches(path string) bool {
+	if c == nil {
+		return false
+	}
+	matched, err := filepath.Match(c.pattern, filepath.Base(path))
+	return err == nil && matched
+}
+
+// targetPath strips the template suffix from a target path, keeping names that would become empty.
+func (c *directoryTemplateConfig) targetPath(path string) string {
+	base := filepath.Base(path)
+	if c.suffix == "" || !strings.HasSuffix(base, c.suffix) || base == c.suffix {
+		return path
+	}
+	return filepath.Join(filepath.Dir(path), strings.TrimSuffix(base, c.suffix))
+}
+
+// render renders a template file from the directory tree.
+func (c *directoryTemplateConfig) render(sourcePath string) ([]byte, error) {
+	templateContent, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", sourcePath, err)
+	}
+	rendered, err := c.engine.ProcessTemplate(string(templateContent), c.context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", sourcePath, err)
+	}
+	return []byte(rendered), nil
+}
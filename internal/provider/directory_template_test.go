@@ -0,0 +1,201 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTemplatePatternSuffix(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "*.tmpl", expected: ".tmpl"},
+		{pattern: "*.yml.tmpl", expected: ".yml.tmpl"},
+		{pattern: "config.?.tpl", expected: ".tpl"},
+		{pattern: "[ab]*.hbs", expected: ".hbs"},
+		{pattern: "alacritty.yml.tmpl", expected: ".tmpl"},
+		{pattern: "*", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			if got := templatePatternSuffix(tc.pattern); got != tc.expected {
+				t.Errorf("Expected suffix %q for %q, got %q", tc.expected, tc.pattern, got)
+			}
+		})
+	}
+}
+
+func TestDirectoryTemplateConfigTargetPath(t *testing.T) {
+	config := &directoryTemplateConfig{pattern: "*.tmpl", suffix: ".tmpl"}
+
+	testCases := map[string]string{
+		"/home/user/.config/i3/config.tmpl": "/home/user/.config/i3/config",
+		"/home/user/.config/i3/config":      "/home/user/.config/i3/config",
+		"/home/user/.config/i3/.tmpl":       "/home/user/.config/i3/.tmpl",
+		"/home/user/.config/app/a.yml.tmpl": "/home/user/.config/app/a.yml",
+	}
+
+	for input, expected := range testCases {
+		if got := config.targetPath(input); got != expected {
+			t.Errorf("Expected target %s for %s, got %s", expected, input, got)
+		}
+	}
+}
+
+func TestBuildDirectoryTemplateConfig(t *testing.T) {
+	r := &DirectoryResource{client: &DotfilesClient{Config: &DotfilesConfig{TemplateEngine: "handlebars"}}}
+
+	t.Run("no pattern", func(t *testing.T) {
+		config, err := r.buildDirectoryTemplateConfig(&DirectoryResourceModel{TemplatePattern: types.StringNull()})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if config != nil {
+			t.Error("Expected nil config without template_pattern")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := r.buildDirectoryTemplateConfig(&DirectoryResourceModel{TemplatePattern: types.StringValue("[*.tmpl")})
+		if err == nil {
+			t.Error("Expected error for malformed template_pattern")
+		}
+	})
+
+	t.Run("engine falls back to provider setting", func(t *testing.T) {
+		data := &DirectoryResourceModel{TemplateEngine: types.StringNull()}
+		if engine := r.directoryTemplateEngine(data); engine != "handlebars" {
+			t.Errorf("Expected provider engine handlebars, got %s", engine)
+		}
+
+		data.TemplateEngine = types.StringValue("mustache")
+		if engine := r.directoryTemplateEngine(data); engine != "mustache" {
+			t.Errorf("Expected resource engine mustache, got %s", engine)
+		}
+
+		if engine := (&DirectoryResource{}).directoryTemplateEngine(&DirectoryResourceModel{}); engine != TemplateEngineGo {
+			t.Errorf("Expected default engine go, got %s", engine)
+		}
+	})
+}
+
+func TestDirectoryResourceSyncWithTemplates(t *testing.T) {
+	sourceDir := t.TempDir()
+	files := map[string]string{
+		"alacritty.yml.tmpl":   "font_size: {{.font_size}}\nplatform: {{.system.platform}}\n",
+		"themes/dark.yml":      "background: '{{not rendered}}'\n",
+		"themes/host.yml.tmpl": "host: {{.hostname}}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	targetDir := filepath.Join(t.TempDir(), "alacritty")
+	r := &DirectoryResource{client: &DotfilesClient{Config: &DotfilesConfig{}, Platform: "linux"}}
+	data := &DirectoryResourceModel{
+		Recursive:           types.BoolValue(true),
+		PreservePermissions: types.BoolValue(true),
+		TemplatePattern:     types.StringValue("*.tmpl"),
+		TemplateEngine:      types.StringNull(),
+		TemplateVars: types.MapValueMust(types.StringType, map[string]attr.Value{
+			"font_size": types.StringValue("12"),
+			"hostname":  types.StringValue("workstation"),
+		}),
+	}
+
+	if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+		t.Fatalf("syncDirectory failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"alacritty.yml":   "font_size: 12\nplatform: linux\n",
+		"themes/dark.yml": "background: '{{not rendered}}'\n",
+		"themes/host.yml": "host: workstation\n",
+	}
+	for name, want := range expected {
+		content, err := os.ReadFile(filepath.Join(targetDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s in target: %v", name, err)
+		}
+		if string(content) != want {
+			t.Errorf("Unexpected content in %s: got %q, want %q", name, content, want)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, "alacritty.yml.tmpl")); !os.IsNotExist(err) {
+		t.Error("Template source name should not be written to target")
+	}
+
+	info, err := os.Stat(filepath.Join(targetDir, "alacritty.yml"))
+	if err != nil {
+		t.Fatalf("Failed to stat rendered file: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected preserved permission 0640, got %o", info.Mode().Perm())
+	}
+}
+
+func TestDirectoryResourceSyncValidatesOutput(t *testing.T) {
+	sourceDir := t.TempDir()
+	files := map[string]string{
+		"settings.toml.tmpl": "[font]\nsize = {{.font_size}}\n",
+		"theme.toml":         "[colors\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "settings.toml"), []byte("[font]\nsize = 11\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &DirectoryResource{client: &DotfilesClient{Config: &DotfilesConfig{}}}
+	data := &DirectoryResourceModel{
+		Recursive:           types.BoolValue(false),
+		PreservePermissions: types.BoolValue(false),
+		TemplatePattern:     types.StringValue("*.tmpl"),
+		TemplateVars:        types.MapValueMust(types.StringType, map[string]attr.Value{"font_size": types.StringValue("")}),
+		ValidateAs:          types.StringValue("toml"),
+	}
+
+	err := r.syncDirectory(context.Background(), sourceDir, targetDir, data)
+	if err == nil || !strings.Contains(err.Error(), "invalid TOML at line") {
+		t.Fatalf("Expected a TOML validation error, got %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "settings.toml")); string(content) != "[font]\nsize = 11\n" {
+		t.Errorf("Expected the existing file to be left untouched, got %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "theme.toml")); !os.IsNotExist(err) {
+		t.Error("Expected the invalid copied file not to be written")
+	}
+
+	data.TemplateVars = types.MapValueMust(types.StringType, map[string]attr.Value{"font_size": types.StringValue("13")})
+	if err := os.WriteFile(filepath.Join(sourceDir, "theme.toml"), []byte("[colors]\nbackground = \"#000\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to fix source: %v", err)
+	}
+	if err := r.syncDirectory(context.Background(), sourceDir, targetDir, data); err != nil {
+		t.Fatalf("syncDirectory failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "settings.toml")); string(content) != "[font]\nsize = 13\n" {
+		t.Errorf("Unexpected rendered content %q", content)
+	}
+}
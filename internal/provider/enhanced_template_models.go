@@ -19,6 +19,9 @@ type EnhancedFileResourceModelWithTemplate struct {
 	TemplateEngine       types.String `tfsdk:"template_engine"`
 	PlatformTemplateVars types.Map    `tfsdk:"platform_template_vars"`
 	TemplateFunctions    types.Map    `tfsdk:"template_functions"`
+	TemplateStrict       types.Bool   `tfsdk:"template_strict"`
+	TemplateDataFiles    types.List   `tfsdk:"template_data_files"`
+	TemplateDataHash     types.String `tfsdk:"template_data_hash"`
 }
 
 // EnhancedSymlinkResourceModelWithTemplate extends EnhancedSymlinkResourceModelWithBackup with template features.
@@ -53,7 +56,7 @@ func GetEnhancedTemplateAttributes() map[string]schema.Attribute {
 			Optional:            true,
 			Computed:            true,
 			Default:             stringdefault.StaticString("go"),
-			MarkdownDescription: "Template engine to use: go (default), handlebars, or mustache",
+			MarkdownDescription: "Template engine to use: go (default), handlebars, mustache, or chezmoi (Go templates with chezmoi function shims)",
 			Validators: []validator.String{
 				validators.ValidTemplateEngine(),
 			},
@@ -76,5 +79,18 @@ func GetEnhancedTemplateAttributes() map[string]schema.Attribute {
 			ElementType:         types.StringType,
 			MarkdownDescription: "Custom template functions (name -> value mappings)",
 		},
+		"template_strict": schema.BoolAttribute{
+			Optional:            true,
+			MarkdownDescription: "Fail rendering when the template uses a variable that is not set, instead of writing `<no value>`. Plans report undefined variables as errors rather than warnings",
+		},
+		"template_data_files": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "YAML, JSON or TOML files in the repository whose top-level keys become template variables. Later files are deep-merged over earlier ones; `platform_template_vars` and then `template_vars` take precedence over them",
+		},
+		"template_data_hash": schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: "SHA256 hash of the template data files, so changing them re-renders the template",
+		},
 	}
 }
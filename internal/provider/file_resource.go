@@ -27,6 +27,8 @@ import (
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &FileResource{}
+var _ resource.ResourceWithImportState = &FileResource{}
+var _ resource.ResourceWithModifyPlan = &FileResource{}
 
 func NewFileResource() resource.Resource {
 	return &FileResource{}
@@ -51,6 +53,9 @@ type FileResourceModel struct {
 	// Template variables (for template processing)
 	TemplateVars types.Map `tfsdk:"template_vars"`
 
+	// Format the written content must parse as
+	ValidateAs types.String `tfsdk:"validate_as"`
+
 	// Computed attributes for state tracking
 	ContentHash  types.String `tfsdk:"content_hash"`
 	LastModified types.String `tfsdk:"last_modified"`
@@ -113,6 +118,7 @@ func (r *FileResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 			MarkdownDescription: "Variables for template processing",
 		},
 		"permission_rules": GetPermissionRulesAttribute(),
+		"validate_as":      GetValidateAsAttribute("Format the rendered or copied file must parse as before it is written"),
 		"content_hash": schema.StringAttribute{
 			Computed:            true,
 			MarkdownDescription: "SHA256 hash of file content",
@@ -153,6 +159,11 @@ func (r *FileResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 	}
 }
 
+// ImportState imports an existing file by its target path.
+func (r *FileResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importTargetPath(ctx, req, resp)
+}
+
 func (r *FileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
@@ -206,7 +217,13 @@ func (r *FileResource) Create(ctx context.Context, req resource.CreateRequest, r
 // prepareFileCreation handles initial setup, validation, and configuration for file creation
 func (r *FileResource) prepareFileCreation(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, resp *resource.CreateResponse) (string, string, *fileops.FileManager, *fileops.PermissionConfig, *fileops.EnhancedBackupConfig, error) {
 	// Get repository information (for local path if it's a Git repository)
-	repositoryLocalPath := r.getRepositoryLocalPath(data.Repository.ValueString())
+	repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		repoErr := errors.ConfigurationError("resolve_repository", "file", "No repository holding source_path", err).
+			WithContext("repository", data.Repository.ValueString())
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, repoErr, "Repository not found")
+		return "", "", nil, nil, nil, err
+	}
 
 	// Build source file path
 	sourcePath := filepath.Join(repositoryLocalPath, data.SourcePath.ValueString())
@@ -333,6 +350,7 @@ func (r *FileResource) processFile(ctx context.Context, data *EnhancedFileResour
 	if data.IsTemplate.ValueBool() {
 		return r.processTemplateFile(ctx, data, sourcePath, expandedTargetPath, permConfig, resp)
 	}
+	data.TemplateDataHash = types.StringNull()
 	return r.processRegularFile(ctx, data, sourcePath, expandedTargetPath, fileManager, permConfig, resp)
 }
 
@@ -348,13 +366,23 @@ func (r *FileResource) processTemplateFile(ctx context.Context, data *EnhancedFi
 		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, templateErr, "Invalid template configuration")
 		return err
 	}
+	if err := r.loadTemplateData(ctx, data, templateConfig); err != nil {
+		dataErr := errors.ConfigurationError("load_template_data", "file", "Failed to load template data files", err).
+			WithPath(expandedTargetPath).
+			WithContext("file_name", data.Name.ValueString())
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, dataErr, "Invalid template data")
+		return err
+	}
 
 	// Process template with enhanced features and retry
 	if !r.client.Config.DryRun {
 		finalErr := errors.Retry(ctx, errors.DefaultRetryConfig(), func() error {
-			return r.processEnhancedTemplate(sourcePath, expandedTargetPath, templateConfig, permConfig)
+			return r.processEnhancedTemplate(sourcePath, expandedTargetPath, templateConfig, permConfig, data.ValidateAs)
 		})
 
+		if addOutputValidationError(ctx, &resp.Diagnostics, finalErr, "file", expandedTargetPath) {
+			return finalErr
+		}
 		if finalErr != nil {
 			templateErr := errors.TemplateError("process_template", "file", "Template processing failed", finalErr).
 				WithPath(expandedTargetPath).
@@ -378,8 +406,22 @@ func (r *FileResource) processTemplateFile(ctx context.Context, data *EnhancedFi
 func (r *FileResource) processRegularFile(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, sourcePath, expandedTargetPath string, fileManager *fileops.FileManager, permConfig *fileops.PermissionConfig, resp *resource.CreateResponse) error {
 	// Regular file copy with enhanced permissions and retry
 	if !r.client.Config.DryRun {
+		content, err := os.ReadFile(sourcePath)
+		if err == nil {
+			err = validateOutput(data.ValidateAs, content)
+		}
+		if err != nil {
+			if !addOutputValidationError(ctx, &resp.Diagnostics, err, "file", expandedTargetPath) {
+				readErr := errors.IOError("validate_output", "file", "Could not read source file", err).
+					WithPath(sourcePath).
+					WithContext("file_name", data.Name.ValueString())
+				errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, readErr, "File operation failed")
+			}
+			return err
+		}
+
 		finalErr := errors.Retry(ctx, errors.DefaultRetryConfig(), func() error {
-			return fileManager.CopyFileWithPermissions(sourcePath, expandedTargetPath, permConfig)
+			return writeOutput(fileManager, expandedTargetPath, content, permConfig)
 		})
 
 		if finalErr != nil {
@@ -505,7 +547,13 @@ func (r *FileResource) Update(ctx context.Context, req resource.UpdateRequest, r
 // prepareFileUpdate handles initial setup, validation, and configuration for file updates
 func (r *FileResource) prepareFileUpdate(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, resp *resource.UpdateResponse) (string, string, *fileops.FileManager, *fileops.PermissionConfig, *fileops.EnhancedBackupConfig, error) {
 	// Get repository local path
-	repositoryLocalPath := r.getRepositoryLocalPath(data.Repository.ValueString())
+	repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		repoErr := errors.ConfigurationError("resolve_repository", "file", "No repository holding source_path", err).
+			WithContext("repository", data.Repository.ValueString())
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, repoErr, "Repository not found")
+		return "", "", nil, nil, nil, err
+	}
 
 	// Build paths
 	sourcePath := filepath.Join(repositoryLocalPath, data.SourcePath.ValueString())
@@ -613,9 +661,18 @@ func (r *FileResource) processFileUpdate(ctx context.Context, data *EnhancedFile
 	if data.IsTemplate.ValueBool() {
 		finalErr = r.processTemplateFileUpdate(ctx, data, sourcePath, expandedTargetPath, permConfig, resp)
 	} else {
-		finalErr = fileManager.CopyFileWithPermissions(sourcePath, expandedTargetPath, permConfig)
+		data.TemplateDataHash = types.StringNull()
+		var content []byte
+		if content, finalErr = os.ReadFile(sourcePath); finalErr == nil {
+			if finalErr = validateOutput(data.ValidateAs, content); finalErr == nil {
+				finalErr = writeOutput(fileManager, expandedTargetPath, content, permConfig)
+			}
+		}
 	}
 
+	if addOutputValidationError(ctx, &resp.Diagnostics, finalErr, "file", expandedTargetPath) {
+		return finalErr
+	}
 	if finalErr != nil {
 		resp.Diagnostics.AddError(
 			"File update failed",
@@ -629,7 +686,6 @@ func (r *FileResource) processFileUpdate(ctx context.Context, data *EnhancedFile
 
 // processTemplateFileUpdate handles template file processing for updates
 func (r *FileResource) processTemplateFileUpdate(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, sourcePath, expandedTargetPath string, permConfig *fileops.PermissionConfig, resp *resource.UpdateResponse) error {
-	_ = ctx // Context not used in this template processing
 	templateConfig, err := buildEnhancedTemplateConfigFromAppModel(data)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -638,8 +694,15 @@ func (r *FileResource) processTemplateFileUpdate(ctx context.Context, data *Enha
 		)
 		return err
 	}
+	if err := r.loadTemplateData(ctx, data, templateConfig); err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid template data",
+			fmt.Sprintf("Failed to load template data files: %s", err.Error()),
+		)
+		return err
+	}
 
-	return r.processEnhancedTemplate(sourcePath, expandedTargetPath, templateConfig, permConfig)
+	return r.processEnhancedTemplate(sourcePath, expandedTargetPath, templateConfig, permConfig, data.ValidateAs)
 }
 
 // finalizeFileUpdate handles post-update commands, metadata updates, and state saving
@@ -713,12 +776,10 @@ func (r *FileResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 	}
 }
 
-// getRepositoryLocalPath returns the local path for a repository.
-func (r *FileResource) getRepositoryLocalPath(repositoryID string) string {
-	// For now, assume repository ID maps to the dotfiles root
-	// NOTE: Repository lookup feature planned for v0.2.0 - will enable proper multi-repository support
-	_ = repositoryID // Placeholder for future repository lookup implementation
-	return r.client.Config.DotfilesRoot
+// getRepositoryLocalPath returns the root source_path is resolved against: the root path
+// of the repository, which honours its subdirectory.
+func (r *FileResource) getRepositoryLocalPath(repositoryID string) (string, error) {
+	return r.client.RepositoryRoot(repositoryID)
 }
 
 // updateComputedAttributes updates computed attributes for state tracking.
@@ -843,6 +904,8 @@ func buildEnhancedTemplateConfig(data *EnhancedFileResourceModelWithTemplate) (*
 		}
 	}
 
+	config.Strict = data.TemplateStrict.ValueBool()
+
 	// Parse template functions (simple string mappings for now)
 	if !data.TemplateFunctions.IsNull() {
 		elements := data.TemplateFunctions.Elements()
@@ -863,6 +926,9 @@ type EnhancedTemplateConfig struct {
 	UserVars        map[string]interface{}
 	PlatformVars    map[string]map[string]interface{}
 	CustomFunctions map[string]interface{}
+	Strict          bool
+	// DataVars holds the merged contents of the template data files, see loadTemplateData
+	DataVars map[string]interface{}
 }
 
 // ValidateEnhancedTemplateConfig validates enhanced template configuration.
@@ -872,7 +938,7 @@ func ValidateEnhancedTemplateConfig(config *EnhancedTemplateConfig) error {
 	}
 
 	// Validate template engine
-	validEngines := []string{"go", "handlebars", "mustache"}
+	validEngines := []string{"go", "handlebars", "mustache", "chezmoi"}
 	valid := false
 	for _, engine := range validEngines {
 		if config.Engine == engine {
@@ -925,48 +991,99 @@ func buildFilePermissionConfig(data *EnhancedFileResourceModel) (*fileops.Permis
 
 // Shell command execution has been removed for security reasons (G204 vulnerability)
 
-// processEnhancedTemplate processes a template with enhanced features.
-func (r *FileResource) processEnhancedTemplate(sourcePath, targetPath string, config *EnhancedTemplateConfig, permConfig *fileops.PermissionConfig) error {
+// processEnhancedTemplate processes a template with enhanced features. The rendered content
+// is checked against validateAs and written atomically, so an invalid render or a failed
+// write leaves the existing file as it was.
+func (r *FileResource) processEnhancedTemplate(sourcePath, targetPath string, config *EnhancedTemplateConfig, permConfig *fileops.PermissionConfig, validateAs types.String) error {
+	rendered, err := r.renderTemplate(sourcePath, config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateOutput(validateAs, []byte(rendered)); err != nil {
+		return err
+	}
+
+	if err := writeOutput(r.fileManager(), targetPath, []byte(rendered), permConfig); err != nil {
+		return fmt.Errorf("failed to write rendered template: %w", err)
+	}
+	return nil
+}
+
+// renderTemplate renders the template at sourcePath with the engine, functions, strictness
+// and variables of config.
+func (r *FileResource) renderTemplate(sourcePath string, config *EnhancedTemplateConfig) (string, error) {
 	// Create template engine based on configuration
 	var engine template.TemplateEngine
 	var err error
 
-	if len(config.CustomFunctions) > 0 {
+	switch {
+	case config.Strict:
+		engine, err = template.CreateStrictTemplateEngine(config.Engine, config.CustomFunctions)
+	case len(config.CustomFunctions) > 0:
 		engine, err = template.CreateTemplateEngineWithFunctions(config.Engine, config.CustomFunctions)
-	} else {
+	default:
 		engine, err = template.CreateTemplateEngine(config.Engine)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to create template engine: %w", err)
+		return "", fmt.Errorf("failed to create template engine: %w", err)
 	}
 
 	// Build comprehensive template context
-	systemInfo := r.client.GetPlatformInfo()
-	templateContext := template.BuildPlatformAwareTemplateContext(
-		systemInfo,
-		config.UserVars,
-		config.PlatformVars,
-	)
+	templateContext := r.templateContext(config)
 
-	// Process template file
-	err = engine.ProcessTemplateFile(sourcePath, targetPath, templateContext, permConfig.FileMode)
+	// Render the template
+	templateContent, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template file: %w", err)
+	}
+	rendered, err := engine.ProcessTemplate(string(templateContent), templateContext)
 	if err != nil {
-		return fmt.Errorf("failed to process template file: %w", err)
+		return "", fmt.Errorf("failed to process template file: %w", err)
 	}
+	return rendered, nil
+}
 
-	// Apply permissions after template processing
-	err = r.fileManager().ApplyPermissions(targetPath, permConfig)
+// writeOutput atomically replaces targetPath with content and applies permConfig. A
+// configured file mode is set before the file is renamed into place, so the content is never
+// readable with broader permissions; otherwise an existing file keeps its mode.
+func writeOutput(fileManager *fileops.FileManager, targetPath string, content []byte, permConfig *fileops.PermissionConfig) error {
+	fileMode := ""
+	if permConfig != nil {
+		fileMode = permConfig.FileMode
+	}
+	mode, err := utils.ParseFileMode(fileMode)
 	if err != nil {
-		return fmt.Errorf("failed to apply permissions after template processing: %w", err)
+		return fmt.Errorf("invalid file mode: %w", err)
 	}
 
+	write := fileManager.WriteFileAtomic
+	if fileMode != "" {
+		write = fileManager.WriteFileAtomicWithMode
+	}
+	if err := write(targetPath, content, mode); err != nil {
+		return err
+	}
+	if err := fileManager.ApplyPermissions(targetPath, permConfig); err != nil {
+		return fmt.Errorf("failed to apply permissions: %w", err)
+	}
 	return nil
 }
 
+// templateContext builds the variables a template is rendered with.
+func (r *FileResource) templateContext(config *EnhancedTemplateConfig) map[string]interface{} {
+	return template.BuildPlatformAwareTemplateContext(
+		r.client.GetPlatformInfo(),
+		config.UserVars,
+		config.PlatformVars,
+		config.DataVars,
+	)
+}
+
 // fileManager creates a file manager instance for this resource.
 func (r *FileResource) fileManager() *fileops.FileManager {
 	platformProvider := platform.DetectPlatform()
-	return fileops.NewFileManager(platformProvider, r.client.Config.DryRun)
+	return fileops.NewFileManager(platformProvider, r.client.Config.DryRun).WithConcurrency(r.client.ConcurrencyManager)
 }
 
 // Application detection functionality has been removed from the file resource.
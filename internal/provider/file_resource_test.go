@@ -5,10 +5,16 @@ package provider
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
 )
 
 func TestFileResource(t *testing.T) {
@@ -172,6 +178,136 @@ func TestFileResourceModel(t *testing.T) {
 	}
 }
 
+func TestFileResourceValidateAs(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "karabiner.json.tmpl")
+	if err := os.WriteFile(source, []byte("{\n  \"profiles\": [{{.profiles}}]\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	target := filepath.Join(tempDir, "karabiner.json")
+	if err := os.WriteFile(target, []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &FileResource{client: &DotfilesClient{Config: &DotfilesConfig{}}}
+	ctx := context.Background()
+	data := &EnhancedFileResourceModelWithTemplate{}
+	data.Name = types.StringValue("karabiner")
+	data.ValidateAs = types.StringValue("json")
+	permConfig := &fileops.PermissionConfig{FileMode: "0644"}
+
+	// A render that is not valid JSON is reported with its position and not written
+	data.TemplateVars = types.MapValueMust(types.StringType, map[string]attr.Value{"profiles": types.StringValue(`{"name": "Default",}`)})
+	resp := &resource.CreateResponse{}
+	if err := r.processTemplateFile(ctx, data, source, target, permConfig, resp); err == nil {
+		t.Fatal("Expected the invalid render to fail")
+	}
+	if !resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics.Errors()[0].Detail(), "line 2, column 35") {
+		t.Errorf("Expected a diagnostic with the line and column, got %v", resp.Diagnostics)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "{}\n" {
+		t.Errorf("Expected the existing file to be left untouched, got %q", content)
+	}
+
+	data.TemplateVars = types.MapValueMust(types.StringType, map[string]attr.Value{"profiles": types.StringValue(`{"name": "Default"}`)})
+	resp = &resource.CreateResponse{}
+	if err := r.processTemplateFile(ctx, data, source, target, permConfig, resp); err != nil {
+		t.Fatalf("processTemplateFile failed: %v", resp.Diagnostics)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "{\n  \"profiles\": [{\"name\": \"Default\"}]\n}\n" {
+		t.Errorf("Unexpected rendered content %q", content)
+	}
+
+	// Copied files are checked too
+	resp = &resource.CreateResponse{}
+	if err := r.processRegularFile(ctx, data, source, target, r.fileManager(), permConfig, resp); err == nil || !resp.Diagnostics.HasError() {
+		t.Error("Expected copying an invalid JSON file to fail")
+	}
+	if content, _ := os.ReadFile(target); !strings.Contains(string(content), "Default") {
+		t.Errorf("Expected the existing file to be left untouched, got %q", content)
+	}
+
+	// Valid copies replace the target atomically with the configured mode
+	valid := filepath.Join(tempDir, "settings.json")
+	if err := os.WriteFile(valid, []byte("{\"theme\": \"dark\"}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	resp = &resource.CreateResponse{}
+	if err := r.processRegularFile(ctx, data, valid, target, r.fileManager(), &fileops.PermissionConfig{FileMode: "0600"}, resp); err != nil {
+		t.Fatalf("processRegularFile failed: %v", resp.Diagnostics)
+	}
+	content, _ := os.ReadFile(target)
+	info, _ := os.Stat(target)
+	if string(content) != "{\"theme\": \"dark\"}\n" || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the copy with mode 0600, got %q %v", content, info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("Temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
 // TestFileResourceCRUD is planned for when file operations are implemented.
 // Currently the resource methods are stubs, so we focus on testing.
 // the schema, metadata, and configuration which are fully functional.
+
+func TestFileResourceSubdirectoryCheckout(t *testing.T) {
+	tempDir := t.TempDir()
+	checkout := filepath.Join(tempDir, "checkout")
+	for name, content := range map[string]string{
+		"zshrc":               "top of the checkout\n",
+		"home/dotfiles/zshrc": "from the subdirectory\n",
+	} {
+		path := filepath.Join(checkout, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// The repository ID carries the root path
+	ctx := context.Background()
+	config := &DotfilesConfig{DotfilesRoot: tempDir}
+	repository := &RepositoryResource{client: &DotfilesClient{Config: config, HomeDir: tempDir}}
+	repoData := &RepositoryResourceModel{
+		Name:         types.StringValue("dotfiles"),
+		LocalPath:    types.StringValue(checkout),
+		Subdirectory: types.StringValue("home/dotfiles"),
+	}
+	if err := repository.setRootPath(repoData); err != nil {
+		t.Fatalf("setRootPath failed: %v", err)
+	}
+
+	// so the file is deployed by another process, in which the repository is unchanged
+	r := &FileResource{client: &DotfilesClient{Config: config, HomeDir: tempDir}}
+	target := filepath.Join(tempDir, "home", ".zshrc")
+	data := &EnhancedFileResourceModelWithTemplate{}
+	data.Name = types.StringValue("zshrc")
+	data.Repository = repoData.RootPath
+	data.SourcePath = types.StringValue("zshrc")
+	data.TargetPath = types.StringValue(target)
+	data.IsTemplate = types.BoolValue(false)
+	data.FileMode = types.StringValue("0644")
+
+	resp := &resource.CreateResponse{}
+	sourcePath, targetPath, fileManager, permConfig, _, err := r.prepareFileCreation(ctx, data, resp)
+	if err != nil {
+		t.Fatalf("prepareFileCreation failed: %v", resp.Diagnostics)
+	}
+	if err := r.processFile(ctx, data, sourcePath, targetPath, fileManager, permConfig, resp); err != nil {
+		t.Fatalf("processFile failed: %v", resp.Diagnostics)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "from the subdirectory\n" {
+		t.Errorf("Expected source_path to resolve within the subdirectory, got %q", content)
+	}
+
+	// Repositories nothing has registered are errors, not dotfiles_root
+	data.Repository = types.StringValue("unregistered")
+	if _, _, _, _, _, err := r.prepareFileCreation(ctx, data, &resource.CreateResponse{}); err == nil {
+		t.Error("Expected error for an unregistered repository")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/template"
+)
+
+// ModifyPlan hashes the template data files, so a changed file shows up as a diff, and
+// checks the template source against the variables it will be rendered with, so undefined
+// variables, unused template_vars and unknown functions are reported at plan time. The hash
+// is only compared with state, never planned as a known value, since a repository update
+// in the same apply may change the files again.
+func (r *FileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var data EnhancedFileResourceModelWithTemplate
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.IsTemplate.IsUnknown() || data.Repository.IsUnknown() || data.TemplateDataFiles.IsUnknown() {
+		return
+	}
+	if !data.IsTemplate.ValueBool() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("template_data_hash"), types.StringNull())...)
+		return
+	}
+	config, err := buildEnhancedTemplateConfig(&data)
+	if err != nil {
+		return // reported on apply
+	}
+	if err := r.loadTemplateData(ctx, &data, config); err != nil {
+		tflog.Debug(ctx, "Template data cannot be read at plan time", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	r.checkTemplate(ctx, &data, config, &resp.Diagnostics)
+
+	if req.State.Raw.IsNull() {
+		return
+	}
+	var stateHash types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("template_data_hash"), &stateHash)...)
+	if data.TemplateDataHash.Equal(stateHash) {
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("template_data_hash"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content_hash"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("last_modified"), types.StringUnknown())...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("file_exists"), types.BoolUnknown())...)
+}
+
+// checkTemplate statically analyzes a template source file. Templates that cannot be read
+// yet, e.g. in a repository that is not cloned, are checked on apply instead.
+func (r *FileResource) checkTemplate(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, config *EnhancedTemplateConfig, diags *diag.Diagnostics) {
+	if !data.IsTemplate.ValueBool() || data.Repository.IsUnknown() || data.SourcePath.IsUnknown() ||
+		data.TemplateEngine.IsUnknown() || data.TemplateVars.IsUnknown() ||
+		data.PlatformTemplateVars.IsUnknown() || data.TemplateFunctions.IsUnknown() || data.TemplateStrict.IsUnknown() {
+		return
+	}
+
+	source := data.SourcePath.ValueString()
+	repoPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		tflog.Debug(ctx, "Template repository cannot be resolved at plan time", map[string]interface{}{
+			"repository": data.Repository.ValueString(),
+			"error":      err.Error(),
+		})
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(repoPath, source))
+	if err != nil {
+		tflog.Debug(ctx, "Template cannot be read at plan time", map[string]interface{}{
+			"source_path": source,
+			"error":       err.Error(),
+		})
+		return
+	}
+
+	analysis, err := template.Analyze(config.Engine, string(content), r.templateContext(config), config.CustomFunctions)
+	if err != nil {
+		diags.AddAttributeError(path.Root("source_path"), "Invalid template",
+			fmt.Sprintf("Template %s could not be parsed: %s", source, err.Error()))
+		return
+	}
+
+	for _, function := range analysis.UnknownFunctions {
+		diags.AddAttributeError(path.Root("source_path"), "Unknown template function",
+			fmt.Sprintf("%s:%d: function %q is not provided by the %s template engine or template_functions", source, function.Line, function.Name, config.Engine))
+	}
+	for _, variable := range analysis.Undefined {
+		if config.Strict {
+			diags.AddAttributeError(path.Root("source_path"), "Undefined template variable",
+				fmt.Sprintf("%s:%d: %s is not set in template_vars, platform_template_vars, template_data_files or the system variables", source, variable.Line, variable.Name))
+		} else {
+			diags.AddAttributeWarning(path.Root("source_path"), "Undefined template variable",
+				fmt.Sprintf("%s:%d: %s is not set in template_vars, platform_template_vars, template_data_files or the system variables and will render as <no value>. Set template_strict to make this an error", source, variable.Line, variable.Name))
+		}
+	}
+
+	if analysis.WholeContext {
+		return
+	}
+	var unused []string
+	for name := range config.UserVars {
+		if !analysis.Referenced[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		diags.AddAttributeWarning(path.Root("template_vars").AtMapKey(name), "Unused template variable",
+			fmt.Sprintf("Template variable %q is not used by %s", name, source))
+	}
+}
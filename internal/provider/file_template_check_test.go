@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+)
+
+// gitconfigTemplate returns a template file model for a gitconfig in root.
+func gitconfigTemplate(t *testing.T, root, content string, vars map[string]string) *EnhancedFileResourceModelWithTemplate {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, "gitconfig.tmpl"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	elements := make(map[string]attr.Value, len(vars))
+	for name, value := range vars {
+		elements[name] = types.StringValue(value)
+	}
+	data := &EnhancedFileResourceModelWithTemplate{}
+	data.Name = types.StringValue("gitconfig")
+	data.Repository = types.StringValue("dotfiles")
+	data.SourcePath = types.StringValue("gitconfig.tmpl")
+	data.IsTemplate = types.BoolValue(true)
+	data.TemplateEngine = types.StringValue("go")
+	data.TemplateVars = types.MapValueMust(types.StringType, elements)
+	return data
+}
+
+func TestFileResourceCheckTemplate(t *testing.T) {
+	root := t.TempDir()
+	r := &FileResource{client: withRepository(&DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: root}, Platform: "linux"}, "dotfiles", root)}
+	ctx := context.Background()
+	check := func(data *EnhancedFileResourceModelWithTemplate) diag.Diagnostics {
+		var diags diag.Diagnostics
+		config, err := buildEnhancedTemplateConfig(data)
+		if err != nil {
+			t.Fatalf("buildEnhancedTemplateConfig failed: %v", err)
+		}
+		r.checkTemplate(ctx, data, config, &diags)
+		return diags
+	}
+	content := "[user]\n\tname = {{ .name }}\n\temail = {{ .email }}\n\tsigningkey = {{ gpgKey }}\n"
+	data := gitconfigTemplate(t, root, content, map[string]string{"name": "Test", "editor": "vim"})
+
+	diags := check(data)
+	if len(diags.Errors()) != 1 || !strings.Contains(diags.Errors()[0].Detail(), `gitconfig.tmpl:4: function "gpgKey"`) {
+		t.Errorf("Expected an unknown function error with its position, got %v", diags.Errors())
+	}
+	warnings := diags.Warnings()
+	if len(warnings) != 2 || !strings.Contains(warnings[0].Detail(), "gitconfig.tmpl:3: email is not set") ||
+		!strings.Contains(warnings[1].Detail(), `"editor" is not used`) {
+		t.Errorf("Expected undefined and unused variable warnings, got %v", warnings)
+	}
+
+	// Strict mode makes undefined variables errors
+	data = gitconfigTemplate(t, root, "[user]\n\temail = {{ .email }}\n", map[string]string{})
+	data.TemplateStrict = types.BoolValue(true)
+	diags = check(data)
+	if len(diags.Errors()) != 1 || !strings.Contains(diags.Errors()[0].Detail(), "gitconfig.tmpl:2: email") {
+		t.Errorf("Expected an undefined variable error, got %v", diags)
+	}
+
+	// A template that cannot be read yet is checked on apply
+	data.SourcePath = types.StringValue("missing.tmpl")
+	diags = check(data)
+	if diags.HasError() || len(diags.Warnings()) != 0 {
+		t.Errorf("Expected no diagnostics for a missing source, got %v", diags)
+	}
+}
+
+func TestFileResourceStrictTemplate(t *testing.T) {
+	root := t.TempDir()
+	r := &FileResource{client: &DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: root}}}
+	data := gitconfigTemplate(t, root, "[user]\n\temail = {{ .email }}\n", map[string]string{})
+	target := filepath.Join(root, "gitconfig")
+	permConfig := &fileops.PermissionConfig{FileMode: "0644"}
+
+	data.TemplateStrict = types.BoolValue(true)
+	resp := &resource.CreateResponse{}
+	if err := r.processTemplateFile(context.Background(), data, filepath.Join(root, "gitconfig.tmpl"), target, permConfig, resp); err == nil {
+		t.Fatal("Expected a strict template with an undefined variable to fail")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written")
+	}
+
+	data.TemplateStrict = types.BoolValue(false)
+	resp = &resource.CreateResponse{}
+	if err := r.processTemplateFile(context.Background(), data, filepath.Join(root, "gitconfig.tmpl"), target, permConfig, resp); err != nil {
+		t.Fatalf("processTemplateFile failed: %v", resp.Diagnostics)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "[user]\n\temail = <no value>\n" {
+		t.Errorf("Expected the default mode to keep rendering <no value>, got %q", content)
+	}
+}
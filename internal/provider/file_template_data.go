@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/configmerge"
+)
+
+// loadTemplateData reads template_data_files from the repository into config.DataVars,
+// deep-merging later files over earlier ones, and records a hash of the files in data so a
+// changed file re-renders the template.
+func (r *FileResource) loadTemplateData(ctx context.Context, data *EnhancedFileResourceModelWithTemplate, config *EnhancedTemplateConfig) error {
+	data.TemplateDataHash = types.StringNull()
+	var files []string
+	if !data.TemplateDataFiles.IsNull() {
+		if diags := data.TemplateDataFiles.ElementsAs(ctx, &files, false); diags.HasError() {
+			return fmt.Errorf("template_data_files must be a list of paths")
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	repoPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	var merged *configmerge.Document
+	for _, file := range files {
+		path, err := repositoryFile(repoPath, file)
+		if err != nil {
+			return err
+		}
+		format, err := configmerge.DetectFormat(file)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template data file: %w", err)
+		}
+		document, err := configmerge.Parse(format, content)
+		if err != nil {
+			return fmt.Errorf("template data file %s: %w", file, err)
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", file, len(content))
+		hash.Write(content)
+		if merged == nil {
+			merged = document
+		} else {
+			merged.Merge(document, configmerge.Options{Arrays: configmerge.ArrayReplace})
+		}
+	}
+
+	values, err := merged.Value()
+	if err != nil {
+		return fmt.Errorf("failed to decode template data: %w", err)
+	}
+	config.DataVars = values
+	data.TemplateDataHash = types.StringValue(hex.EncodeToString(hash.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+)
+
+func TestFileResourceLoadTemplateData(t *testing.T) {
+	root := t.TempDir()
+	r := &FileResource{client: withRepository(&DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: root}, Platform: "linux"}, "dotfiles", root)}
+	ctx := context.Background()
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	load := func(data *EnhancedFileResourceModelWithTemplate) *EnhancedTemplateConfig {
+		t.Helper()
+		config, err := buildEnhancedTemplateConfig(data)
+		if err != nil {
+			t.Fatalf("buildEnhancedTemplateConfig failed: %v", err)
+		}
+		if err := r.loadTemplateData(ctx, data, config); err != nil {
+			t.Fatalf("loadTemplateData failed: %v", err)
+		}
+		return config
+	}
+
+	writeFile("data/team.yaml", "editor: nano\nshell: zsh\ngit:\n  user: team\n  signing: true\nhosts:\n  - alpha\n  - beta\n")
+	writeFile("data/local.json", `{"git": {"user": "local"}, "hosts": ["gamma"]}`)
+	content := "{{ .editor }} {{ .shell }} {{ .git.user }} {{ .git.signing }}{{ range .hosts }} {{ . }}{{ end }}\n"
+	data := gitconfigTemplate(t, root, content, map[string]string{"editor": "vim"})
+	data.TemplateDataFiles = stringList([]string{"data/team.yaml", "data/local.json"})
+	data.PlatformTemplateVars = types.MapValueMust(types.ObjectType{AttrTypes: map[string]attr.Type{"shell": types.StringType}},
+		map[string]attr.Value{
+			"linux": types.ObjectValueMust(map[string]attr.Type{"shell": types.StringType}, map[string]attr.Value{"shell": types.StringValue("bash")}),
+		})
+
+	config := load(data)
+	if config.DataVars["editor"] != "nano" {
+		t.Errorf("Expected the data file values to be loaded, got %v", config.DataVars)
+	}
+	hash := data.TemplateDataHash.ValueString()
+	if len(hash) != 64 {
+		t.Errorf("Expected a SHA256 hash, got %q", hash)
+	}
+
+	// Later files are deep-merged over earlier ones, platform vars and template_vars win
+	target := filepath.Join(root, "gitconfig")
+	resp := &resource.CreateResponse{}
+	if err := r.processTemplateFile(ctx, data, filepath.Join(root, "gitconfig.tmpl"), target, &fileops.PermissionConfig{FileMode: "0644"}, resp); err != nil {
+		t.Fatalf("processTemplateFile failed: %v", resp.Diagnostics)
+	}
+	if rendered, _ := os.ReadFile(target); string(rendered) != "vim bash local true gamma\n" {
+		t.Errorf("Unexpected rendered template %q", rendered)
+	}
+
+	// The hash follows the file contents
+	writeFile("data/local.json", `{"git": {"user": "other"}}`)
+	load(data)
+	if data.TemplateDataHash.ValueString() == hash {
+		t.Error("Expected the hash to change with the data file")
+	}
+
+	// Without data files there is no hash
+	data.TemplateDataFiles = types.ListNull(types.StringType)
+	if config := load(data); config.DataVars != nil || !data.TemplateDataHash.IsNull() {
+		t.Errorf("Expected no data and no hash, got %v and %v", config.DataVars, data.TemplateDataHash)
+	}
+
+	// Invalid, missing and unsupported files and paths outside the repository are errors
+	writeFile("data/broken.yaml", "a: [1, 2\n")
+	for _, file := range []string{"data/broken.yaml", "data/missing.yaml", "data/team.ini", "../outside.yaml"} {
+		data.TemplateDataFiles = stringList([]string{file})
+		config, _ := buildEnhancedTemplateConfig(data)
+		if err := r.loadTemplateData(ctx, data, config); err == nil {
+			t.Errorf("Expected an error for %s", file)
+		}
+	}
+}
@@ -0,0 +1,535 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/inifile"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+var _ resource.Resource = &IniSettingsResource{}
+var _ resource.ResourceWithValidateConfig = &IniSettingsResource{}
+var _ resource.ResourceWithModifyPlan = &IniSettingsResource{}
+
+// iniValuesType is the type of current_values: the values of each managed key.
+var iniValuesType = types.ListType{ElemType: types.StringType}
+
+func NewIniSettingsResource() resource.Resource {
+	return &IniSettingsResource{}
+}
+
+// IniSettingsResource manages individual keys of an INI-style file, such as .gitconfig or
+// .npmrc, that other tools also write to.
+type IniSettingsResource struct {
+	client *DotfilesClient
+}
+
+// IniSettingsResourceModel describes the resource data model.
+type IniSettingsResourceModel struct {
+	ID            types.String      `tfsdk:"id"`
+	TargetPath    types.String      `tfsdk:"target_path"`
+	Syntax        types.String      `tfsdk:"syntax"`
+	FileMode      types.String      `tfsdk:"file_mode"`
+	BackupEnabled types.Bool        `tfsdk:"backup_enabled"`
+	Settings      []IniSettingModel `tfsdk:"setting"`
+
+	CurrentValues types.Map    `tfsdk:"current_values"`
+	BackupPath    types.String `tfsdk:"backup_path"`
+}
+
+// IniSettingModel is a managed key and its values.
+type IniSettingModel struct {
+	Section    types.String `tfsdk:"section"`
+	Subsection types.String `tfsdk:"subsection"`
+	Key        types.String `tfsdk:"key"`
+	Value      types.String `tfsdk:"value"`
+	Values     types.List   `tfsdk:"values"`
+}
+
+func (r *IniSettingsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ini_settings"
+}
+
+func (r *IniSettingsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manages individual keys of an INI-style file co-owned with other tools, such as `~/.gitconfig`, " +
+			"`~/.npmrc`, `~/.pypirc` or `.editorconfig`. Only the configured keys are written; comments, layout and other " +
+			"keys stay as they are. Managed keys changed outside Terraform are detected as drift, and keys are removed " +
+			"from the file when they are dropped from the configuration or the resource is destroyed.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Settings identifier",
+			},
+			"target_path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "File the keys are managed in. It is created when missing; a symlink is followed",
+				Validators: []validator.String{
+					validators.ValidPath(),
+					validators.EnvironmentVariableExpansion(),
+				},
+			},
+			"syntax": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "File syntax: `git` for git-config files, with subsections, quoting and case-insensitive " +
+					"names, or `ini` for plain INI files. Defaults to `git` for `.gitconfig`, `.gitmodules` and `git/config` " +
+					"files and `ini` otherwise",
+			},
+			"file_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Permissions of a file created for the keys (default: 0644). Existing files keep their permissions",
+				Validators: []validator.String{
+					validators.ValidFileMode(),
+				},
+			},
+			"backup_enabled": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Back up the file before changing it. Defaults to the provider's `backup_enabled`",
+			},
+			"current_values": schema.MapAttribute{
+				Computed:    true,
+				ElementType: iniValuesType,
+				MarkdownDescription: "Values of each managed key as found in the file, by dotted key name " +
+					"(e.g. `url.git@github.com:.insteadOf`); an empty list when the key is missing",
+			},
+			"backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Most recent backup of the file taken before it was changed",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"setting": schema.ListNestedBlock{
+				MarkdownDescription: "A managed key",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"section": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Section of the key. Omit it for keys before the first section, as in `.npmrc`",
+						},
+						"subsection": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Subsection of the key in the git syntax, e.g. `origin` for `[remote \"origin\"]`",
+						},
+						"key": schema.StringAttribute{
+							Required:            true,
+							MarkdownDescription: "Name of the key",
+						},
+						"value": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Value of the key. Conflicts with `values`",
+						},
+						"values": schema.ListAttribute{
+							Optional:    true,
+							ElementType: types.StringType,
+							MarkdownDescription: "Values of a multi-valued key, such as git's `remote.<name>.fetch`, replacing " +
+								"every occurrence of the key. An empty list removes the key. Conflicts with `value`",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *IniSettingsResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig checks the syntax and that every key is valid in it and configured once
+// with one of value or values.
+func (r *IniSettingsResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data IniSettingsResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	syntaxKnown := !data.Syntax.IsUnknown() && (!data.Syntax.IsNull() || !data.TargetPath.IsUnknown())
+	if value := data.Syntax.ValueString(); !data.Syntax.IsNull() && !data.Syntax.IsUnknown() && !contains(iniSyntaxNames(), value) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("syntax"),
+			"Invalid Syntax",
+			fmt.Sprintf("syntax must be one of %s, got %q.", strings.Join(iniSyntaxNames(), ", "), value),
+		)
+		syntaxKnown = false
+	}
+	syntax := iniSyntax(&data, data.TargetPath.ValueString())
+
+	seen := make(map[string]bool)
+	for i, setting := range data.Settings {
+		settingPath := path.Root("setting").AtListIndex(i)
+		if !setting.Value.IsUnknown() && !setting.Values.IsUnknown() && setting.Value.IsNull() == setting.Values.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				settingPath.AtName("value"),
+				"Invalid Setting Value",
+				"Exactly one of value or values must be set.",
+			)
+		}
+		if !syntaxKnown || !iniKeyKnown(setting) {
+			continue
+		}
+		key := iniKey(setting)
+		if err := key.Validate(syntax); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				settingPath.AtName("key"),
+				"Invalid Setting Key",
+				fmt.Sprintf("%s cannot be written in the %s syntax: %s.", key, syntax, err),
+			)
+			continue
+		}
+		if id := iniKeyID(key, syntax); seen[id] {
+			resp.Diagnostics.AddAttributeError(
+				settingPath.AtName("key"),
+				"Duplicate Setting",
+				fmt.Sprintf("%s is configured more than once; use values for multi-valued keys.", key),
+			)
+		} else {
+			seen[id] = true
+		}
+	}
+}
+
+// ModifyPlan plans current_values as the configured values, so managed keys changed
+// outside Terraform show up as an update.
+func (r *IniSettingsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan IniSettingsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.CurrentValues = types.MapUnknown(iniValuesType)
+	if desired, known := iniDesiredValues(ctx, &plan); known {
+		plan.CurrentValues = iniValuesMap(desired)
+	}
+
+	// Rewriting the file on drift may take a new backup
+	if !req.State.Raw.IsNull() {
+		var state IniSettingsResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if !plan.CurrentValues.Equal(state.CurrentValues) {
+			plan.BackupPath = types.StringUnknown()
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *IniSettingsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data IniSettingsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = types.StringNull()
+	if err := r.apply(ctx, &data, nil); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write settings")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *IniSettingsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data IniSettingsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	values, err := r.currentValues(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read settings")
+		return
+	}
+	if !values.Equal(data.CurrentValues) {
+		tflog.Info(ctx, "Managed keys differ from the applied values", map[string]interface{}{
+			"target_path": data.TargetPath.ValueString(),
+		})
+	}
+	data.CurrentValues = values
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *IniSettingsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state IniSettingsResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keys managed in another file, or read with another syntax, are released there first
+	var dropped []inifile.Key
+	if !data.TargetPath.Equal(state.TargetPath) || !data.Syntax.Equal(state.Syntax) {
+		if err := r.release(ctx, &state); err != nil {
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove keys from the previous file")
+			return
+		}
+	} else {
+		dropped = droppedIniKeys(&state, &data, iniSyntax(&data, data.TargetPath.ValueString()))
+	}
+
+	data.BackupPath = state.BackupPath
+	if err := r.apply(ctx, &data, dropped); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write settings")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the managed keys, leaving the rest of the file in place.
+func (r *IniSettingsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data IniSettingsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.release(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove managed keys")
+	}
+}
+
+// apply removes the dropped keys and sets the configured ones. The file is only rewritten
+// when its content changes.
+func (r *IniSettingsResource) apply(ctx context.Context, data *IniSettingsResourceModel, dropped []inifile.Key) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	file, existing, existed, err := readIniFile(target, iniSyntax(data, target))
+	if err != nil {
+		return err
+	}
+	desired, _ := iniDesiredValues(ctx, data)
+	data.ID = types.StringValue(target)
+	data.CurrentValues = iniValuesMap(desired)
+
+	for _, key := range dropped {
+		file.Unset(key)
+	}
+	for _, setting := range data.Settings {
+		key := iniKey(setting)
+		if err := file.Set(key, desired[key.String()]); err != nil {
+			return errors.ValidationError("set_key", "ini_settings", "Invalid setting", err).WithPath(target)
+		}
+	}
+
+	updated := file.String()
+	if updated == existing {
+		return nil
+	}
+	mode, err := sharedFileMode("ini_settings", data.FileMode)
+	if err != nil {
+		return err
+	}
+	if err := r.write(ctx, data, target, updated, existed, mode); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Wrote settings", map[string]interface{}{
+		"target_path": target,
+		"keys":        len(data.Settings),
+	})
+	return nil
+}
+
+// release removes every managed key from the target file.
+func (r *IniSettingsResource) release(ctx context.Context, data *IniSettingsResourceModel) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	file, existing, existed, err := readIniFile(target, iniSyntax(data, target))
+	if err != nil || !existed {
+		return err
+	}
+	for _, setting := range data.Settings {
+		file.Unset(iniKey(setting))
+	}
+	if file.String() == existing {
+		return nil
+	}
+	if err := r.write(ctx, data, target, file.String(), true, 0644); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Removed managed keys", map[string]interface{}{
+		"target_path": target,
+	})
+	return nil
+}
+
+// write writes the updated file, recording the backup taken before the change.
+func (r *IniSettingsResource) write(ctx context.Context, data *IniSettingsResourceModel, target, content string, existed bool, mode os.FileMode) error {
+	backupPath, err := r.client.writeShared(ctx, "ini_settings", target, content, existed, mode, data.BackupEnabled)
+	if err != nil {
+		return err
+	}
+	if backupPath != "" {
+		data.BackupPath = types.StringValue(backupPath)
+	}
+	return nil
+}
+
+// currentValues returns the values of the managed keys as they are in the target file.
+func (r *IniSettingsResource) currentValues(data *IniSettingsResourceModel) (types.Map, error) {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return types.MapNull(iniValuesType), err
+	}
+	file, _, _, err := readIniFile(target, iniSyntax(data, target))
+	if err != nil {
+		return types.MapNull(iniValuesType), err
+	}
+	current := make(map[string][]string, len(data.Settings))
+	for _, setting := range data.Settings {
+		key := iniKey(setting)
+		current[key.String()] = file.Get(key)
+	}
+	return iniValuesMap(current), nil
+}
+
+// targetPath returns the expanded path of the file holding the keys.
+func (r *IniSettingsResource) targetPath(data *IniSettingsResourceModel) (string, error) {
+	if r.client == nil {
+		return "", errors.ConfigurationError("resolve_target", "ini_settings", "Provider is not configured", nil)
+	}
+	target, err := platform.DetectPlatform().ExpandPath(data.TargetPath.ValueString())
+	if err != nil {
+		return "", errors.ValidationError("expand_target_path", "ini_settings", "Could not expand target path", err).
+			WithPath(data.TargetPath.ValueString())
+	}
+	return target, nil
+}
+
+// readIniFile parses the target, returning an empty file when it is missing. It also
+// returns the content as read.
+func readIniFile(target string, syntax inifile.Syntax) (*inifile.File, string, bool, error) {
+	content, existed, err := readShared("ini_settings", target)
+	if err != nil {
+		return nil, "", false, err
+	}
+	file, err := inifile.Parse(syntax, content)
+	if err != nil {
+		return nil, "", false, errors.ValidationError("parse", "ini_settings", "The file cannot be parsed", err).
+			WithPath(target).
+			WithContext("syntax", string(syntax))
+	}
+	return file, content, existed, nil
+}
+
+// iniSyntax returns the configured syntax, or the one detected from target.
+func iniSyntax(data *IniSettingsResourceModel, target string) inifile.Syntax {
+	if !data.Syntax.IsNull() && data.Syntax.ValueString() != "" {
+		return inifile.Syntax(data.Syntax.ValueString())
+	}
+	return inifile.DetectSyntax(target)
+}
+
+func iniSyntaxNames() []string {
+	names := make([]string, 0, len(inifile.Syntaxes))
+	for _, syntax := range inifile.Syntaxes {
+		names = append(names, string(syntax))
+	}
+	return names
+}
+
+func iniKey(setting IniSettingModel) inifile.Key {
+	return inifile.Key{
+		Section:    setting.Section.ValueString(),
+		Subsection: setting.Subsection.ValueString(),
+		Name:       setting.Key.ValueString(),
+	}
+}
+
+func iniKeyKnown(setting IniSettingModel) bool {
+	return !setting.Section.IsUnknown() && !setting.Subsection.IsUnknown() && !setting.Key.IsUnknown()
+}
+
+// iniKeyID identifies a key the way the syntax matches it: git section and key names are
+// case-insensitive.
+func iniKeyID(key inifile.Key, syntax inifile.Syntax) string {
+	if syntax == inifile.SyntaxGit {
+		key.Section = strings.ToLower(key.Section)
+		key.Name = strings.ToLower(key.Name)
+	}
+	return key.Section + "\x00" + key.Subsection + "\x00" + key.Name
+}
+
+// iniDesiredValues returns the configured values by dotted key name, and whether they are
+// all known.
+func iniDesiredValues(ctx context.Context, data *IniSettingsResourceModel) (map[string][]string, bool) {
+	desired := make(map[string][]string, len(data.Settings))
+	for _, setting := range data.Settings {
+		if !iniKeyKnown(setting) || setting.Value.IsUnknown() || setting.Values.IsUnknown() {
+			return desired, false
+		}
+		values := []string{}
+		if !setting.Value.IsNull() {
+			values = append(values, setting.Value.ValueString())
+		} else if diags := setting.Values.ElementsAs(ctx, &values, false); diags.HasError() {
+			return desired, false
+		}
+		desired[iniKey(setting).String()] = values
+	}
+	return desired, true
+}
+
+// droppedIniKeys returns the keys in state that are no longer configured.
+func droppedIniKeys(state, plan *IniSettingsResourceModel, syntax inifile.Syntax) []inifile.Key {
+	kept := make(map[string]bool, len(plan.Settings))
+	for _, setting := range plan.Settings {
+		kept[iniKeyID(iniKey(setting), syntax)] = true
+	}
+	var dropped []inifile.Key
+	for _, setting := range state.Settings {
+		if key := iniKey(setting); !kept[iniKeyID(key, syntax)] {
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
+
+// iniValuesMap converts values by key name to current_values.
+func iniValuesMap(values map[string][]string) types.Map {
+	elements := make(map[string]attr.Value, len(values))
+	for name, list := range values {
+		elements[name] = stringList(list)
+	}
+	return types.MapValueMust(iniValuesType, elements)
+}
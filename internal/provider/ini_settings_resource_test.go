@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestIniSettingsResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewIniSettingsResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_ini_settings" {
+			t.Errorf("Expected TypeName dotfiles_ini_settings, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewIniSettingsResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		if !resp.Schema.Attributes["target_path"].IsRequired() {
+			t.Error("Attribute target_path should be required")
+		}
+		for _, attr := range []string{"id", "current_values", "backup_path"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+		if _, ok := resp.Schema.Blocks["setting"]; !ok {
+			t.Error("Expected a setting block")
+		}
+	})
+}
+
+// iniSetting returns a setting with a single value.
+func iniSetting(section, subsection, key, value string) IniSettingModel {
+	setting := IniSettingModel{
+		Section:    types.StringNull(),
+		Subsection: types.StringNull(),
+		Key:        types.StringValue(key),
+		Value:      types.StringValue(value),
+		Values:     types.ListNull(types.StringType),
+	}
+	if section != "" {
+		setting.Section = types.StringValue(section)
+	}
+	if subsection != "" {
+		setting.Subsection = types.StringValue(subsection)
+	}
+	return setting
+}
+
+func TestIniSettingsResourceLifecycle(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, ".gitconfig")
+	original := "# written by git\n[user]\n\tname = Jane Doe\n[core]\n\tautocrlf = input\n"
+	if err := os.WriteFile(target, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+
+	r := &IniSettingsResource{client: &DotfilesClient{Config: &DotfilesConfig{
+		BackupEnabled:   true,
+		BackupDirectory: filepath.Join(tempDir, "backups"),
+	}}}
+	ctx := context.Background()
+	fetch := iniSetting("remote", "origin", "fetch", "")
+	fetch.Value = types.StringNull()
+	fetch.Values = stringList([]string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"})
+	data := &IniSettingsResourceModel{
+		TargetPath: types.StringValue(target),
+		Settings: []IniSettingModel{
+			iniSetting("user", "", "email", "jane@example.com"),
+			iniSetting("url", "git@github.com:", "insteadOf", "https://github.com/"),
+			fetch,
+		},
+	}
+
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	expected := "# written by git\n[user]\n\tname = Jane Doe\n\temail = jane@example.com\n[core]\n\tautocrlf = input\n" +
+		"[url \"git@github.com:\"]\n\tinsteadOf = https://github.com/\n" +
+		"[remote \"origin\"]\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n\tfetch = +refs/tags/*:refs/tags/*\n"
+	if content, _ := os.ReadFile(target); string(content) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, content)
+	}
+	if data.BackupPath.IsNull() {
+		t.Error("Expected the file to be backed up before it was changed")
+	}
+	current, err := r.currentValues(data)
+	if err != nil {
+		t.Fatalf("currentValues failed: %v", err)
+	}
+	if !current.Equal(data.CurrentValues) {
+		t.Errorf("Expected the applied values to be current, got %v, expected %v", current, data.CurrentValues)
+	}
+
+	// git edits a managed key and an unrelated one
+	edited := "[user]\n\tname = Jane\n\temail = jane@work.example\n[core]\n\tautocrlf = input\n" +
+		"[url \"git@github.com:\"]\n\tinsteadOf = https://github.com/\n" +
+		"[remote \"origin\"]\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n\tfetch = +refs/tags/*:refs/tags/*\n"
+	if err := os.WriteFile(target, []byte(edited), 0644); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	current, _ = r.currentValues(data)
+	if email := current.Elements()["user.email"]; !email.Equal(stringList([]string{"jane@work.example"})) {
+		t.Errorf("Expected the edited email to be read back, got %v", email)
+	}
+
+	// Dropping a key from the configuration removes it from the file
+	state := *data
+	data.Settings = data.Settings[:2]
+	if err := r.apply(ctx, data, droppedIniKeys(&state, data, "git")); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	expected = "[user]\n\tname = Jane\n\temail = jane@example.com\n[core]\n\tautocrlf = input\n" +
+		"[url \"git@github.com:\"]\n\tinsteadOf = https://github.com/\n"
+	if content, _ := os.ReadFile(target); string(content) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, content)
+	}
+	if _, ok := data.CurrentValues.Elements()["remote.origin.fetch"]; ok {
+		t.Error("Expected the dropped key to leave current_values")
+	}
+
+	if err := r.release(ctx, data); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "[user]\n\tname = Jane\n[core]\n\tautocrlf = input\n" {
+		t.Errorf("Expected only the managed keys to be removed, got:\n%s", content)
+	}
+}
+
+func TestIniSettingsResourceNewFile(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "pypi", ".pypirc")
+	r := &IniSettingsResource{client: &DotfilesClient{Config: &DotfilesConfig{}}}
+	ctx := context.Background()
+	data := &IniSettingsResourceModel{
+		TargetPath: types.StringValue(target),
+		FileMode:   types.StringValue("0600"),
+		Settings: []IniSettingModel{
+			iniSetting("distutils", "", "index-servers", "pypi"),
+			iniSetting("pypi", "", "username", "__token__"),
+		},
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(target)
+	if string(content) != "[distutils]\nindex-servers = pypi\n\n[pypi]\nusername = __token__\n" {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	expected := iniValuesMap(map[string][]string{
+		"distutils.index-servers": {"pypi"},
+		"pypi.username":           {"__token__"},
+	})
+	if !reflect.DeepEqual(data.CurrentValues, expected) {
+		t.Errorf("Expected current_values %v, got %v", expected, data.CurrentValues)
+	}
+}
+
+func TestIniDesiredValues(t *testing.T) {
+	unknown := iniSetting("core", "", "editor", "")
+	unknown.Value = types.StringUnknown()
+	data := &IniSettingsResourceModel{Settings: []IniSettingModel{iniSetting("core", "", "pager", "less"), unknown}}
+	if _, known := iniDesiredValues(context.Background(), data); known {
+		t.Error("Expected an unknown value to make the values unknown")
+	}
+
+	empty := iniSetting("core", "", "editor", "")
+	empty.Value = types.StringNull()
+	empty.Values = types.ListValueMust(types.StringType, []attr.Value{})
+	data.Settings[1] = empty
+	desired, known := iniDesiredValues(context.Background(), data)
+	if !known || !reflect.DeepEqual(desired, map[string][]string{"core.pager": {"less"}, "core.editor": {}}) {
+		t.Errorf("Unexpected desired values %v (known %v)", desired, known)
+	}
+}
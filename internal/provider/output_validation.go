@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileformat"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+// GetValidateAsAttribute returns the schema attribute selecting the format written files
+// must parse as.
+func GetValidateAsAttribute(description string) schema.StringAttribute {
+	formats := make([]string, len(fileformat.Formats))
+	for i, format := range fileformat.Formats {
+		formats[i] = string(format)
+	}
+	return schema.StringAttribute{
+		Optional:            true,
+		MarkdownDescription: description + ": " + strings.Join(formats, ", ") + ". Content that does not parse is reported with its line and column, and the existing file is left untouched",
+		Validators: []validator.String{
+			validators.OneOf(formats...),
+		},
+	}
+}
+
+// validateOutput checks content against the format configured in validate_as, if any.
+func validateOutput(validateAs types.String, content []byte) error {
+	if validateAs.IsNull() || validateAs.IsUnknown() || validateAs.ValueString() == "" {
+		return nil
+	}
+	return fileformat.Validate(fileformat.Format(validateAs.ValueString()), content)
+}
+
+// addOutputValidationError reports err as invalid output when it comes from validate_as,
+// and returns whether it did.
+func addOutputValidationError(ctx context.Context, diags *diag.Diagnostics, err error, resourceType, targetPath string) bool {
+	var formatErr *fileformat.Error
+	if !stderrors.As(err, &formatErr) {
+		return false
+	}
+	validationErr := errors.ValidationError("validate_output", resourceType, "Content does not parse as "+formatErr.Format.Name()+"; the existing file was left untouched", err).
+		WithPath(targetPath).
+		WithContext("validate_as", string(formatErr.Format))
+	if formatErr.Line > 0 {
+		validationErr = validationErr.WithContext("line", formatErr.Line)
+	}
+	if formatErr.Column > 0 {
+		validationErr = validationErr.WithContext("column", formatErr.Column)
+	}
+	errors.AddErrorToDiagnostics(ctx, diags, validationErr, "Invalid "+formatErr.Format.Name()+" content")
+	return true
+}
@@ -0,0 +1,370 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &PackageResource{}
+
+// NewPackageResource creates a new Stow-style package resource.
+func NewPackageResource() resource.Resource {
+	return &PackageResource{}
+}
+
+// PackageResource links a GNU Stow-style package tree into a target directory.
+type PackageResource struct {
+	client *DotfilesClient
+}
+
+// PackageResourceModel describes the package resource data model.
+type PackageResourceModel struct {
+	ID         types.String `tfsdk:"id"`
+	Repository types.String `tfsdk:"repository"`
+	Package    types.String `tfsdk:"package"`
+	TargetRoot types.String `tfsdk:"target_root"`
+	Ignore     types.List   `tfsdk:"ignore"`
+	Fold       types.Bool   `tfsdk:"fold"`
+
+	// Computed attributes
+	Links types.List `tfsdk:"links"`
+}
+
+// Metadata sets the resource type name.
+func (r *PackageResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_package"
+}
+
+// Schema defines the resource schema.
+func (r *PackageResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Links a GNU Stow-style package directory (e.g. `zsh/.zshrc`, `nvim/.config/nvim/init.lua`) into a target root using the minimal set of symlinks",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Package identifier",
+			},
+			"repository": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Repository ID this package belongs to",
+			},
+			"package": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Package directory in the repository (e.g. `nvim`)",
+				Validators: []validator.String{
+					validators.NotEmpty(),
+				},
+			},
+			"target_root": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("~"),
+				MarkdownDescription: "Directory the package tree is linked into. Defaults to `~`",
+				Validators: []validator.String{
+					validators.ValidPath(),
+				},
+			},
+			"ignore": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Additional glob patterns to skip, matched against entry names and package-relative paths. `.git`, `README*`, `LICENSE*` and similar are always ignored",
+			},
+			"fold": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+				MarkdownDescription: "Link whole directories that do not exist in the target (tree folding). Folded directories are unfolded when another package needs them. Defaults to true",
+			},
+			"links": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Symlinks owned by this package, sorted",
+			},
+		},
+	}
+}
+
+// Configure sets the provider client.
+func (r *PackageResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Resource Configure Type", "Expected *DotfilesClient")
+		return
+	}
+	r.client = client
+}
+
+// Create links the package into the target root.
+func (r *PackageResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data PackageResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.stowPackage(ctx, &data, nil, ""); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to link package")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Read detects missing or replaced links and removes the resource from state when the package is no longer fully linked.
+func (r *PackageResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data PackageResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	packageDir, targetRoot, err := r.resolvePaths(&data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to resolve package paths")
+		return
+	}
+
+	plan, err := fileops.PlanStow(packageDir, targetRoot, r.stowOptions(&data))
+	if err != nil {
+		tflog.Info(ctx, "Package directory is not available - removing from state", map[string]interface{}{
+			"package": data.Package.ValueString(),
+			"error":   err.Error(),
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	if plan.Pending() || len(plan.Conflicts) > 0 {
+		tflog.Info(ctx, "Package links have drifted - removing from state", map[string]interface{}{
+			"package":    data.Package.ValueString(),
+			"operations": len(plan.Operations),
+			"conflicts":  plan.Conflicts,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	links, diags := types.ListValueFrom(ctx, types.StringType, plan.Links)
+	resp.Diagnostics.Append(diags...)
+	data.Links = links
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update re-links the package and removes links that are no longer part of it.
+func (r *PackageResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state PackageResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var previousLinks []string
+	resp.Diagnostics.Append(state.Links.ElementsAs(ctx, &previousLinks, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	previousPackageDir, _, err := r.resolvePaths(&state)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to resolve previous package paths")
+		return
+	}
+
+	if err := r.stowPackage(ctx, &data, previousLinks, previousPackageDir); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to update package links")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the package's links that still point into the package.
+func (r *PackageResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data PackageResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var links []string
+	resp.Diagnostics.Append(data.Links.ElementsAs(ctx, &links, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	packageDir, _, err := r.resolvePaths(&data)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Could not resolve package paths",
+			fmt.Sprintf("Could not resolve paths for package %s: %s", data.Package.ValueString(), err.Error()),
+		)
+		return
+	}
+
+	removed, err := r.fileManager().UnstowLinks(links, packageDir)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Could not remove package links",
+			fmt.Sprintf("Could not remove links for package %s: %s", data.Package.ValueString(), err.Error()),
+		)
+	}
+
+	tflog.Info(ctx, "Package links removed", map[string]interface{}{
+		"package": data.Package.ValueString(),
+		"removed": len(removed),
+	})
+}
+
+// stowPackage plans and applies the package links, then removes previous links that are no longer planned
+// and still point into the previous package directory.
+func (r *PackageResource) stowPackage(ctx context.Context, data *PackageResourceModel, previousLinks []string, previousPackageDir string) error {
+	packageDir, targetRoot, err := r.resolvePaths(data)
+	if err != nil {
+		return err
+	}
+
+	plan, err := fileops.PlanStow(packageDir, targetRoot, r.stowOptions(data))
+	if err != nil {
+		return errors.ValidationError("plan_package", "package", "Failed to plan package links", err).
+			WithPath(packageDir)
+	}
+	if len(plan.Conflicts) > 0 {
+		return errors.ValidationError("plan_package", "package",
+			fmt.Sprintf("Target paths are occupied by unmanaged files: %s", strings.Join(plan.Conflicts, ", ")), nil).
+			WithPath(targetRoot).
+			WithContext("package", data.Package.ValueString())
+	}
+
+	tflog.Debug(ctx, "Applying package plan", map[string]interface{}{
+		"package":     data.Package.ValueString(),
+		"target_root": targetRoot,
+		"operations":  len(plan.Operations),
+		"links":       len(plan.Links),
+	})
+
+	fileManager := r.fileManager()
+	if err := fileManager.ApplyStowPlan(plan); err != nil {
+		return errors.IOError("apply_package", "package", "Failed to create package links", err).
+			WithPath(targetRoot)
+	}
+
+	if stale := staleLinks(previousLinks, plan.Links); len(stale) > 0 {
+		if _, err := fileManager.UnstowLinks(stale, previousPackageDir); err != nil {
+			return errors.IOError("remove_stale_links", "package", "Failed to remove stale package links", err).
+				WithPath(targetRoot)
+		}
+	}
+
+	links, diags := types.ListValueFrom(ctx, types.StringType, plan.Links)
+	if diags.HasError() {
+		return fmt.Errorf("failed to build links list: %v", diags)
+	}
+	data.Links = links
+	data.ID = types.StringValue(data.Repository.ValueString() + ":" + data.Package.ValueString())
+	return nil
+}
+
+// resolvePaths returns the absolute package directory and target root.
+func (r *PackageResource) resolvePaths(data *PackageResourceModel) (string, string, error) {
+	repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		return "", "", errors.ConfigurationError("resolve_repository", "package", "No repository holding the package", err).
+			WithContext("repository", data.Repository.ValueString())
+	}
+	packageDir, err := filepath.Abs(filepath.Join(repositoryLocalPath, data.Package.ValueString()))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute package path: %w", err)
+	}
+
+	targetRoot := "~"
+	if !data.TargetRoot.IsNull() && !data.TargetRoot.IsUnknown() {
+		targetRoot = data.TargetRoot.ValueString()
+	}
+	expandedTargetRoot, err := platform.DetectPlatform().ExpandPath(targetRoot)
+	if err != nil {
+		return "", "", errors.ValidationError("expand_target_root", "package", "Could not expand target root", err).
+			WithPath(targetRoot)
+	}
+	expandedTargetRoot, err = filepath.Abs(expandedTargetRoot)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get absolute target root: %w", err)
+	}
+
+	return packageDir, expandedTargetRoot, nil
+}
+
+// stowOptions builds the planner options from the resource configuration.
+func (r *PackageResource) stowOptions(data *PackageResourceModel) fileops.StowOptions {
+	ignore := append([]string{}, fileops.DefaultStowIgnorePatterns...)
+	if !data.Ignore.IsNull() && !data.Ignore.IsUnknown() {
+		for _, value := range data.Ignore.Elements() {
+			if pattern, ok := value.(types.String); ok {
+				ignore = append(ignore, pattern.ValueString())
+			}
+		}
+	}
+
+	ownerRoot := ""
+	if repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString()); err == nil {
+		if absRoot, err := filepath.Abs(repositoryLocalPath); err == nil {
+			ownerRoot = absRoot
+		}
+	}
+
+	return fileops.StowOptions{
+		Ignore:    ignore,
+		NoFolding: !data.Fold.IsNull() && !data.Fold.ValueBool(),
+		OwnerRoot: ownerRoot,
+	}
+}
+
+// fileManager creates a file manager instance for this resource.
+func (r *PackageResource) fileManager() *fileops.FileManager {
+	dryRun := r.client != nil && r.client.Config != nil && r.client.Config.DryRun
+	return fileops.NewFileManager(platform.DetectPlatform(), dryRun)
+}
+
+// getRepositoryLocalPath returns the root source_path is resolved against: the root path
+// of the repository, which honours its subdirectory.
+func (r *PackageResource) getRepositoryLocalPath(repositoryID string) (string, error) {
+	return r.client.RepositoryRoot(repositoryID)
+}
+
+// staleLinks returns the previous links that are not part of the current plan.
+func staleLinks(previous, current []string) []string {
+	planned := make(map[string]bool, len(current))
+	for _, link := range current {
+		planned[link] = true
+	}
+
+	var stale []string
+	for _, link := range previous {
+		if !planned[link] {
+			stale = append(stale, link)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestPackageResource(t *testing.T) {
+	t.Run("NewPackageResource", func(t *testing.T) {
+		r := NewPackageResource()
+		if r == nil {
+			t.Fatal("NewPackageResource() returned nil")
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewPackageResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_package" {
+			t.Errorf("Expected TypeName dotfiles_package, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewPackageResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+
+		for _, attr := range []string{"id", "repository", "package", "target_root", "ignore", "fold", "links"} {
+			if _, exists := resp.Schema.Attributes[attr]; !exists {
+				t.Errorf("Attribute %s not found in schema", attr)
+			}
+		}
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		packageResource, ok := NewPackageResource().(*PackageResource)
+		if !ok {
+			t.Fatal("NewPackageResource() did not return *PackageResource")
+		}
+		ctx := context.Background()
+
+		resp := &resource.ConfigureResponse{}
+		packageResource.Configure(ctx, resource.ConfigureRequest{ProviderData: &DotfilesClient{}}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Configure with valid client failed: %v", resp.Diagnostics)
+		}
+
+		resp = &resource.ConfigureResponse{}
+		packageResource.Configure(ctx, resource.ConfigureRequest{ProviderData: "invalid"}, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Error("Configure with invalid provider data should error")
+		}
+	})
+}
+
+func TestPackageResourceStow(t *testing.T) {
+	tempDir := t.TempDir()
+	dotfilesRoot := filepath.Join(tempDir, "dotfiles")
+	home := filepath.Join(tempDir, "home")
+	for _, name := range []string{"nvim/.config/nvim/init.lua", "nvim/.config/nvim/lua/plugins.lua", "nvim/README.md"} {
+		path := filepath.Join(dotfilesRoot, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	ctx := context.Background()
+	r := &PackageResource{client: withRepository(&DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: dotfilesRoot}}, "dotfiles", dotfilesRoot)}
+	data := &PackageResourceModel{
+		Repository: types.StringValue("dotfiles"),
+		Package:    types.StringValue("nvim"),
+		TargetRoot: types.StringValue(home),
+		Ignore:     types.ListNull(types.StringType),
+		Fold:       types.BoolValue(true),
+	}
+
+	if err := r.stowPackage(ctx, data, nil, ""); err != nil {
+		t.Fatalf("stowPackage failed: %v", err)
+	}
+	if data.ID.ValueString() != "dotfiles:nvim" {
+		t.Errorf("Unexpected ID %s", data.ID.ValueString())
+	}
+	var folded []string
+	data.Links.ElementsAs(ctx, &folded, false)
+	if !reflect.DeepEqual(folded, []string{filepath.Join(home, ".config")}) {
+		t.Errorf("Expected folded .config link, got %v", folded)
+	}
+	if _, err := os.Lstat(filepath.Join(home, "README.md")); !os.IsNotExist(err) {
+		t.Error("README.md should be ignored by default")
+	}
+
+	// Without folding, files are linked individually once the folded link is gone
+	previousPackageDir, _, err := r.resolvePaths(data)
+	if err != nil {
+		t.Fatalf("resolvePaths failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(home, ".config")); err != nil {
+		t.Fatalf("Failed to remove folded link: %v", err)
+	}
+	data.Fold = types.BoolValue(false)
+	if err := r.stowPackage(ctx, data, folded, previousPackageDir); err != nil {
+		t.Fatalf("stowPackage without folding failed: %v", err)
+	}
+	var links []string
+	data.Links.ElementsAs(ctx, &links, false)
+	expected := []string{
+		filepath.Join(home, ".config", "nvim", "init.lua"),
+		filepath.Join(home, ".config", "nvim", "lua", "plugins.lua"),
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected %v, got %v", expected, links)
+	}
+
+	// An unmanaged file in the way is reported rather than overwritten
+	conflict := filepath.Join(tempDir, "conflict")
+	if err := os.MkdirAll(filepath.Join(conflict, ".config", "nvim"), 0755); err != nil {
+		t.Fatalf("Failed to create conflict directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(conflict, ".config", "nvim", "init.lua"), []byte("local"), 0644); err != nil {
+		t.Fatalf("Failed to create unmanaged file: %v", err)
+	}
+	data.TargetRoot = types.StringValue(conflict)
+	if err := r.stowPackage(ctx, data, nil, ""); err == nil {
+		t.Error("Expected conflict error for unmanaged file")
+	}
+}
+
+func TestStaleLinks(t *testing.T) {
+	stale := staleLinks([]string{"/home/.config", "/home/.zshrc"}, []string{"/home/.zshrc", "/home/.config/nvim"})
+	if !reflect.DeepEqual(stale, []string{"/home/.config"}) {
+		t.Errorf("Unexpected stale links: %v", stale)
+	}
+	if stale := staleLinks(nil, []string{"/home/.zshrc"}); len(stale) != 0 {
+		t.Errorf("Expected no stale links, got %v", stale)
+	}
+}
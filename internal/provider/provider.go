@@ -6,6 +6,7 @@ package provider
 import (
 	"context"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
@@ -42,6 +43,9 @@ type DotfilesProviderModel struct {
 	TargetPlatform     types.String         `tfsdk:"target_platform"`
 	TemplateEngine     types.String         `tfsdk:"template_engine"`
 	LogLevel           types.String         `tfsdk:"log_level"`
+	MaxConcurrency     types.Int64          `tfsdk:"max_concurrency"`
+	GitMirrorDirectory types.String         `tfsdk:"git_mirror_directory"`
+	GitMirrorMaxAge    types.String         `tfsdk:"git_mirror_max_age"`
 	BackupStrategy     *BackupStrategyModel `tfsdk:"backup_strategy"`
 	Recovery           *RecoveryModel       `tfsdk:"recovery"`
 }
@@ -88,7 +92,7 @@ func (p *DotfilesProvider) Schema(ctx context.Context, req provider.SchemaReques
 				Optional:            true,
 			},
 			"template_engine": schema.StringAttribute{
-				MarkdownDescription: "Template engine to use: go (default), handlebars, or mustache",
+				MarkdownDescription: "Template engine to use: go (default), handlebars, mustache, or chezmoi (Go templates with chezmoi function shims)",
 				Optional:            true,
 				Validators: []validator.String{
 					validators.ValidTemplateEngine(),
@@ -98,6 +102,21 @@ func (p *DotfilesProvider) Schema(ctx context.Context, req provider.SchemaReques
 				MarkdownDescription: "Log level: debug, info (default), warn, or error",
 				Optional:            true,
 			},
+			"max_concurrency": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of concurrent file operations used when processing directories. Must be between 1 and 50. Defaults to 10",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(MinConcurrency, MaxConcurrency),
+				},
+			},
+			"git_mirror_directory": schema.StringAttribute{
+				MarkdownDescription: "Directory holding bare mirrors of remote repositories, shared by every `dotfiles_repository` and workspace using the same URL. When set, checkouts are cloned from and updated against the mirror, which is fetched once per run. Unset by default",
+				Optional:            true,
+			},
+			"git_mirror_max_age": schema.StringAttribute{
+				MarkdownDescription: "How long a mirror stays fresh after a fetch (e.g. '1h'). Fresh mirrors are used without contacting the remote, so applies work offline. Defaults to 0, fetching once per run",
+				Optional:            true,
+			},
 		},
 		Blocks: map[string]schema.Block{
 			"backup_strategy": GetBackupStrategySchemaBlock(),
@@ -150,6 +169,7 @@ func (p *DotfilesProvider) Configure(ctx context.Context, req provider.Configure
 		"strategy":        config.Strategy,
 		"target_platform": config.TargetPlatform,
 		"dry_run":         config.DryRun,
+		"max_concurrency": config.MaxConcurrency,
 	})
 
 	resp.DataSourceData = client
@@ -203,6 +223,18 @@ func (p *DotfilesProvider) mapProviderDataToConfig(data *EnhancedProviderModel,
 	if !data.LogLevel.IsNull() {
 		config.LogLevel = data.LogLevel.ValueString()
 	}
+
+	if !data.MaxConcurrency.IsNull() {
+		config.MaxConcurrency = int(data.MaxConcurrency.ValueInt64())
+	}
+
+	if !data.GitMirrorDirectory.IsNull() {
+		config.GitMirrorDirectory = data.GitMirrorDirectory.ValueString()
+	}
+
+	if !data.GitMirrorMaxAge.IsNull() {
+		config.GitMirrorMaxAge = data.GitMirrorMaxAge.ValueString()
+	}
 }
 
 // handleBackupStrategyConfig handles backup strategy configuration and conflict detection
@@ -285,6 +317,13 @@ func (p *DotfilesProvider) Resources(ctx context.Context) []func() resource.Reso
 		NewDirectoryResource,
 		NewApplicationResource,
 		NewFilePermissionsResource,
+		NewPackageResource,
+		NewCaptureResource,
+		NewAdoptResource,
+		NewBlockResource,
+		NewConfigMergeResource,
+		NewIniSettingsResource,
+		NewSSHConfigResource,
 	}
 }
 
@@ -298,6 +337,7 @@ func (p *DotfilesProvider) DataSources(ctx context.Context) []func() datasource.
 	return []func() datasource.DataSource{
 		NewSystemDataSource,
 		NewFileInfoDataSource,
+		NewRepositoryStatusDataSource,
 	}
 }
 
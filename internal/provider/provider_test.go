@@ -9,8 +9,12 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 func TestProvider(t *testing.T) {
@@ -240,6 +244,9 @@ func testDefaultValues(t *testing.T, config *DotfilesConfig) {
 	if config.LogLevel != expectations["LogLevel"] {
 		t.Errorf("Expected default log level '%s', got '%s'", expectations["LogLevel"], config.LogLevel)
 	}
+	if config.MaxConcurrency != DefaultMaxConcurrency {
+		t.Errorf("Expected default max concurrency %d, got %d", DefaultMaxConcurrency, config.MaxConcurrency)
+	}
 }
 
 // testClientWithDefaults tests client creation with default values
@@ -339,6 +346,46 @@ func createValidationTestCases(tmpDir string) []validationTestCase {
 			},
 			expectErr: true,
 		},
+		{
+			name: "Max concurrency too high",
+			config: &DotfilesConfig{
+				DotfilesRoot:   tmpDir,
+				MaxConcurrency: MaxConcurrency + 1,
+			},
+			expectErr: true,
+		},
+		{
+			name: "Negative max concurrency",
+			config: &DotfilesConfig{
+				DotfilesRoot:   tmpDir,
+				MaxConcurrency: -1,
+			},
+			expectErr: true,
+		},
+		{
+			name:      "Git mirror cache",
+			config:    validConfigWithMirror(tmpDir, "1h"),
+			expectErr: false,
+		},
+		{
+			name:      "Invalid git mirror max age",
+			config:    validConfigWithMirror(tmpDir, "-5m"),
+			expectErr: true,
+		},
+	}
+}
+
+// validConfigWithMirror returns an otherwise valid configuration using a git mirror cache
+func validConfigWithMirror(tmpDir, maxAge string) *DotfilesConfig {
+	return &DotfilesConfig{
+		DotfilesRoot:       tmpDir,
+		Strategy:           "symlink",
+		ConflictResolution: "backup",
+		TargetPlatform:     "auto",
+		TemplateEngine:     "go",
+		LogLevel:           "info",
+		GitMirrorDirectory: filepath.Join(tmpDir, "cache", "mirrors"),
+		GitMirrorMaxAge:    maxAge,
 	}
 }
 
@@ -355,7 +402,7 @@ func TestProviderResources(t *testing.T) {
 		t.Error("no resources returned")
 	}
 
-	expectedResources := 6 // repository, file, symlink, directory, application, file_permissions
+	expectedResources := 13 // repository, file, symlink, directory, application, file_permissions, package, capture, adopt, block, config_merge, ini_settings, ssh_config
 	if len(resources) != expectedResources {
 		t.Errorf("expected %d resources, got %d", expectedResources, len(resources))
 	}
@@ -374,7 +421,7 @@ func TestProviderDataSources(t *testing.T) {
 		t.Error("no data sources returned")
 	}
 
-	expectedDataSources := 2 // system, file_info
+	expectedDataSources := 3 // system, file_info, repository_status
 	if len(dataSources) != expectedDataSources {
 		t.Errorf("expected %d data sources, got %d", expectedDataSources, len(dataSources))
 	}
@@ -426,3 +473,27 @@ func TestProtoV6ProviderServer(t *testing.T) {
 		t.Error("failed to create provider server factory")
 	}
 }
+
+func TestProviderSchemaMaxConcurrencyRange(t *testing.T) {
+	ctx := context.Background()
+
+	schemaResp := &provider.SchemaResponse{}
+	New("test")().Schema(ctx, provider.SchemaRequest{}, schemaResp)
+
+	attr, ok := schemaResp.Schema.Attributes["max_concurrency"].(schema.Int64Attribute)
+	if !ok {
+		t.Fatal("max_concurrency attribute not found in schema")
+	}
+
+	cases := map[int64]bool{0: true, -1: true, MinConcurrency: false, MaxConcurrency: false, MaxConcurrency + 1: true}
+	for value, expectErr := range cases {
+		req := validator.Int64Request{Path: path.Root("max_concurrency"), ConfigValue: types.Int64Value(value)}
+		resp := &validator.Int64Response{}
+		for _, v := range attr.Int64Validators() {
+			v.ValidateInt64(ctx, req, resp)
+		}
+		if resp.Diagnostics.HasError() != expectErr {
+			t.Errorf("max_concurrency = %d: expected error %v, got %v", value, expectErr, resp.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/template"
+)
+
+// readRepositoryFile reads sourcePath from the repository with the given ID, or from
+// dotfiles_root when the ID is empty. It returns the content and the absolute path.
+func (c *DotfilesClient) readRepositoryFile(resourceType, repository, sourcePath string) ([]byte, string, error) {
+	repoPath, err := c.RepositoryRoot(repository)
+	if err != nil {
+		return nil, "", errors.ConfigurationError("resolve_repository", resourceType, "No repository holding source_path", err)
+	}
+	source, err := repositoryFile(repoPath, sourcePath)
+	if err != nil {
+		return nil, "", errors.ValidationError("resolve_source", resourceType, "Invalid source path", err).
+			WithPath(sourcePath)
+	}
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return nil, "", errors.IOError("read_source", resourceType, "Failed to read source file", err).WithPath(source)
+	}
+	return content, source, nil
+}
+
+// renderTemplateContent renders content with the platform-aware template context and the
+// given variables.
+func (c *DotfilesClient) renderTemplateContent(content string, engine types.String, vars types.Map) (string, error) {
+	templateEngine, err := template.CreateTemplateEngine(c.templateEngineName(engine))
+	if err != nil {
+		return "", err
+	}
+	userVars, err := directoryTemplateVars(vars)
+	if err != nil {
+		return "", err
+	}
+	return templateEngine.ProcessTemplate(content, template.BuildPlatformAwareTemplateContext(c.GetPlatformInfo(), userVars, nil, nil))
+}
+
+// templateEngineName returns engine when set, falling back to the provider setting.
+func (c *DotfilesClient) templateEngineName(engine types.String) string {
+	if !engine.IsNull() && !engine.IsUnknown() && engine.ValueString() != "" {
+		return engine.ValueString()
+	}
+	if c.Config != nil && contains(ValidTemplateEngines, c.Config.TemplateEngine) {
+		return c.Config.TemplateEngine
+	}
+	return TemplateEngineGo
+}
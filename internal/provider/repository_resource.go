@@ -5,21 +5,30 @@ package provider
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &RepositoryResource{}
+var _ resource.ResourceWithModifyPlan = &RepositoryResource{}
+var _ resource.ResourceWithValidateConfig = &RepositoryResource{}
 
 func NewRepositoryResource() resource.Resource {
 	return &RepositoryResource{}
@@ -41,17 +50,52 @@ type RepositoryResourceModel struct {
 	DefaultDirMode       types.String `tfsdk:"default_dir_mode"`
 
 	// Git-specific attributes
-	GitBranch              types.String `tfsdk:"git_branch"`
-	GitPersonalAccessToken types.String `tfsdk:"git_personal_access_token"`
-	GitUsername            types.String `tfsdk:"git_username"`
-	GitSSHPrivateKeyPath   types.String `tfsdk:"git_ssh_private_key_path"`
-	GitSSHPassphrase       types.String `tfsdk:"git_ssh_passphrase"`
-	GitUpdateInterval      types.String `tfsdk:"git_update_interval"`
+	GitBranch                types.String `tfsdk:"git_branch"`
+	GitRef                   types.String `tfsdk:"git_ref"`
+	GitPersonalAccessToken   types.String `tfsdk:"git_personal_access_token"`
+	GitUsername              types.String `tfsdk:"git_username"`
+	GitSSHPrivateKeyPath     types.String `tfsdk:"git_ssh_private_key_path"`
+	GitSSHPassphrase         types.String `tfsdk:"git_ssh_passphrase"`
+	GitSSHKnownHostsPath     types.String `tfsdk:"git_ssh_known_hosts_path"`
+	GitInsecureIgnoreHostKey types.Bool   `tfsdk:"git_insecure_ignore_host_key"`
+	GitNetrcPath             types.String `tfsdk:"git_netrc_path"`
+	GitCredentialHelper      types.String `tfsdk:"git_credential_helper"`
+	GitUpdateInterval        types.String `tfsdk:"git_update_interval"`
+
+	// Checkout layout
+	Subdirectory      types.String `tfsdk:"subdirectory"`
+	SparsePaths       types.List   `tfsdk:"sparse_paths"`
+	Depth             types.Int64  `tfsdk:"depth"`
+	RecurseSubmodules types.Bool   `tfsdk:"recurse_submodules"`
+	DirtyPolicy       types.String `tfsdk:"dirty_policy"`
+
+	// Commit signature verification
+	VerifySignatures *SignatureVerificationModel `tfsdk:"verify_signatures"`
 
 	// Computed attributes
-	LocalPath  types.String `tfsdk:"local_path"`
-	LastCommit types.String `tfsdk:"last_commit"`
-	LastUpdate types.String `tfsdk:"last_update"`
+	LocalPath      types.String `tfsdk:"local_path"`
+	RootPath       types.String `tfsdk:"root_path"`
+	LastCommit     types.String `tfsdk:"last_commit"`
+	LastUpdate     types.String `tfsdk:"last_update"`
+	LastFetch      types.String `tfsdk:"last_fetch"`
+	ResolvedCommit types.String `tfsdk:"resolved_commit"`
+	IsDirty        types.Bool   `tfsdk:"is_dirty"`
+	ModifiedFiles  types.List   `tfsdk:"modified_files"`
+	Ahead          types.Int64  `tfsdk:"ahead"`
+	Behind         types.Int64  `tfsdk:"behind"`
+}
+
+// Policies for updating a clone with uncommitted changes.
+const (
+	dirtyPolicyFail       = "fail"
+	dirtyPolicyStash      = "stash"
+	dirtyPolicySkipUpdate = "skip_update"
+)
+
+// SignatureVerificationModel describes the keys trusted to sign repository commits.
+type SignatureVerificationModel struct {
+	OpenPGPKeyring    types.String `tfsdk:"openpgp_keyring"`
+	SSHAllowedSigners types.String `tfsdk:"ssh_allowed_signers"`
 }
 
 func (r *RepositoryResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -64,7 +108,7 @@ func (r *RepositoryResource) Schema(ctx context.Context, req resource.SchemaRequ
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed:            true,
-				MarkdownDescription: "Repository identifier",
+				MarkdownDescription: "Repository identifier: the effective root of its files (`root_path`), which dependent resources resolve `source_path` against",
 			},
 			"name": schema.StringAttribute{
 				Required:            true,
@@ -96,6 +140,13 @@ func (r *RepositoryResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Optional:            true,
 				MarkdownDescription: "Git branch to checkout (defaults to repository default branch)",
 			},
+			"git_ref": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Tag, commit SHA (full or abbreviated) or semver constraint (e.g. '~> 1.2', '>= 1.0, < 2.0') to pin the repository to. Constraints are resolved against the remote tags and the highest match is checked out in detached HEAD. Conflicts with `git_branch`",
+				Validators: []validator.String{
+					validators.NotEmpty(),
+				},
+			},
 			"git_personal_access_token": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
@@ -107,16 +158,63 @@ func (r *RepositoryResource) Schema(ctx context.Context, req resource.SchemaRequ
 			},
 			"git_ssh_private_key_path": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Path to SSH private key for Git authentication",
+				MarkdownDescription: "Path to SSH private key for Git authentication. When unset, SSH remotes use the keys held by ssh-agent (`SSH_AUTH_SOCK`)",
 			},
 			"git_ssh_passphrase": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
 				MarkdownDescription: "Passphrase for SSH private key",
 			},
+			"git_ssh_known_hosts_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "known_hosts file SSH host keys are verified against. Defaults to `SSH_KNOWN_HOSTS` or `~/.ssh/known_hosts`; unknown hosts are rejected",
+			},
+			"git_insecure_ignore_host_key": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Skip SSH host key verification. Insecure: only use for hosts that cannot be added to known_hosts (default: false)",
+			},
+			"git_netrc_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "netrc file consulted for HTTPS credentials when no token is set. Defaults to `NETRC` or `~/.netrc`",
+			},
+			"git_credential_helper": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Git credential helper consulted for HTTPS credentials not found in netrc, named as in git's `credential.helper` (e.g. 'osxkeychain', 'store', '!pass-helper')",
+			},
 			"git_update_interval": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Interval to check for updates (e.g., '1h', '30m'). Use 'never' to disable automatic updates",
+				MarkdownDescription: "Interval to check Git repositories for upstream commits (e.g., '1h', '30m'). Once `last_fetch` is older than the interval, upstream is fetched again and new commits are planned as a change to `last_commit`. Use '0' or 'never' to disable automatic updates",
+				Validators: []validator.String{
+					validators.ValidUpdateInterval(),
+				},
+			},
+
+			"subdirectory": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Directory within the repository holding the dotfiles, e.g. when they live inside a monorepo. Exposed as `root_path`, the effective root for `source_path` lookups",
+				Validators: []validator.String{
+					validators.RepositoryRelativePath(),
+				},
+			},
+			"sparse_paths": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Directories, relative to the repository root, to check out from a Git repository. Everything else is left out of the working tree. Changing this re-clones the repository",
+			},
+			"depth": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Limit Git clones and fetches to this many commits of history (0 or unset for full history). Changing this re-clones the repository",
+			},
+			"recurse_submodules": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Clone and update Git submodules",
+			},
+			"dirty_policy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "What to do when the local clone has uncommitted changes to tracked files before an update: `fail` (default) stops the apply, `stash` sets the changes aside and re-applies them after the update, `skip_update` leaves the clone as it is. Stashed files that also changed upstream are kept under `.git/dotfiles-stash`. Re-cloning for a new `sparse_paths` or `depth` also applies the policy to unpushed commits; with `stash` the previous checkout is moved aside and kept if anything in it could not be carried over",
+				Validators: []validator.String{
+					validators.OneOf(dirtyPolicyFail, dirtyPolicyStash, dirtyPolicySkipUpdate),
+				},
 			},
 
 			// Computed attributes
@@ -124,6 +222,10 @@ func (r *RepositoryResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Computed:            true,
 				MarkdownDescription: "Local path where the repository is stored",
 			},
+			"root_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Effective root of the dotfiles: `local_path` joined with `subdirectory`",
+			},
 			"last_commit": schema.StringAttribute{
 				Computed:            true,
 				MarkdownDescription: "SHA of the last commit",
@@ -132,6 +234,46 @@ func (r *RepositoryResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Computed:            true,
 				MarkdownDescription: "Timestamp of the last repository update",
 			},
+			"last_fetch": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Timestamp of the last check of a Git repository for upstream commits, which `git_update_interval` is measured from",
+			},
+			"resolved_commit": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Commit SHA that `git_ref` resolved to",
+			},
+			"is_dirty": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether tracked files in the local clone have uncommitted changes",
+			},
+			"modified_files": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Tracked files with uncommitted changes, relative to the repository root",
+			},
+			"ahead": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Local commits not on the upstream branch, as of the last fetch",
+			},
+			"behind": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Upstream commits not yet checked out, as of the last fetch",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"verify_signatures": schema.SingleNestedBlock{
+				MarkdownDescription: "Require the checked-out commit of a Git repository to be signed by a trusted key. Applies fail, and the previous checkout is kept, when verification fails",
+				Attributes: map[string]schema.Attribute{
+					"openpgp_keyring": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "ASCII-armored OpenPGP public keyring trusted for commit signatures (e.g. `file(\"team.asc\")`)",
+					},
+					"ssh_allowed_signers": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "SSH allowed signers in `ssh-keygen` format (`principals [namespaces=\"git\"] key`), as used by `gpg.ssh.allowedSignersFile`",
+					},
+				},
+			},
 		},
 	}
 }
@@ -198,10 +340,7 @@ func (r *RepositoryResource) Create(ctx context.Context, req resource.CreateRequ
 				"error":       err.Error(),
 				"source_path": sourcePath,
 			})
-			resp.Diagnostics.AddError(
-				"Failed to setup Git repository",
-				fmt.Sprintf("Could not clone or setup Git repository: %s", err.Error()),
-			)
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to setup Git repository")
 			return
 		}
 
@@ -209,6 +348,7 @@ func (r *RepositoryResource) Create(ctx context.Context, req resource.CreateRequ
 		data.LocalPath = types.StringValue(info.LocalPath)
 		data.LastCommit = types.StringValue(info.LastCommit)
 		data.LastUpdate = types.StringValue(info.LastUpdate.Format(time.RFC3339))
+		data.LastFetch = data.LastUpdate
 
 		tflog.Info(ctx, "Git repository cloned successfully", map[string]interface{}{
 			"url":         info.URL,
@@ -234,6 +374,7 @@ func (r *RepositoryResource) Create(ctx context.Context, req resource.CreateRequ
 
 		data.LocalPath = data.SourcePath
 		data.LastUpdate = types.StringValue(time.Now().Format(time.RFC3339))
+		data.LastFetch = types.StringNull()
 
 		// Check if local repository is a Git repository and get commit info
 		localPath := data.SourcePath.ValueString()
@@ -297,8 +438,17 @@ func (r *RepositoryResource) Create(ctx context.Context, req resource.CreateRequ
 		})
 	}
 
-	// Set ID and save state
-	data.ID = data.Name
+	if err := r.setRootPath(&data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Invalid repository subdirectory")
+		return
+	}
+
+	// The root path is the ID, so dependent resources referencing it resolve their files
+	// without looking the repository up
+	data.ID = data.RootPath
+	data.ResolvedCommit = r.resolvedCommit(&data)
+	r.setStatus(ctx, &data)
+	r.registerRepository(&data)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -333,15 +483,24 @@ func (r *RepositoryResource) Read(ctx context.Context, req resource.ReadRequest,
 				} else {
 					info, err := gitManager.GetRepositoryInfo(localPath)
 					if err == nil {
-						// Update computed attributes
+						// Update computed attributes. last_update only moves when the repository is updated,
+						// so the update interval is measured from the last pull.
 						data.LastCommit = types.StringValue(info.LastCommit)
-						data.LastUpdate = types.StringValue(info.LastUpdate.Format(time.RFC3339))
 					} else {
 						tflog.Warn(ctx, "Failed to get repository info", map[string]interface{}{
 							"error": err.Error(),
 						})
 					}
 				}
+
+				// Fetch once the update interval has elapsed; ModifyPlan turns new commits into a planned change
+				if upstream, ok := r.upstreamCommit(ctx, &data, data.GitUpdateInterval); ok && upstream != data.LastCommit.ValueString() {
+					tflog.Info(ctx, "New upstream commits available", map[string]interface{}{
+						"local_path":  localPath,
+						"last_commit": data.LastCommit.ValueString(),
+						"upstream":    upstream,
+					})
+				}
 			} else {
 				tflog.Warn(ctx, "Local repository path no longer exists", map[string]interface{}{
 					"local_path": localPath,
@@ -364,13 +523,21 @@ func (r *RepositoryResource) Read(ctx context.Context, req resource.ReadRequest,
 		}
 	}
 
+	if !data.LocalPath.IsNull() {
+		data.RootPath = types.StringValue(repositoryRootPath(&data))
+		data.ID = data.RootPath
+	}
+	data.ResolvedCommit = r.resolvedCommit(&data)
+	r.setStatus(ctx, &data)
+	r.registerRepository(&data)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data RepositoryResourceModel
+	var data, state RepositoryResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -387,6 +554,23 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 		if !data.LocalPath.IsNull() {
 			localPath := data.LocalPath.ValueString()
 
+			// A different sparse checkout or history depth cannot be applied in place
+			var reclone *recloneStash
+			if !data.SparsePaths.Equal(state.SparsePaths) || !data.Depth.Equal(state.Depth) {
+				tflog.Info(ctx, "Checkout layout changed, re-cloning repository", map[string]interface{}{
+					"local_path": localPath,
+				})
+				var err error
+				if reclone, err = r.removeCheckout(ctx, &data, localPath); err != nil {
+					errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to re-clone Git repository")
+					return
+				}
+				if _, err := os.Stat(localPath); err == nil {
+					errors.AddWarningToDiagnostics(ctx, &resp.Diagnostics, "Checkout layout not changed",
+						fmt.Sprintf("%s has local changes and dirty_policy is skip_update, so it was not re-cloned with the new sparse_paths or depth. Commit or discard the changes and replace the resource to apply them.", localPath))
+				}
+			}
+
 			// Check if local repository exists
 			if _, err := os.Stat(localPath); err == nil {
 				// Update the repository
@@ -400,18 +584,26 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 					return
 				}
 
-				info, err := gitManager.UpdateRepository(ctx, localPath)
+				if _, err := r.cloneURL(ctx, gitManager, &data, localPath); err != nil {
+					errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to update Git repository")
+					return
+				}
+				info, warning, err := r.updateCheckout(ctx, gitManager, &data, localPath)
 				if err != nil {
-					resp.Diagnostics.AddError(
-						"Failed to update Git repository",
-						fmt.Sprintf("Could not update Git repository: %s", err.Error()),
-					)
+					errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to update Git repository")
 					return
 				}
+				if warning != "" {
+					errors.AddWarningToDiagnostics(ctx, &resp.Diagnostics, "Stashed changes conflict with update", warning)
+				}
+				if r.client != nil {
+					r.client.UpstreamCache.Forget(localPath)
+				}
 
 				// Update computed attributes
 				data.LastCommit = types.StringValue(info.LastCommit)
 				data.LastUpdate = types.StringValue(info.LastUpdate.Format(time.RFC3339))
+				data.LastFetch = data.LastUpdate
 
 				tflog.Info(ctx, "Git repository updated successfully", map[string]interface{}{
 					"local_path":  info.LocalPath,
@@ -425,10 +617,7 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 
 				info, err := r.setupGitRepository(ctx, &data)
 				if err != nil {
-					resp.Diagnostics.AddError(
-						"Failed to re-setup Git repository",
-						fmt.Sprintf("Could not re-clone Git repository: %s", err.Error()),
-					)
+					errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to re-setup Git repository")
 					return
 				}
 
@@ -436,15 +625,19 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 				data.LocalPath = types.StringValue(info.LocalPath)
 				data.LastCommit = types.StringValue(info.LastCommit)
 				data.LastUpdate = types.StringValue(info.LastUpdate.Format(time.RFC3339))
+				data.LastFetch = data.LastUpdate
+			}
+
+			if reclone != nil {
+				if warning := reclone.restore(ctx, localPath); warning != "" {
+					errors.AddWarningToDiagnostics(ctx, &resp.Diagnostics, "Local changes not re-applied", warning)
+				}
 			}
 		} else {
 			// No local path set, treat as new setup
 			info, err := r.setupGitRepository(ctx, &data)
 			if err != nil {
-				resp.Diagnostics.AddError(
-					"Failed to setup Git repository",
-					fmt.Sprintf("Could not setup Git repository: %s", err.Error()),
-				)
+				errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to setup Git repository")
 				return
 			}
 
@@ -452,6 +645,7 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 			data.LocalPath = types.StringValue(info.LocalPath)
 			data.LastCommit = types.StringValue(info.LastCommit)
 			data.LastUpdate = types.StringValue(info.LastUpdate.Format(time.RFC3339))
+			data.LastFetch = data.LastUpdate
 		}
 	} else {
 		// Handle local repository update
@@ -466,6 +660,7 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 
 		data.LocalPath = data.SourcePath
 		data.LastUpdate = types.StringValue(time.Now().Format(time.RFC3339))
+		data.LastFetch = types.StringNull()
 
 		// Check if local repository is a Git repository and get commit info
 		localPath := data.SourcePath.ValueString()
@@ -495,11 +690,151 @@ func (r *RepositoryResource) Update(ctx context.Context, req resource.UpdateRequ
 		}
 	}
 
-	// Set ID and save state
-	data.ID = data.Name
+	if err := r.setRootPath(&data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Invalid repository subdirectory")
+		return
+	}
+
+	// The root path is the ID, so dependent resources referencing it resolve their files
+	// without looking the repository up
+	data.ID = data.RootPath
+	data.ResolvedCommit = r.resolvedCommit(&data)
+	r.setStatus(ctx, &data)
+	r.registerRepository(&data)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// ModifyPlan plans a change to last_commit when the update interval has elapsed and
+// the upstream branch has moved, so the next apply pulls the new commits.
+func (r *RepositoryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to refresh on create or destroy
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state RepositoryResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.SourcePath.IsUnknown() || !git.IsGitURL(plan.SourcePath.ValueString()) {
+		return
+	}
+
+	// A changed git_ref already plans a checkout
+	if !plan.GitRef.Equal(state.GitRef) {
+		return
+	}
+
+	upstream, ok := r.upstreamCommit(ctx, &state, plan.GitUpdateInterval)
+	if !ok || upstream == state.LastCommit.ValueString() {
+		return
+	}
+
+	// A dirty clone that skips updates would not reach the planned commit
+	if plan.DirtyPolicy.ValueString() == dirtyPolicySkipUpdate {
+		if status, err := git.RepositoryStatus(state.LocalPath.ValueString()); err == nil && status.IsDirty() {
+			tflog.Info(ctx, "Not planning update of repository with local changes", map[string]interface{}{
+				"name":           state.Name.ValueString(),
+				"modified_files": status.ModifiedFiles,
+			})
+			return
+		}
+	}
+
+	tflog.Info(ctx, "Planning repository update to new upstream commit", map[string]interface{}{
+		"name":        state.Name.ValueString(),
+		"last_commit": state.LastCommit.ValueString(),
+		"upstream":    upstream,
+	})
+
+	// Upstream may move again before apply, which checks out whatever is current then
+	plan.LastCommit = types.StringUnknown()
+	plan.LastUpdate = types.StringUnknown()
+	plan.LastFetch = types.StringUnknown()
+	if gitRef(&plan) != "" {
+		plan.ResolvedCommit = types.StringUnknown()
+	}
+	plan.IsDirty = types.BoolUnknown()
+	plan.ModifiedFiles = types.ListUnknown(types.StringType)
+	plan.Ahead = types.Int64Unknown()
+	plan.Behind = types.Int64Unknown()
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+// ValidateConfig rejects conflicting Git options, invalid checkout layouts and empty trust configuration.
+func (r *RepositoryResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data RepositoryResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.GitBranch.IsNull() && !data.GitRef.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("git_ref"),
+			"Conflicting Git Attributes",
+			"git_ref pins the repository to a tag or commit and cannot be combined with git_branch.",
+		)
+	}
+
+	if !data.Depth.IsNull() && !data.Depth.IsUnknown() && data.Depth.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("depth"),
+			"Invalid Clone Depth",
+			fmt.Sprintf("depth must not be negative, got %d.", data.Depth.ValueInt64()),
+		)
+	}
+
+	if !data.SparsePaths.IsNull() && !data.SparsePaths.IsUnknown() {
+		var sparsePaths []types.String
+		resp.Diagnostics.Append(data.SparsePaths.ElementsAs(ctx, &sparsePaths, false)...)
+		for i, sparsePath := range sparsePaths {
+			validateResp := &validator.StringResponse{}
+			validators.RepositoryRelativePath().ValidateString(ctx, validator.StringRequest{
+				Path:        path.Root("sparse_paths").AtListIndex(i),
+				ConfigValue: sparsePath,
+			}, validateResp)
+			resp.Diagnostics.Append(validateResp.Diagnostics...)
+		}
+
+		if !data.Subdirectory.IsNull() && !data.Subdirectory.IsUnknown() && !resp.Diagnostics.HasError() &&
+			!withinSparsePaths(data.Subdirectory.ValueString(), sparsePaths) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("subdirectory"),
+				"Subdirectory Outside Sparse Checkout",
+				fmt.Sprintf("subdirectory %q is not covered by sparse_paths, so it would not be checked out.", data.Subdirectory.ValueString()),
+			)
+		}
+	}
+
+	if data.GitInsecureIgnoreHostKey.ValueBool() {
+		if !data.GitSSHKnownHostsPath.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("git_insecure_ignore_host_key"),
+				"Conflicting Git Attributes",
+				"git_insecure_ignore_host_key disables host key verification, so git_ssh_known_hosts_path would be ignored.",
+			)
+		} else {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("git_insecure_ignore_host_key"),
+				"SSH Host Key Verification Disabled",
+				"The repository host's identity will not be checked, leaving the clone open to man-in-the-middle attacks. Prefer adding the host to known_hosts.",
+			)
+		}
+	}
+
+	if data.VerifySignatures != nil && data.VerifySignatures.OpenPGPKeyring.IsNull() && data.VerifySignatures.SSHAllowedSigners.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("verify_signatures"),
+			"Missing Trusted Keys",
+			"verify_signatures requires openpgp_keyring, ssh_allowed_signers or both.",
+		)
+	}
+}
+
 func (r *RepositoryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
 	var data RepositoryResourceModel
 
@@ -514,6 +849,9 @@ func (r *RepositoryResource) Delete(ctx context.Context, req resource.DeleteRequ
 
 	// For dotfiles repositories, we typically don't delete the actual files,
 	// just remove them from Terraform state. The local cache will remain.
+	if r.client != nil {
+		r.client.ForgetRepository(data.Name.ValueString())
+	}
 	tflog.Info(ctx, "Repository resource removed from state", map[string]interface{}{
 		"name": data.Name.ValueString(),
 	})
@@ -523,35 +861,7 @@ func (r *RepositoryResource) Delete(ctx context.Context, req resource.DeleteRequ
 func (r *RepositoryResource) setupGitRepository(ctx context.Context, data *RepositoryResourceModel) (*git.RepositoryInfo, error) {
 	sourcePath := data.SourcePath.ValueString()
 
-	// Create authentication config
-	authConfig := &git.AuthConfig{}
-
-	// Configure PAT authentication
-	if !data.GitPersonalAccessToken.IsNull() {
-		authConfig.PersonalAccessToken = data.GitPersonalAccessToken.ValueString()
-		if !data.GitUsername.IsNull() {
-			authConfig.Username = data.GitUsername.ValueString()
-		}
-	}
-
-	// Configure SSH authentication
-	if !data.GitSSHPrivateKeyPath.IsNull() {
-		authConfig.SSHPrivateKeyPath = data.GitSSHPrivateKeyPath.ValueString()
-		if !data.GitSSHPassphrase.IsNull() {
-			authConfig.SSHPassphrase = data.GitSSHPassphrase.ValueString()
-		}
-	}
-
-	// Check for environment variable PAT if not provided
-	if authConfig.PersonalAccessToken == "" {
-		if envPAT := os.Getenv("GITHUB_TOKEN"); envPAT != "" {
-			authConfig.PersonalAccessToken = envPAT
-			tflog.Debug(ctx, "Using GitHub token from GITHUB_TOKEN environment variable")
-		} else if envPAT := os.Getenv("GH_TOKEN"); envPAT != "" {
-			authConfig.PersonalAccessToken = envPAT
-			tflog.Debug(ctx, "Using GitHub token from GH_TOKEN environment variable")
-		}
-	}
+	authConfig := r.buildAuthConfig(data)
 
 	// Create Git manager
 	gitManager, err := git.NewGitManager(authConfig)
@@ -566,6 +876,11 @@ func (r *RepositoryResource) setupGitRepository(ctx context.Context, data *Repos
 		return nil, fmt.Errorf("failed to determine cache path: %w", err)
 	}
 
+	cloneURL, err := r.cloneURL(ctx, gitManager, data, localPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if repository already exists locally
 	if _, err := os.Stat(localPath); err == nil {
 		// Repository exists, try to update it
@@ -573,19 +888,36 @@ func (r *RepositoryResource) setupGitRepository(ctx context.Context, data *Repos
 			"local_path": localPath,
 		})
 
-		info, err := gitManager.UpdateRepository(ctx, localPath)
-		if err != nil {
-			tflog.Warn(ctx, "Failed to update existing repository, will re-clone", map[string]interface{}{
-				"error": err.Error(),
-			})
-
-			// Remove existing directory and re-clone
-			if err := os.RemoveAll(localPath); err != nil {
-				return nil, fmt.Errorf("failed to remove existing repository: %w", err)
+		info, warning, err := r.updateCheckout(ctx, gitManager, data, localPath)
+		if err == nil {
+			if warning != "" {
+				tflog.Warn(ctx, "Stashed changes conflict with update", map[string]interface{}{
+					"local_path": localPath,
+					"detail":     warning,
+				})
 			}
-		} else {
 			return info, nil
 		}
+
+		// Signature and dirty working tree failures must not be bypassed by re-cloning
+		var providerErr *errors.ProviderError
+		if stderrors.As(err, &providerErr) {
+			return nil, err
+		}
+		if status, statusErr := git.RepositoryStatus(localPath); statusErr == nil && status.IsDirty() {
+			return nil, errors.GitError("update", "repository", "Failed to update repository with local changes", err).
+				WithPath(localPath).
+				WithContext("modified_files", strings.Join(status.ModifiedFiles, ", "))
+		}
+
+		tflog.Warn(ctx, "Failed to update existing repository, will re-clone", map[string]interface{}{
+			"error": err.Error(),
+		})
+
+		// Remove existing directory and re-clone
+		if err := os.RemoveAll(localPath); err != nil {
+			return nil, fmt.Errorf("failed to remove existing repository: %w", err)
+		}
 	}
 
 	// Clone repository
@@ -595,16 +927,38 @@ func (r *RepositoryResource) setupGitRepository(ctx context.Context, data *Repos
 	}
 
 	tflog.Debug(ctx, "Cloning Git repository", map[string]interface{}{
-		"url":        sourcePath,
+		"url":        cloneURL,
 		"local_path": localPath,
 		"branch":     branch,
 	})
 
-	info, err := gitManager.CloneRepository(ctx, sourcePath, localPath, branch)
+	cloneOptions := git.CloneOptions{
+		URL:               cloneURL,
+		LocalPath:         localPath,
+		Branch:            branch,
+		Ref:               gitRef(data),
+		Depth:             int(data.Depth.ValueInt64()),
+		RecurseSubmodules: data.RecurseSubmodules.ValueBool(),
+		SingleBranch:      branch != "",
+		SparsePaths:       sparsePaths(ctx, data),
+	}
+	info, err := gitManager.CloneRepositoryWithOptions(ctx, cloneOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
 
+	// A checkout cloned from the mirror points origin back at the source
+	if r.client.MirrorCache != nil {
+		if _, err := r.cloneURL(ctx, gitManager, data, localPath); err != nil {
+			return nil, err
+		}
+		info.URL = data.SourcePath.ValueString()
+	}
+
+	if err := r.verifyCheckout(ctx, data, localPath, ""); err != nil {
+		return nil, err
+	}
+
 	return info, nil
 }
 
@@ -739,6 +1093,12 @@ func (r *RepositoryResource) buildAuthConfig(data *RepositoryResourceModel) *git
 		}
 	}
 
+	// Configure host key verification and credential lookup
+	authConfig.SSHKnownHostsPath = data.GitSSHKnownHostsPath.ValueString()
+	authConfig.SSHSkipHostKeyVerification = data.GitInsecureIgnoreHostKey.ValueBool()
+	authConfig.NetrcPath = data.GitNetrcPath.ValueString()
+	authConfig.CredentialHelper = data.GitCredentialHelper.ValueString()
+
 	// Check for environment variable PAT if not provided
 	if authConfig.PersonalAccessToken == "" {
 		if envPAT := os.Getenv("GITHUB_TOKEN"); envPAT != "" {
@@ -751,6 +1111,470 @@ func (r *RepositoryResource) buildAuthConfig(data *RepositoryResourceModel) *git
 	return authConfig
 }
 
+// upstreamCommit fetches the upstream commit of a Git repository whose update interval has elapsed
+// since last_fetch (or last_update, for state without it), and records the fetch in last_fetch.
+// Results are cached per provider run so dependent resources and the plan after a refresh share
+// one fetch. It returns false when updates are disabled, not yet due, or the fetch failed.
+func (r *RepositoryResource) upstreamCommit(ctx context.Context, data *RepositoryResourceModel, intervalValue types.String) (string, bool) {
+	if intervalValue.IsUnknown() || data.LocalPath.IsNull() || data.LocalPath.ValueString() == "" {
+		return "", false
+	}
+
+	interval, err := git.ParseUpdateInterval(intervalValue.ValueString())
+	if err != nil {
+		tflog.Warn(ctx, "Ignoring invalid update interval", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return "", false
+	}
+	if interval <= 0 {
+		return "", false
+	}
+
+	localPath := data.LocalPath.ValueString()
+	var cache *git.UpstreamCache
+	if r.client != nil {
+		cache = r.client.UpstreamCache
+	}
+	if commit, ok := cache.Fetched(localPath); ok {
+		data.LastFetch = types.StringValue(time.Now().Format(time.RFC3339))
+		return commit, true
+	}
+
+	lastFetch := data.LastFetch.ValueString()
+	if lastFetch == "" {
+		lastFetch = data.LastUpdate.ValueString()
+	}
+	if !git.UpdateDue(lastFetch, interval, time.Now()) {
+		return "", false
+	}
+
+	commit, err := cache.Lookup(localPath, func() (string, error) {
+		gitManager, err := git.NewGitManager(r.buildAuthConfig(data))
+		if err != nil {
+			return "", err
+		}
+		tflog.Debug(ctx, "Update interval elapsed, fetching upstream", map[string]interface{}{
+			"local_path": localPath,
+			"last_fetch": lastFetch,
+			"interval":   interval.String(),
+		})
+		if r.client != nil && r.client.MirrorCache != nil {
+			if _, err := r.cloneURL(ctx, gitManager, data, localPath); err != nil {
+				return "", err
+			}
+		}
+		if ref := gitRef(data); ref != "" {
+			return gitManager.FetchRef(ctx, localPath, ref)
+		}
+		return gitManager.FetchUpstream(ctx, localPath)
+	})
+	if err != nil {
+		tflog.Warn(ctx, "Failed to fetch upstream commits", map[string]interface{}{
+			"local_path": localPath,
+			"error":      err.Error(),
+		})
+		return "", false
+	}
+
+	data.LastFetch = types.StringValue(time.Now().Format(time.RFC3339))
+	return commit, true
+}
+
+// cloneURL returns the URL the checkout is cloned from and updated against: the shared
+// mirror when the provider has a mirror cache, otherwise the source itself. An existing
+// checkout at localPath keeps origin at the source and fetches from the mirror through
+// git.MirrorRemote, so enabling or disabling the cache takes effect on the next update.
+func (r *RepositoryResource) cloneURL(ctx context.Context, gitManager *git.GitManager, data *RepositoryResourceModel, localPath string) (string, error) {
+	sourceURL := data.SourcePath.ValueString()
+	normalizedURL, err := git.NormalizeGitURL(sourceURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize URL: %w", err)
+	}
+
+	mirrorPath := ""
+	if r.client != nil && r.client.MirrorCache != nil {
+		mirror, err := r.client.MirrorCache.Sync(ctx, gitManager, sourceURL)
+		if err != nil {
+			return "", errors.GitError("sync", "repository", "Failed to synchronize repository mirror", err).
+				WithContext("url", sourceURL)
+		}
+		if mirror.FetchError != nil {
+			tflog.Warn(ctx, "Remote unreachable, using cached mirror", map[string]interface{}{
+				"url":        sourceURL,
+				"mirror":     mirror.Path,
+				"last_fetch": mirror.LastFetch.Format(time.RFC3339),
+				"error":      mirror.FetchError.Error(),
+			})
+		}
+		mirrorPath = mirror.Path
+	}
+
+	if _, err := os.Stat(localPath); err == nil {
+		if err := git.SetCheckoutRemotes(localPath, normalizedURL, mirrorPath); err != nil {
+			return "", fmt.Errorf("failed to set checkout remotes: %w", err)
+		}
+	}
+	if mirrorPath != "" {
+		return mirrorPath, nil
+	}
+	return normalizedURL, nil
+}
+
+// updateGitRepository checks out git_ref when set, otherwise pulls the current branch.
+func (r *RepositoryResource) updateGitRepository(ctx context.Context, gitManager *git.GitManager, data *RepositoryResourceModel, localPath string) (*git.RepositoryInfo, error) {
+	if ref := gitRef(data); ref != "" {
+		tflog.Debug(ctx, "Checking out pinned git ref", map[string]interface{}{
+			"local_path": localPath,
+			"git_ref":    ref,
+		})
+	}
+	return gitManager.UpdateRepositoryWithOptions(ctx, git.UpdateOptions{
+		LocalPath:         localPath,
+		Ref:               gitRef(data),
+		Depth:             int(data.Depth.ValueInt64()),
+		RecurseSubmodules: data.RecurseSubmodules.ValueBool(),
+		SparsePaths:       sparsePaths(ctx, data),
+	})
+}
+
+// updateCheckout applies dirty_policy, updates the checkout and verifies its signature.
+// A non-empty warning lists stashed files that also changed upstream and were left in the stash.
+func (r *RepositoryResource) updateCheckout(ctx context.Context, gitManager *git.GitManager, data *RepositoryResourceModel, localPath string) (*git.RepositoryInfo, string, error) {
+	status, err := git.RepositoryStatus(localPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get repository status: %w", err)
+	}
+
+	var stash *git.Stash
+	if status.IsDirty() {
+		policy := dirtyPolicy(data)
+		tflog.Info(ctx, "Local repository has uncommitted changes", map[string]interface{}{
+			"local_path":     localPath,
+			"modified_files": status.ModifiedFiles,
+			"dirty_policy":   policy,
+		})
+
+		switch policy {
+		case dirtyPolicySkipUpdate:
+			info, err := gitManager.GetRepositoryInfo(localPath)
+			if err != nil {
+				return nil, "", err
+			}
+			if lastUpdate, err := time.Parse(time.RFC3339, data.LastUpdate.ValueString()); err == nil {
+				info.LastUpdate = lastUpdate
+			}
+			return info, "", nil
+		case dirtyPolicyStash:
+			stash, err = git.StashChanges(localPath)
+			if err != nil {
+				return nil, "", errors.IOError("stash", "repository", "Failed to stash local changes", err).
+					WithPath(localPath).
+					WithRetryable(false)
+			}
+		default:
+			return nil, "", errors.GitError("update", "repository", "Local repository has uncommitted changes", nil).
+				WithPath(localPath).
+				WithContext("modified_files", strings.Join(status.ModifiedFiles, ", ")).
+				WithContext("dirty_policy", policy).
+				WithRetryable(false)
+		}
+	}
+
+	previousCommit := r.checkoutCommit(gitManager, localPath)
+	info, updateErr := r.updateGitRepository(ctx, gitManager, data, localPath)
+	if updateErr == nil {
+		updateErr = r.verifyCheckout(ctx, data, localPath, previousCommit)
+	}
+	if stash == nil {
+		return info, "", updateErr
+	}
+
+	// Put local changes back whether or not the update succeeded
+	conflicts, err := stash.Restore(localPath)
+	if err != nil {
+		return nil, "", errors.IOError("stash", "repository", "Failed to restore stashed changes", err).
+			WithPath(stash.Dir).
+			WithRetryable(false)
+	}
+	var warning string
+	if len(conflicts) > 0 {
+		warning = fmt.Sprintf("Local changes to %s conflict with upstream changes and were kept in %s.",
+			strings.Join(conflicts, ", "), stash.Dir)
+	}
+	return info, warning, updateErr
+}
+
+// recloneStash holds the local changes of a checkout that was moved aside to be cloned again.
+type recloneStash struct {
+	stash *git.Stash
+	// checkout is where the previous checkout was moved
+	checkout string
+	ahead    int
+}
+
+// removeCheckout removes a checkout so it can be cloned again with a different layout,
+// applying dirty_policy first when it has uncommitted changes or unpushed commits. With
+// skip_update the checkout is left in place; with stash it is moved aside and its changes
+// are returned to be re-applied to the new clone.
+func (r *RepositoryResource) removeCheckout(ctx context.Context, data *RepositoryResourceModel, localPath string) (*recloneStash, error) {
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	status, err := git.RepositoryStatus(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository status: %w", err)
+	}
+
+	if status.IsDirty() || status.Ahead > 0 {
+		policy := dirtyPolicy(data)
+		tflog.Info(ctx, "Local repository has changes that a re-clone would discard", map[string]interface{}{
+			"local_path":     localPath,
+			"modified_files": status.ModifiedFiles,
+			"ahead":          status.Ahead,
+			"dirty_policy":   policy,
+		})
+
+		switch policy {
+		case dirtyPolicySkipUpdate:
+			return nil, nil
+		case dirtyPolicyStash:
+			stash, err := git.StashChanges(localPath)
+			if err != nil {
+				return nil, errors.IOError("stash", "repository", "Failed to stash local changes", err).
+					WithPath(localPath).
+					WithRetryable(false)
+			}
+			reclone := &recloneStash{
+				stash:    stash,
+				checkout: localPath + ".dotfiles-reclone-" + time.Now().UTC().Format("20060102T150405Z"),
+				ahead:    status.Ahead,
+			}
+			if err := os.Rename(localPath, reclone.checkout); err != nil {
+				if stash != nil {
+					_, _ = stash.Restore(localPath)
+				}
+				return nil, errors.IOError("stash", "repository", "Failed to move the checkout aside", err).
+					WithPath(localPath).
+					WithRetryable(false)
+			}
+			if stash != nil {
+				stash.Dir = filepath.Join(reclone.checkout, strings.TrimPrefix(stash.Dir, localPath))
+			}
+			return reclone, nil
+		default:
+			return nil, errors.GitError("reclone", "repository", "Local repository has changes that re-cloning for the new sparse_paths or depth would discard", nil).
+				WithPath(localPath).
+				WithContext("modified_files", strings.Join(status.ModifiedFiles, ", ")).
+				WithContext("ahead", status.Ahead).
+				WithContext("dirty_policy", policy).
+				WithRetryable(false)
+		}
+	}
+
+	if err := os.RemoveAll(localPath); err != nil {
+		return nil, errors.IOError("reclone", "repository", "Failed to remove the checkout to re-clone it", err).
+			WithPath(localPath)
+	}
+	return nil, nil
+}
+
+// restore re-applies stashed changes to the new clone in localPath. The previous checkout
+// is removed once nothing in it is left to recover; otherwise a warning says where it is.
+func (s *recloneStash) restore(ctx context.Context, localPath string) string {
+	var kept []string
+	if s.stash != nil {
+		conflicts, err := s.stash.Restore(localPath)
+		if err != nil {
+			tflog.Warn(ctx, "Failed to restore stashed changes", map[string]interface{}{
+				"local_path": localPath,
+				"error":      err.Error(),
+			})
+			conflicts = s.stash.Files
+		}
+		kept = append(kept, conflicts...)
+	}
+
+	if len(kept) == 0 && s.ahead == 0 {
+		if err := os.RemoveAll(s.checkout); err != nil {
+			tflog.Warn(ctx, "Failed to remove previous checkout", map[string]interface{}{
+				"path":  s.checkout,
+				"error": err.Error(),
+			})
+		}
+		return ""
+	}
+	var details []string
+	if len(kept) > 0 {
+		details = append(details, fmt.Sprintf("changes to %s could not be re-applied", strings.Join(kept, ", ")))
+	}
+	if s.ahead > 0 {
+		details = append(details, fmt.Sprintf("%d local commits are not on the upstream branch", s.ahead))
+	}
+	return fmt.Sprintf("The repository was cloned again, but %s. The previous checkout was kept in %s.",
+		strings.Join(details, " and "), s.checkout)
+}
+
+// dirtyPolicy returns the configured dirty_policy, defaulting to fail.
+func dirtyPolicy(data *RepositoryResourceModel) string {
+	if data.DirtyPolicy.IsNull() || data.DirtyPolicy.IsUnknown() || data.DirtyPolicy.ValueString() == "" {
+		return dirtyPolicyFail
+	}
+	return data.DirtyPolicy.ValueString()
+}
+
+// registerRepository records the root path under the repository name, for dependent
+// state recorded before repository IDs carried the root path.
+func (r *RepositoryResource) registerRepository(data *RepositoryResourceModel) {
+	if r.client == nil || data.RootPath.IsNull() || data.RootPath.ValueString() == "" {
+		return
+	}
+	r.client.RegisterRepository(data.Name.ValueString(), data.RootPath.ValueString())
+}
+
+// setStatus records the working tree status of the checkout. Sources that are not Git
+// repositories are reported clean.
+func (r *RepositoryResource) setStatus(ctx context.Context, data *RepositoryResourceModel) {
+	status := &git.WorkingTreeStatus{}
+	if localPath := data.LocalPath.ValueString(); localPath != "" && r.isGitRepository(localPath) {
+		var err error
+		if status, err = git.RepositoryStatus(localPath); err != nil {
+			tflog.Warn(ctx, "Failed to get repository status", map[string]interface{}{
+				"local_path": localPath,
+				"error":      err.Error(),
+			})
+			status = &git.WorkingTreeStatus{}
+		}
+	}
+
+	modifiedFiles := make([]attr.Value, 0, len(status.ModifiedFiles))
+	for _, file := range status.ModifiedFiles {
+		modifiedFiles = append(modifiedFiles, types.StringValue(file))
+	}
+	data.IsDirty = types.BoolValue(status.IsDirty())
+	data.ModifiedFiles = types.ListValueMust(types.StringType, modifiedFiles)
+	data.Ahead = types.Int64Value(int64(status.Ahead))
+	data.Behind = types.Int64Value(int64(status.Behind))
+}
+
+// setRootPath sets root_path and checks that the configured subdirectory exists in the checkout.
+func (r *RepositoryResource) setRootPath(data *RepositoryResourceModel) error {
+	rootPath := repositoryRootPath(data)
+	if stat, err := os.Stat(rootPath); err != nil || !stat.IsDir() {
+		return errors.ValidationError("set_root_path", "repository",
+			fmt.Sprintf("Subdirectory %q does not exist in the repository", data.Subdirectory.ValueString()), err).
+			WithPath(rootPath).
+			WithContext("source_path", data.SourcePath.ValueString())
+	}
+	data.RootPath = types.StringValue(rootPath)
+	return nil
+}
+
+// repositoryRootPath returns local_path joined with subdirectory.
+func repositoryRootPath(data *RepositoryResourceModel) string {
+	if data.Subdirectory.IsNull() || data.Subdirectory.ValueString() == "" {
+		return data.LocalPath.ValueString()
+	}
+	return filepath.Join(data.LocalPath.ValueString(), data.Subdirectory.ValueString())
+}
+
+// sparsePaths returns the configured sparse checkout directories, or nil for a full checkout.
+func sparsePaths(ctx context.Context, data *RepositoryResourceModel) []string {
+	if data.SparsePaths.IsNull() || data.SparsePaths.IsUnknown() {
+		return nil
+	}
+	var paths []string
+	data.SparsePaths.ElementsAs(ctx, &paths, false)
+	return paths
+}
+
+// withinSparsePaths reports whether dir lies inside one of the sparse checkout directories.
+func withinSparsePaths(dir string, sparsePaths []types.String) bool {
+	dir = filepath.Clean(dir)
+	for _, sparsePath := range sparsePaths {
+		rel, err := filepath.Rel(filepath.Clean(sparsePath.ValueString()), dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCheckout verifies the signature of the checked-out commit when verify_signatures is configured.
+// On failure the checkout is reset to previousCommit, or removed when there is no previous commit,
+// so unverified content is never left in place.
+func (r *RepositoryResource) verifyCheckout(ctx context.Context, data *RepositoryResourceModel, localPath, previousCommit string) error {
+	trusted, ok := signatureConfig(data)
+	if !ok {
+		return nil
+	}
+
+	result, err := git.VerifyHeadSignature(localPath, trusted)
+	if err == nil {
+		tflog.Info(ctx, "Verified commit signature", map[string]interface{}{
+			"local_path": localPath,
+			"commit":     result.Commit,
+			"format":     result.Format,
+			"signer":     result.Signer,
+		})
+		return nil
+	}
+
+	var restoreErr error
+	if previousCommit != "" {
+		restoreErr = git.ResetToCommit(localPath, previousCommit)
+	} else {
+		restoreErr = os.RemoveAll(localPath)
+	}
+	if restoreErr != nil {
+		tflog.Error(ctx, "Failed to discard unverified checkout", map[string]interface{}{
+			"local_path": localPath,
+			"error":      restoreErr.Error(),
+		})
+	}
+
+	return errors.GitError("verify_signature", "repository", "Commit signature verification failed", err).
+		WithPath(localPath).
+		WithContext("source_path", data.SourcePath.ValueString()).
+		WithRetryable(false)
+}
+
+// checkoutCommit returns the commit currently checked out in localPath, or an empty string if unknown.
+func (r *RepositoryResource) checkoutCommit(gitManager *git.GitManager, localPath string) string {
+	info, err := gitManager.GetRepositoryInfo(localPath)
+	if err != nil {
+		return ""
+	}
+	return info.LastCommit
+}
+
+// signatureConfig returns the trusted keys from verify_signatures, or false when verification is not configured.
+func signatureConfig(data *RepositoryResourceModel) (git.SignatureConfig, bool) {
+	if data.VerifySignatures == nil {
+		return git.SignatureConfig{}, false
+	}
+	return git.SignatureConfig{
+		ArmoredKeyRing: data.VerifySignatures.OpenPGPKeyring.ValueString(),
+		AllowedSigners: data.VerifySignatures.SSHAllowedSigners.ValueString(),
+	}, true
+}
+
+// resolvedCommit returns the checked-out commit for repositories pinned with git_ref, or null otherwise.
+func (r *RepositoryResource) resolvedCommit(data *RepositoryResourceModel) types.String {
+	if gitRef(data) == "" || !git.IsGitURL(data.SourcePath.ValueString()) || data.LastCommit.ValueString() == "" {
+		return types.StringNull()
+	}
+	return data.LastCommit
+}
+
+// gitRef returns the configured git_ref, or an empty string when unset.
+func gitRef(data *RepositoryResourceModel) string {
+	if data.GitRef.IsNull() || data.GitRef.IsUnknown() {
+		return ""
+	}
+	return data.GitRef.ValueString()
+}
+
 // isGitRepository checks if a local path contains a Git repository.
 func (r *RepositoryResource) isGitRepository(localPath string) bool {
 	gitDir := filepath.Join(localPath, ".git")
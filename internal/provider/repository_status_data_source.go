@@ -0,0 +1,417 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
+)
+
+var _ datasource.DataSource = &RepositoryStatusDataSource{}
+var _ datasource.DataSourceWithValidateConfig = &RepositoryStatusDataSource{}
+
+func NewRepositoryStatusDataSource() datasource.DataSource {
+	return &RepositoryStatusDataSource{}
+}
+
+// RepositoryStatusDataSource reports the state of a local Git repository without managing it.
+type RepositoryStatusDataSource struct {
+	client *DotfilesClient
+}
+
+// RepositoryStatusDataSourceModel describes the data source data model.
+type RepositoryStatusDataSourceModel struct {
+	ID          types.String `tfsdk:"id"`
+	Path        types.String `tfsdk:"path"`
+	Repository  types.String `tfsdk:"repository"`
+	QueryRemote types.Bool   `tfsdk:"query_remote"`
+
+	LocalPath          types.String          `tfsdk:"local_path"`
+	Branch             types.String          `tfsdk:"branch"`
+	HeadCommit         types.String          `tfsdk:"head_commit"`
+	CommitMessage      types.String          `tfsdk:"commit_message"`
+	CommitAuthor       types.String          `tfsdk:"commit_author"`
+	CommitAuthorEmail  types.String          `tfsdk:"commit_author_email"`
+	CommitTime         types.String          `tfsdk:"commit_time"`
+	IsDirty            types.Bool            `tfsdk:"is_dirty"`
+	ModifiedFiles      types.List            `tfsdk:"modified_files"`
+	Ahead              types.Int64           `tfsdk:"ahead"`
+	Behind             types.Int64           `tfsdk:"behind"`
+	RemoteURLs         types.Map             `tfsdk:"remote_urls"`
+	DefaultBranch      types.String          `tfsdk:"default_branch"`
+	RemoteBranches     types.List            `tfsdk:"remote_branches"`
+	RemoteTags         types.List            `tfsdk:"remote_tags"`
+	Submodules         []RepositorySubmodule `tfsdk:"submodules"`
+	Valid              types.Bool            `tfsdk:"valid"`
+	ValidationWarnings types.List            `tfsdk:"validation_warnings"`
+	ValidationErrors   types.List            `tfsdk:"validation_errors"`
+}
+
+// RepositorySubmodule describes a submodule of the inspected repository.
+type RepositorySubmodule struct {
+	Name   types.String `tfsdk:"name"`
+	Path   types.String `tfsdk:"path"`
+	URL    types.String `tfsdk:"url"`
+	Branch types.String `tfsdk:"branch"`
+	Status types.String `tfsdk:"status"`
+}
+
+func (d *RepositoryStatusDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_repository_status"
+}
+
+func (d *RepositoryStatusDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Reports branch, HEAD commit, working tree status, remotes, submodules and validation results of a local Git repository without managing it",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Data source identifier",
+			},
+			"path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Path to a local Git repository. Conflicts with `repository`",
+			},
+			"repository": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "ID of a `dotfiles_repository` managed in this configuration. Conflicts with `path`",
+			},
+			"query_remote": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Contact the `origin` remote for its default branch, branches and tags (default: true)",
+			},
+			"local_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Absolute path of the inspected repository",
+			},
+			"branch": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Checked-out branch, empty in detached HEAD",
+			},
+			"head_commit": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SHA of the checked-out commit",
+			},
+			"commit_message": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Message of the checked-out commit",
+			},
+			"commit_author": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Author name of the checked-out commit",
+			},
+			"commit_author_email": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Author email of the checked-out commit",
+			},
+			"commit_time": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Author timestamp of the checked-out commit (RFC 3339)",
+			},
+			"is_dirty": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether tracked files have uncommitted changes",
+			},
+			"modified_files": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Tracked files with uncommitted changes, relative to the repository root",
+			},
+			"ahead": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Local commits not on the upstream branch, as of the last fetch",
+			},
+			"behind": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Upstream commits not yet checked out, as of the last fetch",
+			},
+			"remote_urls": schema.MapAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "URL of each configured remote, keyed by remote name",
+			},
+			"default_branch": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Default branch of `origin`, when `query_remote` is enabled",
+			},
+			"remote_branches": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Branches of `origin`, when `query_remote` is enabled",
+			},
+			"remote_tags": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Tags of `origin`, when `query_remote` is enabled",
+			},
+			"submodules": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Submodules of the repository",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Submodule name",
+						},
+						"path": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Submodule path within the repository",
+						},
+						"url": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Submodule URL",
+						},
+						"branch": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Submodule branch",
+						},
+						"status": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Commit checked out in the submodule",
+						},
+					},
+				},
+			},
+			"valid": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the repository passed validation",
+			},
+			"validation_warnings": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Validation warnings, e.g. uncommitted changes in submodules",
+			},
+			"validation_errors": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Validation errors",
+			},
+		},
+	}
+}
+
+func (d *RepositoryStatusDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected Data Source Configure Type", "Expected *DotfilesClient")
+		return
+	}
+	d.client = client
+}
+
+// ValidateConfig requires exactly one of path and repository.
+func (d *RepositoryStatusDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data RepositoryStatusDataSourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Path.IsUnknown() || data.Repository.IsUnknown() {
+		return
+	}
+	if data.Path.IsNull() == data.Repository.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("path"),
+			"Invalid Repository Selection",
+			"Exactly one of path or repository must be set.",
+		)
+	}
+}
+
+func (d *RepositoryStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data RepositoryStatusDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	localPath, err := d.resolvePath(&data)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to locate repository", err.Error())
+		return
+	}
+
+	if err := d.inspect(ctx, &data, localPath); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to read repository status",
+			fmt.Sprintf("Could not inspect Git repository at %s: %s", localPath, err.Error()),
+		)
+		return
+	}
+	if !data.Valid.ValueBool() {
+		var details []string
+		data.ValidationErrors.ElementsAs(ctx, &details, false)
+		resp.Diagnostics.AddWarning(
+			"Invalid Git repository",
+			fmt.Sprintf("The repository at %s failed validation: %s", localPath, strings.Join(details, "; ")),
+		)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// resolvePath returns the absolute path of the repository selected by path or repository ID.
+func (d *RepositoryStatusDataSource) resolvePath(data *RepositoryStatusDataSourceModel) (string, error) {
+	if !data.Repository.IsNull() {
+		if d.client == nil {
+			return "", fmt.Errorf("provider is not configured")
+		}
+		rootPath, err := d.client.RepositoryRoot(data.Repository.ValueString())
+		if err != nil {
+			return "", fmt.Errorf("%w; set path instead", err)
+		}
+		// The root path of a repository with a subdirectory lies inside its checkout
+		return git.WorktreeRoot(rootPath)
+	}
+
+	localPath := data.Path.ValueString()
+	if strings.HasPrefix(localPath, "~") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand path: %w", err)
+		}
+		localPath = filepath.Join(homeDir, localPath[1:])
+	}
+	return filepath.Abs(localPath)
+}
+
+// inspect fills the computed attributes from the repository at localPath.
+func (d *RepositoryStatusDataSource) inspect(ctx context.Context, data *RepositoryStatusDataSourceModel, localPath string) error {
+	gitManager, err := git.NewGitManager(nil)
+	if err != nil {
+		return err
+	}
+
+	// Validation results are reported even when the repository cannot be opened
+	validation, err := gitManager.ValidateRepositoryDetailed(localPath)
+	if validation == nil || (validation.Valid && err != nil) {
+		return err
+	}
+	data.ID = types.StringValue(localPath)
+	data.LocalPath = types.StringValue(localPath)
+	data.Valid = types.BoolValue(validation.Valid)
+	data.ValidationWarnings = stringList(validation.Warnings)
+	data.ValidationErrors = stringList(validation.Errors)
+	if !validation.Valid {
+		clearRepositoryStatus(data)
+		return nil
+	}
+
+	info, err := gitManager.GetRepositoryInfo(localPath)
+	if err != nil {
+		return err
+	}
+	commit, err := git.HeadCommit(localPath)
+	if err != nil {
+		return err
+	}
+	status, err := git.RepositoryStatus(localPath)
+	if err != nil {
+		return err
+	}
+	remotes, err := git.RemoteURLs(localPath)
+	if err != nil {
+		return err
+	}
+	submodules, err := gitManager.ListSubmodules(localPath)
+	if err != nil {
+		return err
+	}
+
+	data.Branch = types.StringValue(info.Branch)
+	data.HeadCommit = types.StringValue(commit.Hash)
+	data.CommitMessage = types.StringValue(commit.Message)
+	data.CommitAuthor = types.StringValue(commit.Author)
+	data.CommitAuthorEmail = types.StringValue(commit.AuthorEmail)
+	data.CommitTime = types.StringValue(commit.Time.Format(time.RFC3339))
+	data.IsDirty = types.BoolValue(status.IsDirty())
+	data.ModifiedFiles = stringList(status.ModifiedFiles)
+	data.Ahead = types.Int64Value(int64(status.Ahead))
+	data.Behind = types.Int64Value(int64(status.Behind))
+
+	remoteURLs := make(map[string]attr.Value, len(remotes))
+	for name, url := range remotes {
+		remoteURLs[name] = types.StringValue(url)
+	}
+	data.RemoteURLs = types.MapValueMust(types.StringType, remoteURLs)
+
+	data.Submodules = make([]RepositorySubmodule, 0, len(submodules))
+	for _, submodule := range submodules {
+		data.Submodules = append(data.Submodules, RepositorySubmodule{
+			Name:   types.StringValue(submodule.Name),
+			Path:   types.StringValue(submodule.Path),
+			URL:    types.StringValue(submodule.URL),
+			Branch: types.StringValue(submodule.Branch),
+			Status: types.StringValue(submodule.Status),
+		})
+	}
+
+	data.DefaultBranch = types.StringNull()
+	data.RemoteBranches = types.ListNull(types.StringType)
+	data.RemoteTags = types.ListNull(types.StringType)
+	originURL, hasOrigin := remotes["origin"]
+	if !hasOrigin || (!data.QueryRemote.IsNull() && !data.QueryRemote.ValueBool()) {
+		return nil
+	}
+
+	remote, err := gitManager.GetRemoteInfo(ctx, originURL)
+	if err != nil {
+		// The local status is still useful offline
+		tflog.Warn(ctx, "Failed to query remote repository", map[string]interface{}{
+			"url":   originURL,
+			"error": err.Error(),
+		})
+		return nil
+	}
+	data.DefaultBranch = types.StringValue(remote.DefaultBranch)
+	data.RemoteBranches = stringList(remote.Branches)
+	data.RemoteTags = stringList(remote.Tags)
+	return nil
+}
+
+// clearRepositoryStatus nulls the attributes that describe the contents of a repository.
+func clearRepositoryStatus(data *RepositoryStatusDataSourceModel) {
+	data.Branch = types.StringNull()
+	data.HeadCommit = types.StringNull()
+	data.CommitMessage = types.StringNull()
+	data.CommitAuthor = types.StringNull()
+	data.CommitAuthorEmail = types.StringNull()
+	data.CommitTime = types.StringNull()
+	data.IsDirty = types.BoolNull()
+	data.ModifiedFiles = types.ListNull(types.StringType)
+	data.Ahead = types.Int64Null()
+	data.Behind = types.Int64Null()
+	data.RemoteURLs = types.MapNull(types.StringType)
+	data.DefaultBranch = types.StringNull()
+	data.RemoteBranches = types.ListNull(types.StringType)
+	data.RemoteTags = types.ListNull(types.StringType)
+	data.Submodules = nil
+}
+
+// stringList converts a string slice to a list value, using an empty list for nil.
+func stringList(values []string) types.List {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
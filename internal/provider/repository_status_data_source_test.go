@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/git"
+)
+
+func TestRepositoryStatusDataSource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		d := NewRepositoryStatusDataSource()
+		resp := &datasource.MetadataResponse{}
+		d.Metadata(context.Background(), datasource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_repository_status" {
+			t.Errorf("Expected TypeName dotfiles_repository_status, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		d := NewRepositoryStatusDataSource()
+		resp := &datasource.SchemaResponse{}
+		d.Schema(context.Background(), datasource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		for _, attr := range []string{"path", "repository", "query_remote"} {
+			if !resp.Schema.Attributes[attr].IsOptional() {
+				t.Errorf("Attribute %s should be optional", attr)
+			}
+		}
+		computedAttrs := []string{
+			"id", "local_path", "branch", "head_commit", "commit_message", "commit_author", "commit_time",
+			"is_dirty", "modified_files", "remote_urls", "default_branch", "submodules", "valid", "validation_errors",
+		}
+		for _, attr := range computedAttrs {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		d := NewRepositoryStatusDataSource().(*RepositoryStatusDataSource)
+		ctx := context.Background()
+
+		resp := &datasource.ConfigureResponse{}
+		d.Configure(ctx, datasource.ConfigureRequest{ProviderData: &DotfilesClient{}}, resp)
+		if resp.Diagnostics.HasError() || d.client == nil {
+			t.Errorf("Configure with valid client failed: %v", resp.Diagnostics)
+		}
+
+		resp = &datasource.ConfigureResponse{}
+		d.Configure(ctx, datasource.ConfigureRequest{ProviderData: "invalid"}, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Error("Configure with invalid provider data should error")
+		}
+	})
+}
+
+func TestRepositoryStatusDataSourceInspect(t *testing.T) {
+	tempDir := t.TempDir()
+	upstreamDir := filepath.Join(tempDir, "upstream")
+	upstream, err := gogit.PlainInit(upstreamDir, false)
+	if err != nil {
+		t.Fatalf("Failed to create upstream repository: %v", err)
+	}
+	head := commitUpstreamFile(t, upstream, "zshrc", "export EDITOR=vim\n")
+	if _, err := upstream.CreateTag("v1.0.0", plumbing.NewHash(head), nil); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+
+	manager, err := git.NewGitManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to create Git manager: %v", err)
+	}
+	localDir := filepath.Join(tempDir, "local")
+	if _, err := manager.CloneRepository(context.Background(), upstreamDir, localDir, ""); err != nil {
+		t.Fatalf("Failed to clone repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(localDir, "zshrc"), []byte("export EDITOR=nvim\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify zshrc: %v", err)
+	}
+
+	client := &DotfilesClient{HomeDir: tempDir}
+	d := &RepositoryStatusDataSource{client: client}
+	ctx := context.Background()
+
+	data := &RepositoryStatusDataSourceModel{
+		Path:        types.StringNull(),
+		Repository:  types.StringValue(localDir),
+		QueryRemote: types.BoolNull(),
+	}
+	localPath, err := d.resolvePath(data)
+	if err != nil || localPath != localDir {
+		t.Fatalf("Expected repository to resolve to %s, got %q, %v", localDir, localPath, err)
+	}
+	if err := d.inspect(ctx, data, localPath); err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+
+	// The root path of a repository with a subdirectory resolves to its checkout
+	if err := os.MkdirAll(filepath.Join(localDir, "home"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	subdirectory := &RepositoryStatusDataSourceModel{Repository: types.StringValue(filepath.Join(localDir, "home"))}
+	if checkout, err := d.resolvePath(subdirectory); err != nil || checkout != localDir {
+		t.Errorf("Expected subdirectory root to resolve to %s, got %q, %v", localDir, checkout, err)
+	}
+
+	if data.HeadCommit.ValueString() != head || data.CommitMessage.ValueString() != "update zshrc" {
+		t.Errorf("Unexpected head commit %s %q", data.HeadCommit, data.CommitMessage)
+	}
+	if data.CommitAuthor.ValueString() != "Test" || data.CommitAuthorEmail.ValueString() != "test@example.com" {
+		t.Errorf("Unexpected commit author %s <%s>", data.CommitAuthor, data.CommitAuthorEmail)
+	}
+	if !data.Valid.ValueBool() || !data.IsDirty.ValueBool() {
+		t.Errorf("Expected a valid, dirty repository, got valid=%v is_dirty=%v", data.Valid, data.IsDirty)
+	}
+	var remotes map[string]string
+	data.RemoteURLs.ElementsAs(ctx, &remotes, false)
+	if remotes["origin"] != upstreamDir {
+		t.Errorf("Expected origin %s, got %v", upstreamDir, remotes)
+	}
+	var tags []string
+	data.RemoteTags.ElementsAs(ctx, &tags, false)
+	if len(tags) != 1 || tags[0] != "v1.0.0" {
+		t.Errorf("Expected remote tag v1.0.0, got %v", tags)
+	}
+	if len(data.Submodules) != 0 {
+		t.Errorf("Expected no submodules, got %v", data.Submodules)
+	}
+
+	// Remote queries can be disabled
+	data.QueryRemote = types.BoolValue(false)
+	if err := d.inspect(ctx, data, localPath); err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if !data.RemoteTags.IsNull() || !data.DefaultBranch.IsNull() {
+		t.Errorf("Expected no remote details, got tags=%v default_branch=%v", data.RemoteTags, data.DefaultBranch)
+	}
+
+	// Unknown repository IDs and non-repositories are errors
+	if _, err := d.resolvePath(&RepositoryStatusDataSourceModel{Repository: types.StringValue("missing")}); err == nil {
+		t.Error("Expected error for unknown repository ID")
+	}
+
+	// Directories that are not repositories are reported as invalid rather than failing
+	invalid := &RepositoryStatusDataSourceModel{}
+	if err := d.inspect(ctx, invalid, tempDir); err != nil {
+		t.Fatalf("Expected an invalid repository to be reported, got %v", err)
+	}
+	var validationErrors []string
+	invalid.ValidationErrors.ElementsAs(ctx, &validationErrors, false)
+	if invalid.Valid.ValueBool() || len(validationErrors) == 0 || invalid.LocalPath.ValueString() != tempDir {
+		t.Errorf("Expected valid=false with validation errors, got valid=%v errors=%v", invalid.Valid, validationErrors)
+	}
+	if !invalid.HeadCommit.IsNull() || !invalid.ModifiedFiles.IsNull() || !invalid.RemoteURLs.IsNull() {
+		t.Errorf("Expected no repository details, got head=%v files=%v remotes=%v", invalid.HeadCommit, invalid.ModifiedFiles, invalid.RemoteURLs)
+	}
+}
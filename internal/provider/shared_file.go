@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/utils"
+)
+
+// readShared returns the content of a file co-owned with other tools and whether it exists.
+func readShared(resourceType, target string) (string, bool, error) {
+	content, err := os.ReadFile(target)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.IOError("read", resourceType, "Failed to read the file", err).WithPath(target)
+	}
+	return string(content), true, nil
+}
+
+// writeShared backs up an existing file when backups are enabled and atomically replaces
+// its content. New files get mode. It returns the backup path, or an empty string when no
+// backup was taken. Nothing is written in dry-run mode.
+func (c *DotfilesClient) writeShared(ctx context.Context, resourceType, target, content string, existed bool, mode os.FileMode, backup types.Bool) (string, error) {
+	if c.Config != nil && c.Config.DryRun {
+		tflog.Info(ctx, "DRY RUN: Skipping write of shared file", map[string]interface{}{
+			"resource":    resourceType,
+			"target_path": target,
+		})
+		return "", nil
+	}
+
+	fileManager := fileops.NewFileManager(platform.DetectPlatform(), false)
+	backupPath := ""
+	if existed && c.backupEnabled(backup) {
+		var err error
+		backupPath, err = fileManager.CreateEnhancedBackup(target, &fileops.EnhancedBackupConfig{
+			Enabled:        true,
+			Directory:      c.Config.BackupDirectory,
+			BackupFormat:   "timestamped",
+			BackupMetadata: true,
+			BackupIndex:    true,
+		})
+		if err != nil {
+			return "", errors.IOError("backup", resourceType, "Failed to back up the file", err).
+				WithPath(target).
+				WithContext("backup_directory", c.Config.BackupDirectory)
+		}
+	}
+
+	if err := fileManager.WriteFileAtomic(target, []byte(content), mode); err != nil {
+		return "", errors.IOError("write", resourceType, "Failed to write the file", err).WithPath(target)
+	}
+	return backupPath, nil
+}
+
+// backupEnabled returns a resource's backup_enabled, defaulting to the provider setting.
+func (c *DotfilesClient) backupEnabled(backup types.Bool) bool {
+	if !backup.IsNull() && !backup.IsUnknown() {
+		return backup.ValueBool()
+	}
+	return c.Config != nil && c.Config.BackupEnabled
+}
+
+// sharedFileMode returns the mode for a new shared file, 0644 unless fileMode is set.
+func sharedFileMode(resourceType string, fileMode types.String) (os.FileMode, error) {
+	if fileMode.IsNull() || fileMode.ValueString() == "" {
+		return 0644, nil
+	}
+	mode, err := utils.ParseFileMode(fileMode.ValueString())
+	if err != nil {
+		return 0, errors.ValidationError("parse_file_mode", resourceType, "Invalid file mode", err)
+	}
+	return mode, nil
+}
@@ -0,0 +1,810 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/errors"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/fileops"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/platform"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/sshconfig"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/utils"
+	"github.com/jamesainslie/terraform-provider-dotfiles/internal/validators"
+)
+
+var _ resource.Resource = &SSHConfigResource{}
+var _ resource.ResourceWithValidateConfig = &SSHConfigResource{}
+var _ resource.ResourceWithModifyPlan = &SSHConfigResource{}
+
+// Defaults of dotfiles_ssh_config.
+const (
+	defaultSSHConfigPath = "~/.ssh/config"
+	defaultSSHConfigMode = "0600"
+)
+
+// maxPublicKeySize bounds how much of an identity file is read to recognize a public key.
+const maxPublicKeySize = 16 * 1024
+
+func NewSSHConfigResource() resource.Resource {
+	return &SSHConfigResource{}
+}
+
+// SSHConfigResource manages Host and Match sections of an OpenSSH client configuration,
+// leaving the sections it does not own alone.
+type SSHConfigResource struct {
+	client *DotfilesClient
+}
+
+// SSHConfigResourceModel describes the resource data model.
+type SSHConfigResourceModel struct {
+	ID                 types.String   `tfsdk:"id"`
+	TargetPath         types.String   `tfsdk:"target_path"`
+	FileMode           types.String   `tfsdk:"file_mode"`
+	Includes           types.List     `tfsdk:"includes"`
+	CheckIdentityFiles types.Bool     `tfsdk:"check_identity_files"`
+	BackupEnabled      types.Bool     `tfsdk:"backup_enabled"`
+	Hosts              []SSHHostModel `tfsdk:"host"`
+
+	ContentHash types.String `tfsdk:"content_hash"`
+	BackupPath  types.String `tfsdk:"backup_path"`
+}
+
+// SSHHostModel is a Host or Match section.
+type SSHHostModel struct {
+	Patterns        types.List   `tfsdk:"patterns"`
+	Match           types.String `tfsdk:"match"`
+	Options         types.Map    `tfsdk:"options"`
+	RepeatedOptions types.Map    `tfsdk:"repeated_options"`
+}
+
+func (r *SSHConfigResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ssh_config"
+}
+
+func (r *SSHConfigResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manages `Host` and `Match` sections and global `Include` lines of an OpenSSH client " +
+			"configuration. The existing file is parsed and only the sections matching the configured patterns or " +
+			"criteria are owned: they are replaced as a whole, edits made to them are detected as drift, and they are " +
+			"removed on destroy. Other sections and comments stay as they are. Option names are validated against " +
+			"ssh_config(5), the file is kept at a non-world-readable mode, and `IdentityFile` paths are checked to exist " +
+			"with permissions ssh accepts.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SSH configuration identifier",
+			},
+			"target_path": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "SSH client configuration file (default: `~/.ssh/config`). It is created when missing",
+				Validators: []validator.String{
+					validators.ValidPath(),
+					validators.EnvironmentVariableExpansion(),
+				},
+			},
+			"file_mode": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Permissions enforced on the file on every apply (default: 0600). Must not be world-readable or writable",
+				Validators: []validator.String{
+					validators.ValidFileMode(),
+					validators.SecureFileMode(),
+				},
+			},
+			"includes": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Paths added as `Include` lines before the first section, so they apply to every host",
+			},
+			"check_identity_files": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Fail the apply when an `IdentityFile` is missing or readable by other users, which ssh " +
+					"rejects (default: true). Paths with ssh tokens other than `%d` are not checked",
+			},
+			"backup_enabled": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Back up the file before changing it. Defaults to the provider's `backup_enabled`",
+			},
+			"content_hash": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "SHA-256 of the owned sections, includes and file mode as found in the file",
+			},
+			"backup_path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Most recent backup of the file taken before it was changed",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"host": schema.ListNestedBlock{
+				MarkdownDescription: "A `Host` or `Match` section. New sections are added before an existing `Host *` " +
+					"section, since ssh uses the first value it finds for each option",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"patterns": schema.ListAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Host patterns of a `Host` section, e.g. `[\"github.com\", \"gh\"]`. Conflicts with `match`",
+						},
+						"match": schema.StringAttribute{
+							Optional:            true,
+							MarkdownDescription: "Criteria of a `Match` section, e.g. `host *.corp exec \"test -f ~/.vpn\"`. Conflicts with `patterns`",
+						},
+						"options": schema.MapAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Options of the section by ssh_config(5) keyword, e.g. `{ HostName = \"github.com\", User = \"git\" }`",
+						},
+						"repeated_options": schema.MapAttribute{
+							Optional:            true,
+							ElementType:         types.ListType{ElemType: types.StringType},
+							MarkdownDescription: "Options given more than once, in order, such as `IdentityFile` or `LocalForward`",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *SSHConfigResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*DotfilesClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			"Expected *DotfilesClient, got something else. Please report this issue to the provider developers.",
+		)
+		return
+	}
+	r.client = client
+}
+
+// ValidateConfig checks the section criteria, that option names are ssh_config keywords
+// given once, and that every value fits on one line.
+func (r *SSHConfigResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data SSHConfigResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var includes []string
+	if !data.Includes.IsUnknown() {
+		resp.Diagnostics.Append(data.Includes.ElementsAs(ctx, &includes, true)...)
+	}
+	for i, include := range includes {
+		if strings.TrimSpace(include) == "" || strings.ContainsAny(include, "\r\n") {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("includes").AtListIndex(i),
+				"Invalid Include",
+				"includes must be non-empty, single-line paths.",
+			)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, host := range data.Hosts {
+		hostPath := path.Root("host").AtListIndex(i)
+		if !host.Patterns.IsUnknown() && !host.Match.IsUnknown() && host.Patterns.IsNull() == host.Match.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				hostPath.AtName("patterns"),
+				"Invalid SSH Section",
+				"Exactly one of patterns or match must be set.",
+			)
+			continue
+		}
+
+		var patterns []string
+		if !host.Patterns.IsNull() && !host.Patterns.IsUnknown() {
+			resp.Diagnostics.Append(host.Patterns.ElementsAs(ctx, &patterns, true)...)
+			if len(patterns) == 0 {
+				resp.Diagnostics.AddAttributeError(hostPath.AtName("patterns"), "Invalid Host Patterns", "patterns must not be empty.")
+			}
+			for _, pattern := range patterns {
+				if pattern == "" || strings.ContainsAny(pattern, " \t\r\n") {
+					resp.Diagnostics.AddAttributeError(
+						hostPath.AtName("patterns"),
+						"Invalid Host Pattern",
+						fmt.Sprintf("Host pattern %q must be non-empty and must not contain whitespace.", pattern),
+					)
+				}
+			}
+		}
+		if match := host.Match.ValueString(); !host.Match.IsNull() && !host.Match.IsUnknown() && (strings.TrimSpace(match) == "" || strings.ContainsAny(match, "\r\n")) {
+			resp.Diagnostics.AddAttributeError(hostPath.AtName("match"), "Invalid Match Criteria", "match must be a non-empty, single-line string.")
+		}
+
+		if block, known, err := sshBlock(ctx, host); known && err == nil {
+			if seen[block.ID()] {
+				resp.Diagnostics.AddAttributeError(
+					hostPath,
+					"Duplicate SSH Section",
+					fmt.Sprintf("%s %s is configured more than once.", block.Keyword, block.Criteria),
+				)
+			}
+			seen[block.ID()] = true
+		}
+
+		options := make(map[string]string)
+		if !host.Options.IsNull() && !host.Options.IsUnknown() {
+			resp.Diagnostics.Append(host.Options.ElementsAs(ctx, &options, true)...)
+		}
+		repeated := make(map[string][]string)
+		if !host.RepeatedOptions.IsNull() && !host.RepeatedOptions.IsUnknown() {
+			resp.Diagnostics.Append(host.RepeatedOptions.ElementsAs(ctx, &repeated, true)...)
+		}
+		names := make(map[string]string)
+		check := func(attribute, name string, values []string) {
+			attrPath := hostPath.AtName(attribute).AtMapKey(name)
+			if !sshconfig.IsOption(name) {
+				resp.Diagnostics.AddAttributeError(
+					attrPath,
+					"Unknown SSH Option",
+					fmt.Sprintf("%q is not an ssh_config option; see ssh_config(5). Host and Match are set with patterns and match.", name),
+				)
+			}
+			if other, ok := names[strings.ToLower(name)]; ok {
+				resp.Diagnostics.AddAttributeError(
+					attrPath,
+					"Duplicate SSH Option",
+					fmt.Sprintf("%s is also set as %s; set each option once, using repeated_options for several values.", name, other),
+				)
+			}
+			names[strings.ToLower(name)] = name
+			for _, value := range values {
+				if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "\r\n") {
+					resp.Diagnostics.AddAttributeError(attrPath, "Invalid SSH Option Value", fmt.Sprintf("Values of %s must be non-empty and single-line.", name))
+				}
+			}
+		}
+		for _, name := range sortedKeys(options) {
+			check("options", name, []string{options[name]})
+		}
+		for _, name := range sortedKeys(repeated) {
+			check("repeated_options", name, repeated[name])
+		}
+	}
+}
+
+// ModifyPlan plans the hash of the owned sections, includes and mode, so sections edited
+// outside Terraform or loosened permissions show up as an update.
+func (r *SSHConfigResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var plan SSHConfigResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ContentHash = types.StringUnknown()
+	if hash, known, err := r.expectedHash(ctx, &plan); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to plan SSH configuration")
+		return
+	} else if known {
+		plan.ContentHash = types.StringValue(hash)
+	}
+
+	// Rewriting the file on drift may take a new backup
+	if !req.State.Raw.IsNull() {
+		var state SSHConfigResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if !plan.ContentHash.Equal(state.ContentHash) {
+			plan.BackupPath = types.StringUnknown()
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *SSHConfigResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data SSHConfigResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.BackupPath = types.StringNull()
+	if err := r.apply(ctx, &data, nil); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write SSH configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SSHConfigResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data SSHConfigResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	hash, err := r.currentHash(ctx, &data)
+	if err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to read SSH configuration")
+		return
+	}
+	if hash != data.ContentHash.ValueString() {
+		tflog.Info(ctx, "Owned SSH sections differ from the applied configuration", map[string]interface{}{
+			"target_path": data.TargetPath.ValueString(),
+		})
+	}
+	data.ContentHash = types.StringValue(hash)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *SSHConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state SSHConfigResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Sections owned in another file are released there first
+	previous := &state
+	if !data.TargetPath.Equal(state.TargetPath) {
+		if err := r.release(ctx, &state); err != nil {
+			errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove sections from the previous file")
+			return
+		}
+		previous = nil
+	}
+
+	data.BackupPath = state.BackupPath
+	if err := r.apply(ctx, &data, previous); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to write SSH configuration")
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete removes the owned sections and includes, leaving the rest of the file in place.
+func (r *SSHConfigResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data SSHConfigResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.release(ctx, &data); err != nil {
+		errors.AddErrorToDiagnostics(ctx, &resp.Diagnostics, err, "Failed to remove SSH sections")
+	}
+}
+
+// apply removes the sections and includes previous owned that are no longer configured,
+// writes the configured ones and enforces the file mode. The file is only rewritten when
+// its content changes.
+func (r *SSHConfigResource) apply(ctx context.Context, data *SSHConfigResourceModel, previous *SSHConfigResourceModel) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	mode, err := sshFileMode(data)
+	if err != nil {
+		return err
+	}
+	blocks, includes, err := sshDesired(ctx, data)
+	if err != nil {
+		return err
+	}
+	if r.checkIdentityFiles(data) {
+		if err := checkIdentityFiles(blocks); err != nil {
+			return err
+		}
+	}
+
+	existing, existed, err := readShared("ssh_config", target)
+	if err != nil {
+		return err
+	}
+	file, err := parseSSHConfig(target, existing)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if err := releaseSSHConfig(ctx, file, previous, blocks, includes); err != nil {
+			return err
+		}
+	}
+	for _, block := range blocks {
+		if err := file.Upsert(block); err != nil {
+			return errors.ValidationError("update_section", "ssh_config", "Invalid SSH section", err).WithPath(target)
+		}
+	}
+	for _, include := range includes {
+		if err := file.AddInclude(include); err != nil {
+			return errors.ValidationError("add_include", "ssh_config", "Invalid include", err).WithPath(target)
+		}
+	}
+
+	data.ID = types.StringValue(target)
+	data.ContentHash = types.StringValue(sshConfigHash(file, blocks, includes, mode))
+
+	if updated := file.String(); updated != existing || !existed {
+		// ssh expects ~/.ssh to be private; an existing directory is left alone
+		if r.client.Config == nil || !r.client.Config.DryRun {
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return errors.IOError("create_directory", "ssh_config", "Failed to create directory", err).WithPath(filepath.Dir(target))
+			}
+		}
+		if err := r.write(ctx, data, target, updated, existed, mode); err != nil {
+			return err
+		}
+		tflog.Info(ctx, "Wrote SSH configuration", map[string]interface{}{
+			"target_path": target,
+			"sections":    len(blocks),
+		})
+	}
+	return r.enforceMode(target, mode)
+}
+
+// release removes the owned sections and includes from the target file.
+func (r *SSHConfigResource) release(ctx context.Context, data *SSHConfigResourceModel) error {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return err
+	}
+	existing, existed, err := readShared("ssh_config", target)
+	if err != nil || !existed {
+		return err
+	}
+	file, err := parseSSHConfig(target, existing)
+	if err != nil {
+		return err
+	}
+	if err := releaseSSHConfig(ctx, file, data, nil, nil); err != nil {
+		return err
+	}
+	if file.String() == existing {
+		return nil
+	}
+	if err := r.write(ctx, data, target, file.String(), true, 0600); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Removed owned SSH sections", map[string]interface{}{
+		"target_path": target,
+	})
+	return nil
+}
+
+// releaseSSHConfig removes the sections and includes owned by data from file, except the
+// ones in keepBlocks and keepIncludes.
+func releaseSSHConfig(ctx context.Context, file *sshconfig.File, data *SSHConfigResourceModel, keepBlocks []sshconfig.Block, keepIncludes []string) error {
+	blocks, includes, err := sshDesired(ctx, data)
+	if err != nil {
+		return err
+	}
+	kept := make(map[string]bool)
+	for _, block := range keepBlocks {
+		kept[block.ID()] = true
+	}
+	for _, include := range keepIncludes {
+		kept["include "+include] = true
+	}
+	for _, block := range blocks {
+		if !kept[block.ID()] {
+			file.Remove(block.ID())
+		}
+	}
+	for _, include := range includes {
+		if !kept["include "+include] {
+			file.RemoveInclude(include)
+		}
+	}
+	return nil
+}
+
+// write writes the updated file, recording the backup taken before the change.
+func (r *SSHConfigResource) write(ctx context.Context, data *SSHConfigResourceModel, target, content string, existed bool, mode os.FileMode) error {
+	backupPath, err := r.client.writeShared(ctx, "ssh_config", target, content, existed, mode, data.BackupEnabled)
+	if err != nil {
+		return err
+	}
+	if backupPath != "" {
+		data.BackupPath = types.StringValue(backupPath)
+	}
+	return nil
+}
+
+// enforceMode sets the file mode, which ssh checks on its configuration.
+func (r *SSHConfigResource) enforceMode(target string, mode os.FileMode) error {
+	dryRun := r.client.Config != nil && r.client.Config.DryRun
+	if _, err := os.Stat(target); err != nil && (dryRun || os.IsNotExist(err)) {
+		return nil
+	}
+	fileManager := fileops.NewFileManager(platform.DetectPlatform(), dryRun)
+	if err := fileManager.ApplyPermissions(target, &fileops.PermissionConfig{FileMode: fmt.Sprintf("%04o", mode)}); err != nil {
+		return errors.PermissionError("chmod", "ssh_config", "Failed to set file permissions", err).WithPath(target)
+	}
+	return nil
+}
+
+// expectedHash returns the content hash of a file holding exactly the configured sections
+// and includes, and whether they are all known.
+func (r *SSHConfigResource) expectedHash(ctx context.Context, data *SSHConfigResourceModel) (string, bool, error) {
+	if !sshConfigKnown(data) {
+		return "", false, nil
+	}
+	mode, err := sshFileMode(data)
+	if err != nil {
+		return "", false, err
+	}
+	blocks, includes, err := sshDesired(ctx, data)
+	if err != nil {
+		return "", false, err
+	}
+	file, _ := sshconfig.Parse("")
+	for _, block := range blocks {
+		if err := file.Upsert(block); err != nil {
+			return "", false, errors.ValidationError("plan_section", "ssh_config", "Invalid SSH section", err)
+		}
+	}
+	for _, include := range includes {
+		if err := file.AddInclude(include); err != nil {
+			return "", false, errors.ValidationError("plan_include", "ssh_config", "Invalid include", err)
+		}
+	}
+	return sshConfigHash(file, blocks, includes, mode), true, nil
+}
+
+// currentHash returns the hash of the owned sections, includes and mode as found on disk.
+func (r *SSHConfigResource) currentHash(ctx context.Context, data *SSHConfigResourceModel) (string, error) {
+	target, err := r.targetPath(data)
+	if err != nil {
+		return "", err
+	}
+	blocks, includes, err := sshDesired(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	existing, existed, err := readShared("ssh_config", target)
+	if err != nil {
+		return "", err
+	}
+	file, err := parseSSHConfig(target, existing)
+	if err != nil {
+		return "", err
+	}
+	var mode os.FileMode
+	if existed {
+		info, err := os.Stat(target)
+		if err != nil {
+			return "", errors.IOError("stat", "ssh_config", "Failed to read file mode", err).WithPath(target)
+		}
+		mode = info.Mode().Perm()
+	}
+	return sshConfigHash(file, blocks, includes, mode), nil
+}
+
+// targetPath returns the expanded path of the SSH configuration.
+func (r *SSHConfigResource) targetPath(data *SSHConfigResourceModel) (string, error) {
+	if r.client == nil {
+		return "", errors.ConfigurationError("resolve_target", "ssh_config", "Provider is not configured", nil)
+	}
+	target := defaultSSHConfigPath
+	if !data.TargetPath.IsNull() && data.TargetPath.ValueString() != "" {
+		target = data.TargetPath.ValueString()
+	}
+	expanded, err := platform.DetectPlatform().ExpandPath(target)
+	if err != nil {
+		return "", errors.ValidationError("expand_target_path", "ssh_config", "Could not expand target path", err).WithPath(target)
+	}
+	return expanded, nil
+}
+
+func (r *SSHConfigResource) checkIdentityFiles(data *SSHConfigResourceModel) bool {
+	return data.CheckIdentityFiles.IsNull() || data.CheckIdentityFiles.ValueBool()
+}
+
+// parseSSHConfig parses the content of target.
+func parseSSHConfig(target, content string) (*sshconfig.File, error) {
+	file, err := sshconfig.Parse(content)
+	if err != nil {
+		return nil, errors.ValidationError("parse", "ssh_config", "The SSH configuration cannot be parsed", err).WithPath(target)
+	}
+	return file, nil
+}
+
+// sshFileMode returns the configured file mode, 0600 by default.
+func sshFileMode(data *SSHConfigResourceModel) (os.FileMode, error) {
+	value := defaultSSHConfigMode
+	if !data.FileMode.IsNull() && data.FileMode.ValueString() != "" {
+		value = data.FileMode.ValueString()
+	}
+	mode, err := utils.ParseFileMode(value)
+	if err != nil {
+		return 0, errors.ValidationError("parse_file_mode", "ssh_config", "Invalid file mode", err)
+	}
+	return mode, nil
+}
+
+// sshDesired returns the configured sections and includes.
+func sshDesired(ctx context.Context, data *SSHConfigResourceModel) ([]sshconfig.Block, []string, error) {
+	blocks := make([]sshconfig.Block, 0, len(data.Hosts))
+	for _, host := range data.Hosts {
+		block, _, err := sshBlock(ctx, host)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	var includes []string
+	if !data.Includes.IsNull() {
+		if diags := data.Includes.ElementsAs(ctx, &includes, false); diags.HasError() {
+			return nil, nil, errors.ValidationError("read_includes", "ssh_config", "Invalid includes", nil)
+		}
+	}
+	return blocks, includes, nil
+}
+
+// sshBlock converts a host block to a section, and reports whether everything it depends
+// on is known. Options are written in name order with their documented spelling, followed
+// by the repeated options.
+func sshBlock(ctx context.Context, host SSHHostModel) (sshconfig.Block, bool, error) {
+	if !sshHostKnown(host) {
+		return sshconfig.Block{}, false, nil
+	}
+	block := sshconfig.Block{Keyword: sshconfig.KeywordMatch, Criteria: host.Match.ValueString()}
+	if !host.Patterns.IsNull() {
+		var patterns []string
+		if diags := host.Patterns.ElementsAs(ctx, &patterns, false); diags.HasError() {
+			return block, false, errors.ValidationError("read_patterns", "ssh_config", "Invalid host patterns", nil)
+		}
+		block = sshconfig.Block{Keyword: sshconfig.KeywordHost, Criteria: strings.Join(patterns, " ")}
+	}
+
+	options := make(map[string]string)
+	repeated := make(map[string][]string)
+	if !host.Options.IsNull() {
+		if diags := host.Options.ElementsAs(ctx, &options, false); diags.HasError() {
+			return block, false, errors.ValidationError("read_options", "ssh_config", "Invalid options", nil)
+		}
+	}
+	if !host.RepeatedOptions.IsNull() {
+		if diags := host.RepeatedOptions.ElementsAs(ctx, &repeated, false); diags.HasError() {
+			return block, false, errors.ValidationError("read_options", "ssh_config", "Invalid repeated options", nil)
+		}
+	}
+	for name, value := range options {
+		block.Options = append(block.Options, sshconfig.Option{Name: sshconfig.CanonicalOption(name), Value: value})
+	}
+	sort.Slice(block.Options, func(i, j int) bool {
+		return strings.ToLower(block.Options[i].Name) < strings.ToLower(block.Options[j].Name)
+	})
+	for _, name := range sortedKeys(repeated) {
+		for _, value := range repeated[name] {
+			block.Options = append(block.Options, sshconfig.Option{Name: sshconfig.CanonicalOption(name), Value: value})
+		}
+	}
+	return block, true, nil
+}
+
+func sshHostKnown(host SSHHostModel) bool {
+	return !host.Patterns.IsUnknown() && !host.Match.IsUnknown() && !host.Options.IsUnknown() && !host.RepeatedOptions.IsUnknown() &&
+		host.Patterns.IsNull() != host.Match.IsNull()
+}
+
+// sshConfigKnown reports whether everything the file content depends on is known.
+func sshConfigKnown(data *SSHConfigResourceModel) bool {
+	if data.Includes.IsUnknown() || data.FileMode.IsUnknown() {
+		return false
+	}
+	for _, host := range data.Hosts {
+		if !sshHostKnown(host) {
+			return false
+		}
+	}
+	return true
+}
+
+// sshConfigHash returns the SHA-256 of the given sections and includes as found in file,
+// and the file mode.
+func sshConfigHash(file *sshconfig.File, blocks []sshconfig.Block, includes []string, mode os.FileMode) string {
+	var content strings.Builder
+	for _, block := range blocks {
+		if found, ok := file.Find(block.ID()); ok {
+			content.WriteString(found.Canonical())
+		} else {
+			content.WriteString("missing " + block.ID())
+		}
+		content.WriteString("\n\n")
+	}
+	present := make(map[string]bool)
+	for _, include := range file.Includes() {
+		present[include] = true
+	}
+	for _, include := range includes {
+		fmt.Fprintf(&content, "include %s %v\n", include, present[include])
+	}
+	fmt.Fprintf(&content, "mode %04o\n", mode)
+
+	sum := sha256.Sum256([]byte(content.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkIdentityFiles checks that the IdentityFile of every section exists and that private
+// keys are not accessible by other users, as ssh refuses such keys. Public keys, used to
+// select agent-backed keys, are normally world-readable and skip the permission check.
+func checkIdentityFiles(blocks []sshconfig.Block) error {
+	for _, block := range blocks {
+		for _, option := range block.Options {
+			if !strings.EqualFold(option.Name, "IdentityFile") {
+				continue
+			}
+			identity := strings.Trim(option.Value, `"`)
+			if strings.EqualFold(identity, "none") || strings.Contains(strings.ReplaceAll(identity, "%d", ""), "%") || strings.Contains(identity, "${") {
+				continue
+			}
+			expanded, err := platform.DetectPlatform().ExpandPath(strings.ReplaceAll(identity, "%d", "~"))
+			if err != nil {
+				return errors.ValidationError("check_identity_file", "ssh_config", "Could not expand IdentityFile", err).WithPath(identity)
+			}
+			info, err := os.Stat(expanded)
+			if err != nil {
+				return errors.ValidationError("check_identity_file", "ssh_config",
+					fmt.Sprintf("IdentityFile of %s %s does not exist", block.Keyword, block.Criteria), err).WithPath(expanded)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 && !isPublicKeyFile(expanded) {
+				return errors.PermissionError("check_identity_file", "ssh_config",
+					fmt.Sprintf("IdentityFile of %s %s has mode %04o; ssh ignores private keys accessible by other users, use 0600",
+						block.Keyword, block.Criteria, info.Mode().Perm()), nil).WithPath(expanded)
+			}
+		}
+	}
+	return nil
+}
+
+// isPublicKeyFile reports whether path holds an SSH public key: it has a .pub suffix, or
+// its content parses as an authorized key.
+func isPublicKeyFile(path string) bool {
+	if strings.HasSuffix(path, ".pub") {
+		return true
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxPublicKeySize))
+	if err != nil {
+		return false
+	}
+	_, _, _, _, err = ssh.ParseAuthorizedKey(content)
+	return err == nil
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,237 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package provider
+
+import (
+	"context"
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHConfigResource(t *testing.T) {
+	t.Run("Metadata", func(t *testing.T) {
+		r := NewSSHConfigResource()
+		resp := &resource.MetadataResponse{}
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "dotfiles"}, resp)
+
+		if resp.TypeName != "dotfiles_ssh_config" {
+			t.Errorf("Expected TypeName dotfiles_ssh_config, got %s", resp.TypeName)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		r := NewSSHConfigResource()
+		resp := &resource.SchemaResponse{}
+		r.Schema(context.Background(), resource.SchemaRequest{}, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Schema validation failed: %v", resp.Diagnostics)
+		}
+		for _, attr := range []string{"id", "content_hash", "backup_path"} {
+			if !resp.Schema.Attributes[attr].IsComputed() {
+				t.Errorf("Attribute %s should be computed", attr)
+			}
+		}
+		if _, ok := resp.Schema.Blocks["host"]; !ok {
+			t.Error("Expected a host block")
+		}
+	})
+}
+
+// sshHost returns a Host section with single-valued options.
+func sshHost(patterns []string, options map[string]string) SSHHostModel {
+	elements := make(map[string]attr.Value, len(options))
+	for name, value := range options {
+		elements[name] = types.StringValue(value)
+	}
+	return SSHHostModel{
+		Patterns:        stringList(patterns),
+		Match:           types.StringNull(),
+		Options:         types.MapValueMust(types.StringType, elements),
+		RepeatedOptions: types.MapNull(types.ListType{ElemType: types.StringType}),
+	}
+}
+
+func TestSSHConfigResourceLifecycle(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, ".ssh", "config")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	original := "Host old-box\n  User legacy\n\nHost *\n  ServerAliveInterval 60\n"
+	if err := os.WriteFile(target, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write target: %v", err)
+	}
+	key := filepath.Join(tempDir, ".ssh", "github")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	r := &SSHConfigResource{client: &DotfilesClient{Config: &DotfilesConfig{
+		BackupEnabled:   true,
+		BackupDirectory: filepath.Join(tempDir, "backups"),
+	}}}
+	ctx := context.Background()
+	deploy := SSHHostModel{
+		Patterns: types.ListNull(types.StringType),
+		Match:    types.StringValue("user deploy"),
+		Options:  types.MapNull(types.StringType),
+		RepeatedOptions: types.MapValueMust(types.ListType{ElemType: types.StringType}, map[string]attr.Value{
+			"LocalForward": stringList([]string{"5432 localhost:5432", "6379 localhost:6379"}),
+		}),
+	}
+	data := &SSHConfigResourceModel{
+		TargetPath: types.StringValue(target),
+		Includes:   stringList([]string{"config.d/*"}),
+		Hosts: []SSHHostModel{
+			sshHost([]string{"github.com", "gh"}, map[string]string{"hostname": "github.com", "User": "git", "IdentityFile": key}),
+			deploy,
+		},
+	}
+
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	expected := "Include config.d/*\n\nHost old-box\n  User legacy\n\n" +
+		"Host github.com gh\n  HostName github.com\n  IdentityFile " + key + "\n  User git\n\n" +
+		"Match user deploy\n  LocalForward 5432 localhost:5432\n  LocalForward 6379 localhost:6379\n\n" +
+		"Host *\n  ServerAliveInterval 60\n"
+	if content, _ := os.ReadFile(target); string(content) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be enforced, got %v", info.Mode().Perm())
+	}
+	if data.BackupPath.IsNull() {
+		t.Error("Expected the file to be backed up before it was changed")
+	}
+	planned, known, err := r.expectedHash(ctx, data)
+	if err != nil || !known || planned != data.ContentHash.ValueString() {
+		t.Errorf("Expected the planned hash to match the applied one (%v, %v)", known, err)
+	}
+	if hash, _ := r.currentHash(ctx, data); hash != data.ContentHash.ValueString() {
+		t.Error("Expected the applied file to be in sync")
+	}
+
+	// Edits to an owned section and loosened permissions are drift, other sections are not
+	edited := strings.Replace(expected, "User legacy", "User someone", 1)
+	if err := os.WriteFile(target, []byte(edited), 0600); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	if hash, _ := r.currentHash(ctx, data); hash != data.ContentHash.ValueString() {
+		t.Error("Expected an edit to an unowned section not to be drift")
+	}
+	if err := os.WriteFile(target, []byte(strings.Replace(edited, "User git", "User me", 1)), 0600); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	if hash, _ := r.currentHash(ctx, data); hash == data.ContentHash.ValueString() {
+		t.Error("Expected an edit to an owned section to be drift")
+	}
+	if err := os.WriteFile(target, []byte(edited), 0600); err != nil {
+		t.Fatalf("Failed to edit target: %v", err)
+	}
+	if err := os.Chmod(target, 0644); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	if hash, _ := r.currentHash(ctx, data); hash == data.ContentHash.ValueString() {
+		t.Error("Expected a world-readable file to be drift")
+	}
+
+	// Dropping a section removes it
+	state := *data
+	data.Hosts = data.Hosts[:1]
+	data.Includes = types.ListNull(types.StringType)
+	if err := r.apply(ctx, data, &state); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	expected = "Host old-box\n  User someone\n\n" +
+		"Host github.com gh\n  HostName github.com\n  IdentityFile " + key + "\n  User git\n\n" +
+		"Host *\n  ServerAliveInterval 60\n"
+	if content, _ := os.ReadFile(target); string(content) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, content)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be restored, got %v", info.Mode().Perm())
+	}
+
+	if err := r.release(ctx, data); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "Host old-box\n  User someone\n\nHost *\n  ServerAliveInterval 60\n" {
+		t.Errorf("Expected only the owned sections to be removed, got:\n%s", content)
+	}
+}
+
+func TestCheckIdentityFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	private := filepath.Join(tempDir, "id_private")
+	if err := os.WriteFile(private, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	open := filepath.Join(tempDir, "id_open")
+	if err := os.WriteFile(open, []byte("key"), 0644); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	public := filepath.Join(tempDir, "id_open.pub")
+	if err := os.WriteFile(public, []byte("key"), 0644); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	authorized := filepath.Join(tempDir, "id_authorized")
+	if err := os.WriteFile(authorized, ssh.MarshalAuthorizedKey(sshKey), 0644); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	r := &SSHConfigResource{client: &DotfilesClient{Config: &DotfilesConfig{}}}
+	ctx := context.Background()
+	for identity, valid := range map[string]bool{
+		private:                         true,
+		open:                            false,
+		public:                          true,
+		authorized:                      true,
+		filepath.Join(tempDir, "nokey"): false,
+		"~/.ssh/%h_key":                 true,
+		"none":                          true,
+	} {
+		data := &SSHConfigResourceModel{Hosts: []SSHHostModel{sshHost([]string{"example"}, map[string]string{"IdentityFile": identity})}}
+		blocks, _, err := sshDesired(ctx, data)
+		if err != nil {
+			t.Fatalf("sshDesired failed: %v", err)
+		}
+		if err := checkIdentityFiles(blocks); (err == nil) != valid {
+			t.Errorf("checkIdentityFiles(%s) = %v, expected valid %v", identity, err, valid)
+		}
+	}
+
+	target := filepath.Join(tempDir, "ssh", "config")
+	data := &SSHConfigResourceModel{
+		TargetPath: types.StringValue(target),
+		Hosts:      []SSHHostModel{sshHost([]string{"example"}, map[string]string{"IdentityFile": open})},
+	}
+	if err := r.apply(ctx, data, nil); err == nil {
+		t.Error("Expected apply to fail for a world-readable identity file")
+	}
+	data.CheckIdentityFiles = types.BoolValue(false)
+	if err := r.apply(ctx, data, nil); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Dir(target)); info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a new ssh directory to be private, got %v", info.Mode().Perm())
+	}
+}
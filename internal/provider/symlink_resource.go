@@ -21,6 +21,8 @@ import (
 )
 
 var _ resource.Resource = &SymlinkResource{}
+var _ resource.ResourceWithMoveState = &SymlinkResource{}
+var _ resource.ResourceWithImportState = &SymlinkResource{}
 
 func NewSymlinkResource() resource.Resource {
 	return &SymlinkResource{}
@@ -38,6 +40,7 @@ type SymlinkResourceModel struct {
 	TargetPath    types.String `tfsdk:"target_path"`
 	ForceUpdate   types.Bool   `tfsdk:"force_update"`
 	CreateParents types.Bool   `tfsdk:"create_parents"`
+	Relative      types.Bool   `tfsdk:"relative"`
 
 	// Enhanced fields
 	Permissions     *PermissionsModel `tfsdk:"permissions"`
@@ -85,6 +88,10 @@ func (r *SymlinkResource) Schema(ctx context.Context, req resource.SchemaRequest
 			Optional:            true,
 			MarkdownDescription: "Create parent directories",
 		},
+		"relative": schema.BoolAttribute{
+			Optional:            true,
+			MarkdownDescription: "Write the link target relative to the link's parent directory instead of as an absolute path",
+		},
 		"permission_rules": GetPermissionRulesAttribute(),
 		"link_exists": schema.BoolAttribute{
 			Computed:            true,
@@ -116,6 +123,81 @@ func (r *SymlinkResource) Schema(ctx context.Context, req resource.SchemaRequest
 	}
 }
 
+// MoveState accepts state from dotfiles_file resources that used strategy = "symlink",
+// so the moved blocks written by migrate-config keep the existing link under management.
+func (r *SymlinkResource) MoveState(ctx context.Context) []resource.StateMover {
+	return []resource.StateMover{
+		{
+			SourceSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":               schema.StringAttribute{Computed: true},
+					"repository":       schema.StringAttribute{Required: true},
+					"name":             schema.StringAttribute{Required: true},
+					"source_path":      schema.StringAttribute{Required: true},
+					"target_path":      schema.StringAttribute{Required: true},
+					"permission_rules": GetPermissionRulesAttribute(),
+				},
+				Blocks: map[string]schema.Block{
+					"permissions": GetPermissionsSchemaBlock(),
+				},
+			},
+			StateMover: r.moveFileState,
+		},
+	}
+}
+
+// fileStateModel holds the dotfiles_file attributes carried over to dotfiles_symlink.
+type fileStateModel struct {
+	ID              types.String      `tfsdk:"id"`
+	Repository      types.String      `tfsdk:"repository"`
+	Name            types.String      `tfsdk:"name"`
+	SourcePath      types.String      `tfsdk:"source_path"`
+	TargetPath      types.String      `tfsdk:"target_path"`
+	Permissions     *PermissionsModel `tfsdk:"permissions"`
+	PermissionRules types.Map         `tfsdk:"permission_rules"`
+}
+
+func (r *SymlinkResource) moveFileState(ctx context.Context, req resource.MoveStateRequest, resp *resource.MoveStateResponse) {
+	if req.SourceTypeName != "dotfiles_file" || req.SourceState == nil {
+		return
+	}
+
+	var source fileStateModel
+	resp.Diagnostics.Append(req.SourceState.Get(ctx, &source)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Debug(ctx, "Moving dotfiles_file state to dotfiles_symlink", map[string]interface{}{
+		"id":          source.ID.ValueString(),
+		"target_path": source.TargetPath.ValueString(),
+	})
+
+	// Computed attributes are refreshed by the next Read
+	data := SymlinkResourceModel{
+		ID:              source.ID,
+		Repository:      source.Repository,
+		Name:            source.Name,
+		SourcePath:      source.SourcePath,
+		TargetPath:      source.TargetPath,
+		ForceUpdate:     types.BoolNull(),
+		CreateParents:   types.BoolNull(),
+		Relative:        types.BoolNull(),
+		Permissions:     source.Permissions,
+		PermissionRules: source.PermissionRules,
+		LinkExists:      types.BoolNull(),
+		IsSymlink:       types.BoolNull(),
+		LinkTarget:      types.StringNull(),
+		LastModified:    types.StringNull(),
+	}
+	resp.Diagnostics.Append(resp.TargetState.Set(ctx, &data)...)
+}
+
+// ImportState imports an existing link by its target path.
+func (r *SymlinkResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	importTargetPath(ctx, req, resp)
+}
+
 func (r *SymlinkResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
@@ -157,7 +239,14 @@ func (r *SymlinkResource) Create(ctx context.Context, req resource.CreateRequest
 
 	// Get repository local path
 	repositoryID := data.Repository.ValueString()
-	repositoryLocalPath := r.getRepositoryLocalPath(repositoryID)
+	repositoryLocalPath, err := r.getRepositoryLocalPath(repositoryID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Repository not found",
+			fmt.Sprintf("Could not resolve repository for symlink %s: %s", data.Name.ValueString(), err.Error()),
+		)
+		return
+	}
 	tflog.Debug(ctx, "Retrieved repository local path", map[string]interface{}{
 		"repository_id":   repositoryID,
 		"repository_path": repositoryLocalPath,
@@ -319,10 +408,14 @@ func (r *SymlinkResource) Create(ctx context.Context, req resource.CreateRequest
 		"source_path":    expandedSourcePath,
 		"target_path":    expandedTargetPath,
 		"create_parents": data.CreateParents.ValueBool(),
+		"relative":       data.Relative.ValueBool(),
 	})
 
 	var finalErr error
-	if data.CreateParents.ValueBool() {
+	if data.Relative.ValueBool() {
+		tflog.Debug(ctx, "Using CreateRelativeSymlink")
+		finalErr = fileManager.CreateRelativeSymlink(expandedSourcePath, expandedTargetPath, data.CreateParents.ValueBool())
+	} else if data.CreateParents.ValueBool() {
 		tflog.Debug(ctx, "Using CreateSymlinkWithParents")
 		finalErr = fileManager.CreateSymlinkWithParents(expandedSourcePath, expandedTargetPath)
 	} else {
@@ -429,20 +522,23 @@ func (r *SymlinkResource) Read(ctx context.Context, req resource.ReadRequest, re
 				// Symlink is corrupted - remove from state to trigger recreation
 				tflog.Info(ctx, "Removing corrupted symlink from state to trigger recreation")
 				return
-			} else {
+			} else if !data.SourcePath.IsNull() {
+				// Imported links have no source yet; the next apply reconciles them
 				// Expand the source path to compare
-				repositoryLocalPath := r.getRepositoryLocalPath(data.Repository.ValueString())
+				repositoryLocalPath, repoErr := r.getRepositoryLocalPath(data.Repository.ValueString())
 				sourcePath := filepath.Join(repositoryLocalPath, data.SourcePath.ValueString())
 				expandedSourcePath, err := platformProvider.ExpandPath(sourcePath)
-				if err == nil {
-					// Make paths absolute for comparison
-					expectedTarget, _ := filepath.Abs(expandedSourcePath)
-					actualTargetAbs, _ := filepath.Abs(actualTarget)
-
-					if expectedTarget != actualTargetAbs {
+				if repoErr != nil {
+					tflog.Warn(ctx, "Could not resolve repository to verify symlink target", map[string]interface{}{
+						"repository": data.Repository.ValueString(),
+						"error":      repoErr.Error(),
+					})
+				} else if err == nil {
+					// Compare semantically so absolute and relative links to the same source are equal
+					if !fileops.SymlinkPointsTo(expandedTargetPath, actualTarget, expandedSourcePath) {
 						tflog.Info(ctx, "Symlink points to wrong target - removing from state", map[string]interface{}{
-							"expected": expectedTarget,
-							"actual":   actualTargetAbs,
+							"expected": expandedSourcePath,
+							"actual":   actualTarget,
 						})
 						// Wrong target - remove from state to trigger recreation
 						return
@@ -493,6 +589,24 @@ func (r *SymlinkResource) Update(ctx context.Context, req resource.UpdateRequest
 		return
 	}
 
+	// Recreate the link if its target no longer matches the configured source or form
+	expandedTargetPath, err := r.reconcileSymlink(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Symlink update failed",
+			fmt.Sprintf("Could not update symlink %s: %s", data.Name.ValueString(), err.Error()),
+		)
+		return
+	}
+
+	if err := r.updateComputedAttributes(ctx, &data, expandedTargetPath); err != nil {
+		resp.Diagnostics.AddWarning(
+			"Could not update symlink metadata",
+			fmt.Sprintf("Symlink updated but could not update metadata: %s", err.Error()),
+		)
+		data.LinkTarget = types.StringNull()
+	}
+
 	// Set ID and save state
 	data.ID = data.Name
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -556,12 +670,60 @@ func (r *SymlinkResource) Delete(ctx context.Context, req resource.DeleteRequest
 	}
 }
 
-// getRepositoryLocalPath returns the local path for a repository.
-func (r *SymlinkResource) getRepositoryLocalPath(repositoryID string) string {
-	// For now, assume repository ID maps to the dotfiles root
-	// NOTE: Repository lookup feature planned for v0.2.0 - will enable proper multi-repository support
-	_ = repositoryID // Placeholder for future repository lookup implementation
-	return r.client.Config.DotfilesRoot
+// reconcileSymlink recreates an existing symlink whose target string differs from the desired one,
+// e.g. after switching between absolute and relative mode. It returns the expanded target path.
+func (r *SymlinkResource) reconcileSymlink(ctx context.Context, data *SymlinkResourceModel) (string, error) {
+	platformProvider := platform.DetectPlatform()
+	expandedTargetPath, err := platformProvider.ExpandPath(data.TargetPath.ValueString())
+	if err != nil {
+		return "", fmt.Errorf("could not expand target path: %w", err)
+	}
+	repositoryLocalPath, err := r.getRepositoryLocalPath(data.Repository.ValueString())
+	if err != nil {
+		return "", err
+	}
+	sourcePath := filepath.Join(repositoryLocalPath, data.SourcePath.ValueString())
+	expandedSourcePath, err := platformProvider.ExpandPath(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("could not expand source path: %w", err)
+	}
+
+	if !utils.IsSymlink(expandedTargetPath) {
+		return expandedTargetPath, nil
+	}
+	actualTarget, err := os.Readlink(expandedTargetPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read symlink: %w", err)
+	}
+
+	desiredTarget := expandedSourcePath
+	if data.Relative.ValueBool() {
+		desiredTarget, err = fileops.RelativeLinkTarget(expandedSourcePath, expandedTargetPath)
+		if err != nil {
+			return "", err
+		}
+	}
+	if actualTarget == desiredTarget {
+		return expandedTargetPath, nil
+	}
+
+	tflog.Debug(ctx, "Recreating symlink", map[string]interface{}{
+		"target_path": expandedTargetPath,
+		"actual":      actualTarget,
+		"desired":     desiredTarget,
+	})
+
+	fileManager := fileops.NewFileManager(platformProvider, r.client.Config.DryRun)
+	if data.Relative.ValueBool() {
+		return expandedTargetPath, fileManager.CreateRelativeSymlink(expandedSourcePath, expandedTargetPath, data.CreateParents.ValueBool())
+	}
+	return expandedTargetPath, fileManager.CreateSymlinkWithParents(expandedSourcePath, expandedTargetPath)
+}
+
+// getRepositoryLocalPath returns the root source_path is resolved against: the root path
+// of the repository, which honours its subdirectory.
+func (r *SymlinkResource) getRepositoryLocalPath(repositoryID string) (string, error) {
+	return r.client.RepositoryRoot(repositoryID)
 }
 
 // updateComputedAttributes updates computed attributes for state tracking.
@@ -5,10 +5,15 @@ package provider
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 func TestSymlinkResource(t *testing.T) {
@@ -167,3 +172,141 @@ func TestSymlinkResourceModel(t *testing.T) {
 		t.Error("CreateParents field not working correctly")
 	}
 }
+
+func TestSymlinkResourceReconcileRelative(t *testing.T) {
+	tempDir := t.TempDir()
+	dotfilesRoot := filepath.Join(tempDir, "dotfiles")
+	source := filepath.Join(dotfilesRoot, "tmux.conf")
+	if err := os.MkdirAll(dotfilesRoot, 0755); err != nil {
+		t.Fatalf("Failed to create dotfiles root: %v", err)
+	}
+	if err := os.WriteFile(source, []byte("set -g mouse on\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	link := filepath.Join(tempDir, "home", ".tmux.conf")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatalf("Failed to create home directory: %v", err)
+	}
+	if err := os.Symlink(source, link); err != nil {
+		t.Fatalf("Failed to create absolute symlink: %v", err)
+	}
+
+	r := &SymlinkResource{client: withRepository(&DotfilesClient{Config: &DotfilesConfig{DotfilesRoot: dotfilesRoot}}, "dotfiles", dotfilesRoot)}
+	data := &SymlinkResourceModel{
+		Repository: types.StringValue("dotfiles"),
+		SourcePath: types.StringValue("tmux.conf"),
+		TargetPath: types.StringValue(link),
+		Relative:   types.BoolValue(true),
+	}
+
+	if _, err := r.reconcileSymlink(context.Background(), data); err != nil {
+		t.Fatalf("reconcileSymlink failed: %v", err)
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("Failed to read link: %v", err)
+	}
+	if filepath.IsAbs(target) {
+		t.Errorf("Expected relative link after switching modes, got %s", target)
+	}
+
+	data.Relative = types.BoolValue(false)
+	if _, err := r.reconcileSymlink(context.Background(), data); err != nil {
+		t.Fatalf("reconcileSymlink failed: %v", err)
+	}
+	target, err = os.Readlink(link)
+	if err != nil {
+		t.Fatalf("Failed to read link: %v", err)
+	}
+	if target != source {
+		t.Errorf("Expected absolute link %s, got %s", source, target)
+	}
+}
+
+func TestSymlinkResourceMoveState(t *testing.T) {
+	ctx := context.Background()
+	r := &SymlinkResource{}
+	movers := r.MoveState(ctx)
+	if len(movers) != 1 || movers[0].SourceSchema == nil {
+		t.Fatalf("Expected one state mover with a source schema, got %d", len(movers))
+	}
+	sourceSchema := *movers[0].SourceSchema
+
+	targetSchema := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, targetSchema)
+	newState := func(s schema.Schema) *tfsdk.State {
+		return &tfsdk.State{Schema: s, Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil)}
+	}
+
+	sourceState := newState(sourceSchema)
+	diags := sourceState.Set(ctx, &fileStateModel{
+		ID:              types.StringValue("gitconfig"),
+		Repository:      types.StringValue("main"),
+		Name:            types.StringValue("gitconfig"),
+		SourcePath:      types.StringValue("gitconfig"),
+		TargetPath:      types.StringValue("~/.gitconfig"),
+		PermissionRules: types.MapNull(types.StringType),
+	})
+	if diags.HasError() {
+		t.Fatalf("Failed to build source state: %v", diags)
+	}
+
+	t.Run("skips other resource types", func(t *testing.T) {
+		resp := &resource.MoveStateResponse{TargetState: *newState(targetSchema.Schema)}
+		movers[0].StateMover(ctx, resource.MoveStateRequest{SourceTypeName: "dotfiles_directory", SourceState: sourceState}, resp)
+		if resp.Diagnostics.HasError() || !resp.TargetState.Raw.IsNull() {
+			t.Error("Expected the mover to skip a dotfiles_directory source")
+		}
+	})
+
+	t.Run("moves dotfiles_file", func(t *testing.T) {
+		resp := &resource.MoveStateResponse{TargetState: *newState(targetSchema.Schema)}
+		movers[0].StateMover(ctx, resource.MoveStateRequest{SourceTypeName: "dotfiles_file", SourceState: sourceState}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("MoveState failed: %v", resp.Diagnostics)
+		}
+
+		var data SymlinkResourceModel
+		if diags := resp.TargetState.Get(ctx, &data); diags.HasError() {
+			t.Fatalf("Failed to read moved state: %v", diags)
+		}
+		if data.ID.ValueString() != "gitconfig" || data.TargetPath.ValueString() != "~/.gitconfig" || data.Repository.ValueString() != "main" {
+			t.Errorf("Unexpected moved state %+v", data)
+		}
+		if !data.LinkExists.IsNull() || !data.LastModified.IsNull() {
+			t.Error("Computed attributes should be left for the next refresh")
+		}
+	})
+}
+
+func TestSymlinkResourceImportState(t *testing.T) {
+	ctx := context.Background()
+	r := &SymlinkResource{}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	newState := func() tfsdk.State {
+		return tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	}
+
+	resp := &resource.ImportStateResponse{State: newState()}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: "~/.vimrc"}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("ImportState failed: %v", resp.Diagnostics)
+	}
+	var data SymlinkResourceModel
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatalf("Failed to read imported state: %v", diags)
+	}
+	if data.ID.ValueString() != "~/.vimrc" || data.TargetPath.ValueString() != "~/.vimrc" {
+		t.Errorf("Expected id and target_path from the import ID, got %+v", data)
+	}
+	if !data.SourcePath.IsNull() {
+		t.Error("source_path should be left for the next apply")
+	}
+
+	resp = &resource.ImportStateResponse{State: newState()}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: ""}, resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("Expected an error for an empty import ID")
+	}
+}
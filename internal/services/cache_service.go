@@ -361,3 +361,59 @@ func (cm *ConcurrencyManager) OperationWrapper(ctx context.Context, operation fu
 
 	return operation()
 }
+
+// RunParallel runs tasks as a bounded worker pool, holding one slot per running task.
+// It returns the first task error and stops launching new tasks once one has failed.
+// A nil manager runs the tasks sequentially. Tasks must not acquire the same manager,
+// otherwise a full pool can deadlock.
+func (cm *ConcurrencyManager) RunParallel(ctx context.Context, tasks []func() error) error {
+	if cm == nil {
+		for _, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := task(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			fail(err)
+			break
+		}
+		if err := cm.Acquire(ctx); err != nil {
+			fail(err)
+			break
+		}
+
+		wg.Add(1)
+		go func(task func() error) {
+			defer wg.Done()
+			defer cm.Release()
+			if err := task(); err != nil {
+				fail(err)
+			}
+		}(task)
+	}
+
+	wg.Wait()
+	return firstErr
+}
@@ -517,3 +517,114 @@ func TestConcurrencyManagerOperationWrapperError(t *testing.T) {
 		t.Errorf("Expected 0 active operations after wrapper error, got %d", cm.ActiveOperations())
 	}
 }
+
+func TestConcurrencyManagerRunParallel(t *testing.T) {
+	cm := NewConcurrencyManager(3)
+	ctx := context.Background()
+
+	var (
+		mu        sync.Mutex
+		running   int
+		maxSeen   int
+		completed int
+	)
+
+	tasks := make([]func() error, 0, 20)
+	for i := 0; i < 20; i++ {
+		tasks = append(tasks, func() error {
+			mu.Lock()
+			running++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond) // Simulate work
+
+			mu.Lock()
+			running--
+			completed++
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	if err := cm.RunParallel(ctx, tasks); err != nil {
+		t.Fatalf("RunParallel failed: %v", err)
+	}
+
+	if completed != len(tasks) {
+		t.Errorf("Expected %d completed tasks, got %d", len(tasks), completed)
+	}
+	if maxSeen > cm.MaxConcurrency() {
+		t.Errorf("Expected at most %d concurrent tasks, saw %d", cm.MaxConcurrency(), maxSeen)
+	}
+	if cm.ActiveOperations() != 0 {
+		t.Errorf("Expected 0 active operations after RunParallel, got %d", cm.ActiveOperations())
+	}
+}
+
+func TestConcurrencyManagerRunParallelError(t *testing.T) {
+	cm := NewConcurrencyManager(2)
+	ctx := context.Background()
+
+	expectedError := fmt.Errorf("task failed")
+	tasks := []func() error{
+		func() error { return nil },
+		func() error { return expectedError },
+		func() error { return nil },
+	}
+
+	err := cm.RunParallel(ctx, tasks)
+	if err != expectedError {
+		t.Errorf("Expected error %v, got %v", expectedError, err)
+	}
+
+	if cm.ActiveOperations() != 0 {
+		t.Errorf("Expected 0 active operations after RunParallel error, got %d", cm.ActiveOperations())
+	}
+}
+
+func TestConcurrencyManagerRunParallelNilManager(t *testing.T) {
+	var cm *ConcurrencyManager
+	ctx := context.Background()
+
+	order := []int{}
+	tasks := []func() error{
+		func() error { order = append(order, 1); return nil },
+		func() error { order = append(order, 2); return nil },
+	}
+
+	if err := cm.RunParallel(ctx, tasks); err != nil {
+		t.Fatalf("RunParallel with nil manager failed: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Errorf("Expected tasks to run sequentially in order, got %v", order)
+	}
+}
+
+func BenchmarkConcurrencyManagerRunParallel(b *testing.B) {
+	ctx := context.Background()
+	tasks := make([]func() error, 0, 64)
+	for i := 0; i < 64; i++ {
+		tasks = append(tasks, func() error {
+			time.Sleep(100 * time.Microsecond) // Simulate I/O-bound work
+			return nil
+		})
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		var cm *ConcurrencyManager
+		for i := 0; i < b.N; i++ {
+			_ = cm.RunParallel(ctx, tasks)
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		cm := NewConcurrencyManager(DefaultMaxConcurrency)
+		for i := 0; i < b.N; i++ {
+			_ = cm.RunParallel(ctx, tasks)
+		}
+	})
+}
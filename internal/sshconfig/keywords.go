@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package sshconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keywords are the ssh_config(5) options of current OpenSSH releases, plus options of
+// widespread vendor builds (UseKeychain on macOS, the GSSAPI key exchange patches) and
+// deprecated aliases OpenSSH still accepts.
+var keywords = []string{
+	"AddKeysToAgent", "AddressFamily", "BatchMode", "BindAddress", "BindInterface",
+	"CanonicalDomains", "CanonicalizeFallbackLocal", "CanonicalizeHostname", "CanonicalizeMaxDots",
+	"CanonicalizePermittedCNAMEs", "CASignatureAlgorithms", "CertificateFile", "ChallengeResponseAuthentication",
+	"ChannelTimeout", "CheckHostIP", "Ciphers", "ClearAllForwardings", "Compression", "ConnectionAttempts",
+	"ConnectTimeout", "ControlMaster", "ControlPath", "ControlPersist", "DynamicForward",
+	"EnableEscapeCommandline", "EnableSSHKeysign", "EscapeChar", "ExitOnForwardFailure", "FingerprintHash",
+	"ForkAfterAuthentication", "ForwardAgent", "ForwardX11", "ForwardX11Timeout", "ForwardX11Trusted",
+	"GatewayPorts", "GlobalKnownHostsFile", "GSSAPIAuthentication", "GSSAPIClientIdentity",
+	"GSSAPIDelegateCredentials", "GSSAPIKeyExchange", "GSSAPIRenewalForcesRekey", "GSSAPIServerIdentity",
+	"GSSAPITrustDns", "HashKnownHosts", "HostbasedAcceptedAlgorithms", "HostbasedAuthentication",
+	"HostbasedKeyTypes", "HostKeyAlgorithms", "HostKeyAlias", "HostName", "IdentitiesOnly", "IdentityAgent",
+	"IdentityFile", "IgnoreUnknown", "Include", "IPQoS", "KbdInteractiveAuthentication", "KbdInteractiveDevices",
+	"KexAlgorithms", "KnownHostsCommand", "LocalCommand", "LocalForward", "LogLevel", "LogVerbose", "MACs",
+	"NoHostAuthenticationForLocalhost", "NumberOfPasswordPrompts", "ObscureKeystrokeTiming",
+	"PasswordAuthentication", "PermitLocalCommand", "PermitRemoteOpen", "PKCS11Provider", "Port",
+	"PreferredAuthentications", "ProxyCommand", "ProxyJump", "ProxyUseFdpass", "PubkeyAcceptedAlgorithms",
+	"PubkeyAcceptedKeyTypes", "PubkeyAuthentication", "RekeyLimit", "RemoteCommand", "RemoteForward",
+	"RequestTTY", "RequiredRSASize", "RevokedHostKeys", "SecurityKeyProvider", "SendEnv",
+	"ServerAliveCountMax", "ServerAliveInterval", "SessionType", "SetEnv", "StdinNull", "StreamLocalBindMask",
+	"StreamLocalBindUnlink", "StrictHostKeyChecking", "SyslogFacility", "Tag", "TCPKeepAlive", "Tunnel",
+	"TunnelDevice", "UpdateHostKeys", "UseKeychain", "User", "UserKnownHostsFile", "VerifyHostKeyDNS",
+	"VisualHostKey", "XAuthLocation",
+}
+
+var keywordIndex = func() map[string]string {
+	index := make(map[string]string, len(keywords))
+	for _, keyword := range keywords {
+		index[strings.ToLower(keyword)] = keyword
+	}
+	return index
+}()
+
+// IsOption reports whether name is an ssh_config option. Names are case-insensitive; Host
+// and Match open sections and are not options.
+func IsOption(name string) bool {
+	_, ok := keywordIndex[strings.ToLower(name)]
+	return ok
+}
+
+// CanonicalOption returns the documented spelling of an option name, or name when it is
+// not an option.
+func CanonicalOption(name string) string {
+	if keyword, ok := keywordIndex[strings.ToLower(name)]; ok {
+		return keyword
+	}
+	return name
+}
+
+// Validate parses data and checks that every option is known, since ssh refuses a file with
+// an unknown option. Files that use IgnoreUnknown are only checked for structure, as the
+// patterns it lists may name any option.
+func Validate(data string) error {
+	if _, err := Parse(data); err != nil {
+		return err
+	}
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for _, text := range lines {
+		if name, _, ok := parseLine(text); ok && strings.EqualFold(name, "IgnoreUnknown") {
+			return nil
+		}
+	}
+	for i, text := range lines {
+		name, _, ok := parseLine(text)
+		if !ok || strings.EqualFold(name, KeywordHost) || strings.EqualFold(name, KeywordMatch) {
+			continue
+		}
+		if !IsOption(name) {
+			return fmt.Errorf("line %d: unknown option %s", i+1, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+// Package sshconfig edits OpenSSH client configuration files section by section, keeping
+// comments and the sections it is not asked to change as they are.
+package sshconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Section keywords.
+const (
+	KeywordHost  = "Host"
+	KeywordMatch = "Match"
+)
+
+// Option is a keyword and its arguments, as written on one line.
+type Option struct {
+	Name  string
+	Value string
+}
+
+// Block is a Host or Match section. Criteria holds the host patterns or match criteria
+// separated by single spaces.
+type Block struct {
+	Keyword  string
+	Criteria string
+	Options  []Option
+}
+
+// ID identifies the section by its keyword and criteria, e.g. `host github.com gh`.
+func (b Block) ID() string {
+	return strings.ToLower(b.Keyword) + " " + normalizeCriteria(b.Criteria)
+}
+
+// Canonical returns a representation of the block that ignores layout, keyword case and
+// the order of different options. Repeated options keep their order, which ssh tries them
+// in.
+func (b Block) Canonical() string {
+	options := make([]Option, len(b.Options))
+	for i, option := range b.Options {
+		options[i] = Option{Name: strings.ToLower(option.Name), Value: option.Value}
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Name < options[j].Name })
+
+	var canonical strings.Builder
+	canonical.WriteString(b.ID())
+	for _, option := range options {
+		canonical.WriteString("\n" + option.Name + " " + option.Value)
+	}
+	return canonical.String()
+}
+
+// section is a Host or Match section as it appears in the file: the comment lines directly
+// above its header, the header and the lines up to the next section.
+type section struct {
+	block  Block
+	lead   []string
+	header string
+	body   []string
+}
+
+func (s *section) lines() []string {
+	lines := append(append([]string{}, s.lead...), s.header)
+	return append(lines, s.body...)
+}
+
+// File is a parsed ssh_config file.
+type File struct {
+	preamble []string
+	sections []*section
+	newline  string
+}
+
+// Parse parses an ssh_config file.
+func Parse(data string) (*File, error) {
+	f := &File{newline: "\n"}
+	if strings.Contains(data, "\r\n") {
+		f.newline = "\r\n"
+		data = strings.ReplaceAll(data, "\r\n", "\n")
+	}
+	if data == "" {
+		return f, nil
+	}
+
+	var current *section
+	for i, text := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		name, value, ok := parseLine(text)
+		if !ok {
+			if current == nil {
+				f.preamble = append(f.preamble, text)
+			} else {
+				current.body = append(current.body, text)
+			}
+			continue
+		}
+		if !strings.EqualFold(name, KeywordHost) && !strings.EqualFold(name, KeywordMatch) {
+			if current == nil {
+				f.preamble = append(f.preamble, text)
+			} else {
+				current.body = append(current.body, text)
+				current.block.Options = append(current.block.Options, Option{Name: name, Value: value})
+			}
+			continue
+		}
+		if value == "" {
+			return nil, fmt.Errorf("line %d: %s without arguments", i+1, name)
+		}
+
+		// Comments directly above a header describe the section it opens
+		next := &section{header: text, block: Block{Keyword: KeywordHost, Criteria: normalizeCriteria(value)}}
+		if strings.EqualFold(name, KeywordMatch) {
+			next.block.Keyword = KeywordMatch
+		}
+		if current == nil {
+			f.preamble, next.lead = splitLead(f.preamble)
+		} else {
+			current.body, next.lead = splitLead(current.body)
+		}
+		f.sections = append(f.sections, next)
+		current = next
+	}
+	return f, nil
+}
+
+// parseLine splits a line into its keyword and arguments. Blank lines and comments are not
+// options.
+func parseLine(text string) (string, string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	end := strings.IndexAny(trimmed, " \t=")
+	if end < 0 {
+		return trimmed, "", true
+	}
+	rest := strings.TrimLeft(trimmed[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+	return trimmed[:end], strings.TrimSpace(rest), true
+}
+
+// splitLead splits off the comment lines at the end of lines.
+func splitLead(lines []string) ([]string, []string) {
+	i := len(lines)
+	for i > 0 && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "#") {
+		i--
+	}
+	return lines[:i], append([]string{}, lines[i:]...)
+}
+
+func normalizeCriteria(criteria string) string {
+	return strings.Join(strings.Fields(criteria), " ")
+}
+
+// String returns the file content. A non-empty file ends with a newline.
+func (f *File) String() string {
+	lines := append([]string{}, f.preamble...)
+	for _, s := range f.sections {
+		lines = append(lines, s.lines()...)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, f.newline) + f.newline
+}
+
+// Blocks returns the Host and Match sections in file order.
+func (f *File) Blocks() []Block {
+	blocks := make([]Block, 0, len(f.sections))
+	for _, s := range f.sections {
+		blocks = append(blocks, s.block)
+	}
+	return blocks
+}
+
+// Find returns the section with the given ID.
+func (f *File) Find(id string) (Block, bool) {
+	if index := f.index(id); index >= 0 {
+		return f.sections[index].block, true
+	}
+	return Block{}, false
+}
+
+func (f *File) index(id string) int {
+	for i, s := range f.sections {
+		if s.block.ID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Upsert replaces the section with the block's ID, keeping the comments above it and the
+// blank lines after it. A new section is added before the first `Host *` section, so the
+// catch-all defaults keep applying last, or at the end of the file.
+func (f *File) Upsert(block Block) error {
+	block.Criteria = normalizeCriteria(block.Criteria)
+	if block.Criteria == "" {
+		return fmt.Errorf("%s section without criteria", block.Keyword)
+	}
+	header := block.Keyword + " " + block.Criteria
+	indent := f.indent()
+	body := make([]string, 0, len(block.Options))
+	for _, option := range block.Options {
+		if strings.ContainsAny(option.Value, "\r\n") || strings.TrimSpace(option.Value) == "" {
+			return fmt.Errorf("option %s of %s must be a non-empty single line", option.Name, header)
+		}
+		body = append(body, indent+option.Name+" "+option.Value)
+	}
+
+	if index := f.index(block.ID()); index >= 0 {
+		existing := f.sections[index]
+		if existing.block.Canonical() == block.Canonical() {
+			return nil
+		}
+		trailing := len(existing.body)
+		for trailing > 0 && strings.TrimSpace(existing.body[trailing-1]) == "" {
+			trailing--
+		}
+		existing.header = header
+		existing.body = append(body, existing.body[trailing:]...)
+		existing.block = block
+		return nil
+	}
+
+	added := &section{block: block, header: header, body: body}
+	for i, s := range f.sections {
+		if s.block.Keyword == KeywordHost && s.block.Criteria == "*" {
+			added.body = append(added.body, "")
+			f.sections = append(f.sections[:i], append([]*section{added}, f.sections[i:]...)...)
+			return nil
+		}
+	}
+	f.separate()
+	f.sections = append(f.sections, added)
+	return nil
+}
+
+// Remove deletes the section with the given ID, with the comments above it.
+func (f *File) Remove(id string) bool {
+	index := f.index(id)
+	if index < 0 {
+		return false
+	}
+	f.sections = append(f.sections[:index], f.sections[index+1:]...)
+	f.trimEnd()
+	return true
+}
+
+// Includes returns the arguments of the Include lines before the first section, which
+// apply to every host.
+func (f *File) Includes() []string {
+	var includes []string
+	for _, text := range f.preamble {
+		if name, value, ok := parseLine(text); ok && strings.EqualFold(name, "Include") {
+			includes = append(includes, value)
+		}
+	}
+	return includes
+}
+
+// AddInclude adds an Include line before the first section, after any existing ones, unless
+// one with the same argument is there.
+func (f *File) AddInclude(path string) error {
+	if strings.ContainsAny(path, "\r\n") || strings.TrimSpace(path) == "" {
+		return fmt.Errorf("include path must be a non-empty single line")
+	}
+	position := 0
+	for i, text := range f.preamble {
+		if name, value, ok := parseLine(text); ok && strings.EqualFold(name, "Include") {
+			if value == path {
+				return nil
+			}
+			position = i + 1
+		}
+	}
+	line := []string{"Include " + path}
+	if position == 0 && len(f.preamble) == 0 && len(f.sections) > 0 {
+		line = append(line, "")
+	}
+	f.preamble = append(f.preamble[:position], append(line, f.preamble[position:]...)...)
+	return nil
+}
+
+// RemoveInclude removes the Include lines with the given argument before the first section.
+func (f *File) RemoveInclude(path string) bool {
+	kept := f.preamble[:0]
+	removed := false
+	for _, text := range f.preamble {
+		if name, value, ok := parseLine(text); ok && strings.EqualFold(name, "Include") && value == path {
+			removed = true
+			continue
+		}
+		kept = append(kept, text)
+	}
+	f.preamble = kept
+	if removed && len(f.preamble) > 0 && strings.TrimSpace(f.preamble[0]) == "" {
+		f.preamble = f.preamble[1:]
+	}
+	f.trimEnd()
+	return removed
+}
+
+// indent returns the indentation of the first indented option, or four spaces.
+func (f *File) indent() string {
+	for _, s := range f.sections {
+		for _, text := range s.body {
+			if _, _, ok := parseLine(text); ok {
+				if indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]; indent != "" {
+					return indent
+				}
+			}
+		}
+	}
+	return "    "
+}
+
+// separate ends the file with a blank line, before a section is appended.
+func (f *File) separate() {
+	if len(f.sections) == 0 {
+		if len(f.preamble) > 0 && strings.TrimSpace(f.preamble[len(f.preamble)-1]) != "" {
+			f.preamble = append(f.preamble, "")
+		}
+		return
+	}
+	last := f.sections[len(f.sections)-1]
+	if len(last.body) == 0 || strings.TrimSpace(last.body[len(last.body)-1]) != "" {
+		last.body = append(last.body, "")
+	}
+}
+
+// trimEnd removes blank lines at the end of the file.
+func (f *File) trimEnd() {
+	if len(f.sections) == 0 {
+		for len(f.preamble) > 0 && strings.TrimSpace(f.preamble[len(f.preamble)-1]) == "" {
+			f.preamble = f.preamble[:len(f.preamble)-1]
+		}
+		return
+	}
+	last := f.sections[len(f.sections)-1]
+	for len(last.body) > 0 && strings.TrimSpace(last.body[len(last.body)-1]) == "" {
+		last.body = last.body[:len(last.body)-1]
+	}
+}
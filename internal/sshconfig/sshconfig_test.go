@@ -0,0 +1,194 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package sshconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+const config = `# ~/.ssh/config
+Include ~/.orbstack/ssh/config
+
+# Work bastion
+Host bastion
+  HostName bastion.corp.example
+  User ops
+
+Match host *.corp.example   exec "test -f ~/.vpn"
+  ProxyJump bastion
+
+Host *
+  ServerAliveInterval 60
+  AddKeysToAgent yes
+`
+
+func mustParse(t *testing.T, data string) *File {
+	t.Helper()
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	f := mustParse(t, config)
+	if f.String() != config {
+		t.Errorf("Expected an unchanged file to round-trip, got:\n%s", f.String())
+	}
+	var ids []string
+	for _, block := range f.Blocks() {
+		ids = append(ids, block.ID())
+	}
+	expected := []string{"host bastion", `match host *.corp.example exec "test -f ~/.vpn"`, "host *"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected sections %v, got %v", expected, ids)
+	}
+	block, ok := f.Find("host bastion")
+	if !ok || !reflect.DeepEqual(block.Options, []Option{{"HostName", "bastion.corp.example"}, {"User", "ops"}}) {
+		t.Errorf("Unexpected bastion section %+v", block)
+	}
+	if includes := f.Includes(); !reflect.DeepEqual(includes, []string{"~/.orbstack/ssh/config"}) {
+		t.Errorf("Unexpected includes %v", includes)
+	}
+
+	equals := mustParse(t, "host=github.com\n\tuser = git\n")
+	if block, ok := equals.Find("host github.com"); !ok || block.Options[0] != (Option{"user", "git"}) {
+		t.Errorf("Expected = separators to be parsed, got %+v", block)
+	}
+	if _, err := Parse("Host\n"); err == nil {
+		t.Error("Expected an error for a Host line without patterns")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	f := mustParse(t, config)
+	github := Block{Keyword: KeywordHost, Criteria: "github.com gh", Options: []Option{
+		{"HostName", "github.com"},
+		{"User", "git"},
+		{"IdentityFile", "~/.ssh/github"},
+	}}
+	if err := f.Upsert(github); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	bastion := Block{Keyword: KeywordHost, Criteria: "bastion", Options: []Option{{"HostName", "bastion.corp.example"}, {"User", "admin"}}}
+	if err := f.Upsert(bastion); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	expected := `# ~/.ssh/config
+Include ~/.orbstack/ssh/config
+
+# Work bastion
+Host bastion
+  HostName bastion.corp.example
+  User admin
+
+Match host *.corp.example   exec "test -f ~/.vpn"
+  ProxyJump bastion
+
+Host github.com gh
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/github
+
+Host *
+  ServerAliveInterval 60
+  AddKeysToAgent yes
+`
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// Reordered options and other layout are not a change
+	reordered := Block{Keyword: KeywordHost, Criteria: "github.com  gh", Options: []Option{
+		{"user", "git"},
+		{"IdentityFile", "~/.ssh/github"},
+		{"HostName", "github.com"},
+	}}
+	if found, _ := f.Find("host github.com gh"); found.Canonical() != reordered.Canonical() {
+		t.Error("Expected option order and case not to matter")
+	}
+	if err := f.Upsert(reordered); err != nil || f.String() != expected {
+		t.Errorf("Expected an equivalent section to be left alone, got:\n%s", f.String())
+	}
+}
+
+func TestUpsertAppends(t *testing.T) {
+	f := mustParse(t, "# nothing yet\n")
+	if err := f.Upsert(Block{Keyword: KeywordMatch, Criteria: "user deploy", Options: []Option{{"IdentityFile", "~/.ssh/deploy"}, {"IdentityFile", "~/.ssh/deploy-old"}}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := f.AddInclude("config.d/*"); err != nil {
+		t.Fatalf("AddInclude failed: %v", err)
+	}
+	expected := "Include config.d/*\n# nothing yet\n\nMatch user deploy\n    IdentityFile ~/.ssh/deploy\n    IdentityFile ~/.ssh/deploy-old\n"
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if err := f.Upsert(Block{Keyword: KeywordHost, Criteria: "x", Options: []Option{{"User", "two\nlines"}}}); err == nil {
+		t.Error("Expected an error for a multi-line option")
+	}
+}
+
+func TestRemove(t *testing.T) {
+	f := mustParse(t, config)
+	if !f.Remove("host bastion") || !f.RemoveInclude("~/.orbstack/ssh/config") {
+		t.Fatal("Expected the section and the include to be removed")
+	}
+	if f.Remove("host missing") {
+		t.Error("Expected a missing section not to be removed")
+	}
+	expected := `# ~/.ssh/config
+
+Match host *.corp.example   exec "test -f ~/.vpn"
+  ProxyJump bastion
+
+Host *
+  ServerAliveInterval 60
+  AddKeysToAgent yes
+`
+	if got := f.String(); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	f.Remove("host *")
+	if got := f.String(); got != "# ~/.ssh/config\n\nMatch host *.corp.example   exec \"test -f ~/.vpn\"\n  ProxyJump bastion\n" {
+		t.Errorf("Expected trailing blank lines to be trimmed, got:\n%s", got)
+	}
+}
+
+func TestIsOption(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"HostName":     true,
+		"identityfile": true,
+		"UseKeychain":  true,
+		"Host":         false,
+		"Match":        false,
+		"HostNmae":     false,
+	} {
+		if IsOption(name) != expected {
+			t.Errorf("IsOption(%s) = %v, expected %v", name, !expected, expected)
+		}
+	}
+	if CanonicalOption("hostname") != "HostName" {
+		t.Errorf("Expected the documented spelling, got %s", CanonicalOption("hostname"))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(config); err != nil {
+		t.Errorf("Expected a valid file, got %v", err)
+	}
+	if err := Validate("Host x\n  HostNmae x.example\n"); err == nil || err.Error() != "line 2: unknown option HostNmae" {
+		t.Errorf("Expected an unknown option error, got %v", err)
+	}
+	if err := Validate("IgnoreUnknown Vendor*\nHost x\n  VendorOption yes\n"); err != nil {
+		t.Errorf("Expected IgnoreUnknown to allow other options, got %v", err)
+	}
+	if err := Validate("Match\n"); err == nil {
+		t.Error("Expected a structural error")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// builtinFunctions are the functions text/template provides to every template.
+var builtinFunctions = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf",
+	"println", "urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// Reference is a name used by a template and the line it is used on.
+type Reference struct {
+	Name string
+	Line int
+}
+
+// Analysis is the result of statically checking a template against the context it will be
+// rendered with.
+type Analysis struct {
+	// Undefined lists variables missing from the context, e.g. `user.email`.
+	Undefined []Reference
+	// UnknownFunctions lists functions the engine does not provide.
+	UnknownFunctions []Reference
+	// Referenced holds the top-level context variables the template uses.
+	Referenced map[string]bool
+	// WholeContext reports that the template passes the whole context on, to a named
+	// template or a range over it, so Referenced may be incomplete.
+	WholeContext bool
+}
+
+// Analyze parses a template for the given engine without executing it and checks the
+// variables and functions it uses. Fields are only checked where the template's dot is the
+// context itself, not inside range or with blocks or named templates, and only as deep as
+// the context holds maps.
+func Analyze(engineType, templateContent string, context map[string]interface{}, customFunctions map[string]interface{}) (*Analysis, error) {
+	functions := getDefaultTemplateFunctions()
+	switch engineType {
+	case "", "go":
+	case "handlebars":
+		templateContent = convertHandlebarsToGo(templateContent)
+	case "mustache":
+		templateContent = convertMustacheToGo(templateContent)
+	case "chezmoi":
+		for name, fn := range chezmoiFunctions() {
+			functions[name] = fn
+		}
+		context = chezmoiContext(context)
+	default:
+		return nil, fmt.Errorf("unsupported template engine: %s", engineType)
+	}
+	for name, fn := range customFunctions {
+		functions[name] = fn
+	}
+	for _, name := range builtinFunctions {
+		functions[name] = nil
+	}
+
+	root := parse.New("template")
+	root.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := root.Parse(templateContent, "", "", trees); err != nil {
+		return nil, err
+	}
+
+	a := &analyzer{
+		content:   templateContent,
+		context:   context,
+		functions: functions,
+		result:    &Analysis{Referenced: make(map[string]bool)},
+	}
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if trees[name].Root != nil {
+			a.walk(trees[name].Root, name == root.Name)
+		}
+	}
+	return a.result, nil
+}
+
+type analyzer struct {
+	content   string
+	context   map[string]interface{}
+	functions map[string]interface{}
+	result    *Analysis
+}
+
+// walk checks a node. atRoot reports whether dot is the context itself.
+func (a *analyzer) walk(node parse.Node, atRoot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			a.walk(child, atRoot)
+		}
+	case *parse.ActionNode:
+		a.pipe(n.Pipe, atRoot)
+	case *parse.IfNode:
+		a.pipe(n.Pipe, atRoot)
+		a.walk(n.List, atRoot)
+		a.walk(n.ElseList, atRoot)
+	case *parse.RangeNode:
+		a.pipe(n.Pipe, atRoot)
+		a.walk(n.List, false)
+		a.walk(n.ElseList, atRoot)
+	case *parse.WithNode:
+		a.pipe(n.Pipe, atRoot)
+		a.walk(n.List, false)
+		a.walk(n.ElseList, atRoot)
+	case *parse.TemplateNode:
+		a.pipe(n.Pipe, atRoot)
+	}
+}
+
+func (a *analyzer) pipe(pipe *parse.PipeNode, atRoot bool) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			a.arg(arg, atRoot)
+		}
+	}
+}
+
+func (a *analyzer) arg(node parse.Node, atRoot bool) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		if atRoot {
+			a.field(n.Ident, n.Position())
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" {
+			return
+		}
+		if len(n.Ident) == 1 {
+			a.result.WholeContext = true
+		} else {
+			a.field(n.Ident[1:], n.Position())
+		}
+	case *parse.DotNode:
+		if atRoot {
+			a.result.WholeContext = true
+		}
+	case *parse.ChainNode:
+		a.arg(n.Node, atRoot)
+	case *parse.PipeNode:
+		a.pipe(n, atRoot)
+	case *parse.IdentifierNode:
+		if _, ok := a.functions[n.Ident]; !ok {
+			a.result.UnknownFunctions = append(a.result.UnknownFunctions, Reference{Name: n.Ident, Line: a.line(n.Position())})
+		}
+	}
+}
+
+// field checks a field chain on the context, e.g. `.user.email`.
+func (a *analyzer) field(idents []string, pos parse.Pos) {
+	a.result.Referenced[idents[0]] = true
+	var current interface{} = a.context
+	for i, ident := range idents {
+		values, ok := current.(map[string]interface{})
+		if !ok {
+			return
+		}
+		value, ok := values[ident]
+		if !ok {
+			a.result.Undefined = append(a.result.Undefined, Reference{Name: strings.Join(idents[:i+1], "."), Line: a.line(pos)})
+			return
+		}
+		current = value
+	}
+}
+
+func (a *analyzer) line(pos parse.Pos) int {
+	return strings.Count(a.content[:min(int(pos), len(a.content))], "\n") + 1
+}
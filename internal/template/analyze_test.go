@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	context := BuildPlatformAwareTemplateContext(
+		map[string]interface{}{"platform": "linux"},
+		map[string]interface{}{"name": "Test", "email": "test@example.com", "editor": "vim"},
+		nil,
+		nil,
+	)
+	content := `[user]
+	name = {{ .name | upper }}
+	email = {{ .mail }}
+{{- if eq .system.platform "macos" }}
+	helper = {{ .system.helper }}
+{{- end }}
+{{ range .hosts }}{{ .undetected }}{{ end }}
+{{ with .name }}{{ . }}{{ end }}
+{{ shout $.name }}`
+
+	analysis, err := Analyze("go", content, context, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	expectedUndefined := []Reference{{"mail", 3}, {"system.helper", 5}, {"hosts", 7}}
+	if !reflect.DeepEqual(analysis.Undefined, expectedUndefined) {
+		t.Errorf("Expected undefined %v, got %v", expectedUndefined, analysis.Undefined)
+	}
+	if !reflect.DeepEqual(analysis.UnknownFunctions, []Reference{{"shout", 9}}) {
+		t.Errorf("Expected the unknown function shout, got %v", analysis.UnknownFunctions)
+	}
+	if !analysis.Referenced["name"] || analysis.Referenced["editor"] || analysis.WholeContext {
+		t.Errorf("Unexpected references %v (whole context %v)", analysis.Referenced, analysis.WholeContext)
+	}
+
+	// Custom functions are known, and passing the context on makes references incomplete
+	analysis, err = Analyze("go", `{{ shout "x" }}{{ template "t" . }}{{ define "t" }}{{ .anything }}{{ end }}`, context, map[string]interface{}{"shout": "x"})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(analysis.UnknownFunctions) != 0 || len(analysis.Undefined) != 0 || !analysis.WholeContext {
+		t.Errorf("Unexpected analysis %+v", analysis)
+	}
+
+	if _, err := Analyze("go", "{{ if .name }}", context, nil); err == nil {
+		t.Error("Expected a syntax error")
+	}
+}
+
+func TestAnalyzeEngines(t *testing.T) {
+	context := map[string]interface{}{"name": "Test"}
+	analysis, err := Analyze("handlebars", "{{name}}\n{{#if missing}}x{{/if}}\n", context, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !reflect.DeepEqual(analysis.Undefined, []Reference{{"missing", 2}}) {
+		t.Errorf("Unexpected undefined variables %v", analysis.Undefined)
+	}
+
+	analysis, err = Analyze("chezmoi", "{{ .chezmoi.os }} {{ joinPath .chezmoi.homeDir \"x\" }}", context, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(analysis.Undefined) != 0 || len(analysis.UnknownFunctions) != 0 {
+		t.Errorf("Expected chezmoi variables and functions to be known, got %+v", analysis)
+	}
+}
+
+func TestStrictTemplateEngine(t *testing.T) {
+	for _, engineType := range []string{"go", "handlebars", "mustache", "chezmoi"} {
+		engine, err := CreateStrictTemplateEngine(engineType, nil)
+		if err != nil {
+			t.Fatalf("CreateStrictTemplateEngine(%s) failed: %v", engineType, err)
+		}
+		content := "{{ .email }}"
+		if _, err := engine.ProcessTemplate(content, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "email") {
+			t.Errorf("Expected %s to fail on a missing variable, got %v", engineType, err)
+		}
+		if result, err := engine.ProcessTemplate(content, map[string]interface{}{"email": "a@b"}); err != nil || result != "a@b" {
+			t.Errorf("Expected %s to render a defined variable, got %q, %v", engineType, result, err)
+		}
+	}
+
+	engine, _ := CreateTemplateEngine("go")
+	if result, _ := engine.ProcessTemplate("{{ .email }}", map[string]interface{}{}); result != "<no value>" {
+		t.Errorf("Expected the default engine to keep rendering <no value>, got %q", result)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package template
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// ChezmoiTemplateEngine renders Go templates written for chezmoi. It adds shims for the
+// chezmoi and sprig functions dotfiles commonly use and a .chezmoi variable describing
+// the local machine. Functions that run commands or read secrets are not provided.
+type ChezmoiTemplateEngine struct {
+	*GoTemplateEngine
+}
+
+// NewChezmoiTemplateEngine creates a Go template engine with chezmoi function shims.
+func NewChezmoiTemplateEngine() (*ChezmoiTemplateEngine, error) {
+	engine, err := NewGoTemplateEngineWithFunctions(chezmoiFunctions())
+	if err != nil {
+		return nil, err
+	}
+	return &ChezmoiTemplateEngine{GoTemplateEngine: engine}, nil
+}
+
+// ProcessTemplate processes a chezmoi template string with the given context.
+func (e *ChezmoiTemplateEngine) ProcessTemplate(templateContent string, context map[string]interface{}) (string, error) {
+	return e.GoTemplateEngine.ProcessTemplate(templateContent, chezmoiContext(context))
+}
+
+// ProcessTemplateFile processes a chezmoi template file and writes the result to output file.
+func (e *ChezmoiTemplateEngine) ProcessTemplateFile(templatePath, outputPath string, context map[string]interface{}, fileMode string) error {
+	return e.GoTemplateEngine.ProcessTemplateFile(templatePath, outputPath, chezmoiContext(context), fileMode)
+}
+
+// chezmoiFunctions returns the chezmoi and sprig functions available to chezmoi templates.
+func chezmoiFunctions() template.FuncMap {
+	return template.FuncMap{
+		"env":      os.Getenv,
+		"joinPath": filepath.Join,
+		"lookPath": func(file string) string {
+			path, err := exec.LookPath(file)
+			if err != nil {
+				return ""
+			}
+			return path
+		},
+		"stat": func(name string) interface{} {
+			info, err := os.Stat(name)
+			if err != nil {
+				return nil
+			}
+			return map[string]interface{}{
+				"name":  info.Name(),
+				"size":  info.Size(),
+				"mode":  int(info.Mode()),
+				"perm":  int(info.Mode().Perm()),
+				"isDir": info.IsDir(),
+			}
+		},
+		"isExecutable": func(name string) bool {
+			info, err := os.Stat(name)
+			return err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0
+		},
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"quote":      func(s string) string { return `"` + s + `"` },
+		"squote":     func(s string) string { return "'" + s + "'" },
+	}
+}
+
+// chezmoiContext returns a copy of context with the .chezmoi variable set, unless the
+// context already defines one.
+func chezmoiContext(context map[string]interface{}) map[string]interface{} {
+	if _, ok := context["chezmoi"]; ok {
+		return context
+	}
+
+	result := make(map[string]interface{}, len(context)+1)
+	for key, value := range context {
+		result[key] = value
+	}
+
+	fqdn, _ := os.Hostname()
+	hostname, _, _ := strings.Cut(fqdn, ".")
+	homeDir, _ := os.UserHomeDir()
+	if system, ok := context["system"].(map[string]interface{}); ok {
+		if dir, ok := system["home_dir"].(string); ok && dir != "" {
+			homeDir = dir
+		}
+	}
+	username := ""
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+
+	result["chezmoi"] = map[string]interface{}{
+		"os":           runtime.GOOS,
+		"arch":         runtime.GOARCH,
+		"hostname":     hostname,
+		"fqdnHostname": fqdn,
+		"homeDir":      homeDir,
+		"username":     username,
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0.
+
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestChezmoiTemplateEngine(t *testing.T) {
+	engine, err := CreateTemplateEngine("chezmoi")
+	if err != nil {
+		t.Fatalf("Failed to create chezmoi engine: %v", err)
+	}
+
+	t.Setenv("CHEZMOI_TEST_EDITOR", "nvim")
+	context := map[string]interface{}{
+		"email":  "me@example.com",
+		"system": map[string]interface{}{"home_dir": "/home/test"},
+	}
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"data variable", `email = {{ .email }}`, "email = me@example.com"},
+		{"os and arch", `{{ .chezmoi.os }}/{{ .chezmoi.arch }}`, runtime.GOOS + "/" + runtime.GOARCH},
+		{"home directory from system info", `{{ joinPath .chezmoi.homeDir ".config" }}`, filepath.Join("/home/test", ".config")},
+		{"env", `{{ env "CHEZMOI_TEST_EDITOR" }}`, "nvim"},
+		{"sprig argument order", `{{ if hasPrefix "me@" .email }}{{ replace "@" " at " .email | quote }}{{ end }}`, `"me at example.com"`},
+		{"missing command", `{{ if lookPath "definitely-not-a-command" }}found{{ else }}missing{{ end }}`, "missing"},
+		{"stat of missing file", `{{ if stat "/definitely/not/here" }}found{{ else }}missing{{ end }}`, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.ProcessTemplate(tt.template, context)
+			if err != nil {
+				t.Fatalf("ProcessTemplate failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("keeps a configured chezmoi variable", func(t *testing.T) {
+		result, err := engine.ProcessTemplate(`{{ .chezmoi.os }}`, map[string]interface{}{
+			"chezmoi": map[string]interface{}{"os": "plan9"},
+		})
+		if err != nil || result != "plan9" {
+			t.Errorf("Expected the configured chezmoi variable, got %q (%v)", result, err)
+		}
+	})
+
+	t.Run("renders files", func(t *testing.T) {
+		dir := t.TempDir()
+		source := filepath.Join(dir, "gitconfig.tmpl")
+		output := filepath.Join(dir, "gitconfig")
+		if err := os.WriteFile(source, []byte(`os = {{ .chezmoi.os }}`), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if err := engine.ProcessTemplateFile(source, output, context, "0600"); err != nil {
+			t.Fatalf("ProcessTemplateFile failed: %v", err)
+		}
+		content, err := os.ReadFile(output)
+		if err != nil || string(content) != "os = "+runtime.GOOS {
+			t.Errorf("Unexpected rendered file %q (%v)", content, err)
+		}
+	})
+}
@@ -24,6 +24,7 @@ type TemplateEngine interface {
 // GoTemplateEngine implements TemplateEngine using Go templates.
 type GoTemplateEngine struct {
 	functions template.FuncMap
+	strict    bool
 }
 
 // NewGoTemplateEngine creates a new Go template engine with default functions.
@@ -52,6 +53,15 @@ func NewGoTemplateEngineWithFunctions(customFunctions map[string]interface{}) (*
 	return engine, nil
 }
 
+// missingKeyOption returns the text/template option for variables missing from the context.
+// Strict engines fail on them instead of rendering `<no value>`.
+func missingKeyOption(strict bool) string {
+	if strict {
+		return "missingkey=error"
+	}
+	return "missingkey=default"
+}
+
 // getDefaultTemplateFunctions returns the standard template functions used across all engines.
 func getDefaultTemplateFunctions() template.FuncMap {
 	return template.FuncMap{
@@ -107,7 +117,7 @@ func getDefaultTemplateFunctions() template.FuncMap {
 // ProcessTemplate processes a template string with the given context.
 func (e *GoTemplateEngine) ProcessTemplate(templateContent string, context map[string]interface{}) (string, error) {
 	// Create template with custom functions
-	tmpl, err := template.New("template").Funcs(e.functions).Parse(templateContent)
+	tmpl, err := template.New("template").Funcs(e.functions).Option(missingKeyOption(e.strict)).Parse(templateContent)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
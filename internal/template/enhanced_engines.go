@@ -17,11 +17,13 @@ import (
 // HandlebarsTemplateEngine implements TemplateEngine using Handlebars-style syntax.
 type HandlebarsTemplateEngine struct {
 	functions template.FuncMap
+	strict    bool
 }
 
 // MustacheTemplateEngine implements TemplateEngine using Mustache-style syntax.
 type MustacheTemplateEngine struct {
 	functions template.FuncMap
+	strict    bool
 }
 
 // NewHandlebarsTemplateEngine creates a new Handlebars-style template engine.
@@ -51,7 +53,7 @@ func (e *HandlebarsTemplateEngine) ProcessTemplate(templateContent string, conte
 	converted := convertHandlebarsToGo(templateContent)
 
 	// Create Go template with functions
-	tmpl, err := template.New("handlebars").Funcs(e.functions).Parse(converted)
+	tmpl, err := template.New("handlebars").Funcs(e.functions).Option(missingKeyOption(e.strict)).Parse(converted)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse handlebars template: %w", err)
 	}
@@ -121,7 +123,7 @@ func (e *MustacheTemplateEngine) ProcessTemplate(templateContent string, context
 	converted := convertMustacheToGo(templateContent)
 
 	// Create Go template with functions
-	tmpl, err := template.New("mustache").Funcs(e.functions).Parse(converted)
+	tmpl, err := template.New("mustache").Funcs(e.functions).Option(missingKeyOption(e.strict)).Parse(converted)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse mustache template: %w", err)
 	}
@@ -304,6 +306,8 @@ func CreateTemplateEngine(engineType string) (TemplateEngine, error) {
 		return NewHandlebarsTemplateEngine()
 	case "mustache":
 		return NewMustacheTemplateEngine()
+	case "chezmoi":
+		return NewChezmoiTemplateEngine()
 	default:
 		return nil, fmt.Errorf("unsupported template engine: %s", engineType)
 	}
@@ -334,23 +338,52 @@ func CreateTemplateEngineWithFunctions(engineType string, customFunctions map[st
 			engine.functions[name] = fn
 		}
 		return engine, nil
+	case "chezmoi":
+		engine, err := NewChezmoiTemplateEngine()
+		if err != nil {
+			return nil, err
+		}
+		for name, fn := range customFunctions {
+			engine.functions[name] = fn
+		}
+		return engine, nil
 	default:
 		return nil, fmt.Errorf("unsupported template engine: %s", engineType)
 	}
 }
 
+// CreateStrictTemplateEngine creates a template engine with custom functions that fails on
+// variables missing from the context instead of rendering them as `<no value>`.
+func CreateStrictTemplateEngine(engineType string, customFunctions map[string]interface{}) (TemplateEngine, error) {
+	engine, err := CreateTemplateEngineWithFunctions(engineType, customFunctions)
+	if err != nil {
+		return nil, err
+	}
+	switch e := engine.(type) {
+	case *GoTemplateEngine:
+		e.strict = true
+	case *ChezmoiTemplateEngine:
+		e.strict = true
+	case *HandlebarsTemplateEngine:
+		e.strict = true
+	case *MustacheTemplateEngine:
+		e.strict = true
+	}
+	return engine, nil
+}
+
 // BuildPlatformAwareTemplateContext creates template context with platform-specific variables.
-func BuildPlatformAwareTemplateContext(systemInfo, userVars map[string]interface{}, platformVars map[string]map[string]interface{}) map[string]interface{} {
+// Variables are layered with a fixed precedence: values loaded from data files are
+// overridden by the current platform's variables, which are overridden by user variables.
+// `system` and `platform_vars` are always the provider's.
+func BuildPlatformAwareTemplateContext(systemInfo, userVars map[string]interface{}, platformVars map[string]map[string]interface{}, dataVars map[string]interface{}) map[string]interface{} {
 	context := make(map[string]interface{})
 
-	// Add user vars at root level
-	for k, v := range userVars {
+	// Add data file values at root level
+	for k, v := range dataVars {
 		context[k] = v
 	}
 
-	// Add system info
-	context["system"] = systemInfo
-
 	// Add platform-specific vars based on current platform
 	if platform, ok := systemInfo["platform"].(string); ok {
 		if platformSpecific, exists := platformVars[platform]; exists {
@@ -360,6 +393,14 @@ func BuildPlatformAwareTemplateContext(systemInfo, userVars map[string]interface
 		}
 	}
 
+	// Add user vars at root level
+	for k, v := range userVars {
+		context[k] = v
+	}
+
+	// Add system info
+	context["system"] = systemInfo
+
 	// Add platform vars to context for template functions
 	context["platform_vars"] = platformVars
 
@@ -183,7 +183,7 @@ func TestPlatformAwareTemplateContext(t *testing.T) {
 	}
 
 	t.Run("Build platform-aware template context", func(t *testing.T) {
-		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars)
+		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars, nil)
 
 		// Verify user vars are included
 		if context["user_name"] != "Test User" {
@@ -224,7 +224,7 @@ func TestPlatformAwareTemplateContext(t *testing.T) {
 [homebrew]
     prefix = {{.homebrew_path}}`
 
-		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars)
+		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars, nil)
 		result, err := engine.ProcessTemplate(templateContent, context)
 		if err != nil {
 			t.Fatalf("Template processing failed: %v", err)
@@ -240,6 +240,29 @@ func TestPlatformAwareTemplateContext(t *testing.T) {
 			t.Error("Template should contain macOS homebrew path")
 		}
 	})
+
+	t.Run("Variable precedence", func(t *testing.T) {
+		dataVars := map[string]interface{}{
+			"editor":    "nano",
+			"diff_tool": "diff",
+			"team":      "platform",
+			"system":    "overridden",
+		}
+		context := BuildPlatformAwareTemplateContext(systemInfo, map[string]interface{}{"editor": "vim"}, platformVars, dataVars)
+
+		if context["editor"] != "vim" {
+			t.Errorf("User variables should override data files, got %v", context["editor"])
+		}
+		if context["diff_tool"] != "opendiff" {
+			t.Errorf("Platform variables should override data files, got %v", context["diff_tool"])
+		}
+		if context["team"] != "platform" {
+			t.Errorf("Data file variables should be included, got %v", context["team"])
+		}
+		if _, ok := context["system"].(map[string]interface{}); !ok {
+			t.Errorf("Data files should not override system info, got %v", context["system"])
+		}
+	})
 }
 
 // TestTemplateFileProcessing tests file-based template processing with enhanced features.
@@ -283,7 +306,7 @@ func TestTemplateFileProcessing(t *testing.T) {
 			},
 		}
 
-		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars)
+		context := BuildPlatformAwareTemplateContext(systemInfo, userVars, platformVars, nil)
 
 		// Process template file
 		engine, err := NewGoTemplateEngine()
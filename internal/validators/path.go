@@ -420,3 +420,36 @@ func (v envVarValidator) ValidateString(ctx context.Context, request validator.S
 		}
 	}
 }
+
+// RepositoryRelativePath returns a validator which ensures a path is relative and stays within the repository.
+func RepositoryRelativePath() validator.String {
+	return repositoryRelativePathValidator{}
+}
+
+// repositoryRelativePathValidator validates paths inside a repository checkout.
+type repositoryRelativePathValidator struct{}
+
+func (v repositoryRelativePathValidator) Description(_ context.Context) string {
+	return "value must be a relative path inside the repository"
+}
+
+func (v repositoryRelativePathValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v repositoryRelativePathValidator) ValidateString(ctx context.Context, request validator.StringRequest, response *validator.StringResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := request.ConfigValue.ValueString()
+	cleaned := filepath.Clean(value)
+	if value == "" || strings.Contains(value, "\x00") || filepath.IsAbs(value) || strings.HasPrefix(value, "~") ||
+		cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		response.Diagnostics.AddAttributeError(
+			request.Path,
+			"Invalid Repository Path",
+			fmt.Sprintf("Path %q must be relative to the repository root and must not leave it.", value),
+		)
+	}
+}
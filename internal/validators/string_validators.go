@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 )
@@ -86,3 +87,43 @@ func OneOf(values ...string) validator.String {
 		values: values,
 	}
 }
+
+// UpdateIntervalValidator validates a duration such as "30m", or "0"/"never" to disable updates.
+type UpdateIntervalValidator struct{}
+
+// Description returns a description of the validator.
+func (v UpdateIntervalValidator) Description(_ context.Context) string {
+	return "value must be a non-negative duration (e.g. '1h', '30m'), '0' or 'never'"
+}
+
+// MarkdownDescription returns a markdown description of the validator.
+func (v UpdateIntervalValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+// ValidateString performs the validation.
+func (v UpdateIntervalValidator) ValidateString(ctx context.Context, request validator.StringRequest, response *validator.StringResponse) {
+	if request.ConfigValue.IsNull() || request.ConfigValue.IsUnknown() {
+		return
+	}
+
+	value := strings.TrimSpace(request.ConfigValue.ValueString())
+	switch strings.ToLower(value) {
+	case "0", "never":
+		return
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		response.Diagnostics.AddAttributeError(
+			request.Path,
+			"Invalid Update Interval",
+			fmt.Sprintf("Value %q is not valid. Expected a duration such as '1h' or '30m', or '0'/'never' to disable updates.", value),
+		)
+	}
+}
+
+// ValidUpdateInterval returns a validator which ensures the value is a valid update interval.
+func ValidUpdateInterval() validator.String {
+	return UpdateIntervalValidator{}
+}
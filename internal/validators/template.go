@@ -91,7 +91,7 @@ type TemplateEngineValidator struct{}
 
 // Description returns a description of the validator.
 func (v TemplateEngineValidator) Description(_ context.Context) string {
-	return "value must be a supported template engine: 'go', 'handlebars', or 'mustache'"
+	return "value must be a supported template engine: 'go', 'handlebars', 'mustache', or 'chezmoi'"
 }
 
 // MarkdownDescription returns a markdown description of the validator.
@@ -110,7 +110,7 @@ func (v TemplateEngineValidator) ValidateString(ctx context.Context, request val
 		return // Empty values default to "go"
 	}
 
-	supportedEngines := []string{"go", "handlebars", "mustache"}
+	supportedEngines := []string{"go", "handlebars", "mustache", "chezmoi"}
 	for _, engine := range supportedEngines {
 		if value == engine {
 			return // Valid engine
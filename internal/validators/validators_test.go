@@ -4,7 +4,12 @@
 package validators
 
 import (
+	"context"
 	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 func TestValidFileMode(t *testing.T) {
@@ -121,3 +126,70 @@ func TestValidTemplateName(t *testing.T) {
 		})
 	}
 }
+
+func TestValidUpdateInterval(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"duration", "30m", false},
+		{"compound duration", "1h30m", false},
+		{"zero", "0", false},
+		{"never", "never", false},
+		{"negative", "-1h", true},
+		{"missing unit", "15", true},
+		{"word", "daily", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("git_update_interval"),
+				ConfigValue: types.StringValue(tc.value),
+			}
+			resp := &validator.StringResponse{}
+			ValidUpdateInterval().ValidateString(context.Background(), req, resp)
+
+			if resp.Diagnostics.HasError() != tc.wantError {
+				t.Errorf("Value %q: expected error %v, got %v", tc.value, tc.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestRepositoryRelativePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"directory", "dotfiles", false},
+		{"nested", "home/dotfiles/", false},
+		{"empty", "", true},
+		{"current directory", ".", true},
+		{"absolute", "/etc", true},
+		{"home", "~/dotfiles", true},
+		{"parent", "../secrets", true},
+		{"escaping", "dotfiles/../../secrets", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := validator.StringRequest{
+				Path:        path.Root("subdirectory"),
+				ConfigValue: types.StringValue(tc.value),
+			}
+			resp := &validator.StringResponse{}
+			RepositoryRelativePath().ValidateString(context.Background(), req, resp)
+
+			if resp.Diagnostics.HasError() != tc.wantError {
+				t.Errorf("Value %q: expected error %v, got %v", tc.value, tc.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}